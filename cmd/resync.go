@@ -69,7 +69,7 @@ func init() {
 	// flags
 	resyncCmd.PersistentFlags().String("ipfs-path", "", "ipfs repository path")
 
-	resyncCmd.PersistentFlags().String("resync-chain", "", "which chain to support, options are currently Ethereum or Bitcoin.")
+	resyncCmd.PersistentFlags().String("resync-chain", "", "which chain to support, options are currently Ethereum, Bitcoin or Omni.")
 	resyncCmd.PersistentFlags().String("resync-type", "", "which type of data to resync")
 	resyncCmd.PersistentFlags().Int("resync-start", 0, "block height to start resync")
 	resyncCmd.PersistentFlags().Int("resync-stop", 0, "block height to stop resync")
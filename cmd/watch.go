@@ -25,6 +25,11 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/electrum"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/esplora"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/graphql"
 	h "github.com/vulcanize/ipfs-blockchain-watcher/pkg/historical"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/ipfs"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
@@ -124,11 +129,48 @@ func startServers(watcher w.Watcher, settings *w.Config) error {
 		return err
 	}
 	logWithCommand.Debug("starting up WS server")
-	_, _, err = rpc.StartWSEndpoint(settings.WSEndpoint, watcher.APIs(), []string{"vdb"}, nil, true)
+	_, _, err = rpc.StartWSEndpoint(settings.WSEndpoint, watcher.APIs(), []string{"vdb", settings.Chain.API()}, nil, true)
 	if err != nil {
 		return err
 	}
+	if settings.GraphQLEndpoint != "" {
+		logWithCommand.Debug("starting up GraphQL server")
+		backend, err := eth.NewEthBackend(settings.ServeDBConn, settings.LogLimits, settings.EthChainConfig)
+		if err != nil {
+			return err
+		}
+		if _, err := graphql.StartHTTPEndpoint(settings.GraphQLEndpoint, backend); err != nil {
+			return err
+		}
+	}
+	if settings.ElectrumEndpoint != "" {
+		logWithCommand.Debug("starting up Electrum server")
+		backend, err := btc.NewBtcBackend(settings.ServeDBConn, settings.BtcParams)
+		if err != nil {
+			return err
+		}
+		server, _, err := electrum.StartTCPEndpoint(settings.ElectrumEndpoint, backend)
+		if err != nil {
+			return err
+		}
+		watcher.Listen(server)
+	}
+	if settings.EsploraEndpoint != "" {
+		logWithCommand.Debug("starting up Esplora server")
+		backend, err := btc.NewBtcBackend(settings.ServeDBConn, settings.BtcParams)
+		if err != nil {
+			return err
+		}
+		if _, err := esplora.StartHTTPEndpoint(settings.EsploraEndpoint, backend); err != nil {
+			return err
+		}
+	}
 	logWithCommand.Debug("starting up HTTP server")
+	if settings.ProxyClient != nil {
+		logWithCommand.Debug("forwarding unsupported HTTP calls to the upstream node")
+		_, _, err = w.StartProxyHTTPEndpoint(settings.HTTPEndpoint, watcher.APIs(), []string{settings.Chain.API()}, settings.ProxyClient)
+		return err
+	}
 	_, _, err = rpc.StartHTTPEndpoint(settings.HTTPEndpoint, watcher.APIs(), []string{settings.Chain.API()}, nil, nil, rpc.HTTPTimeouts{})
 	return err
 }
@@ -140,7 +182,7 @@ func init() {
 	watchCmd.PersistentFlags().String("ipfs-path", "", "ipfs repository path")
 	watchCmd.PersistentFlags().String("ipfs-mode", "", "ipfs operation mode")
 
-	watchCmd.PersistentFlags().String("watcher-chain", "", "which chain to support, options are currently Ethereum or Bitcoin.")
+	watchCmd.PersistentFlags().String("watcher-chain", "", "which chain to support, options are currently Ethereum, Bitcoin or Omni.")
 	watchCmd.PersistentFlags().Bool("watcher-server", false, "turn vdb server on or off")
 	watchCmd.PersistentFlags().String("watcher-ws-path", "", "vdb server ws path")
 	watchCmd.PersistentFlags().String("watcher-http-path", "", "vdb server http path")
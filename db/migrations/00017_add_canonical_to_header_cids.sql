@@ -0,0 +1,31 @@
+-- +goose Up
+ALTER TABLE eth.header_cids ADD COLUMN canonical BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE btc.header_cids ADD COLUMN canonical BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Where competing headers have already been indexed at the same height, only those on the chain
+-- walked back from the tip remain canonical; heights below the first gap in that walk are left as they were
+WITH RECURSIVE canonical_chain AS (
+  (SELECT id, block_number, parent_hash FROM eth.header_cids ORDER BY block_number DESC, td::NUMERIC DESC LIMIT 1)
+  UNION ALL
+  SELECT header_cids.id, header_cids.block_number, header_cids.parent_hash FROM eth.header_cids
+  INNER JOIN canonical_chain ON (header_cids.block_number = canonical_chain.block_number - 1 AND header_cids.block_hash = canonical_chain.parent_hash)
+)
+UPDATE eth.header_cids SET canonical = false
+WHERE block_number IN (SELECT block_number FROM eth.header_cids GROUP BY block_number HAVING COUNT(*) > 1)
+AND block_number >= (SELECT MIN(block_number) FROM canonical_chain)
+AND id NOT IN (SELECT id FROM canonical_chain);
+
+WITH RECURSIVE canonical_chain AS (
+  (SELECT id, block_number, parent_hash FROM btc.header_cids ORDER BY block_number DESC LIMIT 1)
+  UNION ALL
+  SELECT header_cids.id, header_cids.block_number, header_cids.parent_hash FROM btc.header_cids
+  INNER JOIN canonical_chain ON (header_cids.block_number = canonical_chain.block_number - 1 AND header_cids.block_hash = canonical_chain.parent_hash)
+)
+UPDATE btc.header_cids SET canonical = false
+WHERE block_number IN (SELECT block_number FROM btc.header_cids GROUP BY block_number HAVING COUNT(*) > 1)
+AND block_number >= (SELECT MIN(block_number) FROM canonical_chain)
+AND id NOT IN (SELECT id FROM canonical_chain);
+
+-- +goose Down
+ALTER TABLE btc.header_cids DROP COLUMN canonical;
+ALTER TABLE eth.header_cids DROP COLUMN canonical;
@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE btc.tx_inputs ADD COLUMN spent_output_id INTEGER REFERENCES btc.tx_outputs (id) ON DELETE SET NULL DEFERRABLE INITIALLY DEFERRED;
+
+CREATE INDEX tx_inputs_outpoint_index ON btc.tx_inputs USING btree (outpoint_tx_hash, outpoint_index);
+CREATE INDEX tx_inputs_spent_output_id_index ON btc.tx_inputs USING btree (spent_output_id);
+CREATE INDEX tx_outputs_addresses_index ON btc.tx_outputs USING gin (addresses);
+
+-- Link the inputs which have already been indexed to the outputs they spend
+UPDATE btc.tx_inputs SET spent_output_id = tx_outputs.id
+FROM btc.tx_outputs, btc.transaction_cids
+WHERE tx_outputs.tx_id = transaction_cids.id
+AND transaction_cids.tx_hash = tx_inputs.outpoint_tx_hash
+AND tx_outputs.index = tx_inputs.outpoint_index;
+
+-- +goose Down
+DROP INDEX btc.tx_outputs_addresses_index;
+DROP INDEX btc.tx_inputs_spent_output_id_index;
+DROP INDEX btc.tx_inputs_outpoint_index;
+ALTER TABLE btc.tx_inputs DROP COLUMN spent_output_id;
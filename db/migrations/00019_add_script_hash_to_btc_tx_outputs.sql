@@ -0,0 +1,13 @@
+-- +goose Up
+-- pgcrypto provides digest(), which computes the script hashes of the outputs that have already been indexed
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+ALTER TABLE btc.tx_outputs ADD COLUMN script_hash BYTEA;
+UPDATE btc.tx_outputs SET script_hash = digest(pk_script, 'sha256');
+ALTER TABLE btc.tx_outputs ALTER COLUMN script_hash SET NOT NULL;
+
+CREATE INDEX tx_outputs_script_hash_index ON btc.tx_outputs USING btree (script_hash);
+
+-- +goose Down
+DROP INDEX btc.tx_outputs_script_hash_index;
+ALTER TABLE btc.tx_outputs DROP COLUMN script_hash;
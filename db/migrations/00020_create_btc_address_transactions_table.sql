@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE btc.address_transactions (
+  id               SERIAL PRIMARY KEY,
+  address          VARCHAR(66) NOT NULL,
+  tx_id            INTEGER NOT NULL REFERENCES btc.transaction_cids (id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+  UNIQUE (address, tx_id)
+);
+
+CREATE INDEX address_transactions_tx_id_index ON btc.address_transactions USING btree (tx_id);
+
+COMMENT ON TABLE btc.address_transactions IS E'@name BtcAddressTransactions';
+
+-- Index the transactions which have already been indexed under the addresses they pay to and spend from
+INSERT INTO btc.address_transactions (address, tx_id)
+SELECT unnest(addresses), tx_id FROM btc.tx_outputs
+UNION
+SELECT unnest(tx_outputs.addresses), tx_inputs.tx_id FROM btc.tx_inputs
+INNER JOIN btc.tx_outputs ON (tx_inputs.spent_output_id = tx_outputs.id);
+
+-- +goose Down
+DROP TABLE btc.address_transactions;
@@ -0,0 +1,34 @@
+-- +goose Up
+ALTER TABLE btc.tx_inputs ADD COLUMN value BIGINT;
+ALTER TABLE btc.tx_inputs ADD COLUMN script_class INTEGER;
+ALTER TABLE btc.tx_inputs ADD COLUMN addresses VARCHAR(66)[];
+
+ALTER TABLE btc.transaction_cids ADD COLUMN weight INTEGER;
+ALTER TABLE btc.transaction_cids ADD COLUMN vsize INTEGER;
+ALTER TABLE btc.transaction_cids ADD COLUMN fee BIGINT;
+ALTER TABLE btc.transaction_cids ADD COLUMN fee_rate NUMERIC;
+
+CREATE INDEX tx_inputs_addresses_index ON btc.tx_inputs USING gin (addresses);
+
+-- Resolve the inputs which have already been linked to the outputs they spend
+UPDATE btc.tx_inputs SET (value, script_class, addresses) = (tx_outputs.value, tx_outputs.script_class, tx_outputs.addresses)
+FROM btc.tx_outputs
+WHERE tx_inputs.spent_output_id = tx_outputs.id;
+
+-- The weight and vsize of the transactions which have already been indexed are only known once they are resynced, so
+-- only their fee can be backfilled
+UPDATE btc.transaction_cids SET fee = ins.value - outs.value
+FROM (SELECT tx_id, SUM(value) AS value FROM btc.tx_inputs GROUP BY tx_id HAVING COUNT(*) = COUNT(value)) ins,
+(SELECT tx_id, SUM(value) AS value FROM btc.tx_outputs GROUP BY tx_id) outs
+WHERE ins.tx_id = transaction_cids.id
+AND outs.tx_id = transaction_cids.id;
+
+-- +goose Down
+DROP INDEX btc.tx_inputs_addresses_index;
+ALTER TABLE btc.transaction_cids DROP COLUMN fee_rate;
+ALTER TABLE btc.transaction_cids DROP COLUMN fee;
+ALTER TABLE btc.transaction_cids DROP COLUMN vsize;
+ALTER TABLE btc.transaction_cids DROP COLUMN weight;
+ALTER TABLE btc.tx_inputs DROP COLUMN addresses;
+ALTER TABLE btc.tx_inputs DROP COLUMN script_class;
+ALTER TABLE btc.tx_inputs DROP COLUMN value;
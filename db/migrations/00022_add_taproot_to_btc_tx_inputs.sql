@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE btc.tx_inputs ADD COLUMN taproot_spend INTEGER NOT NULL DEFAULT 0;
+
+-- Outputs paying to witness v1 and above programs were indexed as non-standard (0); reclassify them as taproot (8) or
+-- unknown witness (9) outputs. Their bech32m addresses are only recorded once they are resynced
+UPDATE btc.tx_outputs SET script_class = CASE WHEN get_byte(pk_script, 0) = 81 AND length(pk_script) = 34 THEN 8 ELSE 9 END
+WHERE script_class = 0
+AND length(pk_script) BETWEEN 4 AND 42
+AND get_byte(pk_script, 0) BETWEEN 81 AND 96
+AND get_byte(pk_script, 1) = length(pk_script) - 2;
+
+UPDATE btc.tx_inputs SET script_class = tx_outputs.script_class
+FROM btc.tx_outputs
+WHERE tx_inputs.spent_output_id = tx_outputs.id
+AND tx_outputs.script_class IN (8, 9);
+
+-- Inputs spending taproot outputs with a single witness item are key path spends (1), the others are script path spends (2)
+UPDATE btc.tx_inputs SET taproot_spend = CASE WHEN array_length(witness, 1) = 1 THEN 1 ELSE 2 END
+WHERE script_class = 8;
+
+-- +goose Down
+UPDATE btc.tx_inputs SET script_class = 0 WHERE script_class IN (8, 9);
+UPDATE btc.tx_outputs SET script_class = 0 WHERE script_class IN (8, 9);
+ALTER TABLE btc.tx_inputs DROP COLUMN taproot_spend;
@@ -0,0 +1,36 @@
+-- +goose Up
+CREATE TABLE btc.nulldata_outputs (
+  id               SERIAL PRIMARY KEY,
+  tx_id            INTEGER NOT NULL REFERENCES btc.transaction_cids (id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+  output_id        INTEGER NOT NULL REFERENCES btc.tx_outputs (id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+  payload          BYTEA NOT NULL,
+  prefix           BYTEA NOT NULL,
+  UNIQUE (output_id)
+);
+
+CREATE INDEX nulldata_outputs_tx_id_index ON btc.nulldata_outputs USING btree (tx_id);
+
+CREATE INDEX nulldata_outputs_prefix_index ON btc.nulldata_outputs USING btree (prefix);
+
+COMMENT ON TABLE btc.nulldata_outputs IS E'@name BtcNullDataOutputs';
+COMMENT ON COLUMN btc.nulldata_outputs.prefix IS E'The first 4 bytes of the payload, which identify the protocol of most anchoring and timestamping outputs';
+
+-- Index the OP_RETURN outputs which have already been indexed and carry a single data push; those carrying several
+-- pushes are only indexed once they are resynced
+INSERT INTO btc.nulldata_outputs (tx_id, output_id, payload, prefix)
+SELECT tx_id, id, payload, substring(payload from 1 for 4) FROM (
+  SELECT tx_id, id, CASE
+    WHEN length(pk_script) = 1 THEN ''::BYTEA
+    WHEN get_byte(pk_script, 1) = 0 AND length(pk_script) = 2 THEN ''::BYTEA
+    WHEN get_byte(pk_script, 1) BETWEEN 1 AND 75 AND length(pk_script) = get_byte(pk_script, 1) + 2 THEN substring(pk_script from 3)
+    WHEN get_byte(pk_script, 1) = 76 AND length(pk_script) > 2 AND length(pk_script) = get_byte(pk_script, 2) + 3 THEN substring(pk_script from 4)
+    WHEN get_byte(pk_script, 1) = 77 AND length(pk_script) > 3 AND length(pk_script) = get_byte(pk_script, 2) + get_byte(pk_script, 3) * 256 + 4 THEN substring(pk_script from 5)
+  END AS payload
+  FROM btc.tx_outputs
+  WHERE length(pk_script) > 0
+  AND get_byte(pk_script, 0) = 106
+) nulldata
+WHERE payload IS NOT NULL;
+
+-- +goose Down
+DROP TABLE btc.nulldata_outputs;
@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE SCHEMA omni;
+
+-- +goose Down
+DROP SCHEMA omni;
\ No newline at end of file
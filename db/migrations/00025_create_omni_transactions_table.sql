@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE omni.transactions (
+  id                SERIAL PRIMARY KEY,
+  tx_id             INTEGER NOT NULL REFERENCES btc.transaction_cids (id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+  class             INTEGER NOT NULL,
+  version           INTEGER NOT NULL,
+  tx_type           INTEGER NOT NULL,
+  property_id       BIGINT,
+  amount            BIGINT,
+  sender            VARCHAR(66),
+  reference_address VARCHAR(66),
+  payload           BYTEA NOT NULL,
+  UNIQUE (tx_id)
+);
+
+CREATE INDEX transactions_tx_type_index ON omni.transactions USING btree (tx_type);
+
+CREATE INDEX transactions_property_id_index ON omni.transactions USING btree (property_id);
+
+CREATE INDEX transactions_sender_index ON omni.transactions USING btree (sender);
+
+CREATE INDEX transactions_reference_address_index ON omni.transactions USING btree (reference_address);
+
+COMMENT ON TABLE omni.transactions IS E'@name OmniTransactions';
+COMMENT ON COLUMN omni.transactions.class IS E'The Omni Layer class of the bitcoin transaction carrying the payload: 2 for class B (multisig) and 3 for class C (OP_RETURN)';
+COMMENT ON COLUMN omni.transactions.sender IS E'The address contributing the most input value, null if the outputs spent by the transaction could not be resolved';
+
+-- +goose Down
+DROP TABLE omni.transactions;
\ No newline at end of file
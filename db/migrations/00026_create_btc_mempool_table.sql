@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE btc.mempool (
+  id               SERIAL PRIMARY KEY,
+  tx_hash          VARCHAR(66) NOT NULL,
+  witness_hash     VARCHAR(66),
+  segwit           BOOLEAN NOT NULL,
+  weight           INTEGER NOT NULL,
+  vsize            INTEGER NOT NULL,
+  fee              BIGINT,
+  time             BIGINT NOT NULL,
+  addresses        VARCHAR(66)[],
+  raw              BYTEA NOT NULL,
+  UNIQUE (tx_hash)
+);
+
+CREATE INDEX mempool_addresses_index ON btc.mempool USING gin (addresses);
+
+COMMENT ON TABLE btc.mempool IS E'@name BtcMempool';
+COMMENT ON COLUMN btc.mempool.fee IS E'The fee paid by the transaction in satoshis, as reported by the node';
+COMMENT ON COLUMN btc.mempool.time IS E'The unix time at which the transaction entered the mempool of the node';
+COMMENT ON COLUMN btc.mempool.addresses IS E'The addresses paid by the outputs of the transaction';
+
+-- +goose Down
+DROP TABLE btc.mempool;
\ No newline at end of file
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+-- header_work returns the work of a header, the expected number of hashes needed to meet the target its bits encode
+CREATE FUNCTION btc.header_work(bits BIGINT) RETURNS NUMERIC AS $$
+  SELECT CASE WHEN target <= 0 OR bits & 8388608 <> 0 THEN 0
+  ELSE div(115792089237316195423570985008687907853269984665640564039457584007913129639936, target + 1) END
+  FROM (SELECT CASE WHEN bits >> 24 <= 3 THEN ((bits & 8388607) >> (8 * (3 - (bits >> 24)))::INTEGER)::NUMERIC
+    ELSE (bits & 8388607)::NUMERIC * 2::NUMERIC ^ (8 * ((bits >> 24) - 3)) END AS target) AS compact
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+ALTER TABLE btc.header_cids ADD COLUMN chainwork NUMERIC;
+
+-- Compute the chainwork of the headers which have already been indexed, walking up from the headers whose parent has not
+-- been indexed
+WITH RECURSIVE chain AS (
+  SELECT id, block_number, block_hash, btc.header_work(bits) AS chainwork FROM btc.header_cids
+  WHERE NOT EXISTS (SELECT 1 FROM btc.header_cids parent
+                    WHERE parent.block_number = header_cids.block_number - 1 AND parent.block_hash = header_cids.parent_hash)
+  UNION ALL
+  SELECT header_cids.id, header_cids.block_number, header_cids.block_hash, chain.chainwork + btc.header_work(header_cids.bits)
+  FROM btc.header_cids
+  INNER JOIN chain ON (header_cids.block_number = chain.block_number + 1 AND header_cids.parent_hash = chain.block_hash)
+)
+UPDATE btc.header_cids SET chainwork = chain.chainwork
+FROM chain
+WHERE header_cids.id = chain.id;
+
+ALTER TABLE btc.header_cids ALTER COLUMN chainwork SET NOT NULL;
+
+COMMENT ON COLUMN btc.header_cids.chainwork IS E'The cumulative work of the header and of its indexed ancestors, back to the first whose parent has not been indexed';
+
+-- +goose Down
+ALTER TABLE btc.header_cids DROP COLUMN chainwork;
+DROP FUNCTION btc.header_work(BIGINT);
\ No newline at end of file
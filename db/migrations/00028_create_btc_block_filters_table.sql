@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE btc.block_filters (
+  id               SERIAL PRIMARY KEY,
+  header_id        INTEGER NOT NULL REFERENCES btc.header_cids (id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED,
+  filter           BYTEA NOT NULL,
+  filter_hash      VARCHAR(66) NOT NULL,
+  filter_header    VARCHAR(66),
+  UNIQUE (header_id)
+);
+
+COMMENT ON TABLE btc.block_filters IS E'@name BtcBlockFilters';
+COMMENT ON COLUMN btc.block_filters.filter IS E'The BIP158 basic filter of the block';
+COMMENT ON COLUMN btc.block_filters.filter_header IS E'The BIP157 filter header, null until the filter header of the parent block is known';
+
+-- +goose Down
+DROP TABLE btc.block_filters;
\ No newline at end of file
@@ -0,0 +1,20 @@
+-- +goose Up
+-- A transaction included in competing blocks is indexed once for each of them
+ALTER TABLE btc.transaction_cids DROP CONSTRAINT transaction_cids_tx_hash_key;
+ALTER TABLE btc.transaction_cids ADD CONSTRAINT transaction_cids_header_id_tx_hash_key UNIQUE (header_id, tx_hash);
+
+CREATE INDEX transaction_cids_tx_hash_index ON btc.transaction_cids USING btree (tx_hash);
+
+-- +goose Down
+DROP INDEX btc.transaction_cids_tx_hash_index;
+
+-- Only the copy of each transaction included in a canonical block, or the most recently indexed one, is kept
+DELETE FROM btc.transaction_cids A
+USING btc.transaction_cids B, btc.header_cids A_header, btc.header_cids B_header
+WHERE A.tx_hash = B.tx_hash
+AND A.header_id = A_header.id
+AND B.header_id = B_header.id
+AND (B_header.canonical, B.id) > (A_header.canonical, A.id);
+
+ALTER TABLE btc.transaction_cids DROP CONSTRAINT transaction_cids_header_id_tx_hash_key;
+ALTER TABLE btc.transaction_cids ADD CONSTRAINT transaction_cids_tx_hash_key UNIQUE (tx_hash);
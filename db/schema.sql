@@ -30,10 +30,137 @@ CREATE SCHEMA btc;
 CREATE SCHEMA eth;
 
 
+--
+-- Name: omni; Type: SCHEMA; Schema: -; Owner: -
+--
+
+CREATE SCHEMA omni;
+
+
+--
+-- Name: pgcrypto; Type: EXTENSION; Schema: -; Owner: -
+--
+
+CREATE EXTENSION IF NOT EXISTS pgcrypto WITH SCHEMA public;
+
+
+--
+-- Name: EXTENSION pgcrypto; Type: COMMENT; Schema: -; Owner: -
+--
+
+COMMENT ON EXTENSION pgcrypto IS 'cryptographic functions';
+
+
+--
+-- Name: header_work(bigint); Type: FUNCTION; Schema: btc; Owner: -
+--
+
+CREATE FUNCTION btc.header_work(bits bigint) RETURNS numeric
+    LANGUAGE sql IMMUTABLE
+    AS $$
+  SELECT CASE WHEN target <= 0 OR bits & 8388608 <> 0 THEN 0
+  ELSE div(115792089237316195423570985008687907853269984665640564039457584007913129639936, target + 1) END
+  FROM (SELECT CASE WHEN bits >> 24 <= 3 THEN ((bits & 8388607) >> (8 * (3 - (bits >> 24)))::INTEGER)::NUMERIC
+    ELSE (bits & 8388607)::NUMERIC * 2::NUMERIC ^ (8 * ((bits >> 24) - 3)) END AS target) AS compact
+$$;
+
+
 SET default_tablespace = '';
 
 SET default_table_access_method = heap;
 
+--
+-- Name: address_transactions; Type: TABLE; Schema: btc; Owner: -
+--
+
+CREATE TABLE btc.address_transactions (
+    id integer NOT NULL,
+    address character varying(66) NOT NULL,
+    tx_id integer NOT NULL
+);
+
+
+--
+-- Name: TABLE address_transactions; Type: COMMENT; Schema: btc; Owner: -
+--
+
+COMMENT ON TABLE btc.address_transactions IS '@name BtcAddressTransactions';
+
+
+--
+-- Name: address_transactions_id_seq; Type: SEQUENCE; Schema: btc; Owner: -
+--
+
+CREATE SEQUENCE btc.address_transactions_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: address_transactions_id_seq; Type: SEQUENCE OWNED BY; Schema: btc; Owner: -
+--
+
+ALTER SEQUENCE btc.address_transactions_id_seq OWNED BY btc.address_transactions.id;
+
+
+--
+-- Name: block_filters; Type: TABLE; Schema: btc; Owner: -
+--
+
+CREATE TABLE btc.block_filters (
+    id integer NOT NULL,
+    header_id integer NOT NULL,
+    filter bytea NOT NULL,
+    filter_hash character varying(66) NOT NULL,
+    filter_header character varying(66)
+);
+
+
+--
+-- Name: TABLE block_filters; Type: COMMENT; Schema: btc; Owner: -
+--
+
+COMMENT ON TABLE btc.block_filters IS '@name BtcBlockFilters';
+
+
+--
+-- Name: COLUMN block_filters.filter; Type: COMMENT; Schema: btc; Owner: -
+--
+
+COMMENT ON COLUMN btc.block_filters.filter IS 'The BIP158 basic filter of the block';
+
+
+--
+-- Name: COLUMN block_filters.filter_header; Type: COMMENT; Schema: btc; Owner: -
+--
+
+COMMENT ON COLUMN btc.block_filters.filter_header IS 'The BIP157 filter header, null until the filter header of the parent block is known';
+
+
+--
+-- Name: block_filters_id_seq; Type: SEQUENCE; Schema: btc; Owner: -
+--
+
+CREATE SEQUENCE btc.block_filters_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: block_filters_id_seq; Type: SEQUENCE OWNED BY; Schema: btc; Owner: -
+--
+
+ALTER SEQUENCE btc.block_filters_id_seq OWNED BY btc.block_filters.id;
+
+
 --
 -- Name: header_cids; Type: TABLE; Schema: btc; Owner: -
 --
@@ -48,7 +175,9 @@ CREATE TABLE btc.header_cids (
     "timestamp" numeric NOT NULL,
     bits bigint NOT NULL,
     node_id integer NOT NULL,
-    times_validated integer DEFAULT 1 NOT NULL
+    times_validated integer DEFAULT 1 NOT NULL,
+    canonical boolean DEFAULT true NOT NULL,
+    chainwork numeric NOT NULL
 );
 
 
@@ -59,6 +188,13 @@ CREATE TABLE btc.header_cids (
 COMMENT ON TABLE btc.header_cids IS '@name BtcHeaderCids';
 
 
+--
+-- Name: COLUMN header_cids.chainwork; Type: COMMENT; Schema: btc; Owner: -
+--
+
+COMMENT ON COLUMN btc.header_cids.chainwork IS 'The cumulative work of the header and of its indexed ancestors, back to the first whose parent has not been indexed';
+
+
 --
 -- Name: COLUMN header_cids.node_id; Type: COMMENT; Schema: btc; Owner: -
 --
@@ -86,6 +222,119 @@ CREATE SEQUENCE btc.header_cids_id_seq
 ALTER SEQUENCE btc.header_cids_id_seq OWNED BY btc.header_cids.id;
 
 
+--
+-- Name: mempool; Type: TABLE; Schema: btc; Owner: -
+--
+
+CREATE TABLE btc.mempool (
+    id integer NOT NULL,
+    tx_hash character varying(66) NOT NULL,
+    witness_hash character varying(66),
+    segwit boolean NOT NULL,
+    weight integer NOT NULL,
+    vsize integer NOT NULL,
+    fee bigint,
+    "time" bigint NOT NULL,
+    addresses character varying(66)[],
+    raw bytea NOT NULL
+);
+
+
+--
+-- Name: TABLE mempool; Type: COMMENT; Schema: btc; Owner: -
+--
+
+COMMENT ON TABLE btc.mempool IS '@name BtcMempool';
+
+
+--
+-- Name: COLUMN mempool.fee; Type: COMMENT; Schema: btc; Owner: -
+--
+
+COMMENT ON COLUMN btc.mempool.fee IS 'The fee paid by the transaction in satoshis, as reported by the node';
+
+
+--
+-- Name: COLUMN mempool."time"; Type: COMMENT; Schema: btc; Owner: -
+--
+
+COMMENT ON COLUMN btc.mempool."time" IS 'The unix time at which the transaction entered the mempool of the node';
+
+
+--
+-- Name: COLUMN mempool.addresses; Type: COMMENT; Schema: btc; Owner: -
+--
+
+COMMENT ON COLUMN btc.mempool.addresses IS 'The addresses paid by the outputs of the transaction';
+
+
+--
+-- Name: mempool_id_seq; Type: SEQUENCE; Schema: btc; Owner: -
+--
+
+CREATE SEQUENCE btc.mempool_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: mempool_id_seq; Type: SEQUENCE OWNED BY; Schema: btc; Owner: -
+--
+
+ALTER SEQUENCE btc.mempool_id_seq OWNED BY btc.mempool.id;
+
+
+--
+-- Name: nulldata_outputs; Type: TABLE; Schema: btc; Owner: -
+--
+
+CREATE TABLE btc.nulldata_outputs (
+    id integer NOT NULL,
+    tx_id integer NOT NULL,
+    output_id integer NOT NULL,
+    payload bytea NOT NULL,
+    prefix bytea NOT NULL
+);
+
+
+--
+-- Name: TABLE nulldata_outputs; Type: COMMENT; Schema: btc; Owner: -
+--
+
+COMMENT ON TABLE btc.nulldata_outputs IS '@name BtcNullDataOutputs';
+
+
+--
+-- Name: COLUMN nulldata_outputs.prefix; Type: COMMENT; Schema: btc; Owner: -
+--
+
+COMMENT ON COLUMN btc.nulldata_outputs.prefix IS 'The first 4 bytes of the payload, which identify the protocol of most anchoring and timestamping outputs';
+
+
+--
+-- Name: nulldata_outputs_id_seq; Type: SEQUENCE; Schema: btc; Owner: -
+--
+
+CREATE SEQUENCE btc.nulldata_outputs_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: nulldata_outputs_id_seq; Type: SEQUENCE OWNED BY; Schema: btc; Owner: -
+--
+
+ALTER SEQUENCE btc.nulldata_outputs_id_seq OWNED BY btc.nulldata_outputs.id;
+
+
 --
 -- Name: transaction_cids; Type: TABLE; Schema: btc; Owner: -
 --
@@ -98,7 +347,11 @@ CREATE TABLE btc.transaction_cids (
     cid text NOT NULL,
     mh_key text NOT NULL,
     segwit boolean NOT NULL,
-    witness_hash character varying(66)
+    witness_hash character varying(66),
+    weight integer,
+    vsize integer,
+    fee bigint,
+    fee_rate numeric
 );
 
 
@@ -140,7 +393,12 @@ CREATE TABLE btc.tx_inputs (
     witness character varying[],
     sig_script bytea NOT NULL,
     outpoint_tx_hash character varying(66) NOT NULL,
-    outpoint_index numeric NOT NULL
+    outpoint_index numeric NOT NULL,
+    spent_output_id integer,
+    value bigint,
+    script_class integer,
+    addresses character varying(66)[],
+    taproot_spend integer DEFAULT 0 NOT NULL
 );
 
 
@@ -176,7 +434,8 @@ CREATE TABLE btc.tx_outputs (
     pk_script bytea NOT NULL,
     script_class integer NOT NULL,
     addresses character varying(66)[],
-    required_sigs integer NOT NULL
+    required_sigs integer NOT NULL,
+    script_hash bytea NOT NULL
 );
 
 
@@ -220,7 +479,8 @@ CREATE TABLE eth.header_cids (
     uncle_root character varying(66) NOT NULL,
     bloom bytea NOT NULL,
     "timestamp" numeric NOT NULL,
-    times_validated integer DEFAULT 1 NOT NULL
+    times_validated integer DEFAULT 1 NOT NULL,
+    canonical boolean DEFAULT true NOT NULL
 );
 
 
@@ -481,6 +741,65 @@ CREATE SEQUENCE eth.uncle_cids_id_seq
 ALTER SEQUENCE eth.uncle_cids_id_seq OWNED BY eth.uncle_cids.id;
 
 
+--
+-- Name: transactions; Type: TABLE; Schema: omni; Owner: -
+--
+
+CREATE TABLE omni.transactions (
+    id integer NOT NULL,
+    tx_id integer NOT NULL,
+    class integer NOT NULL,
+    version integer NOT NULL,
+    tx_type integer NOT NULL,
+    property_id bigint,
+    amount bigint,
+    sender character varying(66),
+    reference_address character varying(66),
+    payload bytea NOT NULL
+);
+
+
+--
+-- Name: TABLE transactions; Type: COMMENT; Schema: omni; Owner: -
+--
+
+COMMENT ON TABLE omni.transactions IS '@name OmniTransactions';
+
+
+--
+-- Name: COLUMN transactions.class; Type: COMMENT; Schema: omni; Owner: -
+--
+
+COMMENT ON COLUMN omni.transactions.class IS 'The Omni Layer class of the bitcoin transaction carrying the payload: 2 for class B (multisig) and 3 for class C (OP_RETURN)';
+
+
+--
+-- Name: COLUMN transactions.sender; Type: COMMENT; Schema: omni; Owner: -
+--
+
+COMMENT ON COLUMN omni.transactions.sender IS 'The address contributing the most input value, null if the outputs spent by the transaction could not be resolved';
+
+
+--
+-- Name: transactions_id_seq; Type: SEQUENCE; Schema: omni; Owner: -
+--
+
+CREATE SEQUENCE omni.transactions_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: transactions_id_seq; Type: SEQUENCE OWNED BY; Schema: omni; Owner: -
+--
+
+ALTER SEQUENCE omni.transactions_id_seq OWNED BY omni.transactions.id;
+
+
 --
 -- Name: blocks; Type: TABLE; Schema: public; Owner: -
 --
@@ -570,6 +889,20 @@ CREATE SEQUENCE public.nodes_id_seq
 ALTER SEQUENCE public.nodes_id_seq OWNED BY public.nodes.id;
 
 
+--
+-- Name: address_transactions id; Type: DEFAULT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.address_transactions ALTER COLUMN id SET DEFAULT nextval('btc.address_transactions_id_seq'::regclass);
+
+
+--
+-- Name: block_filters id; Type: DEFAULT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.block_filters ALTER COLUMN id SET DEFAULT nextval('btc.block_filters_id_seq'::regclass);
+
+
 --
 -- Name: header_cids id; Type: DEFAULT; Schema: btc; Owner: -
 --
@@ -577,6 +910,20 @@ ALTER SEQUENCE public.nodes_id_seq OWNED BY public.nodes.id;
 ALTER TABLE ONLY btc.header_cids ALTER COLUMN id SET DEFAULT nextval('btc.header_cids_id_seq'::regclass);
 
 
+--
+-- Name: mempool id; Type: DEFAULT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.mempool ALTER COLUMN id SET DEFAULT nextval('btc.mempool_id_seq'::regclass);
+
+
+--
+-- Name: nulldata_outputs id; Type: DEFAULT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.nulldata_outputs ALTER COLUMN id SET DEFAULT nextval('btc.nulldata_outputs_id_seq'::regclass);
+
+
 --
 -- Name: transaction_cids id; Type: DEFAULT; Schema: btc; Owner: -
 --
@@ -647,6 +994,13 @@ ALTER TABLE ONLY eth.transaction_cids ALTER COLUMN id SET DEFAULT nextval('eth.t
 ALTER TABLE ONLY eth.uncle_cids ALTER COLUMN id SET DEFAULT nextval('eth.uncle_cids_id_seq'::regclass);
 
 
+--
+-- Name: transactions id; Type: DEFAULT; Schema: omni; Owner: -
+--
+
+ALTER TABLE ONLY omni.transactions ALTER COLUMN id SET DEFAULT nextval('omni.transactions_id_seq'::regclass);
+
+
 --
 -- Name: goose_db_version id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -661,6 +1015,38 @@ ALTER TABLE ONLY public.goose_db_version ALTER COLUMN id SET DEFAULT nextval('pu
 ALTER TABLE ONLY public.nodes ALTER COLUMN id SET DEFAULT nextval('public.nodes_id_seq'::regclass);
 
 
+--
+-- Name: address_transactions address_transactions_address_tx_id_key; Type: CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.address_transactions
+    ADD CONSTRAINT address_transactions_address_tx_id_key UNIQUE (address, tx_id);
+
+
+--
+-- Name: address_transactions address_transactions_pkey; Type: CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.address_transactions
+    ADD CONSTRAINT address_transactions_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: block_filters block_filters_header_id_key; Type: CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.block_filters
+    ADD CONSTRAINT block_filters_header_id_key UNIQUE (header_id);
+
+
+--
+-- Name: block_filters block_filters_pkey; Type: CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.block_filters
+    ADD CONSTRAINT block_filters_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: header_cids header_cids_block_number_block_hash_key; Type: CONSTRAINT; Schema: btc; Owner: -
 --
@@ -678,19 +1064,51 @@ ALTER TABLE ONLY btc.header_cids
 
 
 --
--- Name: transaction_cids transaction_cids_pkey; Type: CONSTRAINT; Schema: btc; Owner: -
+-- Name: mempool mempool_pkey; Type: CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.mempool
+    ADD CONSTRAINT mempool_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: mempool mempool_tx_hash_key; Type: CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.mempool
+    ADD CONSTRAINT mempool_tx_hash_key UNIQUE (tx_hash);
+
+
+--
+-- Name: nulldata_outputs nulldata_outputs_output_id_key; Type: CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.nulldata_outputs
+    ADD CONSTRAINT nulldata_outputs_output_id_key UNIQUE (output_id);
+
+
+--
+-- Name: nulldata_outputs nulldata_outputs_pkey; Type: CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.nulldata_outputs
+    ADD CONSTRAINT nulldata_outputs_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: transaction_cids transaction_cids_header_id_tx_hash_key; Type: CONSTRAINT; Schema: btc; Owner: -
 --
 
 ALTER TABLE ONLY btc.transaction_cids
-    ADD CONSTRAINT transaction_cids_pkey PRIMARY KEY (id);
+    ADD CONSTRAINT transaction_cids_header_id_tx_hash_key UNIQUE (header_id, tx_hash);
 
 
 --
--- Name: transaction_cids transaction_cids_tx_hash_key; Type: CONSTRAINT; Schema: btc; Owner: -
+-- Name: transaction_cids transaction_cids_pkey; Type: CONSTRAINT; Schema: btc; Owner: -
 --
 
 ALTER TABLE ONLY btc.transaction_cids
-    ADD CONSTRAINT transaction_cids_tx_hash_key UNIQUE (tx_hash);
+    ADD CONSTRAINT transaction_cids_pkey PRIMARY KEY (id);
 
 
 --
@@ -837,6 +1255,22 @@ ALTER TABLE ONLY eth.uncle_cids
     ADD CONSTRAINT uncle_cids_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: transactions transactions_pkey; Type: CONSTRAINT; Schema: omni; Owner: -
+--
+
+ALTER TABLE ONLY omni.transactions
+    ADD CONSTRAINT transactions_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: transactions transactions_tx_id_key; Type: CONSTRAINT; Schema: omni; Owner: -
+--
+
+ALTER TABLE ONLY omni.transactions
+    ADD CONSTRAINT transactions_tx_id_key UNIQUE (tx_id);
+
+
 --
 -- Name: blocks blocks_key_key; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -869,6 +1303,120 @@ ALTER TABLE ONLY public.nodes
     ADD CONSTRAINT nodes_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: address_transactions_tx_id_index; Type: INDEX; Schema: btc; Owner: -
+--
+
+CREATE INDEX address_transactions_tx_id_index ON btc.address_transactions USING btree (tx_id);
+
+
+--
+-- Name: mempool_addresses_index; Type: INDEX; Schema: btc; Owner: -
+--
+
+CREATE INDEX mempool_addresses_index ON btc.mempool USING gin (addresses);
+
+
+--
+-- Name: nulldata_outputs_prefix_index; Type: INDEX; Schema: btc; Owner: -
+--
+
+CREATE INDEX nulldata_outputs_prefix_index ON btc.nulldata_outputs USING btree (prefix);
+
+
+--
+-- Name: nulldata_outputs_tx_id_index; Type: INDEX; Schema: btc; Owner: -
+--
+
+CREATE INDEX nulldata_outputs_tx_id_index ON btc.nulldata_outputs USING btree (tx_id);
+
+
+--
+-- Name: transaction_cids_tx_hash_index; Type: INDEX; Schema: btc; Owner: -
+--
+
+CREATE INDEX transaction_cids_tx_hash_index ON btc.transaction_cids USING btree (tx_hash);
+
+
+--
+-- Name: tx_inputs_addresses_index; Type: INDEX; Schema: btc; Owner: -
+--
+
+CREATE INDEX tx_inputs_addresses_index ON btc.tx_inputs USING gin (addresses);
+
+
+--
+-- Name: tx_inputs_outpoint_index; Type: INDEX; Schema: btc; Owner: -
+--
+
+CREATE INDEX tx_inputs_outpoint_index ON btc.tx_inputs USING btree (outpoint_tx_hash, outpoint_index);
+
+
+--
+-- Name: tx_inputs_spent_output_id_index; Type: INDEX; Schema: btc; Owner: -
+--
+
+CREATE INDEX tx_inputs_spent_output_id_index ON btc.tx_inputs USING btree (spent_output_id);
+
+
+--
+-- Name: tx_outputs_addresses_index; Type: INDEX; Schema: btc; Owner: -
+--
+
+CREATE INDEX tx_outputs_addresses_index ON btc.tx_outputs USING gin (addresses);
+
+
+--
+-- Name: tx_outputs_script_hash_index; Type: INDEX; Schema: btc; Owner: -
+--
+
+CREATE INDEX tx_outputs_script_hash_index ON btc.tx_outputs USING btree (script_hash);
+
+
+--
+-- Name: transactions_property_id_index; Type: INDEX; Schema: omni; Owner: -
+--
+
+CREATE INDEX transactions_property_id_index ON omni.transactions USING btree (property_id);
+
+
+--
+-- Name: transactions_reference_address_index; Type: INDEX; Schema: omni; Owner: -
+--
+
+CREATE INDEX transactions_reference_address_index ON omni.transactions USING btree (reference_address);
+
+
+--
+-- Name: transactions_sender_index; Type: INDEX; Schema: omni; Owner: -
+--
+
+CREATE INDEX transactions_sender_index ON omni.transactions USING btree (sender);
+
+
+--
+-- Name: transactions_tx_type_index; Type: INDEX; Schema: omni; Owner: -
+--
+
+CREATE INDEX transactions_tx_type_index ON omni.transactions USING btree (tx_type);
+
+
+--
+-- Name: address_transactions address_transactions_tx_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.address_transactions
+    ADD CONSTRAINT address_transactions_tx_id_fkey FOREIGN KEY (tx_id) REFERENCES btc.transaction_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
+
+
+--
+-- Name: block_filters block_filters_header_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.block_filters
+    ADD CONSTRAINT block_filters_header_id_fkey FOREIGN KEY (header_id) REFERENCES btc.header_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
+
+
 --
 -- Name: header_cids header_cids_mh_key_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
 --
@@ -885,6 +1433,22 @@ ALTER TABLE ONLY btc.header_cids
     ADD CONSTRAINT header_cids_node_id_fkey FOREIGN KEY (node_id) REFERENCES public.nodes(id) ON DELETE CASCADE;
 
 
+--
+-- Name: nulldata_outputs nulldata_outputs_output_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.nulldata_outputs
+    ADD CONSTRAINT nulldata_outputs_output_id_fkey FOREIGN KEY (output_id) REFERENCES btc.tx_outputs(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
+
+
+--
+-- Name: nulldata_outputs nulldata_outputs_tx_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.nulldata_outputs
+    ADD CONSTRAINT nulldata_outputs_tx_id_fkey FOREIGN KEY (tx_id) REFERENCES btc.transaction_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
+
+
 --
 -- Name: transaction_cids transaction_cids_header_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
 --
@@ -901,6 +1465,14 @@ ALTER TABLE ONLY btc.transaction_cids
     ADD CONSTRAINT transaction_cids_mh_key_fkey FOREIGN KEY (mh_key) REFERENCES public.blocks(key) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
 
 
+--
+-- Name: tx_inputs tx_inputs_spent_output_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
+--
+
+ALTER TABLE ONLY btc.tx_inputs
+    ADD CONSTRAINT tx_inputs_spent_output_id_fkey FOREIGN KEY (spent_output_id) REFERENCES btc.tx_outputs(id) ON DELETE SET NULL DEFERRABLE INITIALLY DEFERRED;
+
+
 --
 -- Name: tx_inputs tx_inputs_tx_id_fkey; Type: FK CONSTRAINT; Schema: btc; Owner: -
 --
@@ -1021,6 +1593,14 @@ ALTER TABLE ONLY eth.uncle_cids
     ADD CONSTRAINT uncle_cids_mh_key_fkey FOREIGN KEY (mh_key) REFERENCES public.blocks(key) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
 
 
+--
+-- Name: transactions transactions_tx_id_fkey; Type: FK CONSTRAINT; Schema: omni; Owner: -
+--
+
+ALTER TABLE ONLY omni.transactions
+    ADD CONSTRAINT transactions_tx_id_fkey FOREIGN KEY (tx_id) REFERENCES btc.transaction_cids(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;
+
+
 --
 -- PostgreSQL database dump complete
 --
@@ -0,0 +1,756 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+)
+
+// APIName is the namespace for the watcher's btc api
+const APIName = "btc"
+
+// APIVersion is the version of the watcher's btc api
+const APIVersion = "0.0.1"
+
+// bitcoind json-rpc error codes
+const (
+	rpcMiscError           = -1
+	rpcInvalidAddressOrKey = -5
+	rpcInvalidParameter    = -8
+)
+
+const (
+	// defaultMaxConf is the default maximum number of confirmations of the outputs returned by listunspent
+	defaultMaxConf = 9999999
+	// defaultUTXOPageSize is the default number of outputs returned by a single getutxoset call
+	defaultUTXOPageSize = 1000
+	// maxUTXOPageSize is the greatest number of outputs returned by a single getutxoset call
+	maxUTXOPageSize = 10000
+	// basicFilterType is the only block filter type served, the BIP158 basic filter
+	basicFilterType = "basic"
+	// maxBlockFilters is the default and greatest number of filters returned by a single getblockfilters call
+	maxBlockFilters = 1000
+)
+
+var (
+	errBlockNotFound       = &rpcError{code: rpcInvalidAddressOrKey, message: "Block not found"}
+	errTxNotFound          = &rpcError{code: rpcInvalidAddressOrKey, message: "No such mempool or blockchain transaction"}
+	errTxNotFoundInBlock   = &rpcError{code: rpcInvalidAddressOrKey, message: "No such transaction found in the provided block"}
+	errBlockHeightOutRange = &rpcError{code: rpcInvalidParameter, message: "Block height out of range"}
+	errNoAddresses         = &rpcError{code: rpcInvalidParameter, message: "At least one address is required"}
+	errInvalidPageSize     = &rpcError{code: rpcInvalidParameter, message: fmt.Sprintf("Count must be between 1 and %d", maxUTXOPageSize)}
+	errInvalidFilterCount  = &rpcError{code: rpcInvalidParameter, message: fmt.Sprintf("Count must be between 1 and %d", maxBlockFilters)}
+	errUnknownFilterType   = &rpcError{code: rpcInvalidAddressOrKey, message: "Unknown filtertype"}
+	errFilterNotFound      = &rpcError{code: rpcMiscError, message: "Filter not found. Block filters are still in the process of being indexed."}
+)
+
+// rpcError is an error carrying a bitcoind json-rpc error code
+type rpcError struct {
+	code    int
+	message string
+}
+
+func (e *rpcError) Error() string {
+	return e.message
+}
+
+// ErrorCode satisfies the rpc.Error interface
+func (e *rpcError) ErrorCode() int {
+	return e.code
+}
+
+// PublicBtcAPI answers the read only bitcoind json-rpc calls from the indexed headers and transactions
+type PublicBtcAPI struct {
+	B *Backend
+}
+
+// NewPublicBtcAPI creates a new PublicBtcAPI with the provided underlying Backend
+func NewPublicBtcAPI(b *Backend) *PublicBtcAPI {
+	return &PublicBtcAPI{
+		B: b,
+	}
+}
+
+// BlockHeaderResult is the verbose response to getblockheader
+type BlockHeaderResult struct {
+	Hash          string  `json:"hash"`
+	Confirmations int64   `json:"confirmations"`
+	Height        int64   `json:"height"`
+	Version       int32   `json:"version"`
+	VersionHex    string  `json:"versionHex"`
+	MerkleRoot    string  `json:"merkleroot"`
+	Time          int64   `json:"time"`
+	MedianTime    int64   `json:"mediantime"`
+	Nonce         uint32  `json:"nonce"`
+	Bits          string  `json:"bits"`
+	Difficulty    float64 `json:"difficulty"`
+	NTx           int     `json:"nTx"`
+	PreviousHash  string  `json:"previousblockhash,omitempty"`
+	NextHash      string  `json:"nextblockhash,omitempty"`
+}
+
+// BlockResult is the verbose response to getblock
+// Tx holds the transaction ids with verbosity 1 and the decoded transactions with verbosity 2
+type BlockResult struct {
+	Hash          string      `json:"hash"`
+	Confirmations int64       `json:"confirmations"`
+	Size          int         `json:"size"`
+	StrippedSize  int         `json:"strippedsize"`
+	Weight        int         `json:"weight"`
+	Height        int64       `json:"height"`
+	Version       int32       `json:"version"`
+	VersionHex    string      `json:"versionHex"`
+	MerkleRoot    string      `json:"merkleroot"`
+	Tx            interface{} `json:"tx"`
+	Time          int64       `json:"time"`
+	MedianTime    int64       `json:"mediantime"`
+	Nonce         uint32      `json:"nonce"`
+	Bits          string      `json:"bits"`
+	Difficulty    float64     `json:"difficulty"`
+	NTx           int         `json:"nTx"`
+	PreviousHash  string      `json:"previousblockhash,omitempty"`
+	NextHash      string      `json:"nextblockhash,omitempty"`
+}
+
+// TxResult is the verbose response to getrawtransaction, and a transaction of a getblock response with verbosity 2
+// The block fields are only set by getrawtransaction
+type TxResult struct {
+	InActiveChain *bool  `json:"in_active_chain,omitempty"`
+	Txid          string `json:"txid"`
+	Hash          string `json:"hash"`
+	Version       int32  `json:"version"`
+	Size          int    `json:"size"`
+	Vsize         int    `json:"vsize"`
+	Weight        int    `json:"weight"`
+	LockTime      uint32 `json:"locktime"`
+	Vin           []Vin  `json:"vin"`
+	Vout          []Vout `json:"vout"`
+	Hex           string `json:"hex"`
+	BlockHash     string `json:"blockhash,omitempty"`
+	Confirmations *int64 `json:"confirmations,omitempty"`
+	Time          int64  `json:"time,omitempty"`
+	BlockTime     int64  `json:"blocktime,omitempty"`
+}
+
+// Vin is a transaction input, coinbase inputs only carry the coinbase script, witness and sequence
+type Vin struct {
+	Coinbase  string     `json:"coinbase,omitempty"`
+	Txid      string     `json:"txid,omitempty"`
+	Vout      *uint32    `json:"vout,omitempty"`
+	ScriptSig *ScriptSig `json:"scriptSig,omitempty"`
+	Witness   []string   `json:"txinwitness,omitempty"`
+	Sequence  uint32     `json:"sequence"`
+}
+
+// ScriptSig is the signature script of a transaction input
+type ScriptSig struct {
+	Asm string `json:"asm"`
+	Hex string `json:"hex"`
+}
+
+// Vout is a transaction output, its value is denominated in BTC
+type Vout struct {
+	Value        float64      `json:"value"`
+	N            uint32       `json:"n"`
+	ScriptPubKey ScriptPubKey `json:"scriptPubKey"`
+}
+
+// ScriptPubKey is the public key script of a transaction output
+type ScriptPubKey struct {
+	Asm       string   `json:"asm"`
+	Hex       string   `json:"hex"`
+	ReqSigs   int      `json:"reqSigs,omitempty"`
+	Type      string   `json:"type"`
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// UnspentResult is an output of the responses to listunspent and getutxoset, its amount is denominated in BTC
+// Address is only set for outputs paying to a single address
+type UnspentResult struct {
+	Txid          string  `json:"txid"`
+	Vout          uint32  `json:"vout"`
+	Address       string  `json:"address,omitempty"`
+	ScriptPubKey  string  `json:"scriptPubKey"`
+	Amount        float64 `json:"amount"`
+	Confirmations int64   `json:"confirmations"`
+	Height        int64   `json:"height"`
+	Coinbase      bool    `json:"coinbase"`
+}
+
+// UTXOSetResult is a page of the response to getutxoset
+// Next is the cursor which retrieves the following page, it is omitted from the last page
+type UTXOSetResult struct {
+	Height    int64            `json:"height"`
+	BestBlock string           `json:"bestblock"`
+	Unspents  []*UnspentResult `json:"unspents"`
+	Next      *int64           `json:"next,omitempty"`
+}
+
+// AddressBalanceResult is the response to getaddressbalance, its values are denominated in satoshis
+type AddressBalanceResult struct {
+	Height   int64 `json:"height"`
+	Balance  int64 `json:"balance"`
+	Received int64 `json:"received"`
+}
+
+// TxOutSetInfoResult is the response to gettxoutsetinfo, its total amount is denominated in BTC
+type TxOutSetInfoResult struct {
+	Height      int64   `json:"height"`
+	BestBlock   string  `json:"bestblock"`
+	TxOuts      int64   `json:"txouts"`
+	TotalAmount float64 `json:"total_amount"`
+}
+
+// BlockFilterResult is the response to getblockfilter, the filter header is hex encoded in the byte order of block hashes
+type BlockFilterResult struct {
+	Filter string `json:"filter"`
+	Header string `json:"header"`
+}
+
+// BlockFiltersResult is a filter of the response to getblockfilters
+type BlockFiltersResult struct {
+	Height    int64  `json:"height"`
+	BlockHash string `json:"blockhash"`
+	Filter    string `json:"filter"`
+	Header    string `json:"header"`
+}
+
+// GetBlockCount returns the height of the canonical chain head
+func (pba *PublicBtcAPI) GetBlockCount() (int64, error) {
+	return pba.B.Retriever.RetrieveLastBlockNumber()
+}
+
+// GetBlockHash returns the hash of the canonical block at the provided height
+func (pba *PublicBtcAPI) GetBlockHash(height int64) (string, error) {
+	header, _, err := pba.B.HeaderByNumber(height)
+	if err == sql.ErrNoRows {
+		return "", errBlockHeightOutRange
+	}
+	if err != nil {
+		return "", err
+	}
+	return header.BlockHash().String(), nil
+}
+
+// GetBlockHeader returns the header with the provided hash
+// If verbose is false the hex encoded serialized header is returned, otherwise the decoded header
+func (pba *PublicBtcAPI) GetBlockHeader(blockHash string, verbose *bool) (interface{}, error) {
+	hash, err := parseHash("blockhash", blockHash)
+	if err != nil {
+		return nil, err
+	}
+	header, blockCtx, err := pba.B.HeaderByHash(*hash)
+	if err == sql.ErrNoRows {
+		return nil, errBlockNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if verbose != nil && !*verbose {
+		var buf bytes.Buffer
+		if err := header.Serialize(&buf); err != nil {
+			return nil, err
+		}
+		return hex.EncodeToString(buf.Bytes()), nil
+	}
+	return &BlockHeaderResult{
+		Hash:          header.BlockHash().String(),
+		Confirmations: blockCtx.Confirmations,
+		Height:        blockCtx.Height,
+		Version:       header.Version,
+		VersionHex:    fmt.Sprintf("%08x", uint32(header.Version)),
+		MerkleRoot:    header.MerkleRoot.String(),
+		Time:          header.Timestamp.Unix(),
+		MedianTime:    blockCtx.MedianTime,
+		Nonce:         header.Nonce,
+		Bits:          fmt.Sprintf("%08x", header.Bits),
+		Difficulty:    DifficultyRatio(header.Bits, pba.B.Params),
+		NTx:           blockCtx.TxCount,
+		PreviousHash:  previousHash(header, blockCtx),
+		NextHash:      nextHash(blockCtx),
+	}, nil
+}
+
+// GetBlock returns the block with the provided hash
+// With verbosity 0 the hex encoded serialized block is returned, with verbosity 1 (the default) the decoded block and
+// the ids of its transactions, and with verbosity 2 the decoded block and its decoded transactions
+func (pba *PublicBtcAPI) GetBlock(blockHash string, verbosity *int) (interface{}, error) {
+	hash, err := parseHash("blockhash", blockHash)
+	if err != nil {
+		return nil, err
+	}
+	block, blockCtx, err := pba.B.BlockByHash(*hash)
+	if err == sql.ErrNoRows {
+		return nil, errBlockNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	level := 1
+	if verbosity != nil {
+		level = *verbosity
+	}
+	if level <= 0 {
+		var buf bytes.Buffer
+		if err := block.Serialize(&buf); err != nil {
+			return nil, err
+		}
+		return hex.EncodeToString(buf.Bytes()), nil
+	}
+	var txs interface{}
+	if level == 1 {
+		txids := make([]string, len(block.Transactions))
+		for i, trx := range block.Transactions {
+			txids[i] = trx.TxHash().String()
+		}
+		txs = txids
+	} else {
+		rawTxs := make([]*TxResult, len(block.Transactions))
+		for i, trx := range block.Transactions {
+			rawTxs[i], err = newTxResult(trx, pba.B.Params)
+			if err != nil {
+				return nil, err
+			}
+		}
+		txs = rawTxs
+	}
+	header := &block.Header
+	return &BlockResult{
+		Hash:          header.BlockHash().String(),
+		Confirmations: blockCtx.Confirmations,
+		Size:          block.SerializeSize(),
+		StrippedSize:  block.SerializeSizeStripped(),
+		Weight:        block.SerializeSizeStripped()*(blockchain.WitnessScaleFactor-1) + block.SerializeSize(),
+		Height:        blockCtx.Height,
+		Version:       header.Version,
+		VersionHex:    fmt.Sprintf("%08x", uint32(header.Version)),
+		MerkleRoot:    header.MerkleRoot.String(),
+		Tx:            txs,
+		Time:          header.Timestamp.Unix(),
+		MedianTime:    blockCtx.MedianTime,
+		Nonce:         header.Nonce,
+		Bits:          fmt.Sprintf("%08x", header.Bits),
+		Difficulty:    DifficultyRatio(header.Bits, pba.B.Params),
+		NTx:           len(block.Transactions),
+		PreviousHash:  previousHash(header, blockCtx),
+		NextHash:      nextHash(blockCtx),
+	}, nil
+}
+
+// GetRawTransaction returns the transaction with the provided id
+// If verbose is false (the default) the hex encoded serialized transaction is returned, otherwise the decoded transaction
+// If a block hash is provided the transaction must belong to that block
+func (pba *PublicBtcAPI) GetRawTransaction(txid string, verbose *bool, blockHash *string) (interface{}, error) {
+	hash, err := parseHash("parameter 1", txid)
+	if err != nil {
+		return nil, err
+	}
+	var wantedBlock *chainhash.Hash
+	if blockHash != nil {
+		wantedBlock, err = parseHash("parameter 3", *blockHash)
+		if err != nil {
+			return nil, err
+		}
+	}
+	trx, header, blockCtx, err := pba.B.TransactionByHash(*hash)
+	if err == sql.ErrNoRows {
+		if wantedBlock != nil {
+			return nil, errTxNotFoundInBlock
+		}
+		return nil, errTxNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if wantedBlock != nil && header.BlockHash() != *wantedBlock {
+		return nil, errTxNotFoundInBlock
+	}
+	if verbose == nil || !*verbose {
+		var buf bytes.Buffer
+		if err := trx.Serialize(&buf); err != nil {
+			return nil, err
+		}
+		return hex.EncodeToString(buf.Bytes()), nil
+	}
+	res, err := newTxResult(trx, pba.B.Params)
+	if err != nil {
+		return nil, err
+	}
+	if wantedBlock != nil {
+		inActiveChain := blockCtx.Confirmations > 0
+		res.InActiveChain = &inActiveChain
+	}
+	confirmations := blockCtx.Confirmations
+	if confirmations < 0 {
+		confirmations = 0
+	}
+	res.BlockHash = header.BlockHash().String()
+	res.Confirmations = &confirmations
+	res.Time = header.Timestamp.Unix()
+	res.BlockTime = header.Timestamp.Unix()
+	return res, nil
+}
+
+// ListUnspent returns the outputs paying to any of the addresses which are unspent at the chain head and have between
+// minConf and maxConf confirmations, 1 and 9999999 by default
+// There is no wallet to list the outputs of, so unlike bitcoind at least one address is required
+func (pba *PublicBtcAPI) ListUnspent(minConf, maxConf *int64, addresses []string) ([]*UnspentResult, error) {
+	addrs, err := pba.parseAddresses(addresses)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, errNoAddresses
+	}
+	min, max := int64(1), int64(defaultMaxConf)
+	if minConf != nil {
+		min = *minConf
+	}
+	if maxConf != nil {
+		max = *maxConf
+	}
+	head, err := pba.B.Retriever.RetrieveLastBlockNumber()
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := pba.B.UTXOs(head, addrs, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*UnspentResult, 0, len(utxos))
+	for _, utxo := range utxos {
+		res := newUnspentResult(utxo, head)
+		if res.Confirmations < min || res.Confirmations > max {
+			continue
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// GetUTXOSet returns a page of the outputs which were unspent as of the canonical block at the provided height,
+// restricted to the outputs paying to any of the addresses if they are provided
+// Pages hold up to count outputs, 1000 by default, and the following page is retrieved by passing the returned next cursor
+func (pba *PublicBtcAPI) GetUTXOSet(height int64, addresses []string, next *int64, count *int) (*UTXOSetResult, error) {
+	addrs, err := pba.parseAddresses(addresses)
+	if err != nil {
+		return nil, err
+	}
+	limit := defaultUTXOPageSize
+	if count != nil {
+		limit = *count
+	}
+	if limit < 1 || limit > maxUTXOPageSize {
+		return nil, errInvalidPageSize
+	}
+	var afterID int64
+	if next != nil {
+		afterID = *next
+	}
+	header, _, err := pba.B.HeaderByNumber(height)
+	if err == sql.ErrNoRows {
+		return nil, errBlockHeightOutRange
+	}
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := pba.B.UTXOs(height, addrs, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := &UTXOSetResult{
+		Height:    height,
+		BestBlock: header.BlockHash().String(),
+		Unspents:  make([]*UnspentResult, len(utxos)),
+	}
+	for i, utxo := range utxos {
+		res.Unspents[i] = newUnspentResult(utxo, height)
+	}
+	if len(utxos) == limit {
+		res.Next = &utxos[len(utxos)-1].ID
+	}
+	return res, nil
+}
+
+// GetAddressBalance returns the total value received by the addresses and their unspent balance as of the canonical
+// block at the provided height, or the chain head if no height is provided
+func (pba *PublicBtcAPI) GetAddressBalance(addresses []string, height *int64) (*AddressBalanceResult, error) {
+	addrs, err := pba.parseAddresses(addresses)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, errNoAddresses
+	}
+	h, err := pba.resolveHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	received, balance, err := pba.B.AddressBalance(h, addrs)
+	if err != nil {
+		return nil, err
+	}
+	return &AddressBalanceResult{
+		Height:   h,
+		Balance:  balance,
+		Received: received,
+	}, nil
+}
+
+// GetTxOutSetInfo returns the number and the total value of the outputs which were unspent as of the canonical block
+// at the provided height, or the chain head if no height is provided
+func (pba *PublicBtcAPI) GetTxOutSetInfo(height *int64) (*TxOutSetInfoResult, error) {
+	h, err := pba.resolveHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	info, err := pba.B.UTXOSetInfo(h)
+	if err == sql.ErrNoRows {
+		return nil, errBlockHeightOutRange
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &TxOutSetInfoResult{
+		Height:      info.Height,
+		BestBlock:   info.BlockHash,
+		TxOuts:      info.Count,
+		TotalAmount: btcutil.Amount(info.Total).ToBTC(),
+	}, nil
+}
+
+// GetBlockFilter returns the BIP158 filter of the block with the provided hash along with its BIP157 filter header
+// Only the basic filter type, the default, is supported
+func (pba *PublicBtcAPI) GetBlockFilter(blockHash string, filterType *string) (*BlockFilterResult, error) {
+	if filterType != nil && *filterType != basicFilterType {
+		return nil, errUnknownFilterType
+	}
+	hash, err := parseHash("blockhash", blockHash)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := pba.B.BlockFilter(*hash)
+	if err == sql.ErrNoRows {
+		return nil, errBlockNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if filter == nil || !filter.FilterHeader.Valid {
+		return nil, errFilterNotFound
+	}
+	return &BlockFilterResult{
+		Filter: hex.EncodeToString(filter.Filter),
+		Header: filter.FilterHeader.String,
+	}, nil
+}
+
+// GetBlockFilters returns the BIP158 filters of up to count consecutive canonical blocks, 1000 by default, starting at
+// the provided height, along with their BIP157 filter headers
+// Fewer filters are returned if the chain head, or a block whose filter has not been indexed, is reached
+func (pba *PublicBtcAPI) GetBlockFilters(startHeight int64, count *int, filterType *string) ([]*BlockFiltersResult, error) {
+	if filterType != nil && *filterType != basicFilterType {
+		return nil, errUnknownFilterType
+	}
+	limit := maxBlockFilters
+	if count != nil {
+		limit = *count
+	}
+	if limit < 1 || limit > maxBlockFilters {
+		return nil, errInvalidFilterCount
+	}
+	if _, err := pba.resolveHeight(&startHeight); err != nil {
+		return nil, err
+	}
+	filters, err := pba.B.BlockFilters(startHeight, int64(limit))
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*BlockFiltersResult, len(filters))
+	for i, filter := range filters {
+		results[i] = &BlockFiltersResult{
+			Height:    filter.BlockNumber,
+			BlockHash: filter.BlockHash,
+			Filter:    hex.EncodeToString(filter.Filter),
+			Header:    filter.FilterHeader.String,
+		}
+	}
+	return results, nil
+}
+
+// parseAddresses decodes the addresses for the backend's network and returns them in the encoding they are indexed with
+func (pba *PublicBtcAPI) parseAddresses(addresses []string) ([]string, error) {
+	encoded := make([]string, len(addresses))
+	for i, address := range addresses {
+		addr, err := DecodeAddress(address, pba.B.Params)
+		if err != nil || !addr.IsForNet(pba.B.Params) {
+			return nil, &rpcError{
+				code:    rpcInvalidAddressOrKey,
+				message: fmt.Sprintf("Invalid Bitcoin address: %s", address),
+			}
+		}
+		encoded[i] = addr.EncodeAddress()
+	}
+	return encoded, nil
+}
+
+// resolveHeight returns the provided height if it is within the canonical chain, or the chain head if it is nil
+func (pba *PublicBtcAPI) resolveHeight(height *int64) (int64, error) {
+	head, err := pba.B.Retriever.RetrieveLastBlockNumber()
+	if err != nil {
+		return 0, err
+	}
+	if height == nil {
+		return head, nil
+	}
+	if *height < 0 || *height > head {
+		return 0, errBlockHeightOutRange
+	}
+	return *height, nil
+}
+
+// newUnspentResult converts an unspent output, counting its confirmations as of the provided height
+func newUnspentResult(utxo UTXOModel, height int64) *UnspentResult {
+	res := &UnspentResult{
+		Txid:          utxo.TxHash,
+		Vout:          uint32(utxo.Index),
+		ScriptPubKey:  hex.EncodeToString(utxo.PkScript),
+		Amount:        btcutil.Amount(utxo.Value).ToBTC(),
+		Confirmations: height - utxo.BlockNumber + 1,
+		Height:        utxo.BlockNumber,
+		Coinbase:      utxo.Coinbase,
+	}
+	if len(utxo.Addresses) == 1 {
+		res.Address = utxo.Addresses[0]
+	}
+	return res
+}
+
+// newTxResult decodes the transaction into the fields bitcoind reports for it
+func newTxResult(trx *wire.MsgTx, params *chaincfg.Params) (*TxResult, error) {
+	var buf bytes.Buffer
+	if err := trx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	weight := trx.SerializeSizeStripped()*(blockchain.WitnessScaleFactor-1) + trx.SerializeSize()
+	res := &TxResult{
+		Txid:     trx.TxHash().String(),
+		Hash:     trx.WitnessHash().String(),
+		Version:  trx.Version,
+		Size:     trx.SerializeSize(),
+		Vsize:    (weight + blockchain.WitnessScaleFactor - 1) / blockchain.WitnessScaleFactor,
+		Weight:   weight,
+		LockTime: trx.LockTime,
+		Vin:      make([]Vin, len(trx.TxIn)),
+		Vout:     make([]Vout, len(trx.TxOut)),
+		Hex:      hex.EncodeToString(buf.Bytes()),
+	}
+	coinbase := blockchain.IsCoinBaseTx(trx)
+	for i, in := range trx.TxIn {
+		vin := Vin{
+			Sequence: in.Sequence,
+		}
+		if len(in.Witness) > 0 {
+			vin.Witness = convertBytesToHexArray(in.Witness)
+		}
+		if coinbase {
+			vin.Coinbase = hex.EncodeToString(in.SignatureScript)
+		} else {
+			index := in.PreviousOutPoint.Index
+			asm, _ := txscript.DisasmString(in.SignatureScript)
+			vin.Txid = in.PreviousOutPoint.Hash.String()
+			vin.Vout = &index
+			vin.ScriptSig = &ScriptSig{
+				Asm: asm,
+				Hex: hex.EncodeToString(in.SignatureScript),
+			}
+		}
+		res.Vin[i] = vin
+	}
+	for i, out := range trx.TxOut {
+		asm, _ := txscript.DisasmString(out.PkScript)
+		scriptClass, addresses, reqSigs, _ := ExtractPkScriptAddrs(out.PkScript, params)
+		vout := Vout{
+			Value: btcutil.Amount(out.Value).ToBTC(),
+			N:     uint32(i),
+			ScriptPubKey: ScriptPubKey{
+				Asm:     asm,
+				Hex:     hex.EncodeToString(out.PkScript),
+				ReqSigs: reqSigs,
+				Type:    ScriptClassName(scriptClass),
+			},
+		}
+		for _, addr := range addresses {
+			vout.ScriptPubKey.Addresses = append(vout.ScriptPubKey.Addresses, addr.EncodeAddress())
+		}
+		res.Vout[i] = vout
+	}
+	return res, nil
+}
+
+// parseHash decodes a hash parameter, reporting malformed values the way bitcoind does
+func parseHash(name, str string) (*chainhash.Hash, error) {
+	if len(str) != 2*chainhash.HashSize {
+		return nil, &rpcError{
+			code:    rpcInvalidParameter,
+			message: fmt.Sprintf("%s must be of length %d (not %d, for '%s')", name, 2*chainhash.HashSize, len(str), str),
+		}
+	}
+	hash, err := chainhash.NewHashFromStr(str)
+	if err != nil {
+		return nil, &rpcError{
+			code:    rpcInvalidParameter,
+			message: fmt.Sprintf("%s must be hexadecimal string (not '%s')", name, str),
+		}
+	}
+	return hash, nil
+}
+
+// DifficultyRatio returns the difficulty of the target encoded in the compact bits, as a multiple of the minimum difficulty
+func DifficultyRatio(bits uint32, params *chaincfg.Params) float64 {
+	max := blockchain.CompactToBig(params.PowLimitBits)
+	target := blockchain.CompactToBig(bits)
+	difficulty, _ := new(big.Float).Quo(new(big.Float).SetInt(max), new(big.Float).SetInt(target)).Float64()
+	return difficulty
+}
+
+// previousHash returns the hash of the parent block, or nothing for the genesis block
+func previousHash(header *wire.BlockHeader, blockCtx *BlockContext) string {
+	if blockCtx.Height == 0 {
+		return ""
+	}
+	return header.PrevBlock.String()
+}
+
+// nextHash returns the hash of the next canonical block, or nothing if there is none
+func nextHash(blockCtx *BlockContext) string {
+	if blockCtx.NextHash == nil {
+		return ""
+	}
+	return blockCtx.NextHash.String()
+}
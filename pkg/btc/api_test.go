@@ -0,0 +1,398 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc/mocks"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// errorCode returns the json-rpc error code carried by the error
+func errorCode(err error) int {
+	rpcErr, ok := err.(interface{ ErrorCode() int })
+	Expect(ok).To(BeTrue())
+	return rpcErr.ErrorCode()
+}
+
+// childPayload converts a block holding the transactions which extends the mock block
+func childPayload(nonce uint32, txs ...*wire.MsgTx) btc.ConvertedPayload {
+	header := mocks.MockBlock.Header
+	header.PrevBlock = mocks.MockBlock.BlockHash()
+	header.Timestamp = header.Timestamp.Add(10 * time.Minute)
+	header.Nonce = nonce
+	blockTxs := make([]*btcutil.Tx, len(txs))
+	for i, trx := range txs {
+		blockTxs[i] = btcutil.NewTx(trx)
+	}
+	payload, err := btc.NewPayloadConverter(&chaincfg.MainNetParams).Convert(btc.BlockPayload{
+		BlockHeight: mocks.MockBlockHeight + 1,
+		Header:      &header,
+		Txs:         blockTxs,
+	})
+	Expect(err).ToNot(HaveOccurred())
+	return payload.(btc.ConvertedPayload)
+}
+
+// genesisPayload converts the mainnet genesis block
+func genesisPayload() btc.ConvertedPayload {
+	genesis := chaincfg.MainNetParams.GenesisBlock
+	payload, err := btc.NewPayloadConverter(&chaincfg.MainNetParams).Convert(btc.BlockPayload{
+		BlockHeight: 0,
+		Header:      &genesis.Header,
+		Txs:         []*btcutil.Tx{btcutil.NewTx(genesis.Transactions[0])},
+	})
+	Expect(err).ToNot(HaveOccurred())
+	return payload.(btc.ConvertedPayload)
+}
+
+// spendingTx returns a transaction spending the output to the pk script
+func spendingTx(outpoint wire.OutPoint, value int64, pkScript []byte) *wire.MsgTx {
+	trx := wire.NewMsgTx(wire.TxVersion)
+	trx.AddTxIn(wire.NewTxIn(&outpoint, []byte{0x51}, nil))
+	trx.AddTxOut(wire.NewTxOut(value, pkScript))
+	return trx
+}
+
+var _ = Describe("API", func() {
+	var (
+		db        *postgres.DB
+		api       *btc.PublicBtcAPI
+		blockHash = mocks.MockBlock.BlockHash().String()
+		txid      = mocks.MockBlock.Transactions[1].TxHash().String()
+		verbose   = true
+		terse     = false
+	)
+	BeforeEach(func() {
+		var err error
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		backend, err := btc.NewBtcBackend(db, &chaincfg.MainNetParams)
+		Expect(err).ToNot(HaveOccurred())
+		api = btc.NewPublicBtcAPI(backend)
+		_, err = btc.NewIPLDPublisherAndIndexer(db, nil, nil).Publish(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		btc.TearDownDB(db)
+	})
+
+	Describe("GetBlockCount and GetBlockHash", func() {
+		It("Return the height of the chain head and the hashes of canonical blocks", func() {
+			count, err := api.GetBlockCount()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(mocks.MockBlockHeight))
+
+			hash, err := api.GetBlockHash(mocks.MockBlockHeight)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hash).To(Equal(blockHash))
+
+			_, err = api.GetBlockHash(mocks.MockBlockHeight + 1)
+			Expect(err).To(HaveOccurred())
+			Expect(errorCode(err)).To(Equal(-8))
+		})
+	})
+
+	Describe("GetBlockHeader", func() {
+		It("Returns the decoded header", func() {
+			res, err := api.GetBlockHeader(blockHash, nil)
+			Expect(err).ToNot(HaveOccurred())
+			header, ok := res.(*btc.BlockHeaderResult)
+			Expect(ok).To(BeTrue())
+			Expect(header.Hash).To(Equal(blockHash))
+			Expect(header.Height).To(Equal(mocks.MockBlockHeight))
+			Expect(header.Confirmations).To(Equal(int64(1)))
+			Expect(header.VersionHex).To(Equal("00000001"))
+			Expect(header.MerkleRoot).To(Equal(mocks.MockBlock.Header.MerkleRoot.String()))
+			Expect(header.Time).To(Equal(int64(1293623863)))
+			Expect(header.MedianTime).To(Equal(int64(1293623863)))
+			Expect(header.Bits).To(Equal("1b04864c"))
+			Expect(header.Nonce).To(Equal(uint32(274148111)))
+			Expect(header.NTx).To(Equal(len(mocks.MockBlock.Transactions)))
+			Expect(header.PreviousHash).To(Equal("000000000002d01c1fccc21636b607dfd930d31d01c3a62104612a1719011250"))
+			Expect(header.NextHash).To(BeEmpty())
+		})
+
+		It("Returns the serialized header when verbose is false", func() {
+			res, err := api.GetBlockHeader(blockHash, &terse)
+			Expect(err).ToNot(HaveOccurred())
+			var buf bytes.Buffer
+			err = mocks.MockBlock.Header.Serialize(&buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(Equal(hex.EncodeToString(buf.Bytes())))
+		})
+
+		It("Returns bitcoind's errors for unknown and malformed hashes", func() {
+			_, err := api.GetBlockHeader("0000000000000000000000000000000000000000000000000000000000000001", nil)
+			Expect(err).To(HaveOccurred())
+			Expect(errorCode(err)).To(Equal(-5))
+			_, err = api.GetBlockHeader("0x01", nil)
+			Expect(err).To(HaveOccurred())
+			Expect(errorCode(err)).To(Equal(-8))
+		})
+	})
+
+	Describe("GetBlock", func() {
+		It("Returns the serialized block with verbosity 0", func() {
+			verbosity := 0
+			res, err := api.GetBlock(blockHash, &verbosity)
+			Expect(err).ToNot(HaveOccurred())
+			var buf bytes.Buffer
+			err = mocks.MockBlock.Serialize(&buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(Equal(hex.EncodeToString(buf.Bytes())))
+		})
+
+		It("Returns the decoded block and its transaction ids by default", func() {
+			res, err := api.GetBlock(blockHash, nil)
+			Expect(err).ToNot(HaveOccurred())
+			block, ok := res.(*btc.BlockResult)
+			Expect(ok).To(BeTrue())
+			Expect(block.Hash).To(Equal(blockHash))
+			Expect(block.Size).To(Equal(mocks.MockBlock.SerializeSize()))
+			Expect(block.Weight).To(Equal(4 * mocks.MockBlock.SerializeSize()))
+			txids := make([]string, len(mocks.MockBlock.Transactions))
+			for i, trx := range mocks.MockBlock.Transactions {
+				txids[i] = trx.TxHash().String()
+			}
+			Expect(block.Tx).To(Equal(txids))
+		})
+
+		It("Returns the decoded block and its decoded transactions with verbosity 2", func() {
+			verbosity := 2
+			res, err := api.GetBlock(blockHash, &verbosity)
+			Expect(err).ToNot(HaveOccurred())
+			block, ok := res.(*btc.BlockResult)
+			Expect(ok).To(BeTrue())
+			txs, ok := block.Tx.([]*btc.TxResult)
+			Expect(ok).To(BeTrue())
+			Expect(txs).To(HaveLen(len(mocks.MockBlock.Transactions)))
+			Expect(txs[0].Vin[0].Coinbase).To(Equal("044c86041b020602"))
+			Expect(txs[0].Vout[0].Value).To(Equal(50.0))
+			Expect(txs[0].Vout[0].ScriptPubKey.Type).To(Equal("pubkey"))
+			Expect(txs[0].BlockHash).To(BeEmpty())
+			Expect(txs[1].Txid).To(Equal(txid))
+			Expect(*txs[1].Vin[0].Vout).To(Equal(mocks.MockBlock.Transactions[1].TxIn[0].PreviousOutPoint.Index))
+		})
+	})
+
+	Describe("GetRawTransaction", func() {
+		It("Returns the serialized transaction by default", func() {
+			res, err := api.GetRawTransaction(txid, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			var buf bytes.Buffer
+			err = mocks.MockBlock.Transactions[1].Serialize(&buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res).To(Equal(hex.EncodeToString(buf.Bytes())))
+		})
+
+		It("Returns the decoded transaction along with its block when verbose", func() {
+			res, err := api.GetRawTransaction(txid, &verbose, &blockHash)
+			Expect(err).ToNot(HaveOccurred())
+			trx, ok := res.(*btc.TxResult)
+			Expect(ok).To(BeTrue())
+			Expect(trx.Txid).To(Equal(txid))
+			Expect(trx.BlockHash).To(Equal(blockHash))
+			Expect(*trx.Confirmations).To(Equal(int64(1)))
+			Expect(*trx.InActiveChain).To(BeTrue())
+			Expect(trx.Vout).To(HaveLen(len(mocks.MockBlock.Transactions[1].TxOut)))
+			Expect(trx.Vout[0].ScriptPubKey.Addresses).To(Equal([]string{mocks.MockTxsMetaData[1].TxOutputs[0].Addresses[0]}))
+		})
+
+		It("Returns bitcoind's error for unknown transactions", func() {
+			_, err := api.GetRawTransaction("0000000000000000000000000000000000000000000000000000000000000001", nil, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(errorCode(err)).To(Equal(-5))
+			otherBlock := mocks.MockBlock.Header.PrevBlock.String()
+			_, err = api.GetRawTransaction(txid, nil, &otherBlock)
+			Expect(err).To(HaveOccurred())
+			Expect(errorCode(err)).To(Equal(-5))
+		})
+	})
+	Describe("GetBlockFilter and GetBlockFilters", func() {
+		var (
+			genesisHash  = chaincfg.MainNetParams.GenesisHash.String()
+			basic        = "basic"
+			extended     = "extended"
+			genesisEntry = &btc.BlockFiltersResult{
+				Height:    0,
+				BlockHash: genesisHash,
+				Filter:    "017fa880",
+				Header:    "02c2392180d0ce2b5b6f8b08d39a11ffe831c673311a3ecf77b97fc3f0303c9f",
+			}
+		)
+		BeforeEach(func() {
+			_, err := btc.NewIPLDPublisherAndIndexer(db, nil, nil).Publish(genesisPayload())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Returns the basic filter of the block and its filter header", func() {
+			filter, err := api.GetBlockFilter(genesisHash, &basic)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filter.Filter).To(Equal(genesisEntry.Filter))
+			Expect(filter.Header).To(Equal(genesisEntry.Header))
+
+			// the mock block spends outputs which have not been indexed, so it has no filter
+			_, err = api.GetBlockFilter(blockHash, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(errorCode(err)).To(Equal(-1))
+			_, err = api.GetBlockFilter("0000000000000000000000000000000000000000000000000000000000000001", nil)
+			Expect(err).To(HaveOccurred())
+			Expect(errorCode(err)).To(Equal(-5))
+			_, err = api.GetBlockFilter(genesisHash, &extended)
+			Expect(err).To(HaveOccurred())
+			Expect(errorCode(err)).To(Equal(-5))
+		})
+
+		It("Returns the filters of consecutive canonical blocks, stopping at the first block without one", func() {
+			filters, err := api.GetBlockFilters(0, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filters).To(Equal([]*btc.BlockFiltersResult{genesisEntry}))
+
+			filters, err = api.GetBlockFilters(mocks.MockBlockHeight, nil, &basic)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filters).To(BeEmpty())
+
+			count := 0
+			_, err = api.GetBlockFilters(0, &count, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(errorCode(err)).To(Equal(-8))
+			_, err = api.GetBlockFilters(mocks.MockBlockHeight+1, nil, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(errorCode(err)).To(Equal(-8))
+		})
+	})
+
+	Describe("Unspent outputs", func() {
+		var (
+			address   = mocks.MockTxsMetaData[1].TxOutputs[0].Addresses[0]
+			recipient = mocks.MockTxsMetaData[2].TxOutputs[0].Addresses[0]
+			spend     = spendingTx(wire.OutPoint{Hash: mocks.MockBlock.Transactions[1].TxHash(), Index: 0},
+				555000000, mocks.MockBlock.Transactions[2].TxOut[0].PkScript)
+			coinbase = spendingTx(wire.OutPoint{Hash: chainhash.Hash{}, Index: wire.MaxPrevOutIndex},
+				5000000000, mocks.MockBlock.Transactions[0].TxOut[0].PkScript)
+			mockHeight = mocks.MockBlockHeight
+		)
+
+		It("Lists the unspent outputs paying to the addresses", func() {
+			unspents, err := api.ListUnspent(nil, nil, []string{address})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unspents).To(HaveLen(1))
+			Expect(unspents[0].Txid).To(Equal(txid))
+			Expect(unspents[0].Vout).To(Equal(uint32(0)))
+			Expect(unspents[0].Address).To(Equal(address))
+			Expect(unspents[0].Amount).To(Equal(5.56))
+			Expect(unspents[0].Confirmations).To(Equal(int64(1)))
+			Expect(unspents[0].Coinbase).To(BeFalse())
+
+			minConf := int64(2)
+			unspents, err = api.ListUnspent(&minConf, nil, []string{address})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unspents).To(BeEmpty())
+
+			_, err = api.ListUnspent(nil, nil, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(errorCode(err)).To(Equal(-8))
+			_, err = api.ListUnspent(nil, nil, []string{"notanaddress"})
+			Expect(err).To(HaveOccurred())
+			Expect(errorCode(err)).To(Equal(-5))
+		})
+
+		It("Summarizes and pages through the unspent outputs at a height", func() {
+			info, err := api.GetTxOutSetInfo(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.Height).To(Equal(mockHeight))
+			Expect(info.BestBlock).To(Equal(blockHash))
+			Expect(info.TxOuts).To(Equal(int64(5)))
+			Expect(info.TotalAmount).To(Equal(103.0))
+
+			count := 2
+			txids := make([]string, 0)
+			var next *int64
+			for pages := 0; ; pages++ {
+				Expect(pages).To(BeNumerically("<", 3))
+				page, err := api.GetUTXOSet(mockHeight, nil, next, &count)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(page.BestBlock).To(Equal(blockHash))
+				for _, unspent := range page.Unspents {
+					txids = append(txids, unspent.Txid)
+				}
+				if page.Next == nil {
+					break
+				}
+				next = page.Next
+			}
+			Expect(txids).To(HaveLen(5))
+
+			_, err = api.GetUTXOSet(mockHeight+1, nil, nil, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(errorCode(err)).To(Equal(-8))
+		})
+
+		It("Spends outputs in canonical blocks and unspends them when the block is reorged out", func() {
+			_, err := btc.NewIPLDPublisherAndIndexer(db, nil, nil).Publish(childPayload(1, spend))
+			Expect(err).ToNot(HaveOccurred())
+
+			balance, err := api.GetAddressBalance([]string{address}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(balance.Height).To(Equal(mockHeight + 1))
+			Expect(balance.Balance).To(Equal(int64(0)))
+			Expect(balance.Received).To(Equal(int64(556000000)))
+			unspents, err := api.ListUnspent(nil, nil, []string{address})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unspents).To(BeEmpty())
+			unspents, err = api.ListUnspent(nil, nil, []string{recipient})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unspents).To(HaveLen(2))
+
+			// the output was still unspent at the previous height
+			balance, err = api.GetAddressBalance([]string{address}, &mockHeight)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(balance.Balance).To(Equal(int64(556000000)))
+			page, err := api.GetUTXOSet(mockHeight, []string{address}, nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(page.Unspents).To(HaveLen(1))
+			Expect(page.Next).To(BeNil())
+
+			// a competing block without the spend replaces it
+			_, err = btc.NewIPLDPublisherAndIndexer(db, nil, nil).Publish(childPayload(2, coinbase))
+			Expect(err).ToNot(HaveOccurred())
+			balance, err = api.GetAddressBalance([]string{address}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(balance.Balance).To(Equal(int64(556000000)))
+			unspents, err = api.ListUnspent(nil, nil, []string{recipient})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unspents).To(HaveLen(1))
+			info, err := api.GetTxOutSetInfo(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.TxOuts).To(Equal(int64(6)))
+			Expect(info.TotalAmount).To(Equal(153.0))
+		})
+	})
+})
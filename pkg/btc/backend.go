@@ -0,0 +1,666 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"bytes"
+	"database/sql"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// medianTimeBlocks is the number of blocks whose timestamps make up the median time past of a block
+const medianTimeBlocks = 11
+
+// Backend retrieves and decodes the bitcoin headers and transactions indexed by the watcher
+type Backend struct {
+	Retriever *CIDRetriever
+	Fetcher   *IPLDPGFetcher
+	DB        *postgres.DB
+	Params    *chaincfg.Params
+}
+
+// NewBtcBackend returns a new Backend for the chain with the provided params
+func NewBtcBackend(db *postgres.DB, params *chaincfg.Params) (*Backend, error) {
+	return &Backend{
+		Retriever: NewCIDRetriever(db),
+		Fetcher:   NewIPLDPGFetcher(db),
+		DB:        db,
+		Params:    params,
+	}, nil
+}
+
+// BlockContext is the location of a header in the indexed chain
+type BlockContext struct {
+	Height        int64
+	Confirmations int64
+	MedianTime    int64
+	TxCount       int
+	// NextHash is the hash of the canonical block at the next height, nil if there is none or the block is not canonical
+	NextHash *chainhash.Hash
+}
+
+// HeaderByNumber returns the canonical header at the provided height, or sql.ErrNoRows if there is none
+func (b *Backend) HeaderByNumber(height int64) (*wire.BlockHeader, *BlockContext, error) {
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	headerCIDs, err := b.Retriever.RetrieveHeaderCIDs(tx, height)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(headerCIDs) < 1 {
+		err = sql.ErrNoRows
+		return nil, nil, err
+	}
+	header, err := b.fetchHeader(tx, headerCIDs[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	blockCtx, err := b.blockContext(tx, headerCIDs[0])
+	return header, blockCtx, err
+}
+
+// HeaderByHash returns the header with the provided hash
+func (b *Backend) HeaderByHash(hash chainhash.Hash) (*wire.BlockHeader, *BlockContext, error) {
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	headerCID, err := b.Retriever.RetrieveHeaderCIDByHash(tx, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	header, err := b.fetchHeader(tx, headerCID)
+	if err != nil {
+		return nil, nil, err
+	}
+	blockCtx, err := b.blockContext(tx, headerCID)
+	return header, blockCtx, err
+}
+
+// BlockByHash returns the block with the provided hash, composed from its header and transaction IPLDs
+func (b *Backend) BlockByHash(hash chainhash.Hash) (*wire.MsgBlock, *BlockContext, error) {
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	headerCID, err := b.Retriever.RetrieveHeaderCIDByHash(tx, hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	header, err := b.fetchHeader(tx, headerCID)
+	if err != nil {
+		return nil, nil, err
+	}
+	txCIDs, err := b.Retriever.RetrieveTxCIDsByHeaderID(tx, headerCID.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	transactions, err := b.fetchTransactions(tx, txCIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	blockCtx, err := b.blockContext(tx, headerCID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &wire.MsgBlock{
+		Header:       *header,
+		Transactions: transactions,
+	}, blockCtx, err
+}
+
+// TransactionByHash returns the transaction with the provided hash, along with the header of the block which includes it
+func (b *Backend) TransactionByHash(hash chainhash.Hash) (*wire.MsgTx, *wire.BlockHeader, *BlockContext, error) {
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	txCID, err := b.Retriever.RetrieveTxCIDByHash(tx, hash)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	transactions, err := b.fetchTransactions(tx, []TxModel{txCID})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	headerCID, err := b.Retriever.RetrieveHeaderCIDByID(tx, txCID.HeaderID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	header, err := b.fetchHeader(tx, headerCID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	blockCtx, err := b.blockContext(tx, headerCID)
+	return transactions[0], header, blockCtx, err
+}
+
+// fetchHeader fetches and decodes the header IPLD referenced by the header cid
+func (b *Backend) fetchHeader(tx *sqlx.Tx, headerCID HeaderModel) (*wire.BlockHeader, error) {
+	headerIPLD, err := b.Fetcher.FetchHeader(tx, headerCID)
+	if err != nil {
+		return nil, err
+	}
+	header := new(wire.BlockHeader)
+	return header, header.Deserialize(bytes.NewReader(headerIPLD.Data))
+}
+
+// fetchTransactions fetches and decodes the transaction IPLDs referenced by the tx cids
+func (b *Backend) fetchTransactions(tx *sqlx.Tx, txCIDs []TxModel) ([]*wire.MsgTx, error) {
+	txIPLDs, err := b.Fetcher.FetchTrxs(tx, txCIDs)
+	if err != nil {
+		return nil, err
+	}
+	transactions := make([]*wire.MsgTx, len(txIPLDs))
+	for i, txIPLD := range txIPLDs {
+		transactions[i] = new(wire.MsgTx)
+		if err := transactions[i].Deserialize(bytes.NewReader(txIPLD.Data)); err != nil {
+			return nil, err
+		}
+	}
+	return transactions, nil
+}
+
+// blockContext locates the header in the indexed chain
+// Headers which are not canonical have -1 confirmations, as they do in bitcoind
+func (b *Backend) blockContext(tx *sqlx.Tx, headerCID HeaderModel) (*BlockContext, error) {
+	height, err := strconv.ParseInt(headerCID.BlockNumber, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	blockCtx := &BlockContext{
+		Height:        height,
+		Confirmations: -1,
+	}
+	timestamps, err := b.Retriever.RetrieveTimestamps(tx, height-medianTimeBlocks+1, height-1)
+	if err != nil {
+		return nil, err
+	}
+	timestamps = append(timestamps, headerCID.Timestamp)
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+	blockCtx.MedianTime = timestamps[len(timestamps)/2] / 1e9
+	blockCtx.TxCount, err = b.Retriever.RetrieveTxCount(tx, headerCID.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !headerCID.Canonical {
+		return blockCtx, nil
+	}
+	head, err := b.Retriever.RetrieveLastBlockNumber()
+	if err != nil {
+		return nil, err
+	}
+	blockCtx.Confirmations = head - height + 1
+	next, err := b.Retriever.RetrieveHeaderCIDs(tx, height+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(next) > 0 {
+		blockCtx.NextHash, err = chainhash.NewHashFromStr(next[0].BlockHash)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return blockCtx, nil
+}
+
+// UTXOSetInfo summarizes the outputs which are unspent as of a canonical block
+type UTXOSetInfo struct {
+	Height    int64
+	BlockHash string
+	Count     int64
+	Total     int64
+}
+
+// UTXOs returns the outputs which are unspent as of the canonical block at the provided height, paying to any of the
+// addresses if they are provided, in pages of up to limit outputs following the output with the provided id
+func (b *Backend) UTXOs(height int64, addresses []string, afterID int64, limit int) ([]UTXOModel, error) {
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	utxos, err := b.Retriever.RetrieveUTXOs(tx, height, addresses, afterID, limit)
+	return utxos, err
+}
+
+// AddressBalance returns the total value received by the addresses and their unspent balance as of the canonical block at the provided height
+func (b *Backend) AddressBalance(height int64, addresses []string) (int64, int64, error) {
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	received, balance, err := b.Retriever.RetrieveAddressBalance(tx, height, addresses)
+	return received, balance, err
+}
+
+// UTXOSetInfo summarizes the outputs which are unspent as of the canonical block at the provided height,
+// or returns sql.ErrNoRows if there is no canonical block at that height
+func (b *Backend) UTXOSetInfo(height int64) (*UTXOSetInfo, error) {
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	headerCIDs, err := b.Retriever.RetrieveHeaderCIDs(tx, height)
+	if err != nil {
+		return nil, err
+	}
+	if len(headerCIDs) < 1 {
+		err = sql.ErrNoRows
+		return nil, err
+	}
+	info := &UTXOSetInfo{
+		Height:    height,
+		BlockHash: headerCIDs[0].BlockHash,
+	}
+	info.Count, info.Total, err = b.Retriever.RetrieveUTXOSetInfo(tx, height)
+	return info, err
+}
+
+// ScriptHashHistory returns the canonical transactions which pay to or spend an output with the provided script hash
+func (b *Backend) ScriptHashHistory(scriptHash []byte) ([]HistoryModel, error) {
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	history, err := b.Retriever.RetrieveScriptHashHistory(tx, scriptHash)
+	return history, err
+}
+
+// ScriptHashUTXOs returns the outputs with the provided script hash which are unspent at the chain head
+func (b *Backend) ScriptHashUTXOs(scriptHash []byte) ([]UTXOModel, error) {
+	head, err := b.Retriever.RetrieveLastBlockNumber()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	utxos, err := b.Retriever.RetrieveScriptHashUTXOs(tx, head, scriptHash)
+	return utxos, err
+}
+
+// ScriptHashesByBlockHash returns the script hashes of the outputs created and spent by the block with the provided hash,
+// or sql.ErrNoRows if the block has not been indexed
+func (b *Backend) ScriptHashesByBlockHash(hash chainhash.Hash) ([][]byte, error) {
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	headerCID, err := b.Retriever.RetrieveHeaderCIDByHash(tx, hash)
+	if err != nil {
+		return nil, err
+	}
+	scriptHashes, err := b.Retriever.RetrieveScriptHashesByHeaderID(tx, headerCID.ID)
+	return scriptHashes, err
+}
+
+// Headers returns up to count consecutive canonical headers starting at the provided height, stopping at the first
+// height without a canonical header
+func (b *Backend) Headers(start, count int64) ([]*wire.BlockHeader, error) {
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	headerCIDs, err := b.Retriever.RetrieveHeaderCIDsByRange(tx, start, start+count-1)
+	if err != nil {
+		return nil, err
+	}
+	headers := make([]*wire.BlockHeader, 0, len(headerCIDs))
+	for i, headerCID := range headerCIDs {
+		if headerCID.BlockNumber != strconv.FormatInt(start+int64(i), 10) {
+			break
+		}
+		var header *wire.BlockHeader
+		header, err = b.fetchHeader(tx, headerCID)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+	return headers, nil
+}
+
+// BlockFilter returns the basic filter of the block with the provided hash, or sql.ErrNoRows if the block has not been
+// indexed; the filter is nil if the block has been indexed without one
+func (b *Backend) BlockFilter(hash chainhash.Hash) (*BlockFilterModel, error) {
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	headerCID, err := b.Retriever.RetrieveHeaderCIDByHash(tx, hash)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := b.Retriever.RetrieveBlockFilterByHeaderID(tx, headerCID.ID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &filter, nil
+}
+
+// BlockFilters returns the basic filters of up to count consecutive canonical blocks starting at the provided height,
+// stopping at the first height without a canonical block whose filter and filter header have been indexed
+func (b *Backend) BlockFilters(start, count int64) ([]BlockFilterWithHeaderModel, error) {
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	filters, err := b.Retriever.RetrieveBlockFiltersByRange(tx, start, start+count-1)
+	if err != nil {
+		return nil, err
+	}
+	for i, filter := range filters {
+		if filter.BlockNumber != start+int64(i) || !filter.FilterHeader.Valid {
+			return filters[:i], nil
+		}
+	}
+	return filters, nil
+}
+
+// IndexedTx is an indexed transaction along with the header which includes it, and the indexed outputs its inputs spend
+type IndexedTx struct {
+	Tx    *wire.MsgTx
+	Block BlockTxModel
+	// Prevouts maps the index of each input to the output it spends, if that output has been indexed
+	Prevouts map[int64]PrevoutModel
+}
+
+// IndexedTransaction returns the transaction with the provided hash along with its block and prevouts, or sql.ErrNoRows
+// if it has not been indexed
+func (b *Backend) IndexedTransaction(hash chainhash.Hash) (*IndexedTx, error) {
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	txCID, err := b.Retriever.RetrieveBlockTxCIDByHash(tx, hash)
+	if err != nil {
+		return nil, err
+	}
+	indexedTxs, err := b.indexedTransactions(tx, []BlockTxModel{txCID})
+	if err != nil {
+		return nil, err
+	}
+	return indexedTxs[0], nil
+}
+
+// AddressTransactions returns up to limit canonical transactions which pay to or spend from the address, newest first,
+// starting after the transaction with the lastSeen hash if it is provided
+// It returns sql.ErrNoRows if the lastSeen transaction is not included in a canonical block
+func (b *Backend) AddressTransactions(address string, lastSeen *chainhash.Hash, limit int) ([]*IndexedTx, error) {
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	beforeHeight, beforeIndex := int64(math.MaxInt64), int64(0)
+	if lastSeen != nil {
+		var lastSeenCID BlockTxModel
+		lastSeenCID, err = b.Retriever.RetrieveBlockTxCIDByHash(tx, *lastSeen)
+		if err != nil {
+			return nil, err
+		}
+		if !lastSeenCID.Canonical {
+			err = sql.ErrNoRows
+			return nil, err
+		}
+		beforeHeight, beforeIndex = lastSeenCID.BlockNumber, lastSeenCID.Index
+	}
+	txCIDs, err := b.Retriever.RetrieveAddressTxCIDs(tx, address, beforeHeight, beforeIndex, limit)
+	if err != nil {
+		return nil, err
+	}
+	indexedTxs, err := b.indexedTransactions(tx, txCIDs)
+	return indexedTxs, err
+}
+
+// Outspends returns the outputs of the transaction with the provided hash along with the inputs which spend them in
+// canonical blocks, or sql.ErrNoRows if the transaction has not been indexed
+func (b *Backend) Outspends(hash chainhash.Hash) ([]OutspendModel, error) {
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	txCID, err := b.Retriever.RetrieveTxCIDByHash(tx, hash)
+	if err != nil {
+		return nil, err
+	}
+	outspends, err := b.Retriever.RetrieveOutspends(tx, txCID.ID)
+	return outspends, err
+}
+
+// indexedTransactions fetches and decodes the transactions referenced by the cids, along with their prevouts
+func (b *Backend) indexedTransactions(tx *sqlx.Tx, txCIDs []BlockTxModel) ([]*IndexedTx, error) {
+	cids := make([]TxModel, len(txCIDs))
+	txIDs := make([]int64, len(txCIDs))
+	indexedTxs := make([]*IndexedTx, len(txCIDs))
+	byID := make(map[int64]*IndexedTx, len(txCIDs))
+	for i, txCID := range txCIDs {
+		cids[i] = txCID.TxModel
+		txIDs[i] = txCID.ID
+		indexedTxs[i] = &IndexedTx{
+			Block:    txCID,
+			Prevouts: make(map[int64]PrevoutModel),
+		}
+		byID[txCID.ID] = indexedTxs[i]
+	}
+	transactions, err := b.fetchTransactions(tx, cids)
+	if err != nil {
+		return nil, err
+	}
+	for i, trx := range transactions {
+		indexedTxs[i].Tx = trx
+	}
+	prevouts, err := b.Retriever.RetrievePrevouts(tx, txIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, prevout := range prevouts {
+		byID[prevout.TxID].Prevouts[prevout.InputIndex] = prevout
+	}
+	return indexedTxs, nil
+}
@@ -0,0 +1,204 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/bech32"
+)
+
+// btcutil v1.0.2 only encodes and decodes witness v0 addresses, which use the bech32 checksum; addresses for witness
+// v1 and above use the bech32m checksum defined by BIP350
+
+const (
+	bech32mCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	bech32mConst   = 0x2bc830a3
+)
+
+var bech32mGenerator = []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+// AddressWitness is a pay-to-witness address for witness versions 1 through 16, such as a taproot address
+// It satisfies the btcutil.Address interface
+type AddressWitness struct {
+	hrp            string
+	witnessVersion byte
+	witnessProgram []byte
+}
+
+// NewAddressWitness returns a new AddressWitness for the witness program of the provided version
+func NewAddressWitness(witnessVersion byte, witnessProgram []byte, params *chaincfg.Params) (*AddressWitness, error) {
+	if witnessVersion < 1 || witnessVersion > 16 {
+		return nil, fmt.Errorf("invalid witness version for a bech32m address: %d", witnessVersion)
+	}
+	if len(witnessProgram) < 2 || len(witnessProgram) > 40 {
+		return nil, fmt.Errorf("invalid witness program length: %d", len(witnessProgram))
+	}
+	return &AddressWitness{
+		hrp:            strings.ToLower(params.Bech32HRPSegwit),
+		witnessVersion: witnessVersion,
+		witnessProgram: witnessProgram,
+	}, nil
+}
+
+// EncodeAddress returns the bech32m encoding of the address
+func (a *AddressWitness) EncodeAddress() string {
+	converted, err := bech32.ConvertBits(a.witnessProgram, 8, 5, true)
+	if err != nil {
+		return ""
+	}
+	return encodeBech32m(a.hrp, append([]byte{a.witnessVersion}, converted...))
+}
+
+// ScriptAddress returns the witness program
+func (a *AddressWitness) ScriptAddress() []byte {
+	return a.witnessProgram
+}
+
+// IsForNet returns whether or not the address is associated with the network
+func (a *AddressWitness) IsForNet(params *chaincfg.Params) bool {
+	return a.hrp == strings.ToLower(params.Bech32HRPSegwit)
+}
+
+// String returns the bech32m encoding of the address
+func (a *AddressWitness) String() string {
+	return a.EncodeAddress()
+}
+
+// WitnessVersion returns the witness version of the address
+func (a *AddressWitness) WitnessVersion() byte {
+	return a.witnessVersion
+}
+
+// DecodeAddress decodes the address for the network with the provided params, it decodes the addresses btcutil
+// does as well as bech32m addresses for witness versions 1 through 16
+func DecodeAddress(address string, params *chaincfg.Params) (btcutil.Address, error) {
+	hrp := strings.ToLower(params.Bech32HRPSegwit)
+	if !strings.HasPrefix(strings.ToLower(address), hrp+"1") {
+		return btcutil.DecodeAddress(address, params)
+	}
+	// Witness v0 addresses are decoded by btcutil, which rejects those encoded with the bech32m checksum
+	decodedHRP, data, err := decodeBech32m(address)
+	if err != nil {
+		return btcutil.DecodeAddress(address, params)
+	}
+	if decodedHRP != hrp {
+		return nil, fmt.Errorf("address %s is not for the %s network", address, params.Name)
+	}
+	if len(data) < 1 || data[0] == 0 || data[0] > 16 {
+		return nil, fmt.Errorf("invalid witness version for a bech32m address: %s", address)
+	}
+	program, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return nil, err
+	}
+	return NewAddressWitness(data[0], program, params)
+}
+
+// encodeBech32m encodes the 5 bit groups with the human readable part and a bech32m checksum
+func encodeBech32m(hrp string, data []byte) string {
+	checksum := bech32mChecksum(hrp, data)
+	var b strings.Builder
+	b.WriteString(hrp)
+	b.WriteByte('1')
+	for _, d := range data {
+		b.WriteByte(bech32mCharset[d])
+	}
+	for _, d := range checksum {
+		b.WriteByte(bech32mCharset[d])
+	}
+	return b.String()
+}
+
+// decodeBech32m decodes a bech32m string, returning its lower-cased human readable part and its 5 bit groups
+// excluding the checksum
+func decodeBech32m(str string) (string, []byte, error) {
+	if len(str) < 8 || len(str) > 90 {
+		return "", nil, fmt.Errorf("invalid bech32m string length %d", len(str))
+	}
+	lower := strings.ToLower(str)
+	if str != lower && str != strings.ToUpper(str) {
+		return "", nil, errors.New("bech32m string is not all lowercase or all uppercase")
+	}
+	one := strings.LastIndexByte(lower, '1')
+	if one < 1 || one+7 > len(lower) {
+		return "", nil, errors.New("invalid bech32m separator index")
+	}
+	hrp := lower[:one]
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", nil, fmt.Errorf("invalid character in bech32m string: %q", hrp[i])
+		}
+	}
+	data := make([]byte, 0, len(lower)-one-1)
+	for _, c := range []byte(lower[one+1:]) {
+		d := bytes.IndexByte([]byte(bech32mCharset), c)
+		if d < 0 {
+			return "", nil, fmt.Errorf("invalid character in bech32m string: %q", c)
+		}
+		data = append(data, byte(d))
+	}
+	if bech32mPolymod(append(bech32mHRPExpand(hrp), data...)) != bech32mConst {
+		return "", nil, errors.New("invalid bech32m checksum")
+	}
+	return hrp, data[:len(data)-6], nil
+}
+
+// bech32mChecksum returns the 6 bech32m checksum groups of the data
+func bech32mChecksum(hrp string, data []byte) []byte {
+	values := append(bech32mHRPExpand(hrp), data...)
+	values = append(values, make([]byte, 6)...)
+	polymod := bech32mPolymod(values) ^ bech32mConst
+	checksum := make([]byte, 6)
+	for i := range checksum {
+		checksum[i] = byte((polymod >> uint(5*(5-i))) & 31)
+	}
+	return checksum
+}
+
+// bech32mHRPExpand expands the human readable part into the values it is checksummed as
+func bech32mHRPExpand(hrp string) []byte {
+	expanded := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+	return expanded
+}
+
+// bech32mPolymod computes the BCH checksum polynomial of the values
+func bech32mPolymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i, g := range bech32mGenerator {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= g
+			}
+		}
+	}
+	return chk
+}
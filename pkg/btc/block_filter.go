@@ -0,0 +1,365 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+	"sort"
+	"strconv"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// BasicFilterP is the Golomb-Rice coding parameter of BIP158 basic filters
+	BasicFilterP = 19
+	// BasicFilterM is the inverse of the false positive rate of BIP158 basic filters
+	BasicFilterM = 784931
+)
+
+// BuildBasicFilter builds the serialized BIP158 basic filter of the block with the provided hash from the scripts it
+// matches: the output scripts of the block, except OP_RETURN outputs, and the scripts of the outputs its inputs spend
+// The scripts are hashed into a Golomb-coded set keyed by the block hash, serialized after the number of scripts it holds
+func BuildBasicFilter(blockHash chainhash.Hash, scripts [][]byte) []byte {
+	values := hashedSetValues(blockHash, scripts)
+	var buf bytes.Buffer
+	// Writes to a bytes.Buffer do not fail
+	_ = wire.WriteVarInt(&buf, 0, uint64(len(values)))
+	w := &bitWriter{buf: &buf}
+	var last uint64
+	for _, value := range values {
+		delta := value - last
+		last = value
+		for q := delta >> BasicFilterP; q > 0; q-- {
+			w.writeBit(true)
+		}
+		w.writeBit(false)
+		w.writeBits(delta, BasicFilterP)
+	}
+	w.flush()
+	return buf.Bytes()
+}
+
+// MatchBasicFilter returns whether or not the serialized BIP158 basic filter of the block with the provided hash matches
+// any of the scripts; a filter can match scripts it was not built from, at a rate of 1 in BasicFilterM
+func MatchBasicFilter(filter []byte, blockHash chainhash.Hash, scripts [][]byte) (bool, error) {
+	r := bytes.NewReader(filter)
+	n, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return false, err
+	}
+	if n == 0 || len(scripts) == 0 {
+		return false, nil
+	}
+	targets := hashedValues(blockHash, n, scripts)
+	br := &bitReader{r: r}
+	var value uint64
+	for i := uint64(0); i < n; i++ {
+		delta, err := br.readGolombRice()
+		if err != nil {
+			return false, err
+		}
+		value += delta
+		for len(targets) > 0 && targets[0] < value {
+			targets = targets[1:]
+		}
+		if len(targets) == 0 {
+			return false, nil
+		}
+		if targets[0] == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FilterHeader returns the BIP157 header of the filter, which commits to the filter and to the header of the filter of the
+// previous block; the previous header of the genesis block's filter is the zero hash
+func FilterHeader(filter []byte, prevHeader chainhash.Hash) chainhash.Hash {
+	filterHash := chainhash.DoubleHashH(filter)
+	return chainhash.DoubleHashH(append(filterHash[:], prevHeader[:]...))
+}
+
+// filterScripts accumulates the scripts matched by the basic filter of a block as its transactions are indexed
+type filterScripts struct {
+	scripts [][]byte
+	// unresolved is set once an input spends an output which could not be resolved, the filter cannot be built without it
+	unresolved bool
+}
+
+// addOutput adds an output script of the block, empty and OP_RETURN scripts are not matched by the filter
+func (f *filterScripts) addOutput(pkScript []byte) {
+	if len(pkScript) == 0 || pkScript[0] == txscript.OP_RETURN {
+		return
+	}
+	f.scripts = append(f.scripts, pkScript)
+}
+
+// addPrevout adds the script of an output spent by the block, nil if the output could not be resolved
+func (f *filterScripts) addPrevout(pkScript []byte) {
+	if pkScript == nil {
+		f.unresolved = true
+		return
+	}
+	if len(pkScript) > 0 {
+		f.scripts = append(f.scripts, pkScript)
+	}
+}
+
+// indexBlockFilter indexes the basic filter of the block along with its filter header, which is left null until the
+// filter header of the parent block is known; it is called once the canonical chain is locked
+// Blocks are not necessarily indexed in order, so the filter headers of the descendants already indexed are computed
+// from the block's own
+// A block spending outputs which could not be resolved has no filter until it is reindexed
+func (in *CIDIndexer) indexBlockFilter(tx *sqlx.Tx, header HeaderModel, headerID int64, scripts *filterScripts) error {
+	if scripts.unresolved {
+		logrus.Debugf("btc indexer unable to build the filter of block %s: the outputs it spends are not all resolved", header.BlockHash)
+		return nil
+	}
+	blockHash, err := chainhash.NewHashFromStr(header.BlockHash)
+	if err != nil {
+		return err
+	}
+	blockNumber, err := strconv.ParseInt(header.BlockNumber, 10, 64)
+	if err != nil {
+		return err
+	}
+	filter := BuildBasicFilter(*blockHash, scripts.scripts)
+	var filterHeader sql.NullString
+	if blockNumber == 0 {
+		filterHeader = sql.NullString{String: FilterHeader(filter, chainhash.Hash{}).String(), Valid: true}
+	} else {
+		var prevHeader sql.NullString
+		err := tx.Get(&prevHeader, `SELECT block_filters.filter_header FROM btc.block_filters
+									INNER JOIN btc.header_cids ON (block_filters.header_id = header_cids.id)
+									WHERE header_cids.block_number = $1 AND header_cids.block_hash = $2`, blockNumber-1, header.ParentHash)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if prevHeader.Valid {
+			prev, err := chainhash.NewHashFromStr(prevHeader.String)
+			if err != nil {
+				return err
+			}
+			filterHeader = sql.NullString{String: FilterHeader(filter, *prev).String(), Valid: true}
+		}
+	}
+	_, err = tx.Exec(`INSERT INTO btc.block_filters (header_id, filter, filter_hash, filter_header) VALUES ($1, $2, $3, $4)
+						ON CONFLICT (header_id) DO UPDATE SET (filter, filter_hash, filter_header) = ($2, $3, COALESCE($4, btc.block_filters.filter_header))`,
+		headerID, filter, chainhash.DoubleHashH(filter).String(), filterHeader)
+	if err != nil || !filterHeader.Valid {
+		return err
+	}
+	return in.linkFilterHeaders(tx, blockNumber, header.BlockHash, filterHeader.String)
+}
+
+// linkFilterHeaders computes the filter headers of the indexed descendants of the block from its filter header, walking
+// up until it reaches descendants whose filter headers are already up to date or whose filters have not been indexed
+func (in *CIDIndexer) linkFilterHeaders(tx *sqlx.Tx, blockNumber int64, blockHash, filterHeader string) error {
+	parents := []BlockFilterWithHeaderModel{{
+		BlockHash:        blockHash,
+		BlockFilterModel: BlockFilterModel{FilterHeader: sql.NullString{String: filterHeader, Valid: true}},
+	}}
+	for len(parents) > 0 {
+		blockNumber++
+		children := make([]BlockFilterWithHeaderModel, 0)
+		for _, parent := range parents {
+			prevHeader, err := chainhash.NewHashFromStr(parent.FilterHeader.String)
+			if err != nil {
+				return err
+			}
+			filters := make([]BlockFilterWithHeaderModel, 0)
+			err = tx.Select(&filters, blockFilters+` WHERE header_cids.block_number = $1 AND header_cids.parent_hash = $2`,
+				blockNumber, parent.BlockHash)
+			if err != nil {
+				return err
+			}
+			for _, child := range filters {
+				childHeader := FilterHeader(child.Filter, *prevHeader).String()
+				if child.FilterHeader.Valid && child.FilterHeader.String == childHeader {
+					continue
+				}
+				if _, err := tx.Exec(`UPDATE btc.block_filters SET filter_header = $2 WHERE id = $1`, child.ID, childHeader); err != nil {
+					return err
+				}
+				child.FilterHeader = sql.NullString{String: childHeader, Valid: true}
+				children = append(children, child)
+			}
+		}
+		parents = children
+	}
+	return nil
+}
+
+// hashedSetValues deduplicates the scripts and hashes them into the sorted values of the set
+func hashedSetValues(blockHash chainhash.Hash, scripts [][]byte) []uint64 {
+	unique := make([][]byte, 0, len(scripts))
+	seen := make(map[string]bool, len(scripts))
+	for _, script := range scripts {
+		if !seen[string(script)] {
+			seen[string(script)] = true
+			unique = append(unique, script)
+		}
+	}
+	return hashedValues(blockHash, uint64(len(unique)), unique)
+}
+
+// hashedValues maps each script to a value in a set of n values, in ascending order
+// The scripts are hashed with SipHash-2-4, keyed by the first 16 bytes of the block hash, into the range [0, n * M)
+func hashedValues(blockHash chainhash.Hash, n uint64, scripts [][]byte) []uint64 {
+	k0 := binary.LittleEndian.Uint64(blockHash[0:8])
+	k1 := binary.LittleEndian.Uint64(blockHash[8:16])
+	modulus := n * BasicFilterM
+	values := make([]uint64, len(scripts))
+	for i, script := range scripts {
+		values[i], _ = bits.Mul64(sipHash24(k0, k1, script), modulus)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	return values
+}
+
+// sipHash24 returns the SipHash-2-4 of the message with the 128 bit key k0, k1
+func sipHash24(k0, k1 uint64, msg []byte) uint64 {
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13) ^ v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16) ^ v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21) ^ v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17) ^ v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+	compress := func(m uint64) {
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+	}
+	length := len(msg)
+	for ; len(msg) >= 8; msg = msg[8:] {
+		compress(binary.LittleEndian.Uint64(msg))
+	}
+	// The last block holds the remaining bytes and the message length in its most significant byte
+	last := uint64(length) << 56
+	for i := len(msg) - 1; i >= 0; i-- {
+		last |= uint64(msg[i]) << (8 * uint(i))
+	}
+	compress(last)
+	v2 ^= 0xff
+	for i := 0; i < 4; i++ {
+		round()
+	}
+	return v0 ^ v1 ^ v2 ^ v3
+}
+
+// bitWriter writes bits to the buffer, most significant bit first
+type bitWriter struct {
+	buf     *bytes.Buffer
+	current byte
+	count   uint
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if bit {
+		w.current |= 1 << (7 - w.count)
+	}
+	w.count++
+	if w.count == 8 {
+		w.buf.WriteByte(w.current)
+		w.current, w.count = 0, 0
+	}
+}
+
+// writeBits writes the n least significant bits of the value
+func (w *bitWriter) writeBits(value uint64, n uint) {
+	for i := n; i > 0; i-- {
+		w.writeBit(value&(1<<(i-1)) != 0)
+	}
+}
+
+// flush writes the last partial byte, padded with zeros
+func (w *bitWriter) flush() {
+	if w.count > 0 {
+		w.buf.WriteByte(w.current)
+		w.current, w.count = 0, 0
+	}
+}
+
+// bitReader reads bits from the reader, most significant bit first
+type bitReader struct {
+	r       io.ByteReader
+	current byte
+	count   uint
+}
+
+func (r *bitReader) readBit() (bool, error) {
+	if r.count == 0 {
+		b, err := r.r.ReadByte()
+		if err == io.EOF {
+			return false, errors.New("truncated golomb-coded set")
+		}
+		if err != nil {
+			return false, err
+		}
+		r.current, r.count = b, 8
+	}
+	r.count--
+	return r.current&(1<<r.count) != 0, nil
+}
+
+// readGolombRice reads a value coded with a unary quotient and a BasicFilterP bit remainder
+func (r *bitReader) readGolombRice() (uint64, error) {
+	var quotient uint64
+	for {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if !bit {
+			break
+		}
+		quotient++
+	}
+	var remainder uint64
+	for i := 0; i < BasicFilterP; i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		remainder <<= 1
+		if bit {
+			remainder |= 1
+		}
+	}
+	return quotient<<BasicFilterP | remainder, nil
+}
@@ -0,0 +1,77 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc_test
+
+import (
+	"encoding/hex"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc/mocks"
+)
+
+var _ = Describe("BlockFilter", func() {
+	Describe("BuildBasicFilter and FilterHeader", func() {
+		It("Reproduces the BIP158 test vector of the testnet genesis block", func() {
+			genesis := chaincfg.TestNet3Params.GenesisBlock
+			scripts := [][]byte{genesis.Transactions[0].TxOut[0].PkScript}
+			filter := btc.BuildBasicFilter(genesis.BlockHash(), scripts)
+			Expect(hex.EncodeToString(filter)).To(Equal("019dfca8"))
+			header := btc.FilterHeader(filter, chainhash.Hash{})
+			Expect(header.String()).To(Equal("21584579b7eb08997773e5aeff3a7f932700042d0ed2a6129012b7d7ae81b750"))
+		})
+
+		It("Serializes an empty set as a zero count", func() {
+			filter := btc.BuildBasicFilter(mocks.MockBlock.BlockHash(), nil)
+			Expect(filter).To(Equal([]byte{0x00}))
+		})
+	})
+
+	Describe("MatchBasicFilter", func() {
+		It("Matches the scripts the filter was built from", func() {
+			blockHash := mocks.MockBlock.BlockHash()
+			scripts := make([][]byte, 0)
+			for _, trx := range mocks.MockBlock.Transactions {
+				for _, output := range trx.TxOut {
+					scripts = append(scripts, output.PkScript)
+				}
+			}
+			filter := btc.BuildBasicFilter(blockHash, scripts)
+			for _, script := range scripts {
+				match, err := btc.MatchBasicFilter(filter, blockHash, [][]byte{script})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(match).To(BeTrue())
+			}
+			match, err := btc.MatchBasicFilter(filter, blockHash, [][]byte{{0x51}, {0x00, 0x14}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(match).To(BeFalse())
+			match, err = btc.MatchBasicFilter(filter, blockHash, [][]byte{{0x51}, scripts[len(scripts)-1]})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(match).To(BeTrue())
+		})
+
+		It("Rejects truncated filters", func() {
+			filter := btc.BuildBasicFilter(mocks.MockBlock.BlockHash(), [][]byte{{0x51}, {0x52}, {0x53}})
+			_, err := btc.MatchBasicFilter(filter[:1], mocks.MockBlock.BlockHash(), [][]byte{{0x54}})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,152 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// FollowerTrackingDepth is the number of recently streamed blocks a streamer remembers, and so the deepest reorg it can
+// walk back through to the fork point
+const FollowerTrackingDepth = 256
+
+// blockClient is the subset of the bitcoind rpc methods used to follow the node's chain
+type blockClient interface {
+	GetBlockCount() (int64, error)
+	GetBlockHash(blockHeight int64) (*chainhash.Hash, error)
+	GetBlock(blockHash *chainhash.Hash) (*wire.MsgBlock, error)
+}
+
+// trackedBlock is a block streamed by a chainFollower
+type trackedBlock struct {
+	height int64
+	hash   chainhash.Hash
+	header wire.BlockHeader
+}
+
+// chainFollower follows the tip of the node's best chain, tracking the blocks it has streamed by hash
+// Every block is streamed once and in order, so no block is skipped when several are connected between syncs; when the
+// node's tip does not extend the last streamed block the follower walks back to the fork point, streaming a
+// BlockDisconnect for each streamed block above it before streaming the blocks replacing them
+type chainFollower struct {
+	// blocks holds the recently streamed blocks in height order, each the parent of the next; the last is our tip
+	blocks []trackedBlock
+}
+
+// sync brings the streamed chain up to the node's tip; it starts at the tip, history is left to backfill
+// It returns early, without error, if quit is closed
+func (f *chainFollower) sync(client blockClient, payloadChan chan shared.RawChainData, quit chan bool) error {
+	tipHeight, err := client.GetBlockCount()
+	if err != nil {
+		return err
+	}
+	tipHash, err := client.GetBlockHash(tipHeight)
+	if err != nil {
+		return err
+	}
+	if len(f.blocks) == 0 {
+		return f.connect(client, tipHeight, tipHeight, payloadChan, quit)
+	}
+	if f.tip().hash == *tipHash {
+		return nil
+	}
+	forkIndex, err := f.forkPoint(client, tipHeight)
+	if err != nil {
+		return err
+	}
+	next := f.tip().height + 1
+	if forkIndex < 0 {
+		logrus.Errorf("btc chain follower: reorg below the %d most recently streamed blocks, the fork point is unknown", len(f.blocks))
+		next = f.blocks[0].height
+	}
+	for len(f.blocks) > forkIndex+1 {
+		block := f.tip()
+		logrus.Warnf("btc chain follower: block %s at height %d disconnected by a reorg", block.hash, block.height)
+		select {
+		case payloadChan <- BlockDisconnect{BlockHeight: block.height, Header: &block.header}:
+		case <-quit:
+			return nil
+		}
+		f.blocks = f.blocks[:len(f.blocks)-1]
+		next = block.height
+	}
+	return f.connect(client, next, tipHeight, payloadChan, quit)
+}
+
+// forkPoint returns the index of the most recent streamed block which is still on the node's chain, or -1 if there is none
+func (f *chainFollower) forkPoint(client blockClient, tipHeight int64) (int, error) {
+	for i := len(f.blocks) - 1; i >= 0; i-- {
+		if f.blocks[i].height > tipHeight {
+			continue
+		}
+		hash, err := client.GetBlockHash(f.blocks[i].height)
+		if err != nil {
+			return 0, err
+		}
+		if *hash == f.blocks[i].hash {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// connect streams the node's blocks at the heights from start to end
+// It stops early if a block does not build on the last streamed block, which means the node reorged while we were
+// fetching; the next sync walks back from there
+func (f *chainFollower) connect(client blockClient, start, end int64, payloadChan chan shared.RawChainData, quit chan bool) error {
+	for height := start; height <= end; height++ {
+		hash, err := client.GetBlockHash(height)
+		if err != nil {
+			return err
+		}
+		block, err := client.GetBlock(hash)
+		if err != nil {
+			return err
+		}
+		if len(f.blocks) > 0 && block.Header.PrevBlock != f.tip().hash {
+			logrus.Debugf("btc chain follower: block %s at height %d does not extend our tip, the node has reorged", hash, height)
+			return nil
+		}
+		select {
+		case payloadChan <- BlockPayload{
+			Header:      &block.Header,
+			BlockHeight: height,
+			Txs:         msgTxsToUtilTxs(block.Transactions),
+		}:
+		case <-quit:
+			return nil
+		}
+		f.blocks = append(f.blocks, trackedBlock{
+			height: height,
+			hash:   *hash,
+			header: block.Header,
+		})
+		if len(f.blocks) > FollowerTrackingDepth {
+			f.blocks = f.blocks[len(f.blocks)-FollowerTrackingDepth:]
+		}
+	}
+	return nil
+}
+
+// tip returns the last streamed block
+func (f *chainFollower) tip() trackedBlock {
+	return f.blocks[len(f.blocks)-1]
+}
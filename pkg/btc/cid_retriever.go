@@ -21,7 +21,7 @@ import (
 	"fmt"
 	"math/big"
 
-	"github.com/ethereum/go-ethereum/common"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
@@ -50,10 +50,10 @@ func (bcr *CIDRetriever) RetrieveFirstBlockNumber() (int64, error) {
 	return blockNumber, err
 }
 
-// RetrieveLastBlockNumber is used to retrieve the latest block number in the db
+// RetrieveLastBlockNumber is used to retrieve the latest canonical block number in the db
 func (bcr *CIDRetriever) RetrieveLastBlockNumber() (int64, error) {
 	var blockNumber int64
-	err := bcr.db.Get(&blockNumber, "SELECT block_number FROM btc.header_cids ORDER BY block_number DESC LIMIT 1 ")
+	err := bcr.db.Get(&blockNumber, "SELECT block_number FROM btc.header_cids WHERE canonical = true ORDER BY block_number DESC LIMIT 1 ")
 	return blockNumber, err
 }
 
@@ -113,12 +113,13 @@ func (bcr *CIDRetriever) Retrieve(filter shared.SubscriptionSettings, blockNumbe
 	return cws, empty, err
 }
 
-// RetrieveHeaderCIDs retrieves and returns all of the header cids at the provided blockheight
+// RetrieveHeaderCIDs retrieves and returns the canonical header cids at the provided blockheight
 func (bcr *CIDRetriever) RetrieveHeaderCIDs(tx *sqlx.Tx, blockNumber int64) ([]HeaderModel, error) {
 	log.Debug("retrieving header cids for block ", blockNumber)
 	headers := make([]HeaderModel, 0)
 	pgStr := `SELECT * FROM btc.header_cids
-				WHERE block_number = $1`
+				WHERE block_number = $1
+				AND canonical = true`
 	return headers, tx.Select(&headers, pgStr, blockNumber)
 }
 
@@ -163,6 +164,17 @@ func (bcr *CIDRetriever) RetrieveTxCIDs(tx *sqlx.Tx, txFilter TxFilter, headerID
 	if len(txFilter.PkScriptClasses) > 0 {
 		pgStr += fmt.Sprintf(` AND tx_outputs.script_class = ANY($%d::INTEGER[])`, id)
 		args = append(args, pq.Array(txFilter.PkScriptClasses))
+		id++
+	}
+	if len(txFilter.NullDataPrefixes) > 0 {
+		prefixes, err := decodeNullDataPrefixes(txFilter.NullDataPrefixes)
+		if err != nil {
+			return nil, err
+		}
+		pgStr += fmt.Sprintf(` AND EXISTS (SELECT 1 FROM btc.nulldata_outputs, unnest($%d::BYTEA[]) AS wanted_prefix
+			WHERE nulldata_outputs.tx_id = transaction_cids.id
+			AND substring(nulldata_outputs.payload from 1 for length(wanted_prefix)) = wanted_prefix)`, id)
+		args = append(args, pq.Array(prefixes))
 	}
 	return results, tx.Select(&results, pgStr, args...)
 }
@@ -217,7 +229,7 @@ func (bcr *CIDRetriever) RetrieveGapsInData(validationLevel int) ([]shared.Gap,
 }
 
 // RetrieveBlockByHash returns all of the CIDs needed to compose an entire block, for a given block hash
-func (bcr *CIDRetriever) RetrieveBlockByHash(blockHash common.Hash) (HeaderModel, []TxModel, error) {
+func (bcr *CIDRetriever) RetrieveBlockByHash(blockHash chainhash.Hash) (HeaderModel, []TxModel, error) {
 	log.Debug("retrieving block cids for block hash ", blockHash.String())
 
 	// Begin new db tx
@@ -284,7 +296,7 @@ func (bcr *CIDRetriever) RetrieveBlockByNumber(blockNumber int64) (HeaderModel,
 }
 
 // RetrieveHeaderCIDByHash returns the header for the given block hash
-func (bcr *CIDRetriever) RetrieveHeaderCIDByHash(tx *sqlx.Tx, blockHash common.Hash) (HeaderModel, error) {
+func (bcr *CIDRetriever) RetrieveHeaderCIDByHash(tx *sqlx.Tx, blockHash chainhash.Hash) (HeaderModel, error) {
 	log.Debug("retrieving header cids for block hash ", blockHash.String())
 	pgStr := `SELECT * FROM btc.header_cids
 			WHERE block_hash = $1`
@@ -292,11 +304,254 @@ func (bcr *CIDRetriever) RetrieveHeaderCIDByHash(tx *sqlx.Tx, blockHash common.H
 	return headerCID, tx.Get(&headerCID, pgStr, blockHash.String())
 }
 
-// RetrieveTxCIDsByHeaderID retrieves all tx CIDs for the given header id
+// RetrieveHeaderCIDByID returns the header with the given id
+func (bcr *CIDRetriever) RetrieveHeaderCIDByID(tx *sqlx.Tx, headerID int64) (HeaderModel, error) {
+	log.Debug("retrieving header cid for header id ", headerID)
+	pgStr := `SELECT * FROM btc.header_cids
+			WHERE id = $1`
+	var headerCID HeaderModel
+	return headerCID, tx.Get(&headerCID, pgStr, headerID)
+}
+
+// RetrieveTxCIDByHash returns the tx cid for the given tx hash, as included in a canonical block
+func (bcr *CIDRetriever) RetrieveTxCIDByHash(tx *sqlx.Tx, txHash chainhash.Hash) (TxModel, error) {
+	log.Debug("retrieving tx cid for tx hash ", txHash.String())
+	pgStr := `SELECT transaction_cids.* FROM btc.transaction_cids
+			INNER JOIN btc.header_cids ON (transaction_cids.header_id = header_cids.id)
+			WHERE transaction_cids.tx_hash = $1
+			AND header_cids.canonical = true`
+	var txCID TxModel
+	return txCID, tx.Get(&txCID, pgStr, txHash.String())
+}
+
+// RetrieveTxCount returns the number of transactions indexed for the given header id
+func (bcr *CIDRetriever) RetrieveTxCount(tx *sqlx.Tx, headerID int64) (int, error) {
+	var count int
+	return count, tx.Get(&count, `SELECT COUNT(*) FROM btc.transaction_cids WHERE header_id = $1`, headerID)
+}
+
+// RetrieveTimestamps returns the timestamps, in unix nanoseconds, of the canonical headers in the provided range of heights
+func (bcr *CIDRetriever) RetrieveTimestamps(tx *sqlx.Tx, startingBlock, endingBlock int64) ([]int64, error) {
+	pgStr := `SELECT timestamp FROM btc.header_cids
+			WHERE block_number BETWEEN $1 AND $2
+			AND canonical = true`
+	timestamps := make([]int64, 0)
+	return timestamps, tx.Select(&timestamps, pgStr, startingBlock, endingBlock)
+}
+
+// RetrieveTxCIDsByHeaderID retrieves all tx CIDs for the given header id, in the order they appear in the block
 func (bcr *CIDRetriever) RetrieveTxCIDsByHeaderID(tx *sqlx.Tx, headerID int64) ([]TxModel, error) {
 	log.Debug("retrieving tx cids for block id ", headerID)
 	pgStr := `SELECT * FROM btc.transaction_cids
-			WHERE header_id = $1`
+			WHERE header_id = $1
+			ORDER BY index`
 	var txCIDs []TxModel
 	return txCIDs, tx.Select(&txCIDs, pgStr, headerID)
 }
+
+// unspentAsOf restricts tx_outputs, joined with the transaction_cids including them, to the outputs which are not spent
+// by an input included in a canonical block at or below the height bound to $1
+// Spends are only counted once their block is canonical, so the outputs spent in blocks which are reorged out are unspent again
+// Inputs are matched by outpoint, as a transaction included in competing blocks has its outputs indexed for each of them
+const unspentAsOf = `NOT EXISTS (SELECT 1 FROM btc.tx_inputs
+				INNER JOIN btc.transaction_cids spending_txs ON (tx_inputs.tx_id = spending_txs.id)
+				INNER JOIN btc.header_cids spending_headers ON (spending_txs.header_id = spending_headers.id)
+				WHERE tx_inputs.outpoint_tx_hash = transaction_cids.tx_hash
+				AND tx_inputs.outpoint_index = tx_outputs.index
+				AND spending_headers.canonical = true
+				AND spending_headers.block_number <= $1)`
+
+// utxosAsOf selects the outputs included in canonical blocks which are unspent as of the canonical block at the height bound to $1
+const utxosAsOf = `SELECT tx_outputs.id, tx_outputs.index, tx_outputs.value, tx_outputs.pk_script, tx_outputs.script_class,
+			tx_outputs.required_sigs, tx_outputs.addresses, transaction_cids.tx_hash, transaction_cids.index = 0 AS coinbase,
+			header_cids.block_number, header_cids.block_hash, header_cids.timestamp
+			FROM btc.tx_outputs
+			INNER JOIN btc.transaction_cids ON (tx_outputs.tx_id = transaction_cids.id)
+			INNER JOIN btc.header_cids ON (transaction_cids.header_id = header_cids.id)
+			WHERE header_cids.canonical = true
+			AND header_cids.block_number <= $1
+			AND ` + unspentAsOf
+
+// RetrieveUTXOs returns the outputs included in canonical blocks which are unspent as of the canonical block at the provided height
+// If addresses are provided only the outputs paying to any of them are returned
+// Outputs are returned in ascending id order, starting after the provided output id and up to the limit if it is positive
+func (bcr *CIDRetriever) RetrieveUTXOs(tx *sqlx.Tx, height int64, addresses []string, afterID int64, limit int) ([]UTXOModel, error) {
+	log.Debug("retrieving utxos at height ", height)
+	args := []interface{}{height, afterID}
+	pgStr := utxosAsOf + ` AND tx_outputs.id > $2`
+	if len(addresses) > 0 {
+		pgStr += fmt.Sprintf(` AND tx_outputs.addresses && $%d::VARCHAR(66)[]`, len(args)+1)
+		args = append(args, pq.Array(addresses))
+	}
+	pgStr += ` ORDER BY tx_outputs.id`
+	if limit > 0 {
+		pgStr += fmt.Sprintf(` LIMIT $%d`, len(args)+1)
+		args = append(args, limit)
+	}
+	utxos := make([]UTXOModel, 0)
+	return utxos, tx.Select(&utxos, pgStr, args...)
+}
+
+// RetrieveScriptHashUTXOs returns the outputs with the provided script hash which are unspent as of the canonical block
+// at the provided height, in the order they were included in the chain
+func (bcr *CIDRetriever) RetrieveScriptHashUTXOs(tx *sqlx.Tx, height int64, scriptHash []byte) ([]UTXOModel, error) {
+	log.Debug("retrieving utxos at height ", height)
+	pgStr := utxosAsOf + ` AND tx_outputs.script_hash = $2
+			ORDER BY header_cids.block_number, transaction_cids.index, tx_outputs.index`
+	utxos := make([]UTXOModel, 0)
+	return utxos, tx.Select(&utxos, pgStr, height, scriptHash)
+}
+
+// RetrieveScriptHashHistory returns the transactions included in canonical blocks which either pay to an output with the
+// provided script hash or spend one, in the order they were included in the chain
+func (bcr *CIDRetriever) RetrieveScriptHashHistory(tx *sqlx.Tx, scriptHash []byte) ([]HistoryModel, error) {
+	pgStr := `SELECT transaction_cids.tx_hash, transaction_cids.index, header_cids.block_number
+			FROM btc.transaction_cids
+			INNER JOIN btc.header_cids ON (transaction_cids.header_id = header_cids.id)
+			WHERE header_cids.canonical = true
+			AND (transaction_cids.id IN (SELECT tx_id FROM btc.tx_outputs WHERE script_hash = $1)
+			OR transaction_cids.id IN (SELECT tx_inputs.tx_id FROM btc.tx_inputs
+										INNER JOIN btc.tx_outputs ON (tx_inputs.spent_output_id = tx_outputs.id)
+										WHERE tx_outputs.script_hash = $1))
+			ORDER BY header_cids.block_number, transaction_cids.index`
+	history := make([]HistoryModel, 0)
+	return history, tx.Select(&history, pgStr, scriptHash)
+}
+
+// RetrieveScriptHashesByHeaderID returns the script hashes of the outputs created and spent by the transactions of the given header id
+func (bcr *CIDRetriever) RetrieveScriptHashesByHeaderID(tx *sqlx.Tx, headerID int64) ([][]byte, error) {
+	pgStr := `SELECT tx_outputs.script_hash FROM btc.tx_outputs
+			INNER JOIN btc.transaction_cids ON (tx_outputs.tx_id = transaction_cids.id)
+			WHERE transaction_cids.header_id = $1
+			UNION
+			SELECT tx_outputs.script_hash FROM btc.tx_outputs
+			INNER JOIN btc.tx_inputs ON (tx_inputs.spent_output_id = tx_outputs.id)
+			INNER JOIN btc.transaction_cids ON (tx_inputs.tx_id = transaction_cids.id)
+			WHERE transaction_cids.header_id = $1`
+	scriptHashes := make([][]byte, 0)
+	return scriptHashes, tx.Select(&scriptHashes, pgStr, headerID)
+}
+
+// RetrieveHeaderCIDsByRange returns the canonical header cids in the provided range of heights, in ascending order
+func (bcr *CIDRetriever) RetrieveHeaderCIDsByRange(tx *sqlx.Tx, startingBlock, endingBlock int64) ([]HeaderModel, error) {
+	pgStr := `SELECT * FROM btc.header_cids
+			WHERE block_number BETWEEN $1 AND $2
+			AND canonical = true
+			ORDER BY block_number`
+	headers := make([]HeaderModel, 0)
+	return headers, tx.Select(&headers, pgStr, startingBlock, endingBlock)
+}
+
+// RetrieveAddressBalance returns the total value paid to the addresses by outputs included in canonical blocks up to
+// the provided height, and the value of those outputs which are unspent as of that height
+func (bcr *CIDRetriever) RetrieveAddressBalance(tx *sqlx.Tx, height int64, addresses []string) (received int64, balance int64, err error) {
+	log.Debug("retrieving balance at height ", height)
+	pgStr := `SELECT COALESCE(SUM(tx_outputs.value), 0) AS received,
+			COALESCE(SUM(tx_outputs.value) FILTER (WHERE ` + unspentAsOf + `), 0) AS balance
+			FROM btc.tx_outputs
+			INNER JOIN btc.transaction_cids ON (tx_outputs.tx_id = transaction_cids.id)
+			INNER JOIN btc.header_cids ON (transaction_cids.header_id = header_cids.id)
+			WHERE header_cids.canonical = true
+			AND header_cids.block_number <= $1
+			AND tx_outputs.addresses && $2::VARCHAR(66)[]`
+	err = tx.QueryRowx(pgStr, height, pq.Array(addresses)).Scan(&received, &balance)
+	return received, balance, err
+}
+
+// RetrieveUTXOSetInfo returns the number and the total value of the outputs which are unspent as of the canonical block at the provided height
+func (bcr *CIDRetriever) RetrieveUTXOSetInfo(tx *sqlx.Tx, height int64) (count int64, total int64, err error) {
+	log.Debug("retrieving utxo set info at height ", height)
+	pgStr := `SELECT COUNT(*), COALESCE(SUM(tx_outputs.value), 0)
+			FROM btc.tx_outputs
+			INNER JOIN btc.transaction_cids ON (tx_outputs.tx_id = transaction_cids.id)
+			INNER JOIN btc.header_cids ON (transaction_cids.header_id = header_cids.id)
+			WHERE header_cids.canonical = true
+			AND header_cids.block_number <= $1
+			AND ` + unspentAsOf
+	err = tx.QueryRowx(pgStr, height).Scan(&count, &total)
+	return count, total, err
+}
+
+// blockFilters selects block filters along with the header of the block they filter
+const blockFilters = `SELECT block_filters.*, header_cids.block_number, header_cids.block_hash
+			FROM btc.block_filters
+			INNER JOIN btc.header_cids ON (block_filters.header_id = header_cids.id)`
+
+// RetrieveBlockFilterByHeaderID returns the filter of the block with the given header id
+func (bcr *CIDRetriever) RetrieveBlockFilterByHeaderID(tx *sqlx.Tx, headerID int64) (BlockFilterModel, error) {
+	pgStr := `SELECT * FROM btc.block_filters
+			WHERE header_id = $1`
+	var filter BlockFilterModel
+	return filter, tx.Get(&filter, pgStr, headerID)
+}
+
+// RetrieveBlockFiltersByRange returns the filters of the canonical blocks in the provided range of heights, in ascending order
+func (bcr *CIDRetriever) RetrieveBlockFiltersByRange(tx *sqlx.Tx, startingBlock, endingBlock int64) ([]BlockFilterWithHeaderModel, error) {
+	pgStr := blockFilters + ` WHERE header_cids.block_number BETWEEN $1 AND $2
+			AND header_cids.canonical = true
+			ORDER BY header_cids.block_number`
+	filters := make([]BlockFilterWithHeaderModel, 0)
+	return filters, tx.Select(&filters, pgStr, startingBlock, endingBlock)
+}
+
+// blockTxCIDs selects transaction cids along with the header which includes them
+const blockTxCIDs = `SELECT transaction_cids.*, header_cids.block_number, header_cids.block_hash, header_cids.timestamp,
+			header_cids.canonical
+			FROM btc.transaction_cids
+			INNER JOIN btc.header_cids ON (transaction_cids.header_id = header_cids.id)`
+
+// RetrieveBlockTxCIDByHash returns the cid of the transaction with the provided hash, along with the header which includes it
+// A transaction included in competing blocks is returned along with the canonical one if there is one
+func (bcr *CIDRetriever) RetrieveBlockTxCIDByHash(tx *sqlx.Tx, txHash chainhash.Hash) (BlockTxModel, error) {
+	pgStr := blockTxCIDs + ` WHERE transaction_cids.tx_hash = $1
+			ORDER BY header_cids.canonical DESC, header_cids.id DESC
+			LIMIT 1`
+	var txCID BlockTxModel
+	return txCID, tx.Get(&txCID, pgStr, txHash.String())
+}
+
+// RetrieveAddressTxCIDs returns the cids of the transactions included in canonical blocks which pay to or spend from the
+// address, newest first, starting below the provided height and index within that height's block and up to the limit
+func (bcr *CIDRetriever) RetrieveAddressTxCIDs(tx *sqlx.Tx, address string, beforeHeight, beforeIndex int64, limit int) ([]BlockTxModel, error) {
+	log.Debug("retrieving tx cids for address ", address)
+	pgStr := blockTxCIDs + `
+			INNER JOIN btc.address_transactions ON (address_transactions.tx_id = transaction_cids.id)
+			WHERE address_transactions.address = $1
+			AND header_cids.canonical = true
+			AND (header_cids.block_number, transaction_cids.index) < ($2, $3)
+			ORDER BY header_cids.block_number DESC, transaction_cids.index DESC
+			LIMIT $4`
+	txCIDs := make([]BlockTxModel, 0)
+	return txCIDs, tx.Select(&txCIDs, pgStr, address, beforeHeight, beforeIndex, limit)
+}
+
+// RetrievePrevouts returns the indexed outputs spent by the inputs of the transactions with the provided ids
+func (bcr *CIDRetriever) RetrievePrevouts(tx *sqlx.Tx, txIDs []int64) ([]PrevoutModel, error) {
+	pgStr := `SELECT tx_inputs.tx_id, tx_inputs.index AS input_index, tx_outputs.value, tx_outputs.pk_script
+			FROM btc.tx_inputs
+			INNER JOIN btc.tx_outputs ON (tx_inputs.spent_output_id = tx_outputs.id)
+			WHERE tx_inputs.tx_id = ANY($1::INTEGER[])`
+	prevouts := make([]PrevoutModel, 0)
+	return prevouts, tx.Select(&prevouts, pgStr, pq.Array(txIDs))
+}
+
+// RetrieveOutspends returns the outputs of the transaction with the provided id in index order, along with the inputs
+// which spend them in canonical blocks
+// Inputs are matched by outpoint, as a transaction included in competing blocks has its outputs indexed for each of them
+func (bcr *CIDRetriever) RetrieveOutspends(tx *sqlx.Tx, txID int64) ([]OutspendModel, error) {
+	pgStr := `SELECT tx_outputs.index AS output_index, spends.tx_hash, spends.input_index, spends.block_number,
+			spends.block_hash, spends.timestamp
+			FROM btc.tx_outputs
+			INNER JOIN btc.transaction_cids ON (tx_outputs.tx_id = transaction_cids.id)
+			LEFT JOIN (SELECT tx_inputs.outpoint_tx_hash, tx_inputs.outpoint_index, spending_txs.tx_hash, tx_inputs.index AS input_index,
+						header_cids.block_number, header_cids.block_hash, header_cids.timestamp
+						FROM btc.tx_inputs
+						INNER JOIN btc.transaction_cids spending_txs ON (tx_inputs.tx_id = spending_txs.id)
+						INNER JOIN btc.header_cids ON (spending_txs.header_id = header_cids.id)
+						WHERE header_cids.canonical = true) spends
+						ON (spends.outpoint_tx_hash = transaction_cids.tx_hash AND spends.outpoint_index = tx_outputs.index)
+			WHERE tx_outputs.tx_id = $1
+			ORDER BY tx_outputs.index`
+	outspends := make([]OutspendModel, 0)
+	return outspends, tx.Select(&outspends, pgStr, txID)
+}
@@ -106,6 +106,15 @@ func (c *Cleaner) vacuumAnalyze(t shared.DataType) error {
 		if err := c.vacuumTxOutputs(); err != nil {
 			return err
 		}
+		if err := c.vacuumAddressTransactions(); err != nil {
+			return err
+		}
+		if err := c.vacuumNullDataOutputs(); err != nil {
+			return err
+		}
+		if err := c.vacuumBlockFilters(); err != nil {
+			return err
+		}
 	case shared.Transactions:
 		if err := c.vacuumTxs(); err != nil {
 			return err
@@ -116,6 +125,12 @@ func (c *Cleaner) vacuumAnalyze(t shared.DataType) error {
 		if err := c.vacuumTxOutputs(); err != nil {
 			return err
 		}
+		if err := c.vacuumAddressTransactions(); err != nil {
+			return err
+		}
+		if err := c.vacuumNullDataOutputs(); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("btc cleaner unrecognized type: %s", t.String())
 	}
@@ -142,6 +157,21 @@ func (c *Cleaner) vacuumTxOutputs() error {
 	return err
 }
 
+func (c *Cleaner) vacuumAddressTransactions() error {
+	_, err := c.db.Exec(`VACUUM ANALYZE btc.address_transactions`)
+	return err
+}
+
+func (c *Cleaner) vacuumNullDataOutputs() error {
+	_, err := c.db.Exec(`VACUUM ANALYZE btc.nulldata_outputs`)
+	return err
+}
+
+func (c *Cleaner) vacuumBlockFilters() error {
+	_, err := c.db.Exec(`VACUUM ANALYZE btc.block_filters`)
+	return err
+}
+
 func (c *Cleaner) vacuumIPLDs() error {
 	_, err := c.db.Exec(`VACUUM ANALYZE public.blocks`)
 	return err
@@ -143,7 +143,7 @@ var _ = Describe("Cleaner", func() {
 		var err error
 		db, err = shared.SetupDB()
 		Expect(err).ToNot(HaveOccurred())
-		repo = btc.NewCIDIndexer(db)
+		repo = btc.NewCIDIndexer(db, nil, nil)
 		cleaner = btc.NewCleaner(db)
 	})
 
@@ -20,8 +20,11 @@ import (
 	"encoding/hex"
 	"fmt"
 
+	"github.com/btcsuite/btcd/blockchain"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
 
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
 )
@@ -47,43 +50,9 @@ func (pc *PayloadConverter) Convert(payload shared.RawChainData) (shared.Convert
 	}
 	txMeta := make([]TxModelWithInsAndOuts, len(btcBlockPayload.Txs))
 	for i, tx := range btcBlockPayload.Txs {
-		txModel := TxModelWithInsAndOuts{
-			TxHash:    tx.Hash().String(),
-			Index:     int64(i),
-			SegWit:    tx.HasWitness(),
-			TxOutputs: make([]TxOutput, len(tx.MsgTx().TxOut)),
-			TxInputs:  make([]TxInput, len(tx.MsgTx().TxIn)),
-		}
-		if tx.HasWitness() {
-			txModel.WitnessHash = tx.WitnessHash().String()
-		}
-		for i, in := range tx.MsgTx().TxIn {
-			txModel.TxInputs[i] = TxInput{
-				Index:                 int64(i),
-				SignatureScript:       in.SignatureScript,
-				PreviousOutPointHash:  in.PreviousOutPoint.Hash.String(),
-				PreviousOutPointIndex: in.PreviousOutPoint.Index,
-				TxWitness:             convertBytesToHexArray(in.Witness),
-			}
-		}
-		for i, out := range tx.MsgTx().TxOut {
-			scriptClass, addresses, numberOfSigs, err := txscript.ExtractPkScriptAddrs(out.PkScript, pc.chainConfig)
-			// if we receive an error but the txscript type isn't NonStandardTy then something went wrong
-			if err != nil && scriptClass != txscript.NonStandardTy {
-				return nil, err
-			}
-			stringAddrs := make([]string, len(addresses))
-			for i, addr := range addresses {
-				stringAddrs[i] = addr.EncodeAddress()
-			}
-			txModel.TxOutputs[i] = TxOutput{
-				Index:        int64(i),
-				Value:        out.Value,
-				PkScript:     out.PkScript,
-				RequiredSigs: int64(numberOfSigs),
-				ScriptClass:  uint8(scriptClass),
-				Addresses:    stringAddrs,
-			}
+		txModel, err := convertTx(tx, int64(i), pc.chainConfig)
+		if err != nil {
+			return nil, err
 		}
 		txMeta[i] = txModel
 	}
@@ -93,6 +62,80 @@ func (pc *PayloadConverter) Convert(payload shared.RawChainData) (shared.Convert
 	}, nil
 }
 
+// ConvertUnconfirmed converts transactions which have not been included in a block, their Index is UnconfirmedTxIndex
+func (pc *PayloadConverter) ConvertUnconfirmed(txs []*btcutil.Tx) ([]TxModelWithInsAndOuts, error) {
+	txMeta := make([]TxModelWithInsAndOuts, len(txs))
+	for i, tx := range txs {
+		txModel, err := convertTx(tx, UnconfirmedTxIndex, pc.chainConfig)
+		if err != nil {
+			return nil, err
+		}
+		txMeta[i] = txModel
+	}
+	return txMeta, nil
+}
+
+// convertTx converts the transaction found at the provided index of its block
+func convertTx(tx *btcutil.Tx, index int64, params *chaincfg.Params) (TxModelWithInsAndOuts, error) {
+	weight := txWeight(tx.MsgTx())
+	txModel := TxModelWithInsAndOuts{
+		TxHash:    tx.Hash().String(),
+		Index:     index,
+		SegWit:    tx.HasWitness(),
+		Weight:    weight,
+		VSize:     (weight + blockchain.WitnessScaleFactor - 1) / blockchain.WitnessScaleFactor,
+		TxOutputs: make([]TxOutput, len(tx.MsgTx().TxOut)),
+		TxInputs:  make([]TxInput, len(tx.MsgTx().TxIn)),
+	}
+	if tx.HasWitness() {
+		txModel.WitnessHash = tx.WitnessHash().String()
+	}
+	for i, in := range tx.MsgTx().TxIn {
+		txModel.TxInputs[i] = TxInput{
+			Index:                 int64(i),
+			SignatureScript:       in.SignatureScript,
+			PreviousOutPointHash:  in.PreviousOutPoint.Hash.String(),
+			PreviousOutPointIndex: in.PreviousOutPoint.Index,
+			TxWitness:             convertBytesToHexArray(in.Witness),
+			TaprootSpend:          taprootSpendType(in.SignatureScript, in.Witness),
+		}
+	}
+	for i, out := range tx.MsgTx().TxOut {
+		txOutput, err := convertTxOutput(int64(i), out, params)
+		if err != nil {
+			return TxModelWithInsAndOuts{}, err
+		}
+		txModel.TxOutputs[i] = txOutput
+	}
+	return txModel, nil
+}
+
+// convertTxOutput converts the output at the provided index, classifying its pk script
+func convertTxOutput(index int64, out *wire.TxOut, params *chaincfg.Params) (TxOutput, error) {
+	scriptClass, addresses, numberOfSigs, err := ExtractPkScriptAddrs(out.PkScript, params)
+	// if we receive an error but the txscript type isn't NonStandardTy then something went wrong
+	if err != nil && scriptClass != txscript.NonStandardTy {
+		return TxOutput{}, err
+	}
+	stringAddrs := make([]string, len(addresses))
+	for i, addr := range addresses {
+		stringAddrs[i] = addr.EncodeAddress()
+	}
+	return TxOutput{
+		Index:        index,
+		Value:        out.Value,
+		PkScript:     out.PkScript,
+		RequiredSigs: int64(numberOfSigs),
+		ScriptClass:  uint8(scriptClass),
+		Addresses:    stringAddrs,
+	}, nil
+}
+
+// txWeight returns the weight of the transaction, as defined by BIP141
+func txWeight(tx *wire.MsgTx) int64 {
+	return int64(tx.SerializeSizeStripped()*(blockchain.WitnessScaleFactor-1) + tx.SerializeSize())
+}
+
 func convertBytesToHexArray(bytea [][]byte) []string {
 	var strs []string
 	for _, b := range bytea {
@@ -40,4 +40,13 @@ var _ = Describe("Converter", func() {
 			Expect(convertedPayload.TxMetaData).To(Equal(mocks.MockTxsMetaData))
 		})
 	})
+
+	Describe("ConvertUnconfirmed", func() {
+		It("Converts mempool txs like block txs, without an index", func() {
+			converter := btc.NewPayloadConverter(&chaincfg.MainNetParams)
+			txMeta, err := converter.ConvertUnconfirmed(mocks.MockMempoolPayload.Txs)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(txMeta).To(Equal(mocks.MockMempoolPayload.TxMetaData))
+		})
+	})
 })
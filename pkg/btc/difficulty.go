@@ -0,0 +1,190 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+// validateHeader checks that the hash of the header meets the target encoded by its bits, that its parent_hash links it
+// to an indexed header, and that its bits follow the retarget rules of the chain
+// A header is rejected if a canonical header is indexed at its parent's height but its parent is not indexed; above a
+// gap, e.g. after downtime or when headers are indexed out of order, it is linked once the gap is filled
+// The retarget rules can only be checked once the parent of the header, and the ancestors its difficulty is derived
+// from, have been indexed; headers are not necessarily indexed in order so the check is skipped until then
+func (in *CIDIndexer) validateHeader(tx *sqlx.Tx, header HeaderModel) error {
+	hash, err := chainhash.NewHashFromStr(header.BlockHash)
+	if err != nil {
+		return err
+	}
+	target := blockchain.CompactToBig(header.Bits)
+	if target.Sign() <= 0 || target.Cmp(in.params.PowLimit) > 0 {
+		return fmt.Errorf("btc header %s has an invalid target %08x", header.BlockHash, header.Bits)
+	}
+	if blockchain.HashToBig(hash).Cmp(target) > 0 {
+		return fmt.Errorf("btc header %s does not meet its target %08x", header.BlockHash, header.Bits)
+	}
+	blockNumber, err := strconv.ParseInt(header.BlockNumber, 10, 64)
+	if err != nil {
+		return err
+	}
+	if err := in.validateParent(tx, header, blockNumber); err != nil {
+		return err
+	}
+	required, ok, err := in.requiredBits(tx, header, blockNumber)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		logrus.Debugf("btc indexer unable to check the difficulty of header %s, its ancestors have not been indexed", header.BlockHash)
+		return nil
+	}
+	if header.Bits != required {
+		return fmt.Errorf("btc header %s has bits %08x, expected %08x", header.BlockHash, header.Bits, required)
+	}
+	return nil
+}
+
+// validateParent checks that the parent of the header is indexed, unless no canonical header is indexed at its height
+func (in *CIDIndexer) validateParent(tx *sqlx.Tx, header HeaderModel, blockNumber int64) error {
+	var link struct {
+		Parent    bool `db:"parent"`
+		Canonical bool `db:"canonical"`
+	}
+	err := tx.Get(&link, `SELECT EXISTS(SELECT 1 FROM btc.header_cids WHERE block_number = $1 AND block_hash = $2) AS parent,
+							EXISTS(SELECT 1 FROM btc.header_cids WHERE block_number = $1 AND canonical = true) AS canonical`,
+		blockNumber-1, header.ParentHash)
+	if err != nil {
+		return err
+	}
+	if !link.Parent && link.Canonical {
+		return fmt.Errorf("btc header %s links to parent %s which has not been indexed", header.BlockHash, header.ParentHash)
+	}
+	return nil
+}
+
+// requiredBits returns the bits the header must have, derived from its indexed ancestors like bitcoind does
+// It returns false if the ancestors needed to derive them have not been indexed
+func (in *CIDIndexer) requiredBits(tx *sqlx.Tx, header HeaderModel, blockNumber int64) (uint32, bool, error) {
+	if blockNumber == 0 {
+		return in.params.PowLimitBits, true, nil
+	}
+	blocksPerRetarget := int64(in.params.TargetTimespan / in.params.TargetTimePerBlock)
+	if blockNumber%blocksPerRetarget != 0 {
+		parents, err := in.ancestors(tx, header, blockNumber, blockNumber-1)
+		if err != nil || len(parents) == 0 {
+			return 0, false, err
+		}
+		parent := parents[0]
+		if !in.params.ReduceMinDifficulty {
+			return parent.Bits, true, nil
+		}
+		// A block mined long enough after its parent can have the minimum difficulty, otherwise it has the difficulty of
+		// the last block since the last retarget which did not
+		reductionTime := int64(in.params.MinDiffReductionTime / time.Second)
+		if unixSeconds(header.Timestamp) > unixSeconds(parent.Timestamp)+reductionTime {
+			return in.params.PowLimitBits, true, nil
+		}
+		return in.prevTestNetBits(tx, parent)
+	}
+	// Networks without retargeting, like regtest, keep the difficulty of the parent
+	if noRetargeting(in.params) {
+		parents, err := in.ancestors(tx, header, blockNumber, blockNumber-1)
+		if err != nil || len(parents) == 0 {
+			return 0, false, err
+		}
+		return parents[0].Bits, true, nil
+	}
+	// At a retarget the difficulty is adjusted by how long the last period took to mine, within the adjustment factor
+	ancestors, err := in.ancestors(tx, header, blockNumber, blockNumber-blocksPerRetarget)
+	if err != nil || int64(len(ancestors)) != blocksPerRetarget {
+		return 0, false, err
+	}
+	last, first := ancestors[0], ancestors[len(ancestors)-1]
+	targetTimespan := int64(in.params.TargetTimespan / time.Second)
+	minTimespan := targetTimespan / in.params.RetargetAdjustmentFactor
+	maxTimespan := targetTimespan * in.params.RetargetAdjustmentFactor
+	timespan := unixSeconds(last.Timestamp) - unixSeconds(first.Timestamp)
+	if timespan < minTimespan {
+		timespan = minTimespan
+	} else if timespan > maxTimespan {
+		timespan = maxTimespan
+	}
+	target := new(big.Int).Mul(blockchain.CompactToBig(last.Bits), big.NewInt(timespan))
+	target.Div(target, big.NewInt(targetTimespan))
+	if target.Cmp(in.params.PowLimit) > 0 {
+		target.Set(in.params.PowLimit)
+	}
+	return blockchain.BigToCompact(target), true, nil
+}
+
+// prevTestNetBits returns the bits of the last block, from the provided one back to the last retarget, which was not
+// mined at the minimum difficulty
+func (in *CIDIndexer) prevTestNetBits(tx *sqlx.Tx, header HeaderModel) (uint32, bool, error) {
+	blocksPerRetarget := int64(in.params.TargetTimespan / in.params.TargetTimePerBlock)
+	for {
+		blockNumber, err := strconv.ParseInt(header.BlockNumber, 10, 64)
+		if err != nil {
+			return 0, false, err
+		}
+		if blockNumber%blocksPerRetarget == 0 || header.Bits != in.params.PowLimitBits {
+			return header.Bits, true, nil
+		}
+		parents, err := in.ancestors(tx, header, blockNumber, blockNumber-1)
+		if err != nil || len(parents) == 0 {
+			return 0, false, err
+		}
+		header = parents[0]
+	}
+}
+
+// ancestors returns the indexed ancestors of the header, from its parent back to the ancestor at the provided height
+// The ancestors are walked back along parent_hash, so the walk stops short at the first ancestor which has not been indexed
+func (in *CIDIndexer) ancestors(tx *sqlx.Tx, header HeaderModel, blockNumber, toBlockNumber int64) ([]HeaderModel, error) {
+	ancestors := make([]HeaderModel, 0)
+	err := tx.Select(&ancestors, `WITH RECURSIVE ancestors AS (
+									SELECT block_number, block_hash, parent_hash, timestamp, bits FROM btc.header_cids
+									WHERE block_number = $1 AND block_hash = $2
+									UNION ALL
+									SELECT header_cids.block_number, header_cids.block_hash, header_cids.parent_hash, header_cids.timestamp, header_cids.bits
+									FROM btc.header_cids
+									INNER JOIN ancestors ON (header_cids.block_number = ancestors.block_number - 1 AND header_cids.block_hash = ancestors.parent_hash)
+									WHERE header_cids.block_number >= $3
+								)
+								SELECT * FROM ancestors ORDER BY block_number DESC`, blockNumber-1, header.ParentHash, toBlockNumber)
+	return ancestors, err
+}
+
+// noRetargeting returns whether or not the difficulty of the network is never adjusted
+// The btcd version in use predates chaincfg.Params.PoWNoRetargeting, regtest is the only such network it defines
+func noRetargeting(params *chaincfg.Params) bool {
+	return params.Net == chaincfg.RegressionNetParams.Net
+}
+
+// unixSeconds converts a header timestamp, indexed in nanoseconds, to seconds
+func unixSeconds(timestamp int64) int64 {
+	return time.Unix(0, timestamp).Unix()
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"math/big"
 
+	"github.com/btcsuite/btcutil"
 	"github.com/multiformats/go-multihash"
 
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/ipfs"
@@ -61,6 +62,36 @@ func (s *ResponseFilterer) Filter(filter shared.SubscriptionSettings, payload sh
 	return IPLDs{}, nil
 }
 
+// FilterUnconfirmed is used to filter through the transactions which have entered the mempool to package those requested
+// into a MempoolIPLDs, it returns false if the subscription did not opt in to unconfirmed transactions or none were requested
+// Satisfies the shared.UnconfirmedFilterer interface
+func (s *ResponseFilterer) FilterUnconfirmed(filter shared.SubscriptionSettings, payload shared.UnconfirmedData) (shared.IPLDs, bool, error) {
+	btcFilters, ok := filter.(*SubscriptionSettings)
+	if !ok {
+		return MempoolIPLDs{}, false, fmt.Errorf("btc filterer expected filter type %T got %T", &SubscriptionSettings{}, filter)
+	}
+	mempoolPayload, ok := payload.(MempoolPayload)
+	if !ok {
+		return MempoolIPLDs{}, false, fmt.Errorf("btc filterer expected payload type %T got %T", MempoolPayload{}, payload)
+	}
+	if !btcFilters.Unconfirmed || btcFilters.TxFilter.Off {
+		return MempoolIPLDs{}, false, nil
+	}
+	response := MempoolIPLDs{
+		Transactions: make([]ipfs.BlockModel, 0, len(mempoolPayload.TxMetaData)),
+	}
+	for i, txMeta := range mempoolPayload.TxMetaData {
+		if checkTransaction(txMeta, btcFilters.TxFilter) {
+			trx, err := txIPLD(mempoolPayload.Txs[i])
+			if err != nil {
+				return MempoolIPLDs{}, false, err
+			}
+			response.Transactions = append(response.Transactions, trx)
+		}
+	}
+	return response, len(response.Transactions) > 0, nil
+}
+
 func (s *ResponseFilterer) filterHeaders(headerFilter HeaderFilter, response *IPLDs, payload ConvertedPayload) error {
 	if !headerFilter.Off {
 		headerBuffer := new(bytes.Buffer)
@@ -92,25 +123,34 @@ func (s *ResponseFilterer) filterTransactions(trxFilter TxFilter, response *IPLD
 		response.Transactions = make([]ipfs.BlockModel, 0, len(payload.TxMetaData))
 		for i, txMeta := range payload.TxMetaData {
 			if checkTransaction(txMeta, trxFilter) {
-				trxBuffer := new(bytes.Buffer)
-				if err := payload.Txs[i].MsgTx().Serialize(trxBuffer); err != nil {
-					return err
-				}
-				data := trxBuffer.Bytes()
-				cid, err := ipld.RawdataToCid(ipld.MBitcoinTx, data, multihash.DBL_SHA2_256)
+				trx, err := txIPLD(payload.Txs[i])
 				if err != nil {
 					return err
 				}
-				response.Transactions = append(response.Transactions, ipfs.BlockModel{
-					Data: data,
-					CID:  cid.String(),
-				})
+				response.Transactions = append(response.Transactions, trx)
 			}
 		}
 	}
 	return nil
 }
 
+// txIPLD serializes the transaction into its IPLD block
+func txIPLD(tx *btcutil.Tx) (ipfs.BlockModel, error) {
+	trxBuffer := new(bytes.Buffer)
+	if err := tx.MsgTx().Serialize(trxBuffer); err != nil {
+		return ipfs.BlockModel{}, err
+	}
+	data := trxBuffer.Bytes()
+	cid, err := ipld.RawdataToCid(ipld.MBitcoinTx, data, multihash.DBL_SHA2_256)
+	if err != nil {
+		return ipfs.BlockModel{}, err
+	}
+	return ipfs.BlockModel{
+		Data: data,
+		CID:  cid.String(),
+	}, nil
+}
+
 // checkTransaction returns true if the provided transaction has a hit on the filter
 func checkTransaction(txMeta TxModelWithInsAndOuts, txFilter TxFilter) bool {
 	passesSegwitFilter := false
@@ -155,5 +195,24 @@ func checkTransaction(txMeta TxModelWithInsAndOuts, txFilter TxFilter) bool {
 			}
 		}
 	}
-	return passesSegwitFilter && passesMultiSigFilter && passesWitnessFilter && passesAddressFilter && passesIndexFilter && passesPkScriptClassFilter
+	passesNullDataFilter := len(txFilter.NullDataPrefixes) == 0
+	if !passesNullDataFilter {
+		prefixes, err := decodeNullDataPrefixes(txFilter.NullDataPrefixes)
+		if err != nil {
+			return false
+		}
+		for _, out := range txMeta.TxOutputs {
+			payload, ok := NullDataPayload(out.PkScript)
+			if !ok {
+				continue
+			}
+			for _, wantedPrefix := range prefixes {
+				if bytes.HasPrefix(payload, wantedPrefix) {
+					passesNullDataFilter = true
+				}
+			}
+		}
+	}
+	return passesSegwitFilter && passesMultiSigFilter && passesWitnessFilter && passesAddressFilter && passesIndexFilter &&
+		passesPkScriptClassFilter && passesNullDataFilter
 }
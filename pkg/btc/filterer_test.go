@@ -0,0 +1,75 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc_test
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc/mocks"
+)
+
+var _ = Describe("Filterer", func() {
+	var (
+		filterer *btc.ResponseFilterer
+		settings *btc.SubscriptionSettings
+	)
+	BeforeEach(func() {
+		filterer = btc.NewResponseFilterer()
+		settings = &btc.SubscriptionSettings{
+			Start:       big.NewInt(0),
+			End:         big.NewInt(0),
+			Unconfirmed: true,
+		}
+	})
+
+	Describe("FilterUnconfirmed", func() {
+		It("Packages the unconfirmed txs which pass the tx filter", func() {
+			response, ok, err := filterer.FilterUnconfirmed(settings, mocks.MockMempoolPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			mempoolIPLDs, ok := response.(btc.MempoolIPLDs)
+			Expect(ok).To(BeTrue())
+			Expect(len(mempoolIPLDs.Transactions)).To(Equal(2))
+			_, err = rlp.EncodeToBytes(mempoolIPLDs)
+			Expect(err).ToNot(HaveOccurred())
+
+			settings.TxFilter.Addresses = mocks.MockMempoolPayload.TxMetaData[1].TxOutputs[0].Addresses
+			response, ok, err = filterer.FilterUnconfirmed(settings, mocks.MockMempoolPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(response.(btc.MempoolIPLDs).Transactions).To(Equal(mempoolIPLDs.Transactions[1:]))
+		})
+
+		It("Does not package unconfirmed txs for subscriptions which have not opted in or filter them all out", func() {
+			settings.Unconfirmed = false
+			_, ok, err := filterer.FilterUnconfirmed(settings, mocks.MockMempoolPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+
+			settings.Unconfirmed = true
+			settings.TxFilter.Indexes = []int64{0}
+			_, ok, err = filterer.FilterUnconfirmed(settings, mocks.MockMempoolPayload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
@@ -17,7 +17,7 @@
 package btc
 
 import (
-	"bytes"
+	"sync"
 	"time"
 
 	"github.com/btcsuite/btcd/rpcclient"
@@ -26,16 +26,23 @@ import (
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
 )
 
+// DefaultPollInterval is how often the HTTPPayloadStreamer polls the node for its tip by default
+const DefaultPollInterval = 5 * time.Second
+
 // HTTPPayloadStreamer satisfies the PayloadStreamer interface for bitcoin over http endpoints (since bitcoin core doesn't support websockets)
+// It polls the node for its tip, streaming every block connected since the last poll and a BlockDisconnect for every
+// streamed block a reorg has disconnected
 type HTTPPayloadStreamer struct {
-	Config   *rpcclient.ConnConfig
-	lastHash []byte
+	Config       *rpcclient.ConnConfig
+	PollInterval time.Duration
+	chain        chainFollower
 }
 
 // NewHTTPPayloadStreamer creates a pointer to a new PayloadStreamer which satisfies the PayloadStreamer interface for bitcoin
 func NewHTTPPayloadStreamer(clientConfig *rpcclient.ConnConfig) *HTTPPayloadStreamer {
 	return &HTTPPayloadStreamer{
-		Config: clientConfig,
+		Config:       clientConfig,
+		PollInterval: DefaultPollInterval,
 	}
 }
 
@@ -47,43 +54,30 @@ func (ps *HTTPPayloadStreamer) Stream(payloadChan chan shared.RawChainData) (sha
 	if err != nil {
 		return nil, err
 	}
-	ticker := time.NewTicker(time.Second * 5)
-	errChan := make(chan error)
+	sub := &HTTPClientSubscription{
+		client:  client,
+		errChan: make(chan error),
+		quit:    make(chan bool),
+	}
 	go func() {
+		ticker := time.NewTicker(ps.PollInterval)
+		defer ticker.Stop()
 		for {
-			// start at
+			if err := ps.chain.sync(client, payloadChan, sub.quit); err != nil {
+				select {
+				case sub.errChan <- err:
+				case <-sub.quit:
+					return
+				}
+			}
 			select {
 			case <-ticker.C:
-				height, err := client.GetBlockCount()
-				if err != nil {
-					errChan <- err
-					continue
-				}
-				blockHash, err := client.GetBlockHash(height)
-				if err != nil {
-					errChan <- err
-					continue
-				}
-				blockHashBytes := blockHash.CloneBytes()
-				if bytes.Equal(blockHashBytes, ps.lastHash) {
-					continue
-				}
-				block, err := client.GetBlock(blockHash)
-				if err != nil {
-					errChan <- err
-					continue
-				}
-				ps.lastHash = blockHashBytes
-				payloadChan <- BlockPayload{
-					Header:      &block.Header,
-					BlockHeight: height,
-					Txs:         msgTxsToUtilTxs(block.Transactions),
-				}
-			default:
+			case <-sub.quit:
+				return
 			}
 		}
 	}()
-	return &HTTPClientSubscription{client: client, errChan: errChan}, nil
+	return sub, nil
 }
 
 // HTTPClientSubscription is a wrapper around the underlying bitcoind rpc client
@@ -91,11 +85,17 @@ func (ps *HTTPPayloadStreamer) Stream(payloadChan chan shared.RawChainData) (sha
 type HTTPClientSubscription struct {
 	client  *rpcclient.Client
 	errChan chan error
+	quit    chan bool
+	// Guards against closing the quit channel twice
+	unsubscribe sync.Once
 }
 
-// Unsubscribe satisfies the rpc.Subscription interface
+// Unsubscribe satisfies the rpc.Subscription interface, it is safe to call more than once
 func (bcs *HTTPClientSubscription) Unsubscribe() {
-	bcs.client.Shutdown()
+	bcs.unsubscribe.Do(func() {
+		close(bcs.quit)
+		bcs.client.Shutdown()
+	})
 }
 
 // Err() satisfies the rpc.Subscription interface
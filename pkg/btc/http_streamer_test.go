@@ -0,0 +1,133 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc_test
+
+import (
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc/mocks"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+var _ = Describe("HTTPPayloadStreamer", func() {
+	var (
+		chain       []*wire.MsgBlock
+		node        *mocks.Node
+		payloadChan chan shared.RawChainData
+		sub         shared.ClientSubscription
+	)
+
+	BeforeEach(func() {
+		chain = mocks.NewMockChain(5)
+		node = mocks.NewNode(chain[:2]...)
+		streamer := btc.NewHTTPPayloadStreamer(node.ConnConfig())
+		streamer.PollInterval = 10 * time.Millisecond
+		payloadChan = make(chan shared.RawChainData, 10)
+		var err error
+		sub, err = streamer.Stream(payloadChan)
+		Expect(err).ToNot(HaveOccurred())
+		// Like the watcher, carry on through errors; the node's chain can change in between the calls of a sync
+		go func(errs <-chan error) {
+			for range errs {
+			}
+		}(sub.Err())
+	})
+
+	AfterEach(func() {
+		sub.Unsubscribe()
+		node.Close()
+	})
+
+	expectConnect := func(block *wire.MsgBlock, height int64) {
+		var payload shared.RawChainData
+		Eventually(payloadChan).Should(Receive(&payload))
+		blockPayload, ok := payload.(btc.BlockPayload)
+		Expect(ok).To(BeTrue())
+		Expect(blockPayload.BlockHeight).To(Equal(height))
+		Expect(blockPayload.Header.BlockHash()).To(Equal(block.BlockHash()))
+		Expect(len(blockPayload.Txs)).To(Equal(len(block.Transactions)))
+	}
+
+	expectDisconnect := func(block *wire.MsgBlock, height int64) {
+		var payload shared.RawChainData
+		Eventually(payloadChan).Should(Receive(&payload))
+		disconnect, ok := payload.(btc.BlockDisconnect)
+		Expect(ok).To(BeTrue())
+		Expect(disconnect.Height()).To(Equal(height))
+		Expect(disconnect.Hash()).To(Equal(block.BlockHash().String()))
+	}
+
+	Describe("Stream", func() {
+		It("Streams the node's tip on start", func() {
+			expectConnect(chain[1], 1)
+			Consistently(payloadChan).ShouldNot(Receive())
+		})
+
+		It("Streams every block connected between polls", func() {
+			expectConnect(chain[1], 1)
+			node.Connect(chain[2:5]...)
+			expectConnect(chain[2], 2)
+			expectConnect(chain[3], 3)
+			expectConnect(chain[4], 4)
+			Consistently(payloadChan).ShouldNot(Receive())
+		})
+
+		It("Walks back to the fork point on a reorg, disconnecting the replaced blocks before connecting the new ones", func() {
+			expectConnect(chain[1], 1)
+			node.Connect(chain[2:4]...)
+			expectConnect(chain[2], 2)
+			expectConnect(chain[3], 3)
+
+			fork2 := mocks.NewMockChildBlock(chain[1], 100)
+			fork3 := mocks.NewMockChildBlock(fork2, 101)
+			fork4 := mocks.NewMockChildBlock(fork3, 102)
+			node.SetChain(chain[0], chain[1], fork2, fork3, fork4)
+			expectDisconnect(chain[3], 3)
+			expectDisconnect(chain[2], 2)
+			expectConnect(fork2, 2)
+			expectConnect(fork3, 3)
+			expectConnect(fork4, 4)
+			Consistently(payloadChan).ShouldNot(Receive())
+		})
+
+		It("Disconnects the blocks above the node's tip when its chain gets shorter", func() {
+			expectConnect(chain[1], 1)
+			node.Connect(chain[2])
+			expectConnect(chain[2], 2)
+
+			fork1 := mocks.NewMockChildBlock(chain[0], 100)
+			node.SetChain(chain[0], fork1)
+			expectDisconnect(chain[2], 2)
+			expectDisconnect(chain[1], 1)
+			expectConnect(fork1, 1)
+			Consistently(payloadChan).ShouldNot(Receive())
+		})
+	})
+
+	Describe("Unsubscribe", func() {
+		It("Can be called more than once", func() {
+			expectConnect(chain[1], 1)
+			sub.Unsubscribe()
+			Expect(sub.Unsubscribe).ToNot(Panic())
+		})
+	})
+})
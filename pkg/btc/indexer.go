@@ -17,8 +17,15 @@
 package btc
 
 import (
+	"crypto/sha256"
+	"database/sql"
 	"fmt"
+	"math/big"
+	"strconv"
 
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
 	"github.com/sirupsen/logrus"
 
 	"github.com/jmoiron/sqlx"
@@ -28,13 +35,28 @@ import (
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
 )
 
+// canonicalLockID is the advisory lock key used to serialize updates to the canonical btc chain
+const canonicalLockID = 8332
+
+// zeroHash is the outpoint hash of coinbase inputs
+var zeroHash = chainhash.Hash{}.String()
+
 type CIDIndexer struct {
 	db *postgres.DB
+	// params are the parameters of the chain headers are validated against, if nil headers are not validated
+	params *chaincfg.Params
+	// prevouts fetches the spent outputs which have not been indexed from the node, it can be nil
+	prevouts PrevoutFetcher
 }
 
-func NewCIDIndexer(db *postgres.DB) *CIDIndexer {
+// NewCIDIndexer returns a new CIDIndexer, validating the headers it indexes against the provided chain parameters if they
+// are not nil, and resolving the outputs spent by the inputs it indexes from the indexed outputs and, for the outputs
+// which have not been indexed, from the provided PrevoutFetcher if it is not nil
+func NewCIDIndexer(db *postgres.DB, params *chaincfg.Params, prevouts PrevoutFetcher) *CIDIndexer {
 	return &CIDIndexer{
-		db: db,
+		db:       db,
+		params:   params,
+		prevouts: prevouts,
 	}
 }
 
@@ -60,73 +82,475 @@ func (in *CIDIndexer) Index(cids shared.CIDsForIndexing) error {
 		}
 	}()
 
+	if in.params != nil {
+		if err = in.validateHeader(tx, cidWrapper.HeaderCID); err != nil {
+			logrus.Error("btc indexer error when validating header")
+			return err
+		}
+	}
 	headerID, err := in.indexHeaderCID(tx, cidWrapper.HeaderCID)
 	if err != nil {
 		logrus.Error("btc indexer error when indexing header")
 		return err
 	}
-	err = in.indexTransactionCIDs(tx, cidWrapper.TransactionCIDs, headerID)
+	scripts, err := in.indexTransactionCIDs(tx, cidWrapper.TransactionCIDs, headerID)
 	if err != nil {
 		logrus.Error("btc indexer error when indexing transactions")
+		return err
+	}
+	err = in.markCanonical(tx, cidWrapper.HeaderCID, headerID)
+	if err != nil {
+		logrus.Error("btc indexer error when marking the canonical chain")
+		return err
+	}
+	err = in.indexBlockFilter(tx, cidWrapper.HeaderCID, headerID, scripts)
+	if err != nil {
+		logrus.Error("btc indexer error when indexing the block filter")
+	}
+	return err
+}
+
+// Disconnect satisfies the shared.BlockDisconnector interface, it marks the disconnected block non-canonical
+// The blocks replacing it are marked canonical as they are indexed
+func (in *CIDIndexer) Disconnect(block shared.DisconnectedBlock) error {
+	tx, err := in.db.Beginx()
+	if err != nil {
+		return err
 	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	_, err = tx.Exec(`SELECT pg_advisory_xact_lock($1)`, canonicalLockID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE btc.header_cids SET canonical = false WHERE block_number = $1 AND block_hash = $2`,
+		block.Height(), block.Hash())
 	return err
 }
 
+// indexHeaderCID indexes the header with its own work as its chainwork, it is linked to its parent's once the canonical
+// chain is locked
 func (in *CIDIndexer) indexHeaderCID(tx *sqlx.Tx, header HeaderModel) (int64, error) {
 	var headerID int64
-	err := tx.QueryRowx(`INSERT INTO btc.header_cids (block_number, block_hash, parent_hash, cid, timestamp, bits, node_id, mh_key, times_validated)
-							VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	err := tx.QueryRowx(`INSERT INTO btc.header_cids (block_number, block_hash, parent_hash, cid, timestamp, bits, node_id, mh_key, times_validated, chainwork)
+							VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, btc.header_work($6))
 							ON CONFLICT (block_number, block_hash) DO UPDATE SET (parent_hash, cid, timestamp, bits, node_id, mh_key, times_validated) = ($3, $4, $5, $6, $7, $8, btc.header_cids.times_validated + 1)
 							RETURNING id`,
 		header.BlockNumber, header.BlockHash, header.ParentHash, header.CID, header.Timestamp, header.Bits, in.db.NodeID, header.MhKey, 1).Scan(&headerID)
 	return headerID, err
 }
 
-func (in *CIDIndexer) indexTransactionCIDs(tx *sqlx.Tx, transactions []TxModelWithInsAndOuts, headerID int64) error {
-	for _, transaction := range transactions {
-		txID, err := in.indexTransactionCID(tx, transaction, headerID)
+// markCanonical decides whether or not the header belongs to the canonical chain, the chain with the most work
+// If it does, the header is marked canonical, the canonical headers above it which it displaces are marked non-canonical,
+// and we walk back along parent_hash, marking its ancestors canonical and the competing headers at their heights
+// non-canonical, until we reach a header which is already canonical (the fork point)
+func (in *CIDIndexer) markCanonical(tx *sqlx.Tx, header HeaderModel, headerID int64) error {
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, canonicalLockID); err != nil {
+		return err
+	}
+	blockNumber, err := strconv.ParseInt(header.BlockNumber, 10, 64)
+	if err != nil {
+		return err
+	}
+	chainWork, err := in.linkHeader(tx, header, headerID, blockNumber)
+	if err != nil {
+		return err
+	}
+	canonical, displaced, err := in.isCanonical(tx, header, headerID, blockNumber, chainWork)
+	if err != nil {
+		return err
+	}
+	if !canonical {
+		_, err := tx.Exec(`UPDATE btc.header_cids SET canonical = false WHERE id = $1`, headerID)
+		return err
+	}
+	if len(displaced) > 0 {
+		if _, err := tx.Exec(`UPDATE btc.header_cids SET canonical = false WHERE id = ANY($1)`, pq.Array(displaced)); err != nil {
+			return err
+		}
+	}
+	id, parentHash := headerID, header.ParentHash
+	for {
+		if _, err := tx.Exec(`UPDATE btc.header_cids SET canonical = (id = $1) WHERE block_number = $2`, id, blockNumber); err != nil {
+			return err
+		}
+		parent := new(HeaderModel)
+		err := tx.Get(parent, `SELECT id, parent_hash, canonical FROM btc.header_cids
+								WHERE block_number = $1 AND block_hash = $2`, blockNumber-1, parentHash)
+		if err == sql.ErrNoRows {
+			return nil
+		}
 		if err != nil {
-			logrus.Error("btc indexer error when indexing header")
 			return err
 		}
-		for _, input := range transaction.TxInputs {
-			if err := in.indexTxInput(tx, input, txID); err != nil {
-				logrus.Error("btc indexer error when indexing tx inputs")
-				return err
+		if parent.Canonical {
+			return nil
+		}
+		id, parentHash = parent.ID, parent.ParentHash
+		blockNumber--
+	}
+}
+
+// linkHeader sets the chainwork of the header to its own work plus the chainwork of its parent, if its parent has been
+// indexed, and returns it
+// Headers are not necessarily indexed in order, so the descendants already indexed above the header have their chainwork
+// rebased onto its own
+func (in *CIDIndexer) linkHeader(tx *sqlx.Tx, header HeaderModel, headerID, blockNumber int64) (*big.Int, error) {
+	var chainWork string
+	err := tx.Get(&chainWork, `UPDATE btc.header_cids SET chainwork = btc.header_work(bits) + COALESCE((SELECT chainwork FROM btc.header_cids
+								WHERE block_number = $2 AND block_hash = $3), 0)
+								WHERE id = $1
+								RETURNING chainwork`, headerID, blockNumber-1, header.ParentHash)
+	if err != nil {
+		return nil, err
+	}
+	// Only the descendants whose chainwork does not build on the header's yet are walked
+	_, err = tx.Exec(`WITH RECURSIVE descendants AS (
+						SELECT id, block_number, block_hash, $3::NUMERIC + btc.header_work(bits) AS chainwork FROM btc.header_cids
+						WHERE block_number = $1 AND parent_hash = $2 AND chainwork <> $3::NUMERIC + btc.header_work(bits)
+						UNION ALL
+						SELECT header_cids.id, header_cids.block_number, header_cids.block_hash, descendants.chainwork + btc.header_work(header_cids.bits)
+						FROM btc.header_cids
+						INNER JOIN descendants ON (header_cids.block_number = descendants.block_number + 1 AND header_cids.parent_hash = descendants.block_hash)
+					)
+					UPDATE btc.header_cids SET chainwork = descendants.chainwork
+					FROM descendants
+					WHERE header_cids.id = descendants.id`, blockNumber+1, header.BlockHash, chainWork)
+	if err != nil {
+		return nil, err
+	}
+	return parseChainWork(chainWork)
+}
+
+// isCanonical returns whether or not the header belongs to the canonical chain, along with the ids of the canonical
+// headers above its height which it displaces
+// The header's branch is compared with the canonical chain above their fork point, the branch with the most work wins and
+// at equal work the canonical chain is kept
+func (in *CIDIndexer) isCanonical(tx *sqlx.Tx, header HeaderModel, headerID, blockNumber int64, chainWork *big.Int) (bool, []int64, error) {
+	var tipNumber int64
+	err := tx.Get(&tipNumber, `SELECT block_number FROM btc.header_cids
+								WHERE canonical = true AND id <> $1
+								ORDER BY block_number DESC LIMIT 1`, headerID)
+	if err == sql.ErrNoRows {
+		return true, nil, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+	// The fork point is the closest canonical ancestor of the header, reached through its non-canonical ancestors
+	var forkID int64
+	err = tx.Get(&forkID, `WITH RECURSIVE branch AS (
+								SELECT id, block_number, parent_hash, canonical FROM btc.header_cids
+								WHERE block_number = $1 AND block_hash = $2
+								UNION ALL
+								SELECT header_cids.id, header_cids.block_number, header_cids.parent_hash, header_cids.canonical
+								FROM btc.header_cids
+								INNER JOIN branch ON (header_cids.block_number = branch.block_number - 1 AND header_cids.block_hash = branch.parent_hash)
+								WHERE NOT branch.canonical
+							)
+							SELECT id FROM branch WHERE canonical`, blockNumber-1, header.ParentHash)
+	if err == sql.ErrNoRows {
+		canonical, err := in.isCanonicalByHeight(tx, header, headerID, blockNumber, tipNumber)
+		return canonical, nil, err
+	}
+	if err != nil {
+		return false, nil, err
+	}
+	// The canonical chain above the fork point competes with the header's branch
+	competitors := make([]struct {
+		ID          int64  `db:"id"`
+		BlockNumber int64  `db:"block_number"`
+		ChainWork   string `db:"chainwork"`
+	}, 0)
+	err = tx.Select(&competitors, `WITH RECURSIVE competitors AS (
+										SELECT id, block_number, block_hash, chainwork FROM btc.header_cids
+										WHERE id = $1
+										UNION ALL
+										SELECT header_cids.id, header_cids.block_number, header_cids.block_hash, header_cids.chainwork
+										FROM btc.header_cids
+										INNER JOIN competitors ON (header_cids.block_number = competitors.block_number + 1 AND header_cids.parent_hash = competitors.block_hash)
+										WHERE header_cids.canonical = true AND header_cids.id <> $2
+									)
+									SELECT id, block_number, chainwork FROM competitors WHERE id <> $1`, forkID, headerID)
+	if err != nil {
+		return false, nil, err
+	}
+	displaced := make([]int64, 0)
+	for _, competitor := range competitors {
+		competitorWork, err := parseChainWork(competitor.ChainWork)
+		if err != nil {
+			return false, nil, err
+		}
+		if competitorWork.Cmp(chainWork) >= 0 {
+			return false, nil, nil
+		}
+		if competitor.BlockNumber > blockNumber {
+			displaced = append(displaced, competitor.ID)
+		}
+	}
+	return true, displaced, nil
+}
+
+// isCanonicalByHeight returns whether or not a header whose branch does not reach the canonical chain belongs to it
+// Without a fork point the work of the header's branch cannot be compared with the canonical chain's, so the longest
+// chain wins
+func (in *CIDIndexer) isCanonicalByHeight(tx *sqlx.Tx, header HeaderModel, headerID, blockNumber, tipNumber int64) (bool, error) {
+	var childParentHashes []string
+	if err := tx.Select(&childParentHashes, `SELECT parent_hash FROM btc.header_cids
+											WHERE block_number = $1 AND canonical = true`, blockNumber+1); err != nil {
+		return false, err
+	}
+	if len(childParentHashes) > 0 {
+		// The canonical chain already extends above this height; the header is canonical only if it is the parent of the canonical child
+		for _, parentHash := range childParentHashes {
+			if parentHash == header.BlockHash {
+				return true, nil
 			}
 		}
-		for _, output := range transaction.TxOutputs {
-			if err := in.indexTxOutput(tx, output, txID); err != nil {
-				logrus.Error("btc indexer error when indexing tx outputs")
+		return false, nil
+	}
+	// A header above the canonical tip is the new head
+	if blockNumber > tipNumber {
+		return true, nil
+	}
+	// At the tip, or below it and above a gap, the header is canonical unless it competes with a canonical header; the
+	// header seen first is kept
+	var competing bool
+	if err := tx.Get(&competing, `SELECT EXISTS(SELECT 1 FROM btc.header_cids
+								WHERE block_number = $1 AND canonical = true AND id <> $2)`, blockNumber, headerID); err != nil {
+		return false, err
+	}
+	return !competing, nil
+}
+
+// parseChainWork parses an indexed chainwork
+func parseChainWork(chainWork string) (*big.Int, error) {
+	work, ok := new(big.Int).SetString(chainWork, 10)
+	if !ok {
+		return nil, fmt.Errorf("btc indexer invalid chainwork %s", chainWork)
+	}
+	return work, nil
+}
+
+// indexTransactionCIDs indexes the transactions of the block, and returns the scripts its basic filter matches
+func (in *CIDIndexer) indexTransactionCIDs(tx *sqlx.Tx, transactions []TxModelWithInsAndOuts, headerID int64) (*filterScripts, error) {
+	scripts := new(filterScripts)
+	for _, transaction := range transactions {
+		if err := in.indexTransaction(tx, transaction, headerID, scripts); err != nil {
+			return nil, err
+		}
+	}
+	return scripts, nil
+}
+
+// indexTransaction indexes the transaction along with its inputs and outputs, resolving the outputs its inputs spend
+// to record the transaction's fee, and adds the scripts of its outputs and of the outputs it spends to the block's
+// filter scripts
+func (in *CIDIndexer) indexTransaction(tx *sqlx.Tx, transaction TxModelWithInsAndOuts, headerID int64, scripts *filterScripts) error {
+	txID, err := in.indexTransactionCID(tx, transaction, headerID)
+	if err != nil {
+		logrus.Error("btc indexer error when indexing header")
+		return err
+	}
+	coinbase := false
+	for _, input := range transaction.TxInputs {
+		if isCoinbaseInput(input) {
+			coinbase = true
+		} else {
+			var pkScript []byte
+			if input, pkScript, err = in.resolvePrevout(tx, input, headerID); err != nil {
+				logrus.Error("btc indexer error when resolving tx inputs")
 				return err
 			}
+			scripts.addPrevout(pkScript)
 		}
+		if err := in.indexTxInput(tx, input, txID); err != nil {
+			logrus.Error("btc indexer error when indexing tx inputs")
+			return err
+		}
+	}
+	for _, output := range transaction.TxOutputs {
+		if err := in.indexTxOutput(tx, output, txID, transaction.TxHash); err != nil {
+			logrus.Error("btc indexer error when indexing tx outputs")
+			return err
+		}
+		scripts.addOutput(output.PkScript)
 	}
-	return nil
+	return in.updateFee(tx, txID, coinbase)
 }
 
+// indexTransactionCID indexes the transaction against the header including it
+// A transaction included in competing blocks is indexed once for each of them, so indexing it in a stale block does not
+// take it away from the canonical one
 func (in *CIDIndexer) indexTransactionCID(tx *sqlx.Tx, transaction TxModelWithInsAndOuts, headerID int64) (int64, error) {
 	var txID int64
-	err := tx.QueryRowx(`INSERT INTO btc.transaction_cids (header_id, tx_hash, index, cid, segwit, witness_hash, mh_key)
-							VALUES ($1, $2, $3, $4, $5, $6, $7)
-							ON CONFLICT (tx_hash) DO UPDATE SET (header_id, index, cid, segwit, witness_hash, mh_key) = ($1, $3, $4, $5, $6, $7)
+	err := tx.QueryRowx(`INSERT INTO btc.transaction_cids (header_id, tx_hash, index, cid, segwit, witness_hash, mh_key, weight, vsize)
+							VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0), NULLIF($9, 0))
+							ON CONFLICT (header_id, tx_hash) DO UPDATE SET (index, cid, segwit, witness_hash, mh_key, weight, vsize) = ($3, $4, $5, $6, $7, NULLIF($8, 0), NULLIF($9, 0))
 							RETURNING id`,
-		headerID, transaction.TxHash, transaction.Index, transaction.CID, transaction.SegWit, transaction.WitnessHash, transaction.MhKey).Scan(&txID)
+		headerID, transaction.TxHash, transaction.Index, transaction.CID, transaction.SegWit, transaction.WitnessHash, transaction.MhKey,
+		transaction.Weight, transaction.VSize).Scan(&txID)
 	return txID, err
 }
 
+// resolvePrevout returns the input along with the value, script class and addresses of the output it spends, taken from
+// the indexed output or, if the output has not been indexed, fetched from the node, and the output's pk script
+// An input whose output cannot be fetched is returned unresolved with a nil pk script, it is resolved once its output
+// is indexed
+// Only outputs included in canonical blocks, or in the block of the header with the provided id which is being indexed
+// and is not marked canonical yet, are resolved from the index
+func (in *CIDIndexer) resolvePrevout(tx *sqlx.Tx, txInput TxInput, headerID int64) (TxInput, []byte, error) {
+	var prevout TxOutput
+	err := tx.Get(&prevout, `SELECT tx_outputs.id, tx_outputs.value, tx_outputs.pk_script, tx_outputs.script_class, tx_outputs.addresses
+							FROM btc.tx_outputs
+							INNER JOIN btc.transaction_cids ON (tx_outputs.tx_id = transaction_cids.id)
+							INNER JOIN btc.header_cids ON (transaction_cids.header_id = header_cids.id)
+							WHERE transaction_cids.tx_hash = $1 AND tx_outputs.index = $2
+							AND (header_cids.canonical = true OR header_cids.id = $3)
+							ORDER BY header_cids.id = $3 DESC
+							LIMIT 1`,
+		txInput.PreviousOutPointHash, txInput.PreviousOutPointIndex, headerID)
+	switch {
+	case err == nil:
+		txInput.SpentOutputID = sql.NullInt64{Int64: prevout.ID, Valid: true}
+	case err == sql.ErrNoRows && in.prevouts != nil:
+		hash, err := chainhash.NewHashFromStr(txInput.PreviousOutPointHash)
+		if err != nil {
+			return txInput, nil, err
+		}
+		prevout, err = in.prevouts.FetchPrevout(wire.OutPoint{Hash: *hash, Index: txInput.PreviousOutPointIndex})
+		if err != nil {
+			logrus.Debugf("btc indexer unable to fetch the output spent by input %d: %s", txInput.Index, err.Error())
+			return txInput, nil, nil
+		}
+	case err == sql.ErrNoRows:
+		return txInput, nil, nil
+	default:
+		return txInput, nil, err
+	}
+	txInput.Value = sql.NullInt64{Int64: prevout.Value, Valid: true}
+	txInput.ScriptClass = sql.NullInt64{Int64: int64(prevout.ScriptClass), Valid: true}
+	txInput.Addresses = prevout.Addresses
+	if prevout.ScriptClass != uint8(WitnessV1TaprootTy) {
+		txInput.TaprootSpend = NotTaprootSpend
+	}
+	// The pk script is never nil for a resolved output, so that it is told apart from an unresolved one
+	if prevout.PkScript == nil {
+		prevout.PkScript = []byte{}
+	}
+	return txInput, prevout.PkScript, nil
+}
+
+// indexTxInput indexes the input, along with the output it spends if it has been resolved
+// Resolutions are never overwritten by unresolved values, so reindexing an input does not lose its resolved output, nor
+// a taproot spend detection cleared by it
 func (in *CIDIndexer) indexTxInput(tx *sqlx.Tx, txInput TxInput, txID int64) error {
-	_, err := tx.Exec(`INSERT INTO btc.tx_inputs (tx_id, index, witness, sig_script, outpoint_tx_hash, outpoint_index)
-						VALUES ($1, $2, $3, $4, $5, $6)
-						ON CONFLICT (tx_id, index) DO UPDATE SET (witness, sig_script, outpoint_tx_hash, outpoint_index) = ($3, $4, $5, $6)`,
-		txID, txInput.Index, pq.Array(txInput.TxWitness), txInput.SignatureScript, txInput.PreviousOutPointHash, txInput.PreviousOutPointIndex)
+	_, err := tx.Exec(`INSERT INTO btc.tx_inputs (tx_id, index, witness, sig_script, outpoint_tx_hash, outpoint_index, spent_output_id, value, script_class, addresses, taproot_spend)
+						VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+						ON CONFLICT (tx_id, index) DO UPDATE SET (witness, sig_script, outpoint_tx_hash, outpoint_index, spent_output_id, value, script_class, addresses, taproot_spend) =
+						($3, $4, $5, $6, COALESCE($7, btc.tx_inputs.spent_output_id), COALESCE($8, btc.tx_inputs.value),
+						COALESCE($9, btc.tx_inputs.script_class), COALESCE($10, btc.tx_inputs.addresses),
+						CASE WHEN COALESCE($9, btc.tx_inputs.script_class, $12) = $12 THEN $11 ELSE $13 END)`,
+		txID, txInput.Index, pq.Array(txInput.TxWitness), txInput.SignatureScript, txInput.PreviousOutPointHash, txInput.PreviousOutPointIndex,
+		txInput.SpentOutputID, txInput.Value, txInput.ScriptClass, txInput.Addresses, txInput.TaprootSpend, int64(WitnessV1TaprootTy), NotTaprootSpend)
+	if err != nil {
+		return err
+	}
+	// Index the transaction under the addresses of the output it spends
+	_, err = tx.Exec(`INSERT INTO btc.address_transactions (address, tx_id)
+						SELECT unnest(addresses), tx_id FROM btc.tx_inputs
+						WHERE tx_id = $1 AND index = $2
+						ON CONFLICT (address, tx_id) DO NOTHING`, txID, txInput.Index)
 	return err
 }
 
-func (in *CIDIndexer) indexTxOutput(tx *sqlx.Tx, txOuput TxOutput, txID int64) error {
-	_, err := tx.Exec(`INSERT INTO btc.tx_outputs (tx_id, index, value, pk_script, script_class, addresses, required_sigs)
-							VALUES ($1, $2, $3, $4, $5, $6, $7)
-							ON CONFLICT (tx_id, index) DO UPDATE SET (value, pk_script, script_class, addresses, required_sigs) = ($3, $4, $5, $6, $7)`,
-		txID, txOuput.Index, txOuput.Value, txOuput.PkScript, txOuput.ScriptClass, txOuput.Addresses, txOuput.RequiredSigs)
+// indexTxOutput indexes the output of the transaction with the provided hash, linking it to the inputs already indexed which spend it
+// Blocks are not necessarily indexed in order, so an output can be indexed after the inputs which spend it
+func (in *CIDIndexer) indexTxOutput(tx *sqlx.Tx, txOuput TxOutput, txID int64, txHash string) error {
+	var outputID int64
+	// The script hash is the sha256 of the pk script, which Electrum clients look outputs up by
+	scriptHash := sha256.Sum256(txOuput.PkScript)
+	err := tx.QueryRowx(`INSERT INTO btc.tx_outputs (tx_id, index, value, pk_script, script_class, addresses, required_sigs, script_hash)
+							VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+							ON CONFLICT (tx_id, index) DO UPDATE SET (value, pk_script, script_class, addresses, required_sigs, script_hash) = ($3, $4, $5, $6, $7, $8)
+							RETURNING id`,
+		txID, txOuput.Index, txOuput.Value, txOuput.PkScript, txOuput.ScriptClass, txOuput.Addresses, txOuput.RequiredSigs, scriptHash[:]).Scan(&outputID)
+	if err != nil {
+		return err
+	}
+	if err := in.indexNullData(tx, txOuput, txID, outputID); err != nil {
+		return err
+	}
+	// Inputs spending an output which is not a taproot output have their taproot spend detection cleared
+	rows, err := tx.Queryx(`UPDATE btc.tx_inputs SET (spent_output_id, value, script_class, addresses, taproot_spend) =
+						($1, $4, $5, $6, CASE WHEN $5 = $7 THEN taproot_spend ELSE $8 END)
+						WHERE outpoint_tx_hash = $2 AND outpoint_index = $3
+						RETURNING tx_id`, outputID, txHash, txOuput.Index, txOuput.Value, txOuput.ScriptClass, txOuput.Addresses,
+		uint8(WitnessV1TaprootTy), NotTaprootSpend)
+	if err != nil {
+		return err
+	}
+	spendingTxIDs := make([]int64, 0)
+	for rows.Next() {
+		var spendingTxID int64
+		if err := rows.Scan(&spendingTxID); err != nil {
+			rows.Close()
+			return err
+		}
+		spendingTxIDs = append(spendingTxIDs, spendingTxID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	// The spending transactions' fees may now be known
+	for _, spendingTxID := range spendingTxIDs {
+		if err := in.updateFee(tx, spendingTxID, false); err != nil {
+			return err
+		}
+	}
+	// Index the transaction, and the transactions already indexed which spend the output, under the output's addresses
+	_, err = tx.Exec(`INSERT INTO btc.address_transactions (address, tx_id)
+						SELECT address, $2::INTEGER FROM unnest($1::VARCHAR(66)[]) AS address
+						UNION
+						SELECT address, tx_inputs.tx_id FROM unnest($1::VARCHAR(66)[]) AS address, btc.tx_inputs
+						WHERE tx_inputs.spent_output_id = $3
+						ON CONFLICT (address, tx_id) DO NOTHING`, txOuput.Addresses, txID, outputID)
 	return err
 }
+
+// indexNullData indexes the payload of the output if it is an OP_RETURN output
+func (in *CIDIndexer) indexNullData(tx *sqlx.Tx, txOuput TxOutput, txID, outputID int64) error {
+	payload, ok := NullDataPayload(txOuput.PkScript)
+	if !ok {
+		return nil
+	}
+	_, err := tx.Exec(`INSERT INTO btc.nulldata_outputs (tx_id, output_id, payload, prefix) VALUES ($1, $2, $3, $4)
+						ON CONFLICT (output_id) DO UPDATE SET (tx_id, payload, prefix) = ($1, $3, $4)`,
+		txID, outputID, payload, NullDataPrefix(payload))
+	return err
+}
+
+// updateFee records the fee and fee rate of the transaction with the provided id if the outputs spent by all of its
+// inputs have been resolved; coinbase transactions pay no fee
+func (in *CIDIndexer) updateFee(tx *sqlx.Tx, txID int64, coinbase bool) error {
+	_, err := tx.Exec(`UPDATE btc.transaction_cids SET (fee, fee_rate) = (fees.fee, fees.fee::NUMERIC / NULLIF(transaction_cids.vsize, 0))
+						FROM (SELECT CASE WHEN $2 THEN 0
+							ELSE (SELECT SUM(value) FROM btc.tx_inputs WHERE tx_id = $1) - (SELECT COALESCE(SUM(value), 0) FROM btc.tx_outputs WHERE tx_id = $1)
+							END AS fee) AS fees
+						WHERE transaction_cids.id = $1
+						AND ($2 OR NOT EXISTS (SELECT 1 FROM btc.tx_inputs WHERE tx_id = $1 AND value IS NULL))`, txID, coinbase)
+	return err
+}
+
+// isCoinbaseInput returns whether or not the input is the input of a coinbase transaction, which spends no output
+func isCoinbaseInput(txInput TxInput) bool {
+	return txInput.PreviousOutPointIndex == wire.MaxPrevOutIndex && txInput.PreviousOutPointHash == zeroHash
+}
@@ -17,6 +17,16 @@
 package btc_test
 
 import (
+	"database/sql"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -36,7 +46,7 @@ var _ = Describe("Indexer", func() {
 	BeforeEach(func() {
 		db, err = shared.SetupDB()
 		Expect(err).ToNot(HaveOccurred())
-		repo = btc.NewCIDIndexer(db)
+		repo = btc.NewCIDIndexer(db, &chaincfg.MainNetParams, nil)
 		// need entries in the public.blocks with the mhkeys or the FK constraint will fail
 		shared.PublishMockIPLD(db, mocks.MockHeaderMhKey, mockData)
 		shared.PublishMockIPLD(db, mocks.MockTrxMhKey1, mockData)
@@ -90,5 +100,570 @@ var _ = Describe("Indexer", func() {
 				}
 			}
 		})
+
+		It("Marks the longest chain of headers as canonical", func() {
+			err = repo.Index(&mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			pgStr := `SELECT block_hash FROM btc.header_cids
+				WHERE block_number = $1
+				AND canonical = true`
+			canonicalHashes := make([]string, 0)
+			err = db.Select(&canonicalHashes, pgStr, mocks.MockHeaderMetaData.BlockNumber)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonicalHashes).To(Equal([]string{mocks.MockHeaderMetaData.BlockHash}))
+
+			// a competing header at the same height does not replace the canonical header, the header seen first is kept
+			forkHeader := mocks.MockHeaderMetaData
+			forkHeader.BlockHash = "0000000000000000000000000000000000000000000000000000000000000001"
+			err = repo.Index(&btc.CIDPayload{HeaderCID: forkHeader})
+			Expect(err).ToNot(HaveOccurred())
+			canonicalHashes = make([]string, 0)
+			err = db.Select(&canonicalHashes, pgStr, mocks.MockHeaderMetaData.BlockNumber)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonicalHashes).To(Equal([]string{mocks.MockHeaderMetaData.BlockHash}))
+
+			// extending the competing header makes its chain canonical
+			extend := func(parent btc.HeaderModel, blockHash string) btc.HeaderModel {
+				child := parent
+				blockNumber, err := strconv.ParseInt(parent.BlockNumber, 10, 64)
+				Expect(err).ToNot(HaveOccurred())
+				child.BlockNumber = strconv.FormatInt(blockNumber+1, 10)
+				child.BlockHash = blockHash
+				child.ParentHash = parent.BlockHash
+				err = repo.Index(&btc.CIDPayload{HeaderCID: child})
+				Expect(err).ToNot(HaveOccurred())
+				return child
+			}
+			forkChild := extend(forkHeader, "0000000000000000000000000000000000000000000000000000000000000002")
+			canonicalHashes = make([]string, 0)
+			err = db.Select(&canonicalHashes, pgStr, mocks.MockHeaderMetaData.BlockNumber)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonicalHashes).To(Equal([]string{forkHeader.BlockHash}))
+
+			// a chain of the same length does not replace it
+			childHeader := extend(mocks.MockHeaderMetaData, "0000000000000000000000000000000000000000000000000000000000000003")
+			canonicalHashes = make([]string, 0)
+			err = db.Select(&canonicalHashes, pgStr, childHeader.BlockNumber)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonicalHashes).To(Equal([]string{forkChild.BlockHash}))
+
+			// a longer chain does
+			grandchildHeader := extend(childHeader, "0000000000000000000000000000000000000000000000000000000000000004")
+			canonicalHashes = make([]string, 0)
+			err = db.Select(&canonicalHashes, `SELECT block_hash FROM btc.header_cids WHERE canonical = true ORDER BY block_number`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonicalHashes).To(Equal([]string{mocks.MockHeaderMetaData.BlockHash, childHeader.BlockHash, grandchildHeader.BlockHash}))
+		})
+
+		It("Keeps the canonical child of an indexed parent when a sibling with equal work is indexed", func() {
+			err = repo.Index(&mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			height, err := strconv.ParseInt(mocks.MockHeaderMetaData.BlockNumber, 10, 64)
+			Expect(err).ToNot(HaveOccurred())
+			child := func(blockHash string) btc.HeaderModel {
+				header := mocks.MockHeaderMetaData
+				header.BlockNumber = strconv.FormatInt(height+1, 10)
+				header.BlockHash = blockHash
+				header.ParentHash = mocks.MockHeaderMetaData.BlockHash
+				err = repo.Index(&btc.CIDPayload{HeaderCID: header})
+				Expect(err).ToNot(HaveOccurred())
+				return header
+			}
+			firstChild := child("0000000000000000000000000000000000000000000000000000000000000001")
+			secondChild := child("0000000000000000000000000000000000000000000000000000000000000002")
+			var firstWork, secondWork string
+			err = db.Get(&firstWork, `SELECT chainwork FROM btc.header_cids WHERE block_hash = $1`, firstChild.BlockHash)
+			Expect(err).ToNot(HaveOccurred())
+			err = db.Get(&secondWork, `SELECT chainwork FROM btc.header_cids WHERE block_hash = $1`, secondChild.BlockHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secondWork).To(Equal(firstWork))
+
+			canonicalHashes := make([]string, 0)
+			err = db.Select(&canonicalHashes, `SELECT block_hash FROM btc.header_cids WHERE canonical = true ORDER BY block_number`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonicalHashes).To(Equal([]string{mocks.MockHeaderMetaData.BlockHash, firstChild.BlockHash}))
+		})
+
+		It("Rejects headers which do not meet their target or whose bits do not follow the retarget rules", func() {
+			// a hash above the target
+			header := mocks.MockHeaderMetaData
+			header.BlockHash = "ff00000000000000000000000000000000000000000000000000000000000000"
+			err = repo.Index(&btc.CIDPayload{HeaderCID: header})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("does not meet its target"))
+			// a target above the proof of work limit
+			header = mocks.MockHeaderMetaData
+			header.BlockHash = "0000000000000000000000000000000000000000000000000000000000000001"
+			header.Bits = 0x1e00ffff
+			err = repo.Index(&btc.CIDPayload{HeaderCID: header})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("invalid target"))
+
+			// between retargets a header must have the bits of its parent
+			err = repo.Index(&mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			height, err := strconv.ParseInt(mocks.MockHeaderMetaData.BlockNumber, 10, 64)
+			Expect(err).ToNot(HaveOccurred())
+			childHeader := mocks.MockHeaderMetaData
+			childHeader.BlockNumber = strconv.FormatInt(height+1, 10)
+			childHeader.BlockHash = "0000000000000000000000000000000000000000000000000000000000000002"
+			childHeader.ParentHash = mocks.MockHeaderMetaData.BlockHash
+			childHeader.Bits = mocks.MockHeaderMetaData.Bits - 1
+			err = repo.Index(&btc.CIDPayload{HeaderCID: childHeader})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("expected %08x", mocks.MockHeaderMetaData.Bits))
+
+			var count int
+			err = db.Get(&count, `SELECT COUNT(*) FROM btc.header_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(1))
+		})
+
+		It("Rejects headers whose parent is not indexed when a canonical header is indexed at the parent's height", func() {
+			err = repo.Index(&mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			height, err := strconv.ParseInt(mocks.MockHeaderMetaData.BlockNumber, 10, 64)
+			Expect(err).ToNot(HaveOccurred())
+			unlinkedHeader := mocks.MockHeaderMetaData
+			unlinkedHeader.BlockNumber = strconv.FormatInt(height+1, 10)
+			unlinkedHeader.BlockHash = "0000000000000000000000000000000000000000000000000000000000000002"
+			unlinkedHeader.ParentHash = "0000000000000000000000000000000000000000000000000000000000000001"
+			err = repo.Index(&btc.CIDPayload{HeaderCID: unlinkedHeader})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("has not been indexed"))
+
+			// above a gap the parent cannot be checked until the gap is filled
+			gapHeader := unlinkedHeader
+			gapHeader.BlockNumber = strconv.FormatInt(height+2, 10)
+			err = repo.Index(&btc.CIDPayload{HeaderCID: gapHeader})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Keeps the bits of the parent at retargets on networks without retargeting", func() {
+			repo = btc.NewCIDIndexer(db, &chaincfg.RegressionNetParams, nil)
+			blocksPerRetarget := int64(chaincfg.RegressionNetParams.TargetTimespan / chaincfg.RegressionNetParams.TargetTimePerBlock)
+			parent := mocks.MockHeaderMetaData
+			parent.BlockNumber = strconv.FormatInt(blocksPerRetarget-1, 10)
+			parent.Bits = chaincfg.RegressionNetParams.PowLimitBits
+			err = repo.Index(&btc.CIDPayload{HeaderCID: parent})
+			Expect(err).ToNot(HaveOccurred())
+
+			retargetHeader := parent
+			retargetHeader.BlockNumber = strconv.FormatInt(blocksPerRetarget, 10)
+			retargetHeader.BlockHash = "0000000000000000000000000000000000000000000000000000000000000001"
+			retargetHeader.ParentHash = parent.BlockHash
+			retargetHeader.Timestamp = parent.Timestamp + int64(10*time.Minute)
+			retargetHeader.Bits = mocks.MockHeaderMetaData.Bits
+			err = repo.Index(&btc.CIDPayload{HeaderCID: retargetHeader})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("expected %08x", chaincfg.RegressionNetParams.PowLimitBits))
+
+			retargetHeader.Bits = chaincfg.RegressionNetParams.PowLimitBits
+			err = repo.Index(&btc.CIDPayload{HeaderCID: retargetHeader})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Records the chainwork of headers, rebasing it once their parent is indexed", func() {
+			height, err := strconv.ParseInt(mocks.MockHeaderMetaData.BlockNumber, 10, 64)
+			Expect(err).ToNot(HaveOccurred())
+			childHeader := mocks.MockHeaderMetaData
+			childHeader.BlockNumber = strconv.FormatInt(height+1, 10)
+			childHeader.BlockHash = "0000000000000000000000000000000000000000000000000000000000000002"
+			childHeader.ParentHash = mocks.MockHeaderMetaData.BlockHash
+			err = repo.Index(&btc.CIDPayload{HeaderCID: childHeader})
+			Expect(err).ToNot(HaveOccurred())
+			work := blockchain.CalcWork(mocks.MockHeaderMetaData.Bits)
+			var chainWork string
+			err = db.Get(&chainWork, `SELECT chainwork FROM btc.header_cids WHERE block_hash = $1`, childHeader.BlockHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(chainWork).To(Equal(work.String()))
+
+			err = repo.Index(&mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			err = db.Get(&chainWork, `SELECT chainwork FROM btc.header_cids WHERE block_hash = $1`, childHeader.BlockHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(chainWork).To(Equal(new(big.Int).Mul(work, big.NewInt(2)).String()))
+		})
+
+		It("Marks the chain with the most work canonical", func() {
+			// testnet allows blocks mined long enough after their parent to have the minimum difficulty
+			repo = btc.NewCIDIndexer(db, &chaincfg.TestNet3Params, nil)
+			err = repo.Index(&mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			height, err := strconv.ParseInt(mocks.MockHeaderMetaData.BlockNumber, 10, 64)
+			Expect(err).ToNot(HaveOccurred())
+			parent := mocks.MockHeaderMetaData
+			for i, hash := range []string{
+				"0000000000000000000000000000000000000000000000000000000000000001",
+				"0000000000000000000000000000000000000000000000000000000000000002",
+			} {
+				lightHeader := parent
+				lightHeader.BlockNumber = strconv.FormatInt(height+int64(i)+1, 10)
+				lightHeader.BlockHash = hash
+				lightHeader.ParentHash = parent.BlockHash
+				lightHeader.Timestamp = parent.Timestamp + int64(30*time.Minute)
+				lightHeader.Bits = chaincfg.TestNet3Params.PowLimitBits
+				err = repo.Index(&btc.CIDPayload{HeaderCID: lightHeader})
+				Expect(err).ToNot(HaveOccurred())
+				parent = lightHeader
+			}
+			var tipHash string
+			err = db.Get(&tipHash, `SELECT block_hash FROM btc.header_cids WHERE canonical = true ORDER BY block_number DESC LIMIT 1`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tipHash).To(Equal(parent.BlockHash))
+
+			// a single block at the parent's difficulty outweighs the longer chain mined at the minimum difficulty
+			heavyHeader := mocks.MockHeaderMetaData
+			heavyHeader.BlockNumber = strconv.FormatInt(height+1, 10)
+			heavyHeader.BlockHash = "0000000000000000000000000000000000000000000000000000000000000003"
+			heavyHeader.ParentHash = mocks.MockHeaderMetaData.BlockHash
+			heavyHeader.Timestamp = mocks.MockHeaderMetaData.Timestamp + int64(10*time.Minute)
+			err = repo.Index(&btc.CIDPayload{HeaderCID: heavyHeader})
+			Expect(err).ToNot(HaveOccurred())
+			canonicalHashes := make([]string, 0)
+			err = db.Select(&canonicalHashes, `SELECT block_hash FROM btc.header_cids WHERE canonical = true ORDER BY block_number`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonicalHashes).To(Equal([]string{mocks.MockHeaderMetaData.BlockHash, heavyHeader.BlockHash}))
+		})
+		It("Keeps the transactions of the canonical block when a competing block including them is indexed", func() {
+			err = repo.Index(&mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			retriever := btc.NewCIDRetriever(db)
+			tx, err := db.Beginx()
+			Expect(err).ToNot(HaveOccurred())
+			utxos, err := retriever.RetrieveUTXOs(tx, 1<<62, nil, 0, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(utxos).ToNot(BeEmpty())
+			Expect(tx.Commit()).ToNot(HaveOccurred())
+
+			forkHeader := mocks.MockHeaderMetaData
+			forkHeader.BlockHash = "0000000000000000000000000000000000000000000000000000000000000001"
+			err = repo.Index(&btc.CIDPayload{HeaderCID: forkHeader, TransactionCIDs: mocks.MockCIDPayload.TransactionCIDs})
+			Expect(err).ToNot(HaveOccurred())
+
+			// the transactions are indexed for both blocks
+			var count int
+			err = db.Get(&count, `SELECT COUNT(*) FROM btc.transaction_cids`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(2 * len(mocks.MockCIDPayload.TransactionCIDs)))
+
+			// and are still retrieved from the canonical block
+			tx, err = db.Beginx()
+			Expect(err).ToNot(HaveOccurred())
+			defer tx.Rollback()
+			for _, trx := range mocks.MockCIDPayload.TransactionCIDs {
+				txHash, err := chainhash.NewHashFromStr(trx.TxHash)
+				Expect(err).ToNot(HaveOccurred())
+				txCID, err := retriever.RetrieveTxCIDByHash(tx, *txHash)
+				Expect(err).ToNot(HaveOccurred())
+				header, err := retriever.RetrieveHeaderCIDByID(tx, txCID.HeaderID)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(header.BlockHash).To(Equal(mocks.MockHeaderMetaData.BlockHash))
+			}
+			forkUTXOs, err := retriever.RetrieveUTXOs(tx, 1<<62, nil, 0, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(forkUTXOs).To(Equal(utxos))
+		})
+
+		It("Links inputs to the outputs they spend whichever is indexed first", func() {
+			height, err := strconv.ParseInt(mocks.MockHeaderMetaData.BlockNumber, 10, 64)
+			Expect(err).ToNot(HaveOccurred())
+			childHeader := mocks.MockHeaderMetaData
+			childHeader.BlockNumber = strconv.FormatInt(height+1, 10)
+			childHeader.BlockHash = "0000000000000000000000000000000000000000000000000000000000000002"
+			childHeader.ParentHash = mocks.MockHeaderMetaData.BlockHash
+			spentTx := mocks.MockTxsMetaDataPostPublish[1]
+			spendingTx := func(txHash string, outpointIndex uint32) btc.TxModelWithInsAndOuts {
+				return btc.TxModelWithInsAndOuts{
+					TxHash: txHash,
+					CID:    mocks.MockTrxCID1.String(),
+					MhKey:  mocks.MockTrxMhKey1,
+					TxInputs: []btc.TxInput{{
+						SignatureScript:       []byte{0x51},
+						PreviousOutPointHash:  spentTx.TxHash,
+						PreviousOutPointIndex: outpointIndex,
+					}},
+				}
+			}
+			// the first spend is indexed before the output it spends
+			err = repo.Index(&btc.CIDPayload{
+				HeaderCID:       childHeader,
+				TransactionCIDs: []btc.TxModelWithInsAndOuts{spendingTx("0000000000000000000000000000000000000000000000000000000000000003", 0)},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Index(&mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			// the second spend is indexed after the output it spends
+			childHeader.BlockHash = "0000000000000000000000000000000000000000000000000000000000000004"
+			err = repo.Index(&btc.CIDPayload{
+				HeaderCID:       childHeader,
+				TransactionCIDs: []btc.TxModelWithInsAndOuts{spendingTx("0000000000000000000000000000000000000000000000000000000000000005", 1)},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			pgStr := `SELECT tx_outputs.index FROM btc.tx_inputs
+				INNER JOIN btc.tx_outputs ON (tx_inputs.spent_output_id = tx_outputs.id)
+				INNER JOIN btc.transaction_cids ON (tx_outputs.tx_id = transaction_cids.id)
+				WHERE transaction_cids.tx_hash = $1
+				ORDER BY tx_outputs.index`
+			spentIndexes := make([]int64, 0)
+			err = db.Select(&spentIndexes, pgStr, spentTx.TxHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(spentIndexes).To(Equal([]int64{0, 1}))
+
+			// the spends are indexed under the addresses of the outputs they spend
+			pgStr = `SELECT transaction_cids.tx_hash FROM btc.address_transactions
+				INNER JOIN btc.transaction_cids ON (address_transactions.tx_id = transaction_cids.id)
+				WHERE address_transactions.address = $1
+				ORDER BY transaction_cids.tx_hash`
+			for i, spendingHash := range []string{
+				"0000000000000000000000000000000000000000000000000000000000000003",
+				"0000000000000000000000000000000000000000000000000000000000000005",
+			} {
+				txHashes := make([]string, 0)
+				err = db.Select(&txHashes, pgStr, spentTx.TxOutputs[i].Addresses[0])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(txHashes).To(ContainElement(spentTx.TxHash))
+				Expect(txHashes).To(ContainElement(spendingHash))
+			}
+		})
+
+		It("Resolves the outputs spent by inputs and records the fees of transactions", func() {
+			height, err := strconv.ParseInt(mocks.MockHeaderMetaData.BlockNumber, 10, 64)
+			Expect(err).ToNot(HaveOccurred())
+			childHeader := mocks.MockHeaderMetaData
+			childHeader.BlockNumber = strconv.FormatInt(height+1, 10)
+			childHeader.BlockHash = "0000000000000000000000000000000000000000000000000000000000000002"
+			childHeader.ParentHash = mocks.MockHeaderMetaData.BlockHash
+			spentTx := mocks.MockTxsMetaDataPostPublish[1]
+			// the second input spends an output which is not indexed, it is fetched from the node
+			unindexedHash, err := chainhash.NewHashFromStr("0000000000000000000000000000000000000000000000000000000000000006")
+			Expect(err).ToNot(HaveOccurred())
+			fetcher := &mocks.PrevoutFetcher{
+				Prevouts: map[wire.OutPoint]btc.TxOutput{
+					{Hash: *unindexedHash, Index: 0}: spentTx.TxOutputs[1],
+				},
+			}
+			err = repo.Index(&mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			repo = btc.NewCIDIndexer(db, &chaincfg.MainNetParams, fetcher)
+			inValue := spentTx.TxOutputs[0].Value + spentTx.TxOutputs[1].Value
+			spendingTx := btc.TxModelWithInsAndOuts{
+				TxHash: "0000000000000000000000000000000000000000000000000000000000000003",
+				CID:    mocks.MockTrxCID1.String(),
+				MhKey:  mocks.MockTrxMhKey1,
+				Weight: 800,
+				VSize:  200,
+				TxInputs: []btc.TxInput{
+					{
+						Index:                 0,
+						SignatureScript:       []byte{0x51},
+						PreviousOutPointHash:  spentTx.TxHash,
+						PreviousOutPointIndex: 0,
+					},
+					{
+						Index:                 1,
+						SignatureScript:       []byte{0x51},
+						PreviousOutPointHash:  unindexedHash.String(),
+						PreviousOutPointIndex: 0,
+					},
+				},
+				TxOutputs: []btc.TxOutput{{
+					Index:       0,
+					Value:       inValue - 1000,
+					PkScript:    spentTx.TxOutputs[0].PkScript,
+					ScriptClass: spentTx.TxOutputs[0].ScriptClass,
+					Addresses:   spentTx.TxOutputs[0].Addresses,
+				}},
+			}
+			err = repo.Index(&btc.CIDPayload{
+				HeaderCID:       childHeader,
+				TransactionCIDs: []btc.TxModelWithInsAndOuts{spendingTx},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fetcher.PassedOutpoints).To(Equal([]wire.OutPoint{{Hash: *unindexedHash, Index: 0}}))
+
+			inputs := make([]btc.TxInput, 0)
+			pgStr := `SELECT tx_inputs.index, tx_inputs.value, tx_inputs.script_class, tx_inputs.addresses FROM btc.tx_inputs
+				INNER JOIN btc.transaction_cids ON (tx_inputs.tx_id = transaction_cids.id)
+				WHERE transaction_cids.tx_hash = $1
+				ORDER BY tx_inputs.index`
+			err = db.Select(&inputs, pgStr, spendingTx.TxHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(inputs)).To(Equal(2))
+			for i, input := range inputs {
+				Expect(input.Value.Int64).To(Equal(spentTx.TxOutputs[i].Value))
+				Expect(input.ScriptClass.Int64).To(Equal(int64(spentTx.TxOutputs[i].ScriptClass)))
+				Expect(input.Addresses).To(Equal(spentTx.TxOutputs[i].Addresses))
+			}
+
+			pgStr = `SELECT tx_hash, weight, vsize, fee, fee_rate FROM btc.transaction_cids WHERE tx_hash = $1`
+			spending := new(btc.TxModel)
+			err = db.Get(spending, pgStr, spendingTx.TxHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(spending.Weight.Int64).To(Equal(int64(800)))
+			Expect(spending.VSize.Int64).To(Equal(int64(200)))
+			Expect(spending.Fee.Valid).To(BeTrue())
+			Expect(spending.Fee.Int64).To(Equal(int64(1000)))
+			Expect(spending.FeeRate.Float64).To(Equal(float64(5)))
+
+			// coinbase transactions pay no fee, and transactions spending unresolved outputs have no fee yet
+			coinbase := new(btc.TxModel)
+			err = db.Get(coinbase, pgStr, mocks.MockTxsMetaDataPostPublish[0].TxHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(coinbase.Fee.Valid).To(BeTrue())
+			Expect(coinbase.Fee.Int64).To(Equal(int64(0)))
+			unresolved := new(btc.TxModel)
+			err = db.Get(unresolved, pgStr, mocks.MockTxsMetaDataPostPublish[2].TxHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unresolved.Fee.Valid).To(BeFalse())
+		})
+
+		It("Indexes the payloads of OP_RETURN outputs by their prefix", func() {
+			pkScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).
+				AddData([]byte("omni")).AddData([]byte{0x00, 0x01}).Script()
+			Expect(err).ToNot(HaveOccurred())
+			payload := mocks.MockCIDPayload
+			payload.TransactionCIDs = make([]btc.TxModelWithInsAndOuts, len(mocks.MockCIDPayload.TransactionCIDs))
+			copy(payload.TransactionCIDs, mocks.MockCIDPayload.TransactionCIDs)
+			nullDataTx := payload.TransactionCIDs[2]
+			nullDataTx.TxOutputs = append(append([]btc.TxOutput{}, nullDataTx.TxOutputs...), btc.TxOutput{
+				Index:       int64(len(nullDataTx.TxOutputs)),
+				PkScript:    pkScript,
+				ScriptClass: uint8(txscript.NullDataTy),
+			})
+			payload.TransactionCIDs[2] = nullDataTx
+			err = repo.Index(&payload)
+			Expect(err).ToNot(HaveOccurred())
+
+			nullData := make([]btc.NullDataModel, 0)
+			pgStr := `SELECT nulldata_outputs.* FROM btc.nulldata_outputs
+				INNER JOIN btc.transaction_cids ON (nulldata_outputs.tx_id = transaction_cids.id)
+				WHERE transaction_cids.tx_hash = $1`
+			err = db.Select(&nullData, pgStr, nullDataTx.TxHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(nullData)).To(Equal(1))
+			Expect(nullData[0].Payload).To(Equal([]byte{'o', 'm', 'n', 'i', 0x00, 0x01}))
+			Expect(nullData[0].Prefix).To(Equal([]byte("omni")))
+
+			var count int
+			err = db.Get(&count, `SELECT COUNT(*) FROM btc.nulldata_outputs`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(1))
+		})
+
+		It("Indexes the basic filters of blocks and links their filter headers", func() {
+			// the headers are fabricated, so they are not validated
+			repo = btc.NewCIDIndexer(db, nil, nil)
+			filterOf := func(blockHash string) (btc.BlockFilterModel, error) {
+				var filter btc.BlockFilterModel
+				err := db.Get(&filter, `SELECT block_filters.* FROM btc.block_filters
+					INNER JOIN btc.header_cids ON (block_filters.header_id = header_cids.id)
+					WHERE header_cids.block_hash = $1`, blockHash)
+				return filter, err
+			}
+			coinbaseTx := mocks.MockTxsMetaDataPostPublish[0]
+			genesisHeader := mocks.MockHeaderMetaData
+			genesisHeader.BlockNumber = "0"
+			childHeader := mocks.MockHeaderMetaData
+			childHeader.BlockNumber = "1"
+			childHeader.BlockHash = "0000000000000000000000000000000000000000000000000000000000000002"
+			childHeader.ParentHash = genesisHeader.BlockHash
+			grandchildHeader := childHeader
+			grandchildHeader.BlockNumber = "2"
+			grandchildHeader.BlockHash = "0000000000000000000000000000000000000000000000000000000000000003"
+			grandchildHeader.ParentHash = childHeader.BlockHash
+			output := mocks.MockTxsMetaDataPostPublish[1].TxOutputs[0]
+			output.Index = 0
+			spendingTx := btc.TxModelWithInsAndOuts{
+				TxHash: "0000000000000000000000000000000000000000000000000000000000000004",
+				CID:    mocks.MockTrxCID2.String(),
+				MhKey:  mocks.MockTrxMhKey2,
+				TxInputs: []btc.TxInput{{
+					Index:                 0,
+					SignatureScript:       []byte{0x51},
+					PreviousOutPointHash:  coinbaseTx.TxHash,
+					PreviousOutPointIndex: 0,
+				}},
+				TxOutputs: []btc.TxOutput{output},
+			}
+			grandchild := btc.CIDPayload{
+				HeaderCID:       grandchildHeader,
+				TransactionCIDs: []btc.TxModelWithInsAndOuts{spendingTx},
+			}
+
+			// the child's filter header is unknown until its parent's filter is indexed
+			err = repo.Index(&btc.CIDPayload{HeaderCID: childHeader})
+			Expect(err).ToNot(HaveOccurred())
+			childFilter, err := filterOf(childHeader.BlockHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(childFilter.Filter).To(Equal([]byte{0x00}))
+			Expect(childFilter.FilterHeader.Valid).To(BeFalse())
+			// the grandchild spends an output which is not indexed yet, it has no filter
+			err = repo.Index(&grandchild)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = filterOf(grandchildHeader.BlockHash)
+			Expect(err).To(Equal(sql.ErrNoRows))
+
+			err = repo.Index(&btc.CIDPayload{
+				HeaderCID:       genesisHeader,
+				TransactionCIDs: []btc.TxModelWithInsAndOuts{coinbaseTx},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			genesisHash, err := chainhash.NewHashFromStr(genesisHeader.BlockHash)
+			Expect(err).ToNot(HaveOccurred())
+			coinbaseScripts := make([][]byte, len(coinbaseTx.TxOutputs))
+			for i, coinbaseOutput := range coinbaseTx.TxOutputs {
+				coinbaseScripts[i] = coinbaseOutput.PkScript
+			}
+			genesisFilter, err := filterOf(genesisHeader.BlockHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(genesisFilter.Filter).To(Equal(btc.BuildBasicFilter(*genesisHash, coinbaseScripts)))
+			Expect(genesisFilter.FilterHash).To(Equal(chainhash.DoubleHashH(genesisFilter.Filter).String()))
+			genesisFilterHeader := btc.FilterHeader(genesisFilter.Filter, chainhash.Hash{})
+			Expect(genesisFilter.FilterHeader.String).To(Equal(genesisFilterHeader.String()))
+			childFilter, err = filterOf(childHeader.BlockHash)
+			Expect(err).ToNot(HaveOccurred())
+			childFilterHeader := btc.FilterHeader(childFilter.Filter, genesisFilterHeader)
+			Expect(childFilter.FilterHeader.String).To(Equal(childFilterHeader.String()))
+
+			// once reindexed the grandchild's filter matches the script of the output it spends
+			err = repo.Index(&grandchild)
+			Expect(err).ToNot(HaveOccurred())
+			grandchildFilter, err := filterOf(grandchildHeader.BlockHash)
+			Expect(err).ToNot(HaveOccurred())
+			grandchildHash, err := chainhash.NewHashFromStr(grandchildHeader.BlockHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(grandchildFilter.Filter).To(Equal(btc.BuildBasicFilter(*grandchildHash, [][]byte{output.PkScript, coinbaseScripts[0]})))
+			match, err := btc.MatchBasicFilter(grandchildFilter.Filter, *grandchildHash, [][]byte{coinbaseScripts[0]})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(match).To(BeTrue())
+			Expect(grandchildFilter.FilterHeader.String).To(Equal(btc.FilterHeader(grandchildFilter.Filter, childFilterHeader).String()))
+		})
+	})
+
+	Describe("Disconnect", func() {
+		It("Marks the disconnected header non-canonical", func() {
+			err = repo.Index(&mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			err = repo.Disconnect(btc.BlockDisconnect{
+				BlockHeight: mocks.MockBlockHeight,
+				Header:      &mocks.MockBlock.Header,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			var canonical bool
+			err = db.Get(&canonical, `SELECT canonical FROM btc.header_cids WHERE block_hash = $1`, mocks.MockHeaderMetaData.BlockHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonical).To(BeFalse())
+
+			// the header replacing it is canonical
+			forkHeader := mocks.MockHeaderMetaData
+			forkHeader.BlockHash = "0000000000000000000000000000000000000000000000000000000000000001"
+			err = repo.Index(&btc.CIDPayload{HeaderCID: forkHeader})
+			Expect(err).ToNot(HaveOccurred())
+			err = db.Get(&canonical, `SELECT canonical FROM btc.header_cids WHERE block_hash = $1`, forkHeader.BlockHash)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonical).To(BeTrue())
+		})
 	})
 })
@@ -0,0 +1,219 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/btcsuite/btcutil"
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+const (
+	// UnconfirmedTxIndex is the Index of the converted transactions which have not been included in a block
+	UnconfirmedTxIndex = -1
+	// DefaultMempoolPollInterval is how often the MempoolWatcher polls the mempool of the node by default
+	DefaultMempoolPollInterval = 2 * time.Second
+)
+
+// mempoolClient is the subset of the bitcoind rpc client used to poll its mempool
+type mempoolClient interface {
+	RawRequest(method string, params []json.RawMessage) (json.RawMessage, error)
+	GetRawTransaction(txHash *chainhash.Hash) (*btcutil.Tx, error)
+}
+
+// mempoolEntryResult is an entry of the verbose getrawmempool result
+// Newer versions of bitcoind only report the fee of the transaction as fees.base
+type mempoolEntryResult struct {
+	Fee  *float64 `json:"fee"`
+	Fees *struct {
+		Base float64 `json:"base"`
+	} `json:"fees"`
+	Time int64 `json:"time"`
+}
+
+// MempoolWatcher satisfies the shared.MempoolWatcher interface for bitcoin
+// It polls the mempool of the node, streaming the transactions which have entered it since the last poll, converted
+// like those of the blocks, along with the hashes of those which have left it
+type MempoolWatcher struct {
+	Config       *rpcclient.ConnConfig
+	PollInterval time.Duration
+	converter    *PayloadConverter
+	// indexer keeps the btc.mempool table in sync with the mempool, it can be nil
+	indexer *MempoolIndexer
+	// known are the hashes of the transactions in the mempool as of the last poll
+	known map[string]bool
+}
+
+// NewMempoolWatcher returns a new MempoolWatcher, which indexes the mempool using the provided MempoolIndexer if it is not nil
+func NewMempoolWatcher(clientConfig *rpcclient.ConnConfig, params *chaincfg.Params, indexer *MempoolIndexer) *MempoolWatcher {
+	return &MempoolWatcher{
+		Config:       clientConfig,
+		PollInterval: DefaultMempoolPollInterval,
+		converter:    NewPayloadConverter(params),
+		indexer:      indexer,
+	}
+}
+
+// Watch is the main loop for polling the mempool of the node
+// Satisfies the shared.MempoolWatcher interface
+func (mw *MempoolWatcher) Watch(payloadChan chan shared.UnconfirmedData) (shared.ClientSubscription, error) {
+	logrus.Debug("watching the btc mempool")
+	mw.known = make(map[string]bool)
+	if mw.indexer != nil {
+		// Pick up from the indexed mempool, so that the transactions which left it while we were down are evicted
+		txHashes, err := mw.indexer.TxHashes()
+		if err != nil {
+			return nil, err
+		}
+		for _, txHash := range txHashes {
+			mw.known[txHash] = true
+		}
+	}
+	client, err := rpcclient.New(mw.Config, nil)
+	if err != nil {
+		return nil, err
+	}
+	sub := &HTTPClientSubscription{
+		client:  client,
+		errChan: make(chan error),
+		quit:    make(chan bool),
+	}
+	go func() {
+		ticker := time.NewTicker(mw.PollInterval)
+		defer ticker.Stop()
+		for {
+			payload, err := mw.poll(client)
+			if err != nil {
+				select {
+				case sub.errChan <- err:
+				case <-sub.quit:
+					return
+				}
+			} else if len(payload.Txs) > 0 || len(payload.Evicted) > 0 {
+				select {
+				case payloadChan <- payload:
+				case <-sub.quit:
+					return
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-sub.quit:
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// poll diffs the mempool of the node against the mempool as of the last poll, and indexes the difference
+func (mw *MempoolWatcher) poll(client mempoolClient) (MempoolPayload, error) {
+	res, err := client.RawRequest("getrawmempool", []json.RawMessage{json.RawMessage("true")})
+	if err != nil {
+		return MempoolPayload{}, err
+	}
+	entries := make(map[string]mempoolEntryResult)
+	if err := json.Unmarshal(res, &entries); err != nil {
+		return MempoolPayload{}, err
+	}
+	payload := MempoolPayload{
+		Txs:     make([]*btcutil.Tx, 0),
+		Entries: make([]MempoolEntry, 0),
+		Evicted: make([]string, 0),
+	}
+	for txHash := range mw.known {
+		if _, ok := entries[txHash]; !ok {
+			payload.Evicted = append(payload.Evicted, txHash)
+		}
+	}
+	sort.Strings(payload.Evicted)
+	entered := make([]string, 0)
+	for txHash := range entries {
+		if !mw.known[txHash] {
+			entered = append(entered, txHash)
+		}
+	}
+	// Stream the transactions in the order they entered the mempool, which puts parents ahead of their children
+	sort.Slice(entered, func(i, j int) bool {
+		if entries[entered[i]].Time != entries[entered[j]].Time {
+			return entries[entered[i]].Time < entries[entered[j]].Time
+		}
+		return entered[i] < entered[j]
+	})
+	for _, txHash := range entered {
+		hash, err := chainhash.NewHashFromStr(txHash)
+		if err != nil {
+			return MempoolPayload{}, err
+		}
+		tx, err := client.GetRawTransaction(hash)
+		if err != nil {
+			if rpcErr, ok := err.(*btcjson.RPCError); ok && rpcErr.Code == btcjson.ErrRPCNoTxInfo {
+				// The transaction left the mempool since we listed it
+				continue
+			}
+			return MempoolPayload{}, err
+		}
+		payload.Txs = append(payload.Txs, tx)
+		payload.Entries = append(payload.Entries, entries[txHash].entry())
+	}
+	payload.TxMetaData, err = mw.converter.ConvertUnconfirmed(payload.Txs)
+	if err != nil {
+		return MempoolPayload{}, err
+	}
+	if mw.indexer != nil && (len(payload.Txs) > 0 || len(payload.Evicted) > 0) {
+		if err := mw.indexer.Index(payload); err != nil {
+			return MempoolPayload{}, err
+		}
+	}
+	for _, txHash := range payload.Evicted {
+		delete(mw.known, txHash)
+	}
+	for _, tx := range payload.Txs {
+		mw.known[tx.Hash().String()] = true
+	}
+	return payload, nil
+}
+
+// entry converts the result to a MempoolEntry
+func (res mempoolEntryResult) entry() MempoolEntry {
+	entry := MempoolEntry{
+		Fee:  -1,
+		Time: res.Time,
+	}
+	var fee *float64
+	switch {
+	case res.Fees != nil:
+		fee = &res.Fees.Base
+	case res.Fee != nil:
+		fee = res.Fee
+	}
+	if fee != nil {
+		if amount, err := btcutil.NewAmount(*fee); err == nil {
+			entry.Fee = int64(amount)
+		}
+	}
+	return entry
+}
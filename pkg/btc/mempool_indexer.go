@@ -0,0 +1,90 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"bytes"
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// MempoolIndexer keeps the btc.mempool table in sync with the mempool of the node
+type MempoolIndexer struct {
+	db *postgres.DB
+}
+
+// NewMempoolIndexer returns a new MempoolIndexer
+func NewMempoolIndexer(db *postgres.DB) *MempoolIndexer {
+	return &MempoolIndexer{
+		db: db,
+	}
+}
+
+// Index removes the transactions which have left the mempool from the btc.mempool table, and adds those which have entered it
+func (mi *MempoolIndexer) Index(payload MempoolPayload) error {
+	tx, err := mi.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	if len(payload.Evicted) > 0 {
+		_, err = tx.Exec(`DELETE FROM btc.mempool WHERE tx_hash = ANY($1)`, pq.Array(payload.Evicted))
+		if err != nil {
+			return err
+		}
+	}
+	for i, trx := range payload.Txs {
+		txBuffer := new(bytes.Buffer)
+		if err = trx.MsgTx().Serialize(txBuffer); err != nil {
+			return err
+		}
+		txMeta := payload.TxMetaData[i]
+		addresses := make([]string, 0)
+		for _, out := range txMeta.TxOutputs {
+			addresses = append(addresses, out.Addresses...)
+		}
+		fee := sql.NullInt64{Int64: payload.Entries[i].Fee, Valid: payload.Entries[i].Fee >= 0}
+		_, err = tx.Exec(`INSERT INTO btc.mempool (tx_hash, witness_hash, segwit, weight, vsize, fee, time, addresses, raw)
+							VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+							ON CONFLICT (tx_hash) DO NOTHING`,
+			txMeta.TxHash, txMeta.WitnessHash, txMeta.SegWit, txMeta.Weight, txMeta.VSize, fee, payload.Entries[i].Time,
+			pq.Array(addresses), txBuffer.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// TxHashes returns the hashes of the transactions in the btc.mempool table
+func (mi *MempoolIndexer) TxHashes() ([]string, error) {
+	txHashes := make([]string, 0)
+	return txHashes, mi.db.Select(&txHashes, `SELECT tx_hash FROM btc.mempool`)
+}
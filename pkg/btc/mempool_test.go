@@ -0,0 +1,145 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc_test
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc/mocks"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+var _ = Describe("MempoolWatcher", func() {
+	var (
+		node        *mocks.Node
+		payloadChan chan shared.UnconfirmedData
+		sub         shared.ClientSubscription
+	)
+
+	BeforeEach(func() {
+		node = mocks.NewNode()
+		node.SetMempool(mocks.MockBlock.Transactions[1])
+		watcher := btc.NewMempoolWatcher(node.ConnConfig(), &chaincfg.MainNetParams, nil)
+		watcher.PollInterval = 10 * time.Millisecond
+		payloadChan = make(chan shared.UnconfirmedData, 10)
+		var err error
+		sub, err = watcher.Watch(payloadChan)
+		Expect(err).ToNot(HaveOccurred())
+		go func(errs <-chan error) {
+			for range errs {
+			}
+		}(sub.Err())
+	})
+
+	AfterEach(func() {
+		sub.Unsubscribe()
+		node.Close()
+	})
+
+	expectPayload := func() btc.MempoolPayload {
+		var payload shared.UnconfirmedData
+		Eventually(payloadChan).Should(Receive(&payload))
+		mempoolPayload, ok := payload.(btc.MempoolPayload)
+		Expect(ok).To(BeTrue())
+		return mempoolPayload
+	}
+
+	Describe("Watch", func() {
+		It("Streams the node's mempool on start, converted as unconfirmed txs", func() {
+			payload := expectPayload()
+			Expect(len(payload.Txs)).To(Equal(1))
+			Expect(payload.Txs[0].Hash().String()).To(Equal(mocks.MockTransactions[1].Hash().String()))
+			Expect(payload.TxMetaData).To(Equal([]btc.TxModelWithInsAndOuts{mocks.MockMempoolPayload.TxMetaData[0]}))
+			Expect(payload.Entries).To(Equal([]btc.MempoolEntry{{Fee: 1000, Time: 0}}))
+			Expect(payload.Evicted).To(BeEmpty())
+		})
+
+		It("Streams the txs which have entered and left the mempool since the last poll", func() {
+			expectPayload()
+			node.SetMempool(mocks.MockBlock.Transactions[2])
+			payload := expectPayload()
+			Expect(len(payload.Txs)).To(Equal(1))
+			Expect(payload.Txs[0].Hash().String()).To(Equal(mocks.MockTransactions[2].Hash().String()))
+			Expect(payload.Evicted).To(Equal([]string{mocks.MockTransactions[1].Hash().String()}))
+
+			node.SetMempool()
+			payload = expectPayload()
+			Expect(payload.Txs).To(BeEmpty())
+			Expect(payload.Evicted).To(Equal([]string{mocks.MockTransactions[2].Hash().String()}))
+			Consistently(payloadChan, 50*time.Millisecond).ShouldNot(Receive())
+		})
+	})
+})
+
+var _ = Describe("MempoolIndexer", func() {
+	var (
+		db      *postgres.DB
+		err     error
+		indexer *btc.MempoolIndexer
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		indexer = btc.NewMempoolIndexer(db)
+	})
+	AfterEach(func() {
+		btc.TearDownDB(db)
+	})
+
+	Describe("Index", func() {
+		It("Indexes the txs which have entered the mempool and removes those which have left it", func() {
+			err = indexer.Index(mocks.MockMempoolPayload)
+			Expect(err).ToNot(HaveOccurred())
+			txs := make([]btc.MempoolModel, 0)
+			err = db.Select(&txs, `SELECT * FROM btc.mempool ORDER BY time`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(txs)).To(Equal(2))
+			for i, tx := range txs {
+				txMeta := mocks.MockMempoolPayload.TxMetaData[i]
+				Expect(tx.TxHash).To(Equal(txMeta.TxHash))
+				Expect(tx.SegWit).To(Equal(txMeta.SegWit))
+				Expect(tx.Weight).To(Equal(txMeta.Weight))
+				Expect(tx.VSize).To(Equal(txMeta.VSize))
+				Expect(tx.Time).To(Equal(mocks.MockMempoolPayload.Entries[i].Time))
+				var raw bytes.Buffer
+				Expect(mocks.MockMempoolPayload.Txs[i].MsgTx().Serialize(&raw)).To(Succeed())
+				Expect(tx.Raw).To(Equal(raw.Bytes()))
+				addresses := make([]string, 0)
+				for _, out := range txMeta.TxOutputs {
+					addresses = append(addresses, out.Addresses...)
+				}
+				Expect([]string(tx.Addresses)).To(Equal(addresses))
+			}
+			Expect(txs[0].Fee.Valid).To(BeTrue())
+			Expect(txs[0].Fee.Int64).To(Equal(int64(1000)))
+			Expect(txs[1].Fee.Valid).To(BeFalse())
+
+			err = indexer.Index(btc.MempoolPayload{Evicted: []string{txs[0].TxHash}})
+			Expect(err).ToNot(HaveOccurred())
+			txHashes, err := indexer.TxHashes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(txHashes).To(Equal([]string{txs[1].TxHash}))
+		})
+	})
+})
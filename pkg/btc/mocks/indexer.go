@@ -25,8 +25,9 @@ import (
 
 // CIDIndexer is the underlying struct for the Indexer interface
 type CIDIndexer struct {
-	PassedCIDPayload []*btc.CIDPayload
-	ReturnErr        error
+	PassedCIDPayload  []*btc.CIDPayload
+	PassedDisconnects []shared.DisconnectedBlock
+	ReturnErr         error
 }
 
 // Index indexes a cidPayload in Postgres
@@ -38,3 +39,9 @@ func (repo *CIDIndexer) Index(cids shared.CIDsForIndexing) error {
 	repo.PassedCIDPayload = append(repo.PassedCIDPayload, cidPayload)
 	return repo.ReturnErr
 }
+
+// Disconnect records the disconnected block
+func (repo *CIDIndexer) Disconnect(block shared.DisconnectedBlock) error {
+	repo.PassedDisconnects = append(repo.PassedDisconnects, block)
+	return repo.ReturnErr
+}
@@ -0,0 +1,201 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mocks
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// Node is a bitcoind rpc stand-in, it answers getblockcount, getblockhash and getblock from the chain it has been loaded with
+// and getrawmempool and getrawtransaction from the mempool it has been loaded with
+type Node struct {
+	sync.Mutex
+	server  *httptest.Server
+	chain   []*wire.MsgBlock
+	mempool []*wire.MsgTx
+}
+
+// mempoolEntry is an entry of the verbose getrawmempool result
+type mempoolEntry struct {
+	Fees struct {
+		Base float64 `json:"base"`
+	} `json:"fees"`
+	Time int64 `json:"time"`
+}
+
+// nodeRequest is a json-rpc request sent by a rpcclient.Client
+type nodeRequest struct {
+	ID     interface{}       `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// nodeError is a json-rpc error returned to a rpcclient.Client
+type nodeError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewNode starts a new Node serving the chain, whose first block is at height 0
+func NewNode(chain ...*wire.MsgBlock) *Node {
+	n := &Node{chain: chain}
+	n.server = httptest.NewServer(http.HandlerFunc(n.serveHTTP))
+	return n
+}
+
+// ConnConfig returns the config for a rpcclient.Client connecting to the node
+func (n *Node) ConnConfig() *rpcclient.ConnConfig {
+	return &rpcclient.ConnConfig{
+		Host:         strings.TrimPrefix(n.server.URL, "http://"),
+		HTTPPostMode: true,
+		DisableTLS:   true,
+		User:         "user",
+		Pass:         "pass",
+	}
+}
+
+// Connect appends the blocks to the node's chain
+func (n *Node) Connect(blocks ...*wire.MsgBlock) {
+	n.Lock()
+	defer n.Unlock()
+	n.chain = append(n.chain, blocks...)
+}
+
+// SetChain replaces the node's chain, as a reorg would
+func (n *Node) SetChain(chain ...*wire.MsgBlock) {
+	n.Lock()
+	defer n.Unlock()
+	n.chain = chain
+}
+
+// SetMempool replaces the node's mempool, the txs entered it in the order they are provided
+func (n *Node) SetMempool(txs ...*wire.MsgTx) {
+	n.Lock()
+	defer n.Unlock()
+	n.mempool = txs
+}
+
+// Close shuts the node down
+func (n *Node) Close() {
+	n.server.Close()
+}
+
+func (n *Node) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var req nodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := n.handle(req)
+	res := map[string]interface{}{"id": req.ID, "result": result, "error": nil}
+	if err != nil {
+		res["result"] = nil
+		res["error"] = nodeError{Code: -5, Message: err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func (n *Node) handle(req nodeRequest) (interface{}, error) {
+	n.Lock()
+	defer n.Unlock()
+	switch req.Method {
+	case "getblockcount":
+		return len(n.chain) - 1, nil
+	case "getblockhash":
+		var height int
+		if len(req.Params) < 1 || json.Unmarshal(req.Params[0], &height) != nil {
+			return nil, fmt.Errorf("invalid params")
+		}
+		if height < 0 || height >= len(n.chain) {
+			return nil, fmt.Errorf("block height out of range")
+		}
+		return n.chain[height].BlockHash().String(), nil
+	case "getblock":
+		var hash string
+		if len(req.Params) < 1 || json.Unmarshal(req.Params[0], &hash) != nil {
+			return nil, fmt.Errorf("invalid params")
+		}
+		for _, block := range n.chain {
+			if block.BlockHash().String() == hash {
+				var buf bytes.Buffer
+				if err := block.Serialize(&buf); err != nil {
+					return nil, err
+				}
+				return hex.EncodeToString(buf.Bytes()), nil
+			}
+		}
+		return nil, fmt.Errorf("block not found")
+	case "getrawmempool":
+		entries := make(map[string]mempoolEntry, len(n.mempool))
+		for i, tx := range n.mempool {
+			entry := mempoolEntry{Time: int64(i)}
+			entry.Fees.Base = 0.00001
+			entries[tx.TxHash().String()] = entry
+		}
+		return entries, nil
+	case "getrawtransaction":
+		var hash string
+		if len(req.Params) < 1 || json.Unmarshal(req.Params[0], &hash) != nil {
+			return nil, fmt.Errorf("invalid params")
+		}
+		for _, tx := range n.mempool {
+			if tx.TxHash().String() == hash {
+				var buf bytes.Buffer
+				if err := tx.Serialize(&buf); err != nil {
+					return nil, err
+				}
+				return hex.EncodeToString(buf.Bytes()), nil
+			}
+		}
+		return nil, fmt.Errorf("no such mempool transaction")
+	default:
+		return nil, fmt.Errorf("method %s not found", req.Method)
+	}
+}
+
+// NewMockChain returns a chain of the provided length built on top of the MockBlock
+func NewMockChain(length int) []*wire.MsgBlock {
+	chain := make([]*wire.MsgBlock, length)
+	parent := &MockBlock
+	for i := range chain {
+		chain[i] = NewMockChildBlock(parent, uint32(i))
+		parent = chain[i]
+	}
+	return chain
+}
+
+// NewMockChildBlock returns a block building on the parent, blocks with the same parent and nonce are identical
+func NewMockChildBlock(parent *wire.MsgBlock, nonce uint32) *wire.MsgBlock {
+	header := parent.Header
+	header.PrevBlock = parent.BlockHash()
+	header.Nonce = nonce
+	return &wire.MsgBlock{
+		Header:       header,
+		Transactions: []*wire.MsgTx{MockBlock.Transactions[0]},
+	}
+}
@@ -0,0 +1,41 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mocks
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+)
+
+// PrevoutFetcher is a mock btc.PrevoutFetcher, it returns the outputs it has been loaded with
+type PrevoutFetcher struct {
+	Prevouts        map[wire.OutPoint]btc.TxOutput
+	PassedOutpoints []wire.OutPoint
+}
+
+// FetchPrevout returns the loaded output at the outpoint, or an error if there is none
+func (f *PrevoutFetcher) FetchPrevout(outpoint wire.OutPoint) (btc.TxOutput, error) {
+	f.PassedOutpoints = append(f.PassedOutpoints, outpoint)
+	prevout, ok := f.Prevouts[outpoint]
+	if !ok {
+		return btc.TxOutput{}, fmt.Errorf("no output at outpoint %s", outpoint.String())
+	}
+	return prevout, nil
+}
@@ -300,6 +300,8 @@ var (
 			TxHash: MockBlock.Transactions[0].TxHash().String(),
 			Index:  0,
 			SegWit: MockBlock.Transactions[0].HasWitness(),
+			Weight: int64(4 * MockBlock.Transactions[0].SerializeSize()),
+			VSize:  int64(MockBlock.Transactions[0].SerializeSize()),
 			TxInputs: []btc.TxInput{
 				{
 					Index: 0,
@@ -337,6 +339,8 @@ var (
 			TxHash: MockBlock.Transactions[1].TxHash().String(),
 			Index:  1,
 			SegWit: MockBlock.Transactions[1].HasWitness(),
+			Weight: int64(4 * MockBlock.Transactions[1].SerializeSize()),
+			VSize:  int64(MockBlock.Transactions[1].SerializeSize()),
 			TxInputs: []btc.TxInput{
 				{
 					Index: 0,
@@ -413,6 +417,8 @@ var (
 			TxHash: MockBlock.Transactions[2].TxHash().String(),
 			Index:  2,
 			SegWit: MockBlock.Transactions[2].HasWitness(),
+			Weight: int64(4 * MockBlock.Transactions[2].SerializeSize()),
+			VSize:  int64(MockBlock.Transactions[2].SerializeSize()),
 			TxInputs: []btc.TxInput{
 				{
 					Index: 0,
@@ -492,6 +498,8 @@ var (
 			TxHash: MockBlock.Transactions[0].TxHash().String(),
 			Index:  0,
 			SegWit: MockBlock.Transactions[0].HasWitness(),
+			Weight: int64(4 * MockBlock.Transactions[0].SerializeSize()),
+			VSize:  int64(MockBlock.Transactions[0].SerializeSize()),
 			TxInputs: []btc.TxInput{
 				{
 					Index: 0,
@@ -531,6 +539,8 @@ var (
 			TxHash: MockBlock.Transactions[1].TxHash().String(),
 			Index:  1,
 			SegWit: MockBlock.Transactions[1].HasWitness(),
+			Weight: int64(4 * MockBlock.Transactions[1].SerializeSize()),
+			VSize:  int64(MockBlock.Transactions[1].SerializeSize()),
 			TxInputs: []btc.TxInput{
 				{
 					Index: 0,
@@ -609,6 +619,8 @@ var (
 			TxHash: MockBlock.Transactions[2].TxHash().String(),
 			Index:  2,
 			SegWit: MockBlock.Transactions[2].HasWitness(),
+			Weight: int64(4 * MockBlock.Transactions[2].SerializeSize()),
+			VSize:  int64(MockBlock.Transactions[2].SerializeSize()),
 			TxInputs: []btc.TxInput{
 				{
 					Index: 0,
@@ -698,8 +710,28 @@ var (
 		HeaderCID:       MockHeaderMetaData,
 		TransactionCIDs: MockTxsMetaDataPostPublish,
 	}
+	// MockMempoolPayload has the non-coinbase MockTransactions entering the mempool
+	MockMempoolPayload = btc.MempoolPayload{
+		Txs:        MockTransactions[1:],
+		TxMetaData: unconfirmedTxsMetaData(MockTxsMetaData[1:]),
+		Entries: []btc.MempoolEntry{
+			{Fee: 1000, Time: 1},
+			{Fee: -1, Time: 2},
+		},
+		Evicted: []string{},
+	}
 )
 
+// unconfirmedTxsMetaData returns a copy of the tx metadata as converted when the txs are in the mempool
+func unconfirmedTxsMetaData(txsMetaData []btc.TxModelWithInsAndOuts) []btc.TxModelWithInsAndOuts {
+	unconfirmed := make([]btc.TxModelWithInsAndOuts, len(txsMetaData))
+	for i, txMeta := range txsMetaData {
+		txMeta.Index = btc.UnconfirmedTxIndex
+		unconfirmed[i] = txMeta
+	}
+	return unconfirmed
+}
+
 func stringSliceFromAddresses(addrs []btcutil.Address) []string {
 	strs := make([]string, len(addrs))
 	for i, addr := range addrs {
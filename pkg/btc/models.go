@@ -16,7 +16,11 @@
 
 package btc
 
-import "github.com/lib/pq"
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
 
 // HeaderModel is the db model for btc.header_cids table
 type HeaderModel struct {
@@ -30,18 +34,25 @@ type HeaderModel struct {
 	Bits           uint32 `db:"bits"`
 	NodeID         int64  `db:"node_id"`
 	TimesValidated int64  `db:"times_validated"`
+	Canonical      bool   `db:"canonical"`
+	ChainWork      string `db:"chainwork"`
 }
 
 // TxModel is the db model for btc.transaction_cids table
 type TxModel struct {
-	ID          int64  `db:"id"`
-	HeaderID    int64  `db:"header_id"`
-	Index       int64  `db:"index"`
-	TxHash      string `db:"tx_hash"`
-	CID         string `db:"cid"`
-	MhKey       string `db:"mh_key"`
-	SegWit      bool   `db:"segwit"`
-	WitnessHash string `db:"witness_hash"`
+	ID          int64         `db:"id"`
+	HeaderID    int64         `db:"header_id"`
+	Index       int64         `db:"index"`
+	TxHash      string        `db:"tx_hash"`
+	CID         string        `db:"cid"`
+	MhKey       string        `db:"mh_key"`
+	SegWit      bool          `db:"segwit"`
+	WitnessHash string        `db:"witness_hash"`
+	Weight      sql.NullInt64 `db:"weight"`
+	VSize       sql.NullInt64 `db:"vsize"`
+	// Fee is null until the outputs spent by every input of the transaction have been resolved
+	Fee     sql.NullInt64   `db:"fee"`
+	FeeRate sql.NullFloat64 `db:"fee_rate"`
 }
 
 // TxModelWithInsAndOuts is the db model for btc.transaction_cids table that includes the children tx_input and tx_output tables
@@ -54,19 +65,28 @@ type TxModelWithInsAndOuts struct {
 	MhKey       string `db:"mh_key"`
 	SegWit      bool   `db:"segwit"`
 	WitnessHash string `db:"witness_hash"`
+	Weight      int64  `db:"weight"`
+	VSize       int64  `db:"vsize"`
 	TxInputs    []TxInput
 	TxOutputs   []TxOutput
 }
 
 // TxInput is the db model for btc.tx_inputs table
 type TxInput struct {
-	ID                    int64    `db:"id"`
-	TxID                  int64    `db:"tx_id"`
-	Index                 int64    `db:"index"`
-	TxWitness             []string `db:"witness"`
-	SignatureScript       []byte   `db:"sig_script"`
-	PreviousOutPointIndex uint32   `db:"outpoint_tx_hash"`
-	PreviousOutPointHash  string   `db:"outpoint_index"`
+	ID                    int64         `db:"id"`
+	TxID                  int64         `db:"tx_id"`
+	Index                 int64         `db:"index"`
+	TxWitness             []string      `db:"witness"`
+	SignatureScript       []byte        `db:"sig_script"`
+	PreviousOutPointIndex uint32        `db:"outpoint_index"`
+	PreviousOutPointHash  string        `db:"outpoint_tx_hash"`
+	SpentOutputID         sql.NullInt64 `db:"spent_output_id"`
+	// The value, script class and addresses of the spent output, null until it has been resolved
+	Value       sql.NullInt64  `db:"value"`
+	ScriptClass sql.NullInt64  `db:"script_class"`
+	Addresses   pq.StringArray `db:"addresses"`
+	// TaprootSpend is how the input spends a taproot output, NotTaprootSpend if its output is not a taproot output
+	TaprootSpend uint8 `db:"taproot_spend"`
 }
 
 // TxOutput is the db model for btc.tx_outputs table
@@ -80,3 +100,92 @@ type TxOutput struct {
 	RequiredSigs int64          `db:"required_sigs"`
 	Addresses    pq.StringArray `db:"addresses"`
 }
+
+// NullDataModel is the db model for btc.nulldata_outputs table
+type NullDataModel struct {
+	ID       int64  `db:"id"`
+	TxID     int64  `db:"tx_id"`
+	OutputID int64  `db:"output_id"`
+	Payload  []byte `db:"payload"`
+	Prefix   []byte `db:"prefix"`
+}
+
+// BlockFilterModel is the db model for btc.block_filters table
+type BlockFilterModel struct {
+	ID           int64          `db:"id"`
+	HeaderID     int64          `db:"header_id"`
+	Filter       []byte         `db:"filter"`
+	FilterHash   string         `db:"filter_hash"`
+	FilterHeader sql.NullString `db:"filter_header"`
+}
+
+// MempoolModel is the db model for btc.mempool table
+type MempoolModel struct {
+	ID          int64          `db:"id"`
+	TxHash      string         `db:"tx_hash"`
+	WitnessHash string         `db:"witness_hash"`
+	SegWit      bool           `db:"segwit"`
+	Weight      int64          `db:"weight"`
+	VSize       int64          `db:"vsize"`
+	Fee         sql.NullInt64  `db:"fee"`
+	Time        int64          `db:"time"`
+	Addresses   pq.StringArray `db:"addresses"`
+	Raw         []byte         `db:"raw"`
+}
+
+// UTXOModel is an unspent btc.tx_outputs entry along with the transaction and canonical header which include it
+type UTXOModel struct {
+	ID           int64          `db:"id"`
+	TxHash       string         `db:"tx_hash"`
+	Index        int64          `db:"index"`
+	Value        int64          `db:"value"`
+	PkScript     []byte         `db:"pk_script"`
+	ScriptClass  uint8          `db:"script_class"`
+	RequiredSigs int64          `db:"required_sigs"`
+	Addresses    pq.StringArray `db:"addresses"`
+	BlockNumber  int64          `db:"block_number"`
+	BlockHash    string         `db:"block_hash"`
+	Timestamp    int64          `db:"timestamp"`
+	Coinbase     bool           `db:"coinbase"`
+}
+
+// HistoryModel is a transaction in the history of an output script, along with the canonical header which includes it
+type HistoryModel struct {
+	TxHash      string `db:"tx_hash"`
+	Index       int64  `db:"index"`
+	BlockNumber int64  `db:"block_number"`
+}
+
+// BlockTxModel is a transaction cid along with the header which includes it
+type BlockTxModel struct {
+	TxModel
+	BlockNumber int64  `db:"block_number"`
+	BlockHash   string `db:"block_hash"`
+	Timestamp   int64  `db:"timestamp"`
+	Canonical   bool   `db:"canonical"`
+}
+
+// BlockFilterWithHeaderModel is a block filter along with the header of the block it filters
+type BlockFilterWithHeaderModel struct {
+	BlockFilterModel
+	BlockNumber int64  `db:"block_number"`
+	BlockHash   string `db:"block_hash"`
+}
+
+// PrevoutModel is an indexed output spent by the input of a transaction
+type PrevoutModel struct {
+	TxID       int64  `db:"tx_id"`
+	InputIndex int64  `db:"input_index"`
+	Value      int64  `db:"value"`
+	PkScript   []byte `db:"pk_script"`
+}
+
+// OutspendModel is an output of a transaction, along with the input which spends it in a canonical block if there is one
+type OutspendModel struct {
+	OutputIndex int64          `db:"output_index"`
+	TxHash      sql.NullString `db:"tx_hash"`
+	InputIndex  sql.NullInt64  `db:"input_index"`
+	BlockNumber sql.NullInt64  `db:"block_number"`
+	BlockHash   sql.NullString `db:"block_hash"`
+	Timestamp   sql.NullInt64  `db:"timestamp"`
+}
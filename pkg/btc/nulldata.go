@@ -0,0 +1,66 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// NullDataPrefixSize is the size of the payload prefix nulldata outputs are indexed by, which is how most anchoring and
+// timestamping protocols tag their outputs
+const NullDataPrefixSize = 4
+
+// NullDataPayload returns the data pushed by an OP_RETURN output, concatenated in the order it is pushed
+// Any OP_RETURN script followed only by pushes is decoded, including those too large for txscript to class as nulldata
+func NullDataPayload(pkScript []byte) ([]byte, bool) {
+	if len(pkScript) == 0 || pkScript[0] != txscript.OP_RETURN {
+		return nil, false
+	}
+	if !txscript.IsPushOnlyScript(pkScript[1:]) {
+		return nil, false
+	}
+	pushes, err := txscript.PushedData(pkScript[1:])
+	if err != nil {
+		return nil, false
+	}
+	return bytes.Join(pushes, nil), true
+}
+
+// NullDataPrefix returns the prefix the payload is indexed by, the whole payload if it is shorter than NullDataPrefixSize
+func NullDataPrefix(payload []byte) []byte {
+	if len(payload) > NullDataPrefixSize {
+		return payload[:NullDataPrefixSize]
+	}
+	return payload
+}
+
+// decodeNullDataPrefixes decodes the hex encoded prefixes of a TxFilter
+func decodeNullDataPrefixes(prefixes []string) ([][]byte, error) {
+	decoded := make([][]byte, len(prefixes))
+	for i, prefix := range prefixes {
+		b, err := hex.DecodeString(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nulldata prefix %s: %v", prefix, err)
+		}
+		decoded[i] = b
+	}
+	return decoded, nil
+}
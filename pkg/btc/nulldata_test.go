@@ -0,0 +1,61 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc_test
+
+import (
+	"bytes"
+
+	"github.com/btcsuite/btcd/txscript"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc/mocks"
+)
+
+var _ = Describe("NullData", func() {
+	Describe("NullDataPayload", func() {
+		It("Concatenates the data pushed by OP_RETURN outputs", func() {
+			pkScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).
+				AddData([]byte("omni")).AddData([]byte{0x00, 0x01}).Script()
+			Expect(err).ToNot(HaveOccurred())
+			payload, ok := btc.NullDataPayload(pkScript)
+			Expect(ok).To(BeTrue())
+			Expect(payload).To(Equal([]byte{'o', 'm', 'n', 'i', 0x00, 0x01}))
+			Expect(btc.NullDataPrefix(payload)).To(Equal([]byte("omni")))
+			Expect(btc.NullDataPrefix([]byte{0x01})).To(Equal([]byte{0x01}))
+		})
+
+		It("Decodes payloads larger than the standard nulldata limit", func() {
+			data := bytes.Repeat([]byte{0x01}, 200)
+			pkScript, err := txscript.NewScriptBuilder().AddOp(txscript.OP_RETURN).AddFullData(data).Script()
+			Expect(err).ToNot(HaveOccurred())
+			payload, ok := btc.NullDataPayload(pkScript)
+			Expect(ok).To(BeTrue())
+			Expect(payload).To(Equal(data))
+		})
+
+		It("Ignores outputs which are not OP_RETURN outputs or push other opcodes", func() {
+			_, ok := btc.NullDataPayload(mocks.MockTxsMetaData[1].TxOutputs[0].PkScript)
+			Expect(ok).To(BeFalse())
+			_, ok = btc.NullDataPayload([]byte{txscript.OP_RETURN, txscript.OP_DUP})
+			Expect(ok).To(BeFalse())
+			_, ok = btc.NullDataPayload(nil)
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
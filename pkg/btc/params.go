@@ -0,0 +1,92 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// sigNetPowLimit is the highest proof of work target of the default signet, 0x00000377ae000000...
+var sigNetPowLimit = new(big.Int).Lsh(big.NewInt(0x0377ae), 216)
+
+// sigNetGenesisBlock is the genesis block of the default signet, it carries the same coinbase as the mainnet genesis block
+var sigNetGenesisBlock = func() wire.MsgBlock {
+	block := *chaincfg.MainNetParams.GenesisBlock
+	block.Header.Timestamp = time.Unix(1598918400, 0)
+	block.Header.Bits = 0x1e0377ae
+	block.Header.Nonce = 52613770
+	return block
+}()
+
+var sigNetGenesisHash = sigNetGenesisBlock.BlockHash()
+
+// SigNetParams are the chain parameters of the default signet, which the btcd version in use does not define
+// Signet blocks are signed by the network's challenge script; only their proof of work is validated by the watcher
+// Signet addresses use the testnet prefixes, which are already registered by chaincfg
+var SigNetParams = chaincfg.Params{
+	Name:        "signet",
+	Net:         wire.BitcoinNet(0x40cf030a),
+	DefaultPort: "38333",
+
+	GenesisBlock:             &sigNetGenesisBlock,
+	GenesisHash:              &sigNetGenesisHash,
+	PowLimit:                 sigNetPowLimit,
+	PowLimitBits:             0x1e0377ae,
+	BIP0034Height:            1,
+	BIP0065Height:            1,
+	BIP0066Height:            1,
+	CoinbaseMaturity:         100,
+	SubsidyReductionInterval: 210000,
+	TargetTimespan:           time.Hour * 24 * 14,
+	TargetTimePerBlock:       time.Minute * 10,
+	RetargetAdjustmentFactor: 4,
+	ReduceMinDifficulty:      false,
+	GenerateSupported:        false,
+
+	RelayNonStdTxs:   false,
+	Bech32HRPSegwit:  chaincfg.TestNet3Params.Bech32HRPSegwit,
+	PubKeyHashAddrID: chaincfg.TestNet3Params.PubKeyHashAddrID,
+	ScriptHashAddrID: chaincfg.TestNet3Params.ScriptHashAddrID,
+	PrivateKeyID:     chaincfg.TestNet3Params.PrivateKeyID,
+	HDPrivateKeyID:   chaincfg.TestNet3Params.HDPrivateKeyID,
+	HDPublicKeyID:    chaincfg.TestNet3Params.HDPublicKeyID,
+	HDCoinType:       chaincfg.TestNet3Params.HDCoinType,
+}
+
+// NetworkParams returns the chain parameters of the named bitcoin network, mainnet if no network is named
+func NetworkParams(network string) (*chaincfg.Params, error) {
+	switch strings.ToLower(network) {
+	case "", "mainnet", "main":
+		return &chaincfg.MainNetParams, nil
+	case "testnet3", "testnet", "test":
+		return &chaincfg.TestNet3Params, nil
+	case "regtest":
+		return &chaincfg.RegressionNetParams, nil
+	case "signet":
+		return &SigNetParams, nil
+	case "simnet":
+		return &chaincfg.SimNetParams, nil
+	default:
+		return nil, fmt.Errorf("invalid bitcoin network %s", network)
+	}
+}
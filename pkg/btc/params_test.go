@@ -0,0 +1,55 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc_test
+
+import (
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+)
+
+var _ = Describe("Params", func() {
+	Describe("SigNetParams", func() {
+		It("Matches the genesis block and proof of work limit of the default signet", func() {
+			Expect(btc.SigNetParams.GenesisHash.String()).To(Equal("00000008819873e925422c1ff0f99f7cc9bbb232af63a077a480a3633bee1ef6"))
+			Expect(btc.SigNetParams.PowLimit.Cmp(blockchain.CompactToBig(btc.SigNetParams.PowLimitBits))).To(Equal(0))
+		})
+	})
+
+	Describe("NetworkParams", func() {
+		It("Resolves the chain parameters of the named network, defaulting to mainnet", func() {
+			for network, params := range map[string]*chaincfg.Params{
+				"":         &chaincfg.MainNetParams,
+				"mainnet":  &chaincfg.MainNetParams,
+				"testnet3": &chaincfg.TestNet3Params,
+				"testnet":  &chaincfg.TestNet3Params,
+				"regtest":  &chaincfg.RegressionNetParams,
+				"signet":   &btc.SigNetParams,
+				"simnet":   &chaincfg.SimNetParams,
+			} {
+				resolved, err := btc.NetworkParams(network)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resolved).To(Equal(params))
+			}
+			_, err := btc.NetworkParams("notanet")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
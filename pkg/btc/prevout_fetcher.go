@@ -0,0 +1,63 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// PrevoutFetcher fetches the outputs spent by transaction inputs, for the outputs which have not been indexed
+type PrevoutFetcher interface {
+	FetchPrevout(outpoint wire.OutPoint) (TxOutput, error)
+}
+
+// RPCPrevoutFetcher satisfies the PrevoutFetcher interface by retrieving the spent transactions from a bitcoin node
+// with getrawtransaction; the node needs to run with -txindex to serve transactions which are not in its mempool or wallet
+type RPCPrevoutFetcher struct {
+	// RPCPrevoutFetcher is thread-safe as long as the underlying client is thread-safe
+	client      *rpcclient.Client
+	chainConfig *chaincfg.Params
+}
+
+// NewRPCPrevoutFetcher returns a new RPCPrevoutFetcher for the node, classifying the outputs it fetches for the chain with the provided params
+func NewRPCPrevoutFetcher(c *rpcclient.ConnConfig, chainConfig *chaincfg.Params) (*RPCPrevoutFetcher, error) {
+	client, err := rpcclient.New(c, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &RPCPrevoutFetcher{
+		client:      client,
+		chainConfig: chainConfig,
+	}, nil
+}
+
+// FetchPrevout fetches and converts the output at the outpoint
+func (f *RPCPrevoutFetcher) FetchPrevout(outpoint wire.OutPoint) (TxOutput, error) {
+	tx, err := f.client.GetRawTransaction(&outpoint.Hash)
+	if err != nil {
+		return TxOutput{}, fmt.Errorf("bitcoin RPCPrevoutFetcher getrawtransaction err for tx %s: %s", outpoint.Hash.String(), err.Error())
+	}
+	outputs := tx.MsgTx().TxOut
+	if int(outpoint.Index) >= len(outputs) {
+		return TxOutput{}, fmt.Errorf("bitcoin RPCPrevoutFetcher tx %s has no output %d", outpoint.Hash.String(), outpoint.Index)
+	}
+	return convertTxOutput(int64(outpoint.Index), outputs[outpoint.Index], f.chainConfig)
+}
@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/btcsuite/btcd/chaincfg"
+
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/ipfs/ipld"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
@@ -33,9 +35,11 @@ type IPLDPublisherAndIndexer struct {
 }
 
 // NewIPLDPublisherAndIndexer creates a pointer to a new IPLDPublisherAndIndexer which satisfies the IPLDPublisher interface
-func NewIPLDPublisherAndIndexer(db *postgres.DB) *IPLDPublisherAndIndexer {
+// The chain parameters, which can be nil, are used to validate headers, and the PrevoutFetcher, which can be nil, to
+// resolve the spent outputs which have not been indexed
+func NewIPLDPublisherAndIndexer(db *postgres.DB, params *chaincfg.Params, prevouts PrevoutFetcher) *IPLDPublisherAndIndexer {
 	return &IPLDPublisherAndIndexer{
-		indexer: NewCIDIndexer(db),
+		indexer: NewCIDIndexer(db, params, prevouts),
 	}
 }
 
@@ -87,12 +91,18 @@ func (pub *IPLDPublisherAndIndexer) Publish(payload shared.ConvertedData) (share
 		Timestamp:   ipldPayload.Header.Timestamp.UnixNano(),
 		Bits:        ipldPayload.Header.Bits,
 	}
+	if pub.indexer.params != nil {
+		if err = pub.indexer.validateHeader(tx, header); err != nil {
+			return nil, err
+		}
+	}
 	headerID, err := pub.indexer.indexHeaderCID(tx, header)
 	if err != nil {
 		return nil, err
 	}
 
 	// Publish and index txs
+	scripts := new(filterScripts)
 	for i, txNode := range txNodes {
 		if err := shared.PublishIPLD(tx, txNode); err != nil {
 			return nil, err
@@ -100,22 +110,19 @@ func (pub *IPLDPublisherAndIndexer) Publish(payload shared.ConvertedData) (share
 		txModel := ipldPayload.TxMetaData[i]
 		txModel.CID = txNode.Cid().String()
 		txModel.MhKey = shared.MultihashKeyFromCID(txNode.Cid())
-		txID, err := pub.indexer.indexTransactionCID(tx, txModel, headerID)
-		if err != nil {
+		if err := pub.indexer.indexTransaction(tx, txModel, headerID, scripts); err != nil {
 			return nil, err
 		}
-		for _, input := range txModel.TxInputs {
-			if err := pub.indexer.indexTxInput(tx, input, txID); err != nil {
-				return nil, err
-			}
-		}
-		for _, output := range txModel.TxOutputs {
-			if err := pub.indexer.indexTxOutput(tx, output, txID); err != nil {
-				return nil, err
-			}
-		}
 	}
 
+	// Update the canonical chain
+	if err = pub.indexer.markCanonical(tx, header, headerID); err != nil {
+		return nil, err
+	}
+
+	// Index the block's filter
+	err = pub.indexer.indexBlockFilter(tx, header, headerID, scripts)
+
 	// This IPLDPublisher does both publishing and indexing, we do not need to pass anything forward to the indexer
 	return nil, err
 }
@@ -124,3 +131,8 @@ func (pub *IPLDPublisherAndIndexer) Publish(payload shared.ConvertedData) (share
 func (pub *IPLDPublisherAndIndexer) Index(cids shared.CIDsForIndexing) error {
 	return nil
 }
+
+// Disconnect satisfies the shared.BlockDisconnector interface
+func (pub *IPLDPublisherAndIndexer) Disconnect(block shared.DisconnectedBlock) error {
+	return pub.indexer.Disconnect(block)
+}
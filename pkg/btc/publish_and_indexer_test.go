@@ -19,6 +19,7 @@ package btc_test
 import (
 	"bytes"
 
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-ipfs-blockstore"
 	"github.com/ipfs/go-ipfs-ds-help"
@@ -44,7 +45,7 @@ var _ = Describe("PublishAndIndexer", func() {
 	BeforeEach(func() {
 		db, err = shared.SetupDB()
 		Expect(err).ToNot(HaveOccurred())
-		repo = btc.NewIPLDPublisherAndIndexer(db)
+		repo = btc.NewIPLDPublisherAndIndexer(db, &chaincfg.MainNetParams, nil)
 	})
 	AfterEach(func() {
 		btc.TearDownDB(db)
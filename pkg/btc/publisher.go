@@ -107,6 +107,8 @@ func (pub *IPLDPublisher) publishTransactions(transactions []*ipld.BtcTx, txTrie
 			TxHash:      trxMeta[i].TxHash,
 			SegWit:      trxMeta[i].SegWit,
 			WitnessHash: trxMeta[i].WitnessHash,
+			Weight:      trxMeta[i].Weight,
+			VSize:       trxMeta[i].VSize,
 			TxInputs:    trxMeta[i].TxInputs,
 			TxOutputs:   trxMeta[i].TxOutputs,
 		}
@@ -18,6 +18,7 @@ package btc
 
 import (
 	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/spf13/viper"
@@ -33,6 +34,8 @@ type SubscriptionSettings struct {
 	End          *big.Int // set to 0 or a negative value to have no ending block
 	HeaderFilter HeaderFilter
 	TxFilter     TxFilter
+	// Unconfirmed opts in to the transactions which pass the TxFilter as they enter the mempool, ahead of their confirmation
+	Unconfirmed bool
 }
 
 // HeaderFilter contains filter settings for headers
@@ -49,6 +52,8 @@ type TxFilter struct {
 	PkScriptClasses []uint8  // allow filtering for txs that have at least one tx output with the specified pkscript class
 	MultiSig        bool     // allow filtering for txs that have at least one tx output that requires more than one signature
 	Addresses       []string // allow filtering for txs that have at least one tx output with at least one of the provided addresses
+	// allow filtering for txs that have at least one OP_RETURN output whose payload starts with one of the provided hex encoded prefixes
+	NullDataPrefixes []string
 }
 
 // Init is used to initialize a EthSubscription struct with env variables
@@ -77,15 +82,21 @@ func NewBtcSubscriptionConfig() (*SubscriptionSettings, error) {
 	if !ok {
 		return nil, errors.New("watcher.btcSubscription.txFilter.indexes needs to be an array of int64s")
 	}
+	nullDataPrefixes := viper.GetStringSlice("watcher.btcSubscription.txFilter.nullDataPrefixes")
+	if _, err := decodeNullDataPrefixes(nullDataPrefixes); err != nil {
+		return nil, fmt.Errorf("watcher.btcSubscription.txFilter.nullDataPrefixes needs to be an array of hex strings: %v", err)
+	}
 	sc.TxFilter = TxFilter{
-		Off:             viper.GetBool("watcher.btcSubscription.txFilter.off"),
-		Segwit:          viper.GetBool("watcher.btcSubscription.txFilter.segwit"),
-		WitnessHashes:   viper.GetStringSlice("watcher.btcSubscription.txFilter.witnessHashes"),
-		PkScriptClasses: pkScriptClasses,
-		Indexes:         indexes,
-		MultiSig:        viper.GetBool("watcher.btcSubscription.txFilter.multiSig"),
-		Addresses:       viper.GetStringSlice("watcher.btcSubscription.txFilter.addresses"),
+		Off:              viper.GetBool("watcher.btcSubscription.txFilter.off"),
+		Segwit:           viper.GetBool("watcher.btcSubscription.txFilter.segwit"),
+		WitnessHashes:    viper.GetStringSlice("watcher.btcSubscription.txFilter.witnessHashes"),
+		PkScriptClasses:  pkScriptClasses,
+		Indexes:          indexes,
+		MultiSig:         viper.GetBool("watcher.btcSubscription.txFilter.multiSig"),
+		Addresses:        viper.GetStringSlice("watcher.btcSubscription.txFilter.addresses"),
+		NullDataPrefixes: nullDataPrefixes,
 	}
+	sc.Unconfirmed = viper.GetBool("watcher.btcSubscription.unconfirmed")
 	return sc, nil
 }
 
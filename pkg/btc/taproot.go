@@ -0,0 +1,142 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+)
+
+// Script classes which btcd v0.20 predates, numbered as in later btcd releases
+// These are the additional values TxFilter.PkScriptClasses can be filtered with
+const (
+	// WitnessV1TaprootTy is the class of pay-to-taproot outputs, witness v1 programs of 32 bytes
+	WitnessV1TaprootTy = txscript.NullDataTy + 1
+	// WitnessUnknownTy is the class of outputs paying to witness programs of versions not yet defined
+	WitnessUnknownTy = txscript.NullDataTy + 2
+)
+
+// How a taproot input spends its output, as recorded in btc.tx_inputs
+const (
+	// NotTaprootSpend marks inputs which do not spend a taproot output
+	NotTaprootSpend uint8 = iota
+	// TaprootKeyPathSpend marks inputs which spend a taproot output with a signature for its output key
+	TaprootKeyPathSpend
+	// TaprootScriptPathSpend marks inputs which spend a taproot output by satisfying one of its committed scripts
+	TaprootScriptPathSpend
+)
+
+const (
+	taprootProgramSize = 32
+	// annexTag is the first byte of the annex, an optional last witness item ignored when detecting spend paths
+	annexTag = 0x50
+	// tapscriptLeafVersion is the leaf version of the scripts defined by BIP342, carried in the control block
+	tapscriptLeafVersion = 0xc0
+	controlBlockBaseSize = 33
+	controlBlockNodeSize = 32
+	controlBlockMaxDepth = 128
+)
+
+// ExtractPkScriptAddrs wraps txscript.ExtractPkScriptAddrs to classify witness v1 and above outputs, which txscript
+// returns as non-standard, and to return their bech32m addresses
+func ExtractPkScriptAddrs(pkScript []byte, params *chaincfg.Params) (txscript.ScriptClass, []btcutil.Address, int, error) {
+	version, program, ok := witnessProgram(pkScript)
+	if !ok {
+		return txscript.ExtractPkScriptAddrs(pkScript, params)
+	}
+	scriptClass := WitnessUnknownTy
+	requiredSigs := 0
+	if version == 1 && len(program) == taprootProgramSize {
+		scriptClass = WitnessV1TaprootTy
+		requiredSigs = 1
+	}
+	addr, err := NewAddressWitness(version, program, params)
+	if err != nil {
+		return txscript.NonStandardTy, nil, 0, err
+	}
+	return scriptClass, []btcutil.Address{addr}, requiredSigs, nil
+}
+
+// GetScriptClass wraps txscript.GetScriptClass to classify witness v1 and above outputs
+func GetScriptClass(pkScript []byte) txscript.ScriptClass {
+	version, program, ok := witnessProgram(pkScript)
+	switch {
+	case !ok:
+		return txscript.GetScriptClass(pkScript)
+	case version == 1 && len(program) == taprootProgramSize:
+		return WitnessV1TaprootTy
+	default:
+		return WitnessUnknownTy
+	}
+}
+
+// ScriptClassName returns the name bitcoind gives to the script class
+func ScriptClassName(scriptClass txscript.ScriptClass) string {
+	switch scriptClass {
+	case WitnessV1TaprootTy:
+		return "witness_v1_taproot"
+	case WitnessUnknownTy:
+		return "witness_unknown"
+	default:
+		return scriptClass.String()
+	}
+}
+
+// witnessProgram returns the version and program of a pk script paying to a witness v1 to v16 program: a version
+// opcode followed by a single push of 2 to 40 bytes
+func witnessProgram(pkScript []byte) (byte, []byte, bool) {
+	if len(pkScript) < 4 || len(pkScript) > 42 {
+		return 0, nil, false
+	}
+	if pkScript[0] < txscript.OP_1 || pkScript[0] > txscript.OP_16 {
+		return 0, nil, false
+	}
+	if int(pkScript[1]) != len(pkScript)-2 {
+		return 0, nil, false
+	}
+	return pkScript[0] - txscript.OP_1 + 1, pkScript[2:], true
+}
+
+// taprootSpendType detects how an input spends a taproot output from its witness; the output spent is not known when
+// a block is converted, so the indexer clears the detection if the resolved output turns out not to be a taproot output
+// Key path spends carry a single 64 or 65 byte signature; script path spends end in a control block, which is
+// recognized by its size and tapscript leaf version
+func taprootSpendType(sigScript []byte, witness [][]byte) uint8 {
+	if len(sigScript) != 0 || len(witness) == 0 {
+		return NotTaprootSpend
+	}
+	if len(witness) >= 2 && len(witness[len(witness)-1]) > 0 && witness[len(witness)-1][0] == annexTag {
+		witness = witness[:len(witness)-1]
+	}
+	if len(witness) == 1 {
+		if size := len(witness[0]); size == 64 || size == 65 {
+			return TaprootKeyPathSpend
+		}
+		return NotTaprootSpend
+	}
+	controlBlock := witness[len(witness)-1]
+	size := len(controlBlock)
+	if size < controlBlockBaseSize || (size-controlBlockBaseSize)%controlBlockNodeSize != 0 ||
+		(size-controlBlockBaseSize)/controlBlockNodeSize > controlBlockMaxDepth {
+		return NotTaprootSpend
+	}
+	if controlBlock[0]&0xfe != tapscriptLeafVersion {
+		return NotTaprootSpend
+	}
+	return TaprootScriptPathSpend
+}
@@ -0,0 +1,149 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc/mocks"
+)
+
+var _ = Describe("Taproot", func() {
+	var (
+		// BIP86 test vector
+		taprootAddress  = "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr"
+		taprootPkScript = "5120a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c"
+	)
+
+	Describe("DecodeAddress", func() {
+		It("Decodes bech32m addresses for witness versions 1 through 16", func() {
+			addr, err := btc.DecodeAddress(taprootAddress, &chaincfg.MainNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr.IsForNet(&chaincfg.MainNetParams)).To(BeTrue())
+			Expect(addr.EncodeAddress()).To(Equal(taprootAddress))
+			Expect(hex.EncodeToString(addr.ScriptAddress())).To(Equal(taprootPkScript[4:]))
+
+			// BIP350 test vectors
+			for _, valid := range []string{
+				"BC1SW50QGDZ25J",
+				"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs",
+				"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y",
+			} {
+				addr, err = btc.DecodeAddress(valid, &chaincfg.MainNetParams)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(addr.EncodeAddress()).To(Equal(strings.ToLower(valid)))
+			}
+		})
+
+		It("Still decodes the addresses btcutil decodes", func() {
+			addr, err := btc.DecodeAddress("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", &chaincfg.MainNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr).To(BeAssignableToTypeOf(&btcutil.AddressWitnessPubKeyHash{}))
+			addr, err = btc.DecodeAddress(mocks.MockTxsMetaData[1].TxOutputs[0].Addresses[0], &chaincfg.MainNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(addr.EncodeAddress()).To(Equal(mocks.MockTxsMetaData[1].TxOutputs[0].Addresses[0]))
+		})
+
+		It("Rejects addresses encoded with the wrong checksum or for another network", func() {
+			for _, invalid := range []string{
+				// witness v1 with a bech32 checksum
+				"bc1p38j9r5y49hruaue7wxjce0updqjuyyx0kh56v8s25huc6995vvpql3jow4",
+				// witness v0 with a bech32m checksum
+				"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh",
+				// testnet address
+				"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq47zagq",
+			} {
+				_, err := btc.DecodeAddress(invalid, &chaincfg.MainNetParams)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+
+	Describe("ExtractPkScriptAddrs", func() {
+		It("Classifies witness v1 and above outputs", func() {
+			pkScript, err := hex.DecodeString(taprootPkScript)
+			Expect(err).ToNot(HaveOccurred())
+			class, addrs, reqSigs, err := btc.ExtractPkScriptAddrs(pkScript, &chaincfg.MainNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(class).To(Equal(btc.WitnessV1TaprootTy))
+			Expect(btc.ScriptClassName(class)).To(Equal("witness_v1_taproot"))
+			Expect(reqSigs).To(Equal(1))
+			Expect(len(addrs)).To(Equal(1))
+			Expect(addrs[0].EncodeAddress()).To(Equal(taprootAddress))
+
+			class, addrs, _, err = btc.ExtractPkScriptAddrs([]byte{txscript.OP_16, 0x02, 0x75, 0x1e}, &chaincfg.MainNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(class).To(Equal(btc.WitnessUnknownTy))
+			Expect(btc.ScriptClassName(class)).To(Equal("witness_unknown"))
+			Expect(addrs[0].EncodeAddress()).To(Equal("bc1sw50qgdz25j"))
+		})
+
+		It("Defers to txscript for other outputs", func() {
+			out := mocks.MockTxsMetaData[1].TxOutputs[0]
+			class, addrs, _, err := btc.ExtractPkScriptAddrs(out.PkScript, &chaincfg.MainNetParams)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uint8(class)).To(Equal(out.ScriptClass))
+			Expect(addrs[0].EncodeAddress()).To(Equal(out.Addresses[0]))
+		})
+	})
+
+	Describe("Convert", func() {
+		It("Classifies taproot outputs and detects how inputs spend taproot outputs", func() {
+			pkScript, err := hex.DecodeString(taprootPkScript)
+			Expect(err).ToNot(HaveOccurred())
+			signature := bytes.Repeat([]byte{0x01}, 64)
+			controlBlock := append([]byte{0xc1}, bytes.Repeat([]byte{0x02}, 64)...)
+			tx := wire.NewMsgTx(2)
+			// key path spend
+			tx.AddTxIn(&wire.TxIn{Witness: wire.TxWitness{signature}})
+			// key path spend with an annex
+			tx.AddTxIn(&wire.TxIn{Witness: wire.TxWitness{signature, {0x50, 0x01}}})
+			// script path spend
+			tx.AddTxIn(&wire.TxIn{Witness: wire.TxWitness{signature, {txscript.OP_TRUE}, controlBlock}})
+			// witness v0 pubkey hash spend
+			tx.AddTxIn(&wire.TxIn{Witness: wire.TxWitness{append(signature, 0x01, 0x01), append([]byte{0x02}, bytes.Repeat([]byte{0x03}, 32)...)}})
+			tx.AddTxOut(wire.NewTxOut(1000, pkScript))
+			payload := btc.BlockPayload{
+				BlockHeight: mocks.MockBlockHeight,
+				Header:      &mocks.MockBlock.Header,
+				Txs:         []*btcutil.Tx{btcutil.NewTx(tx)},
+			}
+
+			converted, err := btc.NewPayloadConverter(&chaincfg.MainNetParams).Convert(payload)
+			Expect(err).ToNot(HaveOccurred())
+			txMeta := converted.(btc.ConvertedPayload).TxMetaData[0]
+			Expect(txMeta.TxOutputs[0].ScriptClass).To(Equal(uint8(btc.WitnessV1TaprootTy)))
+			Expect(txMeta.TxOutputs[0].Addresses).To(Equal(pq.StringArray{taprootAddress}))
+			Expect(txMeta.TxOutputs[0].RequiredSigs).To(Equal(int64(1)))
+			Expect(txMeta.TxInputs[0].TaprootSpend).To(Equal(btc.TaprootKeyPathSpend))
+			Expect(txMeta.TxInputs[1].TaprootSpend).To(Equal(btc.TaprootKeyPathSpend))
+			Expect(txMeta.TxInputs[2].TaprootSpend).To(Equal(btc.TaprootScriptPathSpend))
+			Expect(txMeta.TxInputs[3].TaprootSpend).To(Equal(btc.NotTaprootSpend))
+		})
+	})
+})
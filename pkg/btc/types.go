@@ -32,6 +32,23 @@ type BlockPayload struct {
 	Txs         []*btcutil.Tx
 }
 
+// BlockDisconnect is streamed in place of a BlockPayload when a previously streamed block is disconnected by a reorg
+// It satisfies the shared.DisconnectedBlock interface
+type BlockDisconnect struct {
+	BlockHeight int64
+	Header      *wire.BlockHeader
+}
+
+// Height satisfies the shared.DisconnectedBlock interface
+func (bd BlockDisconnect) Height() int64 {
+	return bd.BlockHeight
+}
+
+// Hash satisfies the shared.DisconnectedBlock interface
+func (bd BlockDisconnect) Hash() string {
+	return bd.Header.BlockHash().String()
+}
+
 // ConvertedPayload is a custom type which packages raw BTC data for publishing to IPFS and filtering to subscribers
 // Returned by PayloadConverter
 // Passed to IPLDPublisher and ResponseFilterer
@@ -45,6 +62,34 @@ func (cp ConvertedPayload) Height() int64 {
 	return cp.BlockPayload.BlockHeight
 }
 
+// Hash satisfies the StreamedIPLDs interface
+func (cp ConvertedPayload) Hash() string {
+	return cp.BlockPayload.Header.BlockHash().String()
+}
+
+// ParentHash satisfies the StreamedIPLDs interface
+func (cp ConvertedPayload) ParentHash() string {
+	return cp.BlockPayload.Header.PrevBlock.String()
+}
+
+// MempoolPayload packages the changes to the mempool of the node since it was last polled
+// It satisfies the shared.UnconfirmedData interface
+type MempoolPayload struct {
+	// Txs are the transactions which have entered the mempool, along with their converted form and mempool entries
+	Txs        []*btcutil.Tx
+	TxMetaData []TxModelWithInsAndOuts
+	Entries    []MempoolEntry
+	// Evicted are the hashes of the transactions which have left the mempool, because they were confirmed, replaced or expired
+	Evicted []string
+}
+
+// MempoolEntry is what the node reports of a transaction in its mempool
+type MempoolEntry struct {
+	// Fee is the fee paid by the transaction in satoshis, -1 if the node did not report it
+	Fee  int64
+	Time int64
+}
+
 // CIDPayload is a struct to hold all the CIDs and their associated meta data for indexing in Postgres
 // Returned by IPLDPublisher
 // Passed to CIDIndexer
@@ -74,3 +119,14 @@ type IPLDs struct {
 func (i IPLDs) Height() int64 {
 	return i.BlockNumber.Int64()
 }
+
+// MempoolIPLDs is used to package the raw IPLD block data of unconfirmed transactions served to subscribers
+// Returned by the ResponseFilterer
+type MempoolIPLDs struct {
+	Transactions []ipfs.BlockModel
+}
+
+// Height satisfies the StreamedIPLDs interface, unconfirmed transactions are not at any height
+func (i MempoolIPLDs) Height() int64 {
+	return 0
+}
@@ -0,0 +1,218 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/go-zeromq/zmq4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// HashBlockTopic is the ZMQ topic bitcoind publishes block hash notifications on, with -zmqpubhashblock
+// Notifications only trigger a sync over rpc, so the rawblock topic, which carries the whole block, is not subscribed to
+const HashBlockTopic = "hashblock"
+
+// zmqRedialInterval is how long the streamer waits before redialing the endpoint after losing its connection
+var zmqRedialInterval = 5 * time.Second
+
+// ZMQConfig is the config for a ZMQPayloadStreamer
+type ZMQConfig struct {
+	// Endpoint bitcoind publishes its block notifications on, e.g. "tcp://127.0.0.1:28332"
+	Endpoint string
+	// Config for the rpc client the notified blocks are fetched with
+	RPC *rpcclient.ConnConfig
+}
+
+// ZMQPayloadStreamer satisfies the PayloadStreamer interface for bitcoin using bitcoind's zmq block notifications
+// Notifications are only used as a trigger: on each one the streamer syncs with the node's tip over rpc, in the same way
+// as the HTTPPayloadStreamer does on each poll, so that no block is skipped when several are connected at once or a
+// notification is dropped, and the blocks disconnected by a reorg are streamed as BlockDisconnects
+type ZMQPayloadStreamer struct {
+	Config *ZMQConfig
+	chain  chainFollower
+}
+
+// NewZMQPayloadStreamer creates a pointer to a new ZMQPayloadStreamer which satisfies the PayloadStreamer interface for bitcoin
+func NewZMQPayloadStreamer(config *ZMQConfig) *ZMQPayloadStreamer {
+	return &ZMQPayloadStreamer{
+		Config: config,
+	}
+}
+
+// Stream subscribes to the block notifications and streams the blocks they announce, starting with the node's current tip
+// Satisfies the shared.PayloadStreamer interface
+func (ps *ZMQPayloadStreamer) Stream(payloadChan chan shared.RawChainData) (shared.ClientSubscription, error) {
+	logrus.Debugf("streaming block payloads from btc zmq endpoint %s", ps.Config.Endpoint)
+	client, err := rpcclient.New(ps.Config.RPC, nil)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := ps.subscribe()
+	if err != nil {
+		client.Shutdown()
+		return nil, err
+	}
+	zmqSub := &ZMQClientSubscription{
+		client:  client,
+		sub:     sub,
+		errChan: make(chan error),
+		quit:    make(chan bool),
+	}
+	notifications := make(chan struct{}, 1)
+	// Sync up to the current tip straight away, rather than waiting on the next block
+	notifications <- struct{}{}
+	go zmqSub.receive(ps, notifications)
+	go func() {
+		for {
+			select {
+			case <-notifications:
+				if err := ps.chain.sync(client, payloadChan, zmqSub.quit); err != nil {
+					zmqSub.sendErr(err)
+				}
+			case <-zmqSub.quit:
+				return
+			}
+		}
+	}()
+	return zmqSub, nil
+}
+
+// subscribe dials the endpoint with a new socket subscribed to the block hash topic
+func (ps *ZMQPayloadStreamer) subscribe() (zmq4.Socket, error) {
+	sub := zmq4.NewSub(context.Background())
+	if err := sub.Dial(ps.Config.Endpoint); err != nil {
+		sub.Close()
+		return nil, err
+	}
+	if err := sub.SetOption(zmq4.OptionSubscribe, HashBlockTopic); err != nil {
+		sub.Close()
+		return nil, err
+	}
+	return sub, nil
+}
+
+// ZMQClientSubscription is a wrapper around the zmq subscription and the bitcoind rpc client
+// to fit the shared.ClientSubscription interface
+type ZMQClientSubscription struct {
+	client *rpcclient.Client
+	// Guards the socket, which is replaced when the endpoint is redialed
+	mu      sync.Mutex
+	sub     zmq4.Socket
+	errChan chan error
+	quit    chan bool
+	// Guards against closing the quit channel twice
+	unsubscribe sync.Once
+}
+
+// receive forwards the notifications received to the sync process, redialing the endpoint whenever the connection is lost
+func (zs *ZMQClientSubscription) receive(ps *ZMQPayloadStreamer, notifications chan struct{}) {
+	for {
+		msg, err := zs.socket().Recv()
+		if err == nil {
+			if len(msg.Frames) > 0 {
+				logrus.Debugf("btc zmq %s notification received", msg.Frames[0])
+			}
+			select {
+			case notifications <- struct{}{}:
+			default:
+				// A sync is already pending, it will pick up this block too
+			}
+			continue
+		}
+		select {
+		case <-zs.quit:
+			return
+		default:
+		}
+		zs.sendErr(err)
+		zs.socket().Close()
+		if !zs.redial(ps) {
+			return
+		}
+		// Blocks connected while disconnected were not notified
+		select {
+		case notifications <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// redial replaces the socket with a new one dialed to the endpoint, retrying until it succeeds or the subscription is
+// closed; it returns false if the subscription was closed
+func (zs *ZMQClientSubscription) redial(ps *ZMQPayloadStreamer) bool {
+	for {
+		select {
+		case <-time.After(zmqRedialInterval):
+		case <-zs.quit:
+			return false
+		}
+		sub, err := ps.subscribe()
+		if err != nil {
+			zs.sendErr(err)
+			continue
+		}
+		zs.mu.Lock()
+		select {
+		case <-zs.quit:
+			// Unsubscribed while dialing
+			zs.mu.Unlock()
+			sub.Close()
+			return false
+		default:
+		}
+		zs.sub = sub
+		zs.mu.Unlock()
+		return true
+	}
+}
+
+// socket returns the current socket
+func (zs *ZMQClientSubscription) socket() zmq4.Socket {
+	zs.mu.Lock()
+	defer zs.mu.Unlock()
+	return zs.sub
+}
+
+// sendErr sends the error to the subscriber unless the subscription has been closed
+func (zs *ZMQClientSubscription) sendErr(err error) {
+	select {
+	case zs.errChan <- err:
+	case <-zs.quit:
+	}
+}
+
+// Unsubscribe satisfies the rpc.Subscription interface, it is safe to call more than once
+func (zs *ZMQClientSubscription) Unsubscribe() {
+	zs.unsubscribe.Do(func() {
+		zs.mu.Lock()
+		close(zs.quit)
+		zs.sub.Close()
+		zs.mu.Unlock()
+		zs.client.Shutdown()
+	})
+}
+
+// Err() satisfies the rpc.Subscription interface
+func (zs *ZMQClientSubscription) Err() <-chan error {
+	return zs.errChan
+}
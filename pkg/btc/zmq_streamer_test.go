@@ -0,0 +1,123 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package btc_test
+
+import (
+	"context"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/go-zeromq/zmq4"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc/mocks"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+var _ = Describe("ZMQPayloadStreamer", func() {
+	var (
+		chain       []*wire.MsgBlock
+		node        *mocks.Node
+		pub         zmq4.Socket
+		payloadChan chan shared.RawChainData
+		sub         shared.ClientSubscription
+	)
+
+	BeforeEach(func() {
+		chain = mocks.NewMockChain(5)
+		node = mocks.NewNode(chain[:2]...)
+		// The zmq publisher stand-in for bitcoind
+		pub = zmq4.NewPub(context.Background())
+		err := pub.Listen("tcp://127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		streamer := btc.NewZMQPayloadStreamer(&btc.ZMQConfig{
+			Endpoint: "tcp://" + pub.Addr().String(),
+			RPC:      node.ConnConfig(),
+		})
+		payloadChan = make(chan shared.RawChainData, 10)
+		sub, err = streamer.Stream(payloadChan)
+		Expect(err).ToNot(HaveOccurred())
+		// Like the watcher, carry on through errors; the node's chain can change in between the calls of a sync
+		go func(errs <-chan error) {
+			for range errs {
+			}
+		}(sub.Err())
+	})
+
+	AfterEach(func() {
+		sub.Unsubscribe()
+		pub.Close()
+		node.Close()
+	})
+
+	// notify publishes the notification until it is acted on, since a zmq subscription takes effect asynchronously
+	notify := func(topic string, body []byte) {
+		Eventually(func() int {
+			err := pub.Send(zmq4.NewMsgFrom([]byte(topic), body, []byte{0, 0, 0, 0}))
+			Expect(err).ToNot(HaveOccurred())
+			return len(payloadChan)
+		}).ShouldNot(BeZero())
+	}
+
+	expectPayload := func(block *wire.MsgBlock, height int64) {
+		var payload shared.RawChainData
+		Eventually(payloadChan).Should(Receive(&payload))
+		blockPayload, ok := payload.(btc.BlockPayload)
+		Expect(ok).To(BeTrue())
+		Expect(blockPayload.BlockHeight).To(Equal(height))
+		Expect(blockPayload.Header.BlockHash()).To(Equal(block.BlockHash()))
+		Expect(len(blockPayload.Txs)).To(Equal(len(block.Transactions)))
+	}
+
+	Describe("Stream", func() {
+		It("Streams the node's tip on start", func() {
+			expectPayload(chain[1], 1)
+			Consistently(payloadChan).ShouldNot(Receive())
+		})
+
+		It("Streams every block connected since the last streamed block when notified", func() {
+			expectPayload(chain[1], 1)
+			node.Connect(chain[2:4]...)
+			hash := chain[3].BlockHash()
+			notify(btc.HashBlockTopic, hash[:])
+			expectPayload(chain[2], 2)
+			expectPayload(chain[3], 3)
+			Consistently(payloadChan).ShouldNot(Receive())
+		})
+
+		It("Ignores rawblock notifications", func() {
+			expectPayload(chain[1], 1)
+			node.Connect(chain[2])
+			hash := chain[2].BlockHash()
+			notify(btc.HashBlockTopic, hash[:])
+			expectPayload(chain[2], 2)
+			node.Connect(chain[3])
+			err := pub.Send(zmq4.NewMsgFrom([]byte("rawblock"), []byte("raw block"), []byte{0, 0, 0, 0}))
+			Expect(err).ToNot(HaveOccurred())
+			Consistently(payloadChan).ShouldNot(Receive())
+		})
+	})
+
+	Describe("Unsubscribe", func() {
+		It("Can be called more than once", func() {
+			expectPayload(chain[1], 1)
+			sub.Unsubscribe()
+			Expect(sub.Unsubscribe).ToNot(Panic())
+		})
+	})
+})
@@ -27,6 +27,7 @@ import (
 
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/omni"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
 )
@@ -38,13 +39,17 @@ func NewResponseFilterer(chain shared.ChainType) (shared.ResponseFilterer, error
 		return eth.NewResponseFilterer(), nil
 	case shared.Bitcoin:
 		return btc.NewResponseFilterer(), nil
+	case shared.Omni:
+		return omni.NewResponseFilterer(), nil
 	default:
 		return nil, fmt.Errorf("invalid chain %s for filterer constructor", chain.String())
 	}
 }
 
 // NewCIDIndexer constructs a CIDIndexer for the provided chain type
-func NewCIDIndexer(chain shared.ChainType, db *postgres.DB, ipfsMode shared.IPFSMode) (shared.CIDIndexer, error) {
+// For bitcoin and omni the node client, which can be nil, is used to resolve the spent outputs which have not been indexed,
+// and headers are validated against the chain parameters of the bitcoin network
+func NewCIDIndexer(chain shared.ChainType, db *postgres.DB, ipfsMode shared.IPFSMode, client interface{}, btcParams *chaincfg.Params) (shared.CIDIndexer, error) {
 	switch chain {
 	case shared.Ethereum:
 		switch ipfsMode {
@@ -56,14 +61,31 @@ func NewCIDIndexer(chain shared.ChainType, db *postgres.DB, ipfsMode shared.IPFS
 			return nil, fmt.Errorf("ethereum CIDIndexer unexpected ipfs mode %s", ipfsMode.String())
 		}
 	case shared.Bitcoin:
+		prevouts, err := newPrevoutFetcher(client, btcParams)
+		if err != nil {
+			return nil, err
+		}
 		switch ipfsMode {
 		case shared.LocalInterface, shared.RemoteClient:
-			return btc.NewCIDIndexer(db), nil
+			return btc.NewCIDIndexer(db, btcParams, prevouts), nil
 		case shared.DirectPostgres:
-			return eth.NewIPLDPublisherAndIndexer(db), nil
+			return btc.NewIPLDPublisherAndIndexer(db, btcParams, prevouts), nil
 		default:
 			return nil, fmt.Errorf("bitcoin CIDIndexer unexpected ipfs mode %s", ipfsMode.String())
 		}
+	case shared.Omni:
+		prevouts, err := newPrevoutFetcher(client, btcParams)
+		if err != nil {
+			return nil, err
+		}
+		switch ipfsMode {
+		case shared.LocalInterface, shared.RemoteClient:
+			return omni.NewCIDIndexer(db, btcParams, prevouts), nil
+		case shared.DirectPostgres:
+			return omni.NewIPLDPublisherAndIndexer(db, btcParams, prevouts), nil
+		default:
+			return nil, fmt.Errorf("omni CIDIndexer unexpected ipfs mode %s", ipfsMode.String())
+		}
 	default:
 		return nil, fmt.Errorf("invalid chain %s for indexer constructor", chain.String())
 	}
@@ -76,12 +98,15 @@ func NewCIDRetriever(chain shared.ChainType, db *postgres.DB) (shared.CIDRetriev
 		return eth.NewCIDRetriever(db), nil
 	case shared.Bitcoin:
 		return btc.NewCIDRetriever(db), nil
+	case shared.Omni:
+		return omni.NewCIDRetriever(db), nil
 	default:
 		return nil, fmt.Errorf("invalid chain %s for retriever constructor", chain.String())
 	}
 }
 
 // NewPayloadStreamer constructs a PayloadStreamer for the provided chain type
+// For bitcoin a *btc.ZMQConfig selects the zmq notification driven streamer, a *rpcclient.ConnConfig the polling streamer
 func NewPayloadStreamer(chain shared.ChainType, clientOrConfig interface{}) (shared.PayloadStreamer, chan shared.RawChainData, error) {
 	switch chain {
 	case shared.Ethereum:
@@ -91,15 +116,33 @@ func NewPayloadStreamer(chain shared.ChainType, clientOrConfig interface{}) (sha
 		}
 		streamChan := make(chan shared.RawChainData, eth.PayloadChanBufferSize)
 		return eth.NewPayloadStreamer(ethClient), streamChan, nil
+	case shared.Bitcoin, shared.Omni:
+		streamChan := make(chan shared.RawChainData, btc.PayloadChanBufferSize)
+		switch config := clientOrConfig.(type) {
+		case *btc.ZMQConfig:
+			return btc.NewZMQPayloadStreamer(config), streamChan, nil
+		case *rpcclient.ConnConfig:
+			return btc.NewHTTPPayloadStreamer(config), streamChan, nil
+		default:
+			return nil, nil, fmt.Errorf("bitcoin payload streamer constructor expected client config type %T or %T got %T", &btc.ZMQConfig{}, &rpcclient.ConnConfig{}, clientOrConfig)
+		}
+	default:
+		return nil, nil, fmt.Errorf("invalid chain %s for streamer constructor", chain.String())
+	}
+}
+
+// NewMempoolWatcher constructs a MempoolWatcher for the provided chain type, which indexes the mempool in the provided db
+func NewMempoolWatcher(chain shared.ChainType, db *postgres.DB, client interface{}, btcParams *chaincfg.Params) (shared.MempoolWatcher, chan shared.UnconfirmedData, error) {
+	switch chain {
 	case shared.Bitcoin:
-		btcClientConn, ok := clientOrConfig.(*rpcclient.ConnConfig)
+		connConfig, ok := client.(*rpcclient.ConnConfig)
 		if !ok {
-			return nil, nil, fmt.Errorf("bitcoin payload streamer constructor expected client config type %T got %T", rpcclient.ConnConfig{}, clientOrConfig)
+			return nil, nil, fmt.Errorf("bitcoin mempool watcher constructor expected client type %T got %T", &rpcclient.ConnConfig{}, client)
 		}
-		streamChan := make(chan shared.RawChainData, btc.PayloadChanBufferSize)
-		return btc.NewHTTPPayloadStreamer(btcClientConn), streamChan, nil
+		mempoolChan := make(chan shared.UnconfirmedData, btc.PayloadChanBufferSize)
+		return btc.NewMempoolWatcher(connConfig, btcParams, btc.NewMempoolIndexer(db)), mempoolChan, nil
 	default:
-		return nil, nil, fmt.Errorf("invalid chain %s for streamer constructor", chain.String())
+		return nil, nil, fmt.Errorf("invalid chain %s for mempool watcher constructor", chain.String())
 	}
 }
 
@@ -112,7 +155,7 @@ func NewPaylaodFetcher(chain shared.ChainType, client interface{}, timeout time.
 			return nil, fmt.Errorf("ethereum payload fetcher constructor expected client type %T got %T", &rpc.Client{}, client)
 		}
 		return eth.NewPayloadFetcher(batchClient, timeout), nil
-	case shared.Bitcoin:
+	case shared.Bitcoin, shared.Omni:
 		connConfig, ok := client.(*rpcclient.ConnConfig)
 		if !ok {
 			return nil, fmt.Errorf("bitcoin payload fetcher constructor expected client type %T got %T", &rpcclient.Client{}, client)
@@ -124,12 +167,19 @@ func NewPaylaodFetcher(chain shared.ChainType, client interface{}, timeout time.
 }
 
 // NewPayloadConverter constructs a PayloadConverter for the provided chain type
-func NewPayloadConverter(chain shared.ChainType) (shared.PayloadConverter, error) {
+// For omni the node client, which can be nil, is used to resolve the senders of the Omni Layer transactions
+func NewPayloadConverter(chain shared.ChainType, client interface{}, btcParams *chaincfg.Params, ethConfig *params.ChainConfig) (shared.PayloadConverter, error) {
 	switch chain {
 	case shared.Ethereum:
-		return eth.NewPayloadConverter(params.MainnetChainConfig), nil
+		return eth.NewPayloadConverter(ethConfig), nil
 	case shared.Bitcoin:
-		return btc.NewPayloadConverter(&chaincfg.MainNetParams), nil
+		return btc.NewPayloadConverter(btcParams), nil
+	case shared.Omni:
+		prevouts, err := newPrevoutFetcher(client, btcParams)
+		if err != nil {
+			return nil, err
+		}
+		return omni.NewPayloadConverter(btcParams, prevouts), nil
 	default:
 		return nil, fmt.Errorf("invalid chain %s for converter constructor", chain.String())
 	}
@@ -156,13 +206,28 @@ func NewIPLDFetcher(chain shared.ChainType, ipfsPath string, db *postgres.DB, ip
 		default:
 			return nil, fmt.Errorf("bitcoin IPLDFetcher unexpected ipfs mode %s", ipfsMode.String())
 		}
+	case shared.Omni:
+		switch ipfsMode {
+		case shared.LocalInterface, shared.RemoteClient:
+			fetcher, err := btc.NewIPLDFetcher(ipfsPath)
+			if err != nil {
+				return nil, err
+			}
+			return omni.NewIPLDFetcher(fetcher), nil
+		case shared.DirectPostgres:
+			return omni.NewIPLDFetcher(btc.NewIPLDPGFetcher(db)), nil
+		default:
+			return nil, fmt.Errorf("omni IPLDFetcher unexpected ipfs mode %s", ipfsMode.String())
+		}
 	default:
 		return nil, fmt.Errorf("invalid chain %s for IPLD fetcher constructor", chain.String())
 	}
 }
 
 // NewIPLDPublisher constructs an IPLDPublisher for the provided chain type
-func NewIPLDPublisher(chain shared.ChainType, ipfsPath string, db *postgres.DB, ipfsMode shared.IPFSMode) (shared.IPLDPublisher, error) {
+// For bitcoin and omni the node client, which can be nil, is used to resolve the spent outputs which have not been indexed,
+// and headers are validated against the chain parameters of the bitcoin network
+func NewIPLDPublisher(chain shared.ChainType, ipfsPath string, db *postgres.DB, ipfsMode shared.IPFSMode, client interface{}, btcParams *chaincfg.Params) (shared.IPLDPublisher, error) {
 	switch chain {
 	case shared.Ethereum:
 		switch ipfsMode {
@@ -178,31 +243,92 @@ func NewIPLDPublisher(chain shared.ChainType, ipfsPath string, db *postgres.DB,
 		case shared.LocalInterface, shared.RemoteClient:
 			return btc.NewIPLDPublisher(ipfsPath)
 		case shared.DirectPostgres:
-			return btc.NewIPLDPublisherAndIndexer(db), nil
+			prevouts, err := newPrevoutFetcher(client, btcParams)
+			if err != nil {
+				return nil, err
+			}
+			return btc.NewIPLDPublisherAndIndexer(db, btcParams, prevouts), nil
 		default:
 			return nil, fmt.Errorf("bitcoin IPLDPublisher unexpected ipfs mode %s", ipfsMode.String())
 		}
+	case shared.Omni:
+		switch ipfsMode {
+		case shared.LocalInterface, shared.RemoteClient:
+			return omni.NewIPLDPublisher(ipfsPath)
+		case shared.DirectPostgres:
+			prevouts, err := newPrevoutFetcher(client, btcParams)
+			if err != nil {
+				return nil, err
+			}
+			return omni.NewIPLDPublisherAndIndexer(db, btcParams, prevouts), nil
+		default:
+			return nil, fmt.Errorf("omni IPLDPublisher unexpected ipfs mode %s", ipfsMode.String())
+		}
 	default:
 		return nil, fmt.Errorf("invalid chain %s for publisher constructor", chain.String())
 	}
 }
 
+// newPrevoutFetcher constructs a PrevoutFetcher for the bitcoin node client, or returns nil if there is no client
+func newPrevoutFetcher(client interface{}, btcParams *chaincfg.Params) (btc.PrevoutFetcher, error) {
+	if client == nil {
+		return nil, nil
+	}
+	connConfig, ok := client.(*rpcclient.ConnConfig)
+	if !ok {
+		return nil, fmt.Errorf("bitcoin prevout fetcher constructor expected client type %T got %T", &rpcclient.ConnConfig{}, client)
+	}
+	return btc.NewRPCPrevoutFetcher(connConfig, btcParams)
+}
+
+// PublicAPI is a chain's public api along with the listeners that keep it up to date
+type PublicAPI struct {
+	rpc.API
+	// ServeListener is fed the payloads served by the watcher, it can be nil
+	ServeListener shared.PayloadListener
+	// SyncListener is fed the payloads synced by the watcher, it can be nil
+	SyncListener shared.PayloadListener
+}
+
 // NewPublicAPI constructs a PublicAPI for the provided chain type
-func NewPublicAPI(chain shared.ChainType, db *postgres.DB, ipfsPath string) (rpc.API, error) {
+// If an upstream client is provided, the api forwards the calls it cannot answer from the index to it
+// The log limits bound the eth_getLogs queries of an Ethereum api, and are ignored for Bitcoin
+// The bitcoin chain parameters are those of the network a Bitcoin api serves, and are ignored for Ethereum
+// The ethereum chain config is that of the network an Ethereum api serves, and is ignored for Bitcoin
+// Omni is served the Bitcoin api, as the Omni Layer transactions are indexed on top of the bitcoin data
+func NewPublicAPI(chain shared.ChainType, db *postgres.DB, ipfsPath string, upstream *rpc.Client, logLimits eth.LogFilterLimits, btcParams *chaincfg.Params, ethConfig *params.ChainConfig) (PublicAPI, error) {
 	switch chain {
 	case shared.Ethereum:
-		backend, err := eth.NewEthBackend(db)
+		backend, err := eth.NewEthBackend(db, logLimits, ethConfig)
 		if err != nil {
-			return rpc.API{}, err
+			return PublicAPI{}, err
 		}
-		return rpc.API{
-			Namespace: eth.APIName,
-			Version:   eth.APIVersion,
-			Service:   eth.NewPublicEthAPI(backend),
-			Public:    true,
+		api := eth.NewPublicEthAPI(backend, upstream)
+		return PublicAPI{
+			API: rpc.API{
+				Namespace: eth.APIName,
+				Version:   eth.APIVersion,
+				Service:   api,
+				Public:    true,
+			},
+			ServeListener: api.Events,
+			SyncListener:  api.Fees,
+		}, nil
+	case shared.Bitcoin, shared.Omni:
+		backend, err := btc.NewBtcBackend(db, btcParams)
+		if err != nil {
+			return PublicAPI{}, err
+		}
+		return PublicAPI{
+			API: rpc.API{
+				Namespace: btc.APIName,
+				Version:   btc.APIVersion,
+				Service:   btc.NewPublicBtcAPI(backend),
+				Public:    true,
+			},
 		}, nil
 	default:
-		return rpc.API{}, fmt.Errorf("invalid chain %s for public api constructor", chain.String())
+		return PublicAPI{}, fmt.Errorf("invalid chain %s for public api constructor", chain.String())
 	}
 }
 
@@ -211,7 +337,8 @@ func NewCleaner(chain shared.ChainType, db *postgres.DB) (shared.Cleaner, error)
 	switch chain {
 	case shared.Ethereum:
 		return eth.NewCleaner(db), nil
-	case shared.Bitcoin:
+	case shared.Bitcoin, shared.Omni:
+		// The Omni Layer transactions are removed along with the bitcoin transactions carrying them
 		return btc.NewCleaner(db), nil
 	default:
 		return nil, fmt.Errorf("invalid chain %s for cleaner constructor", chain.String())
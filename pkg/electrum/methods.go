@@ -0,0 +1,444 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package electrum
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	v "github.com/vulcanize/ipfs-blockchain-watcher/version"
+)
+
+const (
+	// maxHeaders is the greatest number of headers returned by a single blockchain.block.headers call
+	maxHeaders = 2016
+	// relayFee is the minimum relay fee reported to clients, bitcoind's default, in BTC per kilobyte
+	relayFee = 0.00001
+)
+
+// serverSoftware is the server's name and version reported by server.version, server.banner and server.features
+var serverSoftware = "ipfs-blockchain-watcher " + v.VersionWithMeta
+
+// headerResult is a block header as returned by blockchain.headers.subscribe and its notifications
+type headerResult struct {
+	Height int64  `json:"height"`
+	Hex    string `json:"hex"`
+}
+
+// headersResult is the response to blockchain.block.headers
+type headersResult struct {
+	Count int    `json:"count"`
+	Hex   string `json:"hex"`
+	Max   int    `json:"max"`
+}
+
+// merkleResult is the response to blockchain.transaction.get_merkle
+type merkleResult struct {
+	BlockHeight int64    `json:"block_height"`
+	Merkle      []string `json:"merkle"`
+	Pos         int      `json:"pos"`
+}
+
+// balanceResult is the response to blockchain.scripthash.get_balance, its values are denominated in satoshis
+// The watcher does not index the mempool so the unconfirmed balance is always 0
+type balanceResult struct {
+	Confirmed   int64 `json:"confirmed"`
+	Unconfirmed int64 `json:"unconfirmed"`
+}
+
+// historyResult is an entry of the response to blockchain.scripthash.get_history
+type historyResult struct {
+	TxHash string `json:"tx_hash"`
+	Height int64  `json:"height"`
+}
+
+// unspentResult is an entry of the response to blockchain.scripthash.listunspent, its value is denominated in satoshis
+type unspentResult struct {
+	TxHash string `json:"tx_hash"`
+	TxPos  int64  `json:"tx_pos"`
+	Height int64  `json:"height"`
+	Value  int64  `json:"value"`
+}
+
+// serverVersion negotiates the protocol version; it answers with the one version the server speaks whatever the client asks for
+func (s *Server) serverVersion(sess *session, params []json.RawMessage) (interface{}, error) {
+	var clientName string
+	var protocolVersion interface{}
+	if err := parseParams(params, 0, &clientName, &protocolVersion); err != nil {
+		return nil, err
+	}
+	return []string{serverSoftware, ProtocolVersion}, nil
+}
+
+func (s *Server) serverBanner(sess *session, params []json.RawMessage) (interface{}, error) {
+	return fmt.Sprintf("Welcome to %s, serving blocks indexed in PG-IPFS", serverSoftware), nil
+}
+
+func (s *Server) serverDonationAddress(sess *session, params []json.RawMessage) (interface{}, error) {
+	return "", nil
+}
+
+func (s *Server) serverFeatures(sess *session, params []json.RawMessage) (interface{}, error) {
+	return map[string]interface{}{
+		"genesis_hash":   s.backend.Params.GenesisHash.String(),
+		"hosts":          map[string]interface{}{},
+		"protocol_max":   ProtocolVersion,
+		"protocol_min":   ProtocolVersion,
+		"pruning":        nil,
+		"server_version": serverSoftware,
+		"hash_function":  "sha256",
+	}, nil
+}
+
+// serverPeersSubscribe returns no peers, the server does not take part in peer discovery
+func (s *Server) serverPeersSubscribe(sess *session, params []json.RawMessage) (interface{}, error) {
+	return []interface{}{}, nil
+}
+
+func (s *Server) serverPing(sess *session, params []json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+// headersSubscribe returns the header of the chain head, and subscribes the session to new chain heads
+func (s *Server) headersSubscribe(sess *session, params []json.RawMessage) (interface{}, error) {
+	tip, err := s.chainTip()
+	if err != nil {
+		return nil, err
+	}
+	s.Lock()
+	sess.headers = true
+	s.Unlock()
+	return tip, nil
+}
+
+// blockHeader returns the hex encoded canonical header at the provided height
+// Checkpoint proofs are not supported, so cp_height must be 0
+func (s *Server) blockHeader(sess *session, params []json.RawMessage) (interface{}, error) {
+	var height, cpHeight int64
+	if err := parseParams(params, 1, &height, &cpHeight); err != nil {
+		return nil, err
+	}
+	if cpHeight != 0 {
+		return nil, newError(badRequest, "checkpoint proofs are not supported")
+	}
+	header, _, err := s.backend.HeaderByNumber(height)
+	if err == sql.ErrNoRows {
+		return nil, newError(badRequest, "height %d out of range", height)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return serializeHeader(header)
+}
+
+// blockHeaders returns up to count concatenated hex encoded canonical headers starting at the provided height
+func (s *Server) blockHeaders(sess *session, params []json.RawMessage) (interface{}, error) {
+	var start, count, cpHeight int64
+	if err := parseParams(params, 2, &start, &count, &cpHeight); err != nil {
+		return nil, err
+	}
+	if cpHeight != 0 {
+		return nil, newError(badRequest, "checkpoint proofs are not supported")
+	}
+	if start < 0 || count < 0 {
+		return nil, newError(badRequest, "start height and count must be non-negative")
+	}
+	if count > maxHeaders {
+		count = maxHeaders
+	}
+	res := &headersResult{Max: maxHeaders}
+	if count == 0 {
+		return res, nil
+	}
+	headers, err := s.backend.Headers(start, count)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, header := range headers {
+		if err := header.Serialize(&buf); err != nil {
+			return nil, err
+		}
+	}
+	res.Count = len(headers)
+	res.Hex = hex.EncodeToString(buf.Bytes())
+	return res, nil
+}
+
+// estimateFee returns -1, the watcher does not track the mempool to estimate fees from
+func (s *Server) estimateFee(sess *session, params []json.RawMessage) (interface{}, error) {
+	return -1, nil
+}
+
+func (s *Server) relayFee(sess *session, params []json.RawMessage) (interface{}, error) {
+	return relayFee, nil
+}
+
+// transactionGet returns the hex encoded transaction, or the decoded transaction if verbose
+func (s *Server) transactionGet(sess *session, params []json.RawMessage) (interface{}, error) {
+	var txHash string
+	var verbose bool
+	if err := parseParams(params, 1, &txHash, &verbose); err != nil {
+		return nil, err
+	}
+	res, err := s.api.GetRawTransaction(txHash, &verbose, nil)
+	if err != nil {
+		return nil, newError(badRequest, "%v", err)
+	}
+	return res, nil
+}
+
+// transactionGetMerkle returns the merkle branch proving the transaction is included in the canonical block at the provided height
+func (s *Server) transactionGetMerkle(sess *session, params []json.RawMessage) (interface{}, error) {
+	var txHash string
+	var height int64
+	if err := parseParams(params, 2, &txHash, &height); err != nil {
+		return nil, err
+	}
+	hash, err := chainhash.NewHashFromStr(txHash)
+	if err != nil {
+		return nil, newError(badRequest, "invalid tx hash %s", txHash)
+	}
+	_, header, blockCtx, err := s.backend.TransactionByHash(*hash)
+	if err == sql.ErrNoRows {
+		return nil, newError(badRequest, "tx %s not found", txHash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if blockCtx.Confirmations < 0 || blockCtx.Height != height {
+		return nil, newError(badRequest, "tx %s not in the block at height %d", txHash, height)
+	}
+	block, _, err := s.backend.BlockByHash(header.BlockHash())
+	if err != nil {
+		return nil, err
+	}
+	txHashes := make([]chainhash.Hash, len(block.Transactions))
+	pos := -1
+	for i, trx := range block.Transactions {
+		txHashes[i] = trx.TxHash()
+		if txHashes[i] == *hash {
+			pos = i
+		}
+	}
+	if pos < 0 {
+		return nil, newError(badRequest, "tx %s not in the block at height %d", txHash, height)
+	}
+	return &merkleResult{
+		BlockHeight: height,
+		Merkle:      merkleBranch(txHashes, pos),
+		Pos:         pos,
+	}, nil
+}
+
+// scriptHashGetBalance returns the value of the unspent outputs with the scripthash
+func (s *Server) scriptHashGetBalance(sess *session, params []json.RawMessage) (interface{}, error) {
+	scriptHash, _, err := parseScriptHash(params)
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := s.backend.ScriptHashUTXOs(scriptHash)
+	if err != nil {
+		return nil, err
+	}
+	res := &balanceResult{}
+	for _, utxo := range utxos {
+		res.Confirmed += utxo.Value
+	}
+	return res, nil
+}
+
+// scriptHashGetHistory returns the transactions which pay to or spend an output with the scripthash
+func (s *Server) scriptHashGetHistory(sess *session, params []json.RawMessage) (interface{}, error) {
+	scriptHash, _, err := parseScriptHash(params)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.backend.ScriptHashHistory(scriptHash)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]historyResult, len(history))
+	for i, entry := range history {
+		res[i] = historyResult{
+			TxHash: entry.TxHash,
+			Height: entry.BlockNumber,
+		}
+	}
+	return res, nil
+}
+
+// scriptHashGetMempool returns nothing, the watcher does not index the mempool
+func (s *Server) scriptHashGetMempool(sess *session, params []json.RawMessage) (interface{}, error) {
+	if _, _, err := parseScriptHash(params); err != nil {
+		return nil, err
+	}
+	return []interface{}{}, nil
+}
+
+// scriptHashListUnspent returns the unspent outputs with the scripthash
+func (s *Server) scriptHashListUnspent(sess *session, params []json.RawMessage) (interface{}, error) {
+	scriptHash, _, err := parseScriptHash(params)
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := s.backend.ScriptHashUTXOs(scriptHash)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]unspentResult, len(utxos))
+	for i, utxo := range utxos {
+		res[i] = unspentResult{
+			TxHash: utxo.TxHash,
+			TxPos:  utxo.Index,
+			Height: utxo.BlockNumber,
+			Value:  utxo.Value,
+		}
+	}
+	return res, nil
+}
+
+// scriptHashSubscribe returns the status of the scripthash, and subscribes the session to changes of it
+func (s *Server) scriptHashSubscribe(sess *session, params []json.RawMessage) (interface{}, error) {
+	scriptHash, key, err := parseScriptHash(params)
+	if err != nil {
+		return nil, err
+	}
+	status, err := s.status(scriptHash)
+	if err != nil {
+		return nil, err
+	}
+	s.Lock()
+	sess.scriptHashes[key] = status
+	s.Unlock()
+	return status, nil
+}
+
+// scriptHashUnsubscribe unsubscribes the session from the scripthash, returning whether or not it was subscribed
+func (s *Server) scriptHashUnsubscribe(sess *session, params []json.RawMessage) (interface{}, error) {
+	_, key, err := parseScriptHash(params)
+	if err != nil {
+		return nil, err
+	}
+	s.Lock()
+	defer s.Unlock()
+	_, subscribed := sess.scriptHashes[key]
+	delete(sess.scriptHashes, key)
+	return subscribed, nil
+}
+
+// mempoolGetFeeHistogram returns an empty histogram, the watcher does not index the mempool
+func (s *Server) mempoolGetFeeHistogram(sess *session, params []json.RawMessage) (interface{}, error) {
+	return []interface{}{}, nil
+}
+
+// chainTip returns the header of the canonical chain head
+func (s *Server) chainTip() (*headerResult, error) {
+	head, err := s.backend.Retriever.RetrieveLastBlockNumber()
+	if err != nil {
+		return nil, err
+	}
+	header, _, err := s.backend.HeaderByNumber(head)
+	if err != nil {
+		return nil, err
+	}
+	headerHex, err := serializeHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	return &headerResult{
+		Height: head,
+		Hex:    headerHex,
+	}, nil
+}
+
+// status returns the Electrum status of the scripthash, nil if it has no history
+func (s *Server) status(scriptHash []byte) (*string, error) {
+	history, err := s.backend.ScriptHashHistory(scriptHash)
+	if err != nil {
+		return nil, err
+	}
+	return historyStatus(history), nil
+}
+
+// historyStatus hashes the history of a scripthash into its status, the sha256 of each tx_hash:height: entry concatenated
+func historyStatus(history []btc.HistoryModel) *string {
+	if len(history) == 0 {
+		return nil
+	}
+	h := sha256.New()
+	for _, entry := range history {
+		fmt.Fprintf(h, "%s:%d:", entry.TxHash, entry.BlockNumber)
+	}
+	status := hex.EncodeToString(h.Sum(nil))
+	return &status
+}
+
+// parseScriptHash decodes the scripthash param, the reversed sha256 of an output script, into the sha256 it is indexed by
+// It also returns the scripthash in the canonical form it is keyed by in subscriptions
+func parseScriptHash(params []json.RawMessage) ([]byte, string, error) {
+	var scriptHash string
+	if err := parseParams(params, 1, &scriptHash); err != nil {
+		return nil, "", err
+	}
+	if len(scriptHash) != 2*chainhash.HashSize {
+		return nil, "", newError(badRequest, "invalid scripthash %s", scriptHash)
+	}
+	hash, err := chainhash.NewHashFromStr(scriptHash)
+	if err != nil {
+		return nil, "", newError(badRequest, "invalid scripthash %s", scriptHash)
+	}
+	return hash[:], hash.String(), nil
+}
+
+// serializeHeader hex encodes the header
+func serializeHeader(header *wire.BlockHeader) (string, error) {
+	var buf bytes.Buffer
+	if err := header.Serialize(&buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+// merkleBranch returns the hashes, from the bottom of the tree up, which combine with the hash at pos into the merkle root
+func merkleBranch(hashes []chainhash.Hash, pos int) []string {
+	level := make([]chainhash.Hash, len(hashes))
+	copy(level, hashes)
+	branch := make([]string, 0)
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		branch = append(branch, level[pos^1].String())
+		next := make([]chainhash.Hash, len(level)/2)
+		for i := range next {
+			next[i] = *blockchain.HashMerkleBranches(&level[2*i], &level[2*i+1])
+		}
+		level = next
+		pos /= 2
+	}
+	return branch
+}
@@ -0,0 +1,207 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package electrum
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+const (
+	// notificationBufferSize is the number of served blocks which can be queued for notification
+	notificationBufferSize = 100
+	// indexPollInterval is how often a served block is checked for in the index before its subscribers are notified
+	indexPollInterval = 500 * time.Millisecond
+	// indexTimeout is how long a served block is waited on to be indexed before it is skipped
+	indexTimeout = 5 * time.Minute
+)
+
+// servedBlock is a block served by the watcher, to notify subscribers of
+type servedBlock struct {
+	hash   chainhash.Hash
+	height int64
+}
+
+// subscription is a session's subscription to a scripthash
+type subscription struct {
+	sess       *session
+	key        string
+	lastStatus *string
+}
+
+// Notify satisfies the shared.PayloadListener interface
+// It queues the served block, subscribers are notified once the block has been indexed
+func (s *Server) Notify(payload shared.ConvertedData) {
+	btcPayload, ok := payload.(btc.ConvertedPayload)
+	if !ok {
+		log.Errorf("electrum server: expected payload type %T got %T", btc.ConvertedPayload{}, payload)
+		return
+	}
+	block := servedBlock{
+		hash:   btcPayload.Header.BlockHash(),
+		height: btcPayload.Height(),
+	}
+	select {
+	case s.blocks <- block:
+	default:
+		// The next block notified refreshes every subscription, which covers the changes made by the dropped block
+		log.Warnf("electrum server: notification queue full, dropping block %d", block.height)
+		s.Lock()
+		s.refreshAll = true
+		s.Unlock()
+	}
+}
+
+// NotifyReorg satisfies the shared.PayloadListener interface
+// A reorg can change the history of any scripthash, so the next block notified refreshes every subscription
+func (s *Server) NotifyReorg(forkHeight int64) {
+	s.Lock()
+	s.refreshAll = true
+	s.Unlock()
+}
+
+// notifyLoop notifies the subscribed sessions of each queued block once it is indexed
+func (s *Server) notifyLoop() {
+	for {
+		select {
+		case block := <-s.blocks:
+			scriptHashes, err := s.waitForIndexing(block)
+			if err != nil {
+				log.Errorf("electrum server: skipping notifications for block %d: %v", block.height, err)
+				continue
+			}
+			s.notifyBlock(scriptHashes)
+		case <-s.quit:
+			log.Info("quitting electrum notification process")
+			return
+		}
+	}
+}
+
+// waitForIndexing polls the index until the block is found, and returns the script hashes its transactions touch
+// Blocks are served before they are indexed, so the index can lag the Serve process
+func (s *Server) waitForIndexing(block servedBlock) ([][]byte, error) {
+	deadline := time.Now().Add(indexTimeout)
+	for {
+		scriptHashes, err := s.backend.ScriptHashesByBlockHash(block.hash)
+		if err != sql.ErrNoRows {
+			return scriptHashes, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("block %s was not indexed within %s", block.hash, indexTimeout)
+		}
+		select {
+		case <-time.After(indexPollInterval):
+		case <-s.quit:
+			return nil, fmt.Errorf("electrum server stopped")
+		}
+	}
+}
+
+// notifyBlock notifies the header subscribers of a new chain head, and the scripthash subscribers whose status the
+// block changed; after a reorg every scripthash subscription is refreshed
+func (s *Server) notifyBlock(scriptHashes [][]byte) {
+	touched := make(map[string]bool, len(scriptHashes))
+	for _, scriptHash := range scriptHashes {
+		hash, err := chainhash.NewHash(scriptHash)
+		if err != nil {
+			log.Errorf("electrum server: invalid indexed script hash %x: %v", scriptHash, err)
+			continue
+		}
+		touched[hash.String()] = true
+	}
+
+	tip, err := s.chainTip()
+	if err != nil {
+		log.Errorf("electrum server: unable to retrieve the chain tip: %v", err)
+	}
+	s.Lock()
+	refreshAll := s.refreshAll
+	s.refreshAll = false
+	headerSubscribers := make([]*session, 0)
+	newTip := tip != nil && (s.tip == nil || *s.tip != *tip)
+	if newTip {
+		s.tip = tip
+	}
+	subs := make([]subscription, 0)
+	for sess := range s.sessions {
+		if newTip && sess.headers {
+			headerSubscribers = append(headerSubscribers, sess)
+		}
+		for key, lastStatus := range sess.scriptHashes {
+			if refreshAll || touched[key] {
+				subs = append(subs, subscription{sess: sess, key: key, lastStatus: lastStatus})
+			}
+		}
+	}
+	s.Unlock()
+
+	for _, sess := range headerSubscribers {
+		s.sendNotification(sess, "blockchain.headers.subscribe", tip)
+	}
+	statuses := make(map[string]*string)
+	for _, sub := range subs {
+		status, ok := statuses[sub.key]
+		if !ok {
+			hash, err := chainhash.NewHashFromStr(sub.key)
+			if err != nil {
+				log.Errorf("electrum server: invalid subscribed scripthash %s: %v", sub.key, err)
+				continue
+			}
+			status, err = s.status(hash[:])
+			if err != nil {
+				log.Errorf("electrum server: unable to compute the status of scripthash %s: %v", sub.key, err)
+				continue
+			}
+			statuses[sub.key] = status
+		}
+		if sameStatus(status, sub.lastStatus) {
+			continue
+		}
+		s.Lock()
+		_, subscribed := sub.sess.scriptHashes[sub.key]
+		if subscribed {
+			sub.sess.scriptHashes[sub.key] = status
+		}
+		s.Unlock()
+		if subscribed {
+			s.sendNotification(sub.sess, "blockchain.scripthash.subscribe", sub.key, status)
+		}
+	}
+}
+
+// sendNotification sends the notification to the session, logging failures; the session is closed by its reader
+func (s *Server) sendNotification(sess *session, method string, params ...interface{}) {
+	if err := sess.send(&notification{JSONRPC: "2.0", Method: method, Params: params}); err != nil {
+		log.Debugf("electrum session %s: unable to send %s notification: %v", sess.conn.RemoteAddr(), method, err)
+	}
+}
+
+// sameStatus returns whether or not two statuses, nil for no history, are equal
+func sameStatus(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,298 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package electrum
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+)
+
+// ProtocolVersion is the version of the Electrum protocol spoken by the server
+const ProtocolVersion = "1.4"
+
+// maxRequestSize bounds the length of a single line, and so of a single request or batch of requests
+const maxRequestSize = 1 << 20
+
+// json-rpc and Electrum error codes
+const (
+	parseError     = -32700
+	invalidRequest = -32600
+	methodNotFound = -32601
+	invalidParams  = -32602
+	badRequest     = 1
+)
+
+// rpcError is a json-rpc error object
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// newError returns an rpcError with the provided code and formatted message
+func newError(code int, format string, args ...interface{}) *rpcError {
+	return &rpcError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// request is a json-rpc request; Electrum requests carry positional params
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// response is a json-rpc response, it carries either a result, which may be null, or an error
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// notification is a json-rpc notification sent to a subscribed session
+type notification struct {
+	JSONRPC string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// handler answers a method call from a session with its decoded positional params
+type handler func(sess *session, params []json.RawMessage) (interface{}, error)
+
+// Server answers Electrum protocol requests, sent as line-delimited json-rpc over tcp, from the indexed bitcoin data
+// It satisfies the shared.PayloadListener interface, notifying its subscribed sessions of the blocks served by the watcher
+type Server struct {
+	// Guards the sessions, their subscriptions and the notification state
+	sync.Mutex
+	backend  *btc.Backend
+	api      *btc.PublicBtcAPI
+	handlers map[string]handler
+	sessions map[*session]struct{}
+	// blocks queues the served blocks to notify subscribers of once they are indexed
+	blocks chan servedBlock
+	// refreshAll is set by a reorg, it makes the next notified block refresh every scripthash subscription
+	refreshAll bool
+	// tip is the last header notified to header subscribers
+	tip  *headerResult
+	quit chan bool
+}
+
+// NewServer returns a new Server reading from the provided backend, and starts its notification process
+func NewServer(backend *btc.Backend) *Server {
+	s := &Server{
+		backend:  backend,
+		api:      btc.NewPublicBtcAPI(backend),
+		sessions: make(map[*session]struct{}),
+		blocks:   make(chan servedBlock, notificationBufferSize),
+		quit:     make(chan bool),
+	}
+	s.handlers = map[string]handler{
+		"server.version":                    s.serverVersion,
+		"server.banner":                     s.serverBanner,
+		"server.donation_address":           s.serverDonationAddress,
+		"server.features":                   s.serverFeatures,
+		"server.peers.subscribe":            s.serverPeersSubscribe,
+		"server.ping":                       s.serverPing,
+		"blockchain.headers.subscribe":      s.headersSubscribe,
+		"blockchain.block.header":           s.blockHeader,
+		"blockchain.block.headers":          s.blockHeaders,
+		"blockchain.estimatefee":            s.estimateFee,
+		"blockchain.relayfee":               s.relayFee,
+		"blockchain.transaction.get":        s.transactionGet,
+		"blockchain.transaction.get_merkle": s.transactionGetMerkle,
+		"blockchain.scripthash.get_balance": s.scriptHashGetBalance,
+		"blockchain.scripthash.get_history": s.scriptHashGetHistory,
+		"blockchain.scripthash.get_mempool": s.scriptHashGetMempool,
+		"blockchain.scripthash.listunspent": s.scriptHashListUnspent,
+		"blockchain.scripthash.subscribe":   s.scriptHashSubscribe,
+		"blockchain.scripthash.unsubscribe": s.scriptHashUnsubscribe,
+		"mempool.get_fee_histogram":         s.mempoolGetFeeHistogram,
+	}
+	go s.notifyLoop()
+	return s
+}
+
+// StartTCPEndpoint starts an Electrum server on the provided endpoint, it returns the server and the endpoint's listener
+func StartTCPEndpoint(endpoint string, backend *btc.Backend) (*Server, net.Listener, error) {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	s := NewServer(backend)
+	go s.Serve(listener)
+	return s, listener, nil
+}
+
+// Serve accepts connections on the listener, serving each in its own session, until the listener is closed
+func (s *Server) Serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Infof("electrum server no longer accepting connections: %v", err)
+			return
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// Stop shuts down the notification process
+func (s *Server) Stop() {
+	close(s.quit)
+}
+
+// serveConn reads and answers the requests sent over the connection, one line at a time, until it is closed
+func (s *Server) serveConn(conn net.Conn) {
+	sess := &session{
+		conn:         conn,
+		scriptHashes: make(map[string]*string),
+	}
+	s.Lock()
+	s.sessions[sess] = struct{}{}
+	s.Unlock()
+	defer func() {
+		s.Lock()
+		delete(s.sessions, sess)
+		s.Unlock()
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), maxRequestSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := sess.send(s.handleLine(sess, line)); err != nil {
+			log.Debugf("electrum session %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Debugf("electrum session %s: %v", conn.RemoteAddr(), err)
+	}
+}
+
+// handleLine answers a single request, or a batch of requests
+func (s *Server) handleLine(sess *session, line []byte) interface{} {
+	if line[0] != '[' {
+		return s.handleRequest(sess, line)
+	}
+	var batch []json.RawMessage
+	if err := json.Unmarshal(line, &batch); err != nil {
+		return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: newError(parseError, "invalid JSON: %v", err)}
+	}
+	if len(batch) == 0 {
+		return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: newError(invalidRequest, "empty batch")}
+	}
+	responses := make([]*response, len(batch))
+	for i, req := range batch {
+		responses[i] = s.handleRequest(sess, req)
+	}
+	return responses
+}
+
+// handleRequest decodes the request, dispatches it to the handler of its method and wraps the outcome in a response
+func (s *Server) handleRequest(sess *session, raw []byte) *response {
+	res := &response{JSONRPC: "2.0", ID: json.RawMessage("null")}
+	var req request
+	if err := json.Unmarshal(raw, &req); err != nil {
+		res.Error = newError(parseError, "invalid JSON: %v", err)
+		return res
+	}
+	if req.ID != nil {
+		res.ID = req.ID
+	}
+	handle, ok := s.handlers[req.Method]
+	if !ok {
+		res.Error = newError(methodNotFound, "unknown method %q", req.Method)
+		return res
+	}
+	var params []json.RawMessage
+	if len(req.Params) > 0 && !bytes.Equal(req.Params, []byte("null")) {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			res.Error = newError(invalidParams, "params must be an array")
+			return res
+		}
+	}
+	result, err := handle(sess, params)
+	if err != nil {
+		rpcErr, ok := err.(*rpcError)
+		if !ok {
+			log.Errorf("electrum server %s: %v", req.Method, err)
+			rpcErr = newError(badRequest, "%v", err)
+		}
+		res.Error = rpcErr
+		return res
+	}
+	res.Result, err = json.Marshal(result)
+	if err != nil {
+		res.Error = newError(badRequest, "%v", err)
+	}
+	return res
+}
+
+// parseParams decodes the positional params into the args, of which the first required must be present
+func parseParams(params []json.RawMessage, required int, args ...interface{}) error {
+	if len(params) < required || len(params) > len(args) {
+		return newError(invalidParams, "expected between %d and %d params, got %d", required, len(args), len(params))
+	}
+	for i, param := range params {
+		if err := json.Unmarshal(param, args[i]); err != nil {
+			return newError(invalidParams, "invalid param %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// session is a connected client
+type session struct {
+	conn net.Conn
+	// Serializes the responses and notifications written to the connection
+	writeMu sync.Mutex
+	// The subscription fields are guarded by the Server's lock
+	headers bool
+	// scriptHashes maps the subscribed scripthashes to the status last sent for them, nil if they have no history
+	scriptHashes map[string]*string
+}
+
+// send writes the message to the connection as a single line
+func (sess *session) send(msg interface{}) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	sess.writeMu.Lock()
+	defer sess.writeMu.Unlock()
+	_, err = sess.conn.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,311 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package electrum_test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc/mocks"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/electrum"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// message is a response or notification received from the server
+type message struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// client is a connection to the server under test
+type client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID int
+}
+
+func dial(listener net.Listener) *client {
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	Expect(err).ToNot(HaveOccurred())
+	return &client{conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// send writes the line to the server and returns the line it answers with
+func (c *client) send(line string) []byte {
+	_, err := c.conn.Write([]byte(line + "\n"))
+	Expect(err).ToNot(HaveOccurred())
+	return c.read()
+}
+
+func (c *client) read() []byte {
+	Expect(c.conn.SetReadDeadline(time.Now().Add(10 * time.Second))).To(Succeed())
+	line, err := c.reader.ReadBytes('\n')
+	Expect(err).ToNot(HaveOccurred())
+	return line
+}
+
+// call sends a request for the method and returns the response to it
+func (c *client) call(method string, params ...interface{}) message {
+	c.nextID++
+	req, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      c.nextID,
+		"method":  method,
+		"params":  params,
+	})
+	Expect(err).ToNot(HaveOccurred())
+	var res message
+	Expect(json.Unmarshal(c.send(string(req)), &res)).To(Succeed())
+	Expect(string(res.ID)).To(Equal(fmt.Sprint(c.nextID)))
+	return res
+}
+
+// result calls the method and decodes its result into the out value
+func (c *client) result(out interface{}, method string, params ...interface{}) {
+	res := c.call(method, params...)
+	Expect(res.Error).To(BeNil())
+	Expect(json.Unmarshal(res.Result, out)).To(Succeed())
+}
+
+// scriptHash returns the Electrum scripthash of the pk script
+func scriptHash(pkScript []byte) string {
+	return chainhash.Hash(sha256.Sum256(pkScript)).String()
+}
+
+// childPayload converts a block holding the transactions which extends the mock block
+func childPayload(txs ...*wire.MsgTx) btc.ConvertedPayload {
+	header := mocks.MockBlock.Header
+	header.PrevBlock = mocks.MockBlock.BlockHash()
+	header.Timestamp = header.Timestamp.Add(10 * time.Minute)
+	blockTxs := make([]*btcutil.Tx, len(txs))
+	for i, trx := range txs {
+		blockTxs[i] = btcutil.NewTx(trx)
+	}
+	payload, err := btc.NewPayloadConverter(&chaincfg.MainNetParams).Convert(btc.BlockPayload{
+		BlockHeight: mocks.MockBlockHeight + 1,
+		Header:      &header,
+		Txs:         blockTxs,
+	})
+	Expect(err).ToNot(HaveOccurred())
+	return payload.(btc.ConvertedPayload)
+}
+
+var _ = Describe("Server", func() {
+	var (
+		db       *postgres.DB
+		server   *electrum.Server
+		listener net.Listener
+		c        *client
+		spentTx  = mocks.MockBlock.Transactions[1]
+		watched  = scriptHash(spentTx.TxOut[0].PkScript)
+	)
+	BeforeEach(func() {
+		var err error
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		backend, err := btc.NewBtcBackend(db, &chaincfg.MainNetParams)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = btc.NewIPLDPublisherAndIndexer(db, nil, nil).Publish(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+		server, listener, err = electrum.StartTCPEndpoint("127.0.0.1:0", backend)
+		Expect(err).ToNot(HaveOccurred())
+		c = dial(listener)
+	})
+	AfterEach(func() {
+		c.conn.Close()
+		listener.Close()
+		server.Stop()
+		btc.TearDownDB(db)
+	})
+
+	Describe("Protocol", func() {
+		It("Negotiates the protocol version", func() {
+			var version []string
+			c.result(&version, "server.version", "test", "1.4")
+			Expect(version).To(HaveLen(2))
+			Expect(version[1]).To(Equal(electrum.ProtocolVersion))
+		})
+
+		It("Returns json-rpc errors for unknown methods and invalid params", func() {
+			res := c.call("blockchain.unknown")
+			Expect(res.Error).ToNot(BeNil())
+			Expect(res.Error.Code).To(Equal(-32601))
+			res = c.call("blockchain.scripthash.get_balance", "notahash")
+			Expect(res.Error).ToNot(BeNil())
+			var res2 message
+			Expect(json.Unmarshal(c.send("{"), &res2)).To(Succeed())
+			Expect(res2.Error.Code).To(Equal(-32700))
+		})
+
+		It("Answers batches of requests", func() {
+			var batch []message
+			line := `[{"jsonrpc":"2.0","id":1,"method":"server.ping"},{"jsonrpc":"2.0","id":2,"method":"blockchain.relayfee"}]`
+			Expect(json.Unmarshal(c.send(line), &batch)).To(Succeed())
+			Expect(batch).To(HaveLen(2))
+			Expect(string(batch[0].ID)).To(Equal("1"))
+			Expect(string(batch[1].ID)).To(Equal("2"))
+			Expect(batch[1].Error).To(BeNil())
+		})
+	})
+
+	Describe("Blocks and transactions", func() {
+		It("Returns the serialized headers", func() {
+			var buf bytes.Buffer
+			Expect(mocks.MockBlock.Header.Serialize(&buf)).To(Succeed())
+			var header string
+			c.result(&header, "blockchain.block.header", mocks.MockBlockHeight)
+			Expect(header).To(Equal(hex.EncodeToString(buf.Bytes())))
+
+			var tip struct {
+				Height int64  `json:"height"`
+				Hex    string `json:"hex"`
+			}
+			c.result(&tip, "blockchain.headers.subscribe")
+			Expect(tip.Height).To(Equal(mocks.MockBlockHeight))
+			Expect(tip.Hex).To(Equal(header))
+
+			res := c.call("blockchain.block.header", mocks.MockBlockHeight+1)
+			Expect(res.Error).ToNot(BeNil())
+		})
+
+		It("Returns the serialized transactions and their merkle branches", func() {
+			var buf bytes.Buffer
+			Expect(spentTx.Serialize(&buf)).To(Succeed())
+			var raw string
+			c.result(&raw, "blockchain.transaction.get", spentTx.TxHash().String())
+			Expect(raw).To(Equal(hex.EncodeToString(buf.Bytes())))
+
+			var merkle struct {
+				BlockHeight int64    `json:"block_height"`
+				Merkle      []string `json:"merkle"`
+				Pos         int      `json:"pos"`
+			}
+			c.result(&merkle, "blockchain.transaction.get_merkle", spentTx.TxHash().String(), mocks.MockBlockHeight)
+			Expect(merkle.BlockHeight).To(Equal(mocks.MockBlockHeight))
+			Expect(merkle.Pos).To(Equal(1))
+			// fold the branch back into the merkle root
+			txs := make([]*btcutil.Tx, len(mocks.MockBlock.Transactions))
+			for i, trx := range mocks.MockBlock.Transactions {
+				txs[i] = btcutil.NewTx(trx)
+			}
+			root := blockchain.BuildMerkleTreeStore(txs, false)
+			hash := spentTx.TxHash()
+			pos := merkle.Pos
+			for _, branch := range merkle.Merkle {
+				sibling, err := chainhash.NewHashFromStr(branch)
+				Expect(err).ToNot(HaveOccurred())
+				if pos%2 == 0 {
+					hash = *blockchain.HashMerkleBranches(&hash, sibling)
+				} else {
+					hash = *blockchain.HashMerkleBranches(sibling, &hash)
+				}
+				pos /= 2
+			}
+			Expect(hash).To(Equal(*root[len(root)-1]))
+			Expect(hash).To(Equal(mocks.MockBlock.Header.MerkleRoot))
+		})
+	})
+
+	Describe("Scripthashes", func() {
+		It("Returns the balance, history and unspent outputs of a scripthash", func() {
+			var balance struct {
+				Confirmed   int64 `json:"confirmed"`
+				Unconfirmed int64 `json:"unconfirmed"`
+			}
+			c.result(&balance, "blockchain.scripthash.get_balance", watched)
+			Expect(balance.Confirmed).To(Equal(spentTx.TxOut[0].Value))
+			Expect(balance.Unconfirmed).To(BeZero())
+
+			var history []struct {
+				TxHash string `json:"tx_hash"`
+				Height int64  `json:"height"`
+			}
+			c.result(&history, "blockchain.scripthash.get_history", watched)
+			Expect(history).To(HaveLen(1))
+			Expect(history[0].TxHash).To(Equal(spentTx.TxHash().String()))
+			Expect(history[0].Height).To(Equal(mocks.MockBlockHeight))
+
+			var unspents []struct {
+				TxHash string `json:"tx_hash"`
+				TxPos  int64  `json:"tx_pos"`
+				Height int64  `json:"height"`
+				Value  int64  `json:"value"`
+			}
+			c.result(&unspents, "blockchain.scripthash.listunspent", watched)
+			Expect(unspents).To(HaveLen(1))
+			Expect(unspents[0].TxHash).To(Equal(spentTx.TxHash().String()))
+			Expect(unspents[0].TxPos).To(BeZero())
+			Expect(unspents[0].Value).To(Equal(spentTx.TxOut[0].Value))
+
+			var status *string
+			c.result(&status, "blockchain.scripthash.subscribe", scriptHash([]byte{0x51}))
+			Expect(status).To(BeNil())
+		})
+
+		It("Notifies subscribers when a served block changes the status of their scripthash", func() {
+			var status *string
+			c.result(&status, "blockchain.scripthash.subscribe", watched)
+			Expect(status).ToNot(BeNil())
+
+			spend := wire.NewMsgTx(wire.TxVersion)
+			spend.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: spentTx.TxHash(), Index: 0}, []byte{0x51}, nil))
+			spend.AddTxOut(wire.NewTxOut(spentTx.TxOut[0].Value, []byte{0x51}))
+			payload := childPayload(spend)
+			_, err := btc.NewIPLDPublisherAndIndexer(db, nil, nil).Publish(payload)
+			Expect(err).ToNot(HaveOccurred())
+			server.Notify(payload)
+
+			var notification message
+			Expect(json.Unmarshal(c.read(), &notification)).To(Succeed())
+			Expect(notification.Method).To(Equal("blockchain.scripthash.subscribe"))
+			var params []*string
+			Expect(json.Unmarshal(notification.Params, &params)).To(Succeed())
+			Expect(params).To(HaveLen(2))
+			Expect(*params[0]).To(Equal(watched))
+			Expect(params[1]).ToNot(BeNil())
+			Expect(*params[1]).ToNot(Equal(*status))
+
+			var balance struct {
+				Confirmed int64 `json:"confirmed"`
+			}
+			c.result(&balance, "blockchain.scripthash.get_balance", watched)
+			Expect(balance.Confirmed).To(BeZero())
+		})
+	})
+})
@@ -0,0 +1,242 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package esplora
+
+import (
+	"encoding/hex"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+)
+
+// Block is the Esplora representation of a block
+type Block struct {
+	ID                string  `json:"id"`
+	Height            int64   `json:"height"`
+	Version           int32   `json:"version"`
+	Timestamp         int64   `json:"timestamp"`
+	TxCount           int     `json:"tx_count"`
+	Size              int     `json:"size"`
+	Weight            int     `json:"weight"`
+	MerkleRoot        string  `json:"merkle_root"`
+	PreviousBlockHash *string `json:"previousblockhash"`
+	MedianTime        int64   `json:"mediantime"`
+	Nonce             uint32  `json:"nonce"`
+	Bits              uint32  `json:"bits"`
+	Difficulty        float64 `json:"difficulty"`
+}
+
+// Status is the Esplora confirmation status of a transaction
+type Status struct {
+	Confirmed   bool    `json:"confirmed"`
+	BlockHeight *int64  `json:"block_height,omitempty"`
+	BlockHash   *string `json:"block_hash,omitempty"`
+	BlockTime   *int64  `json:"block_time,omitempty"`
+}
+
+// Transaction is the Esplora representation of a transaction
+type Transaction struct {
+	Txid     string `json:"txid"`
+	Version  int32  `json:"version"`
+	Locktime uint32 `json:"locktime"`
+	Vin      []Vin  `json:"vin"`
+	Vout     []Vout `json:"vout"`
+	Size     int    `json:"size"`
+	Weight   int    `json:"weight"`
+	// Fee is only reported when every output spent by the transaction has been resolved
+	Fee    *int64 `json:"fee,omitempty"`
+	Status Status `json:"status"`
+}
+
+// Vin is the Esplora representation of a transaction input
+type Vin struct {
+	Txid         string   `json:"txid"`
+	Vout         uint32   `json:"vout"`
+	Prevout      *Vout    `json:"prevout"`
+	ScriptSig    string   `json:"scriptsig"`
+	ScriptSigAsm string   `json:"scriptsig_asm"`
+	Witness      []string `json:"witness,omitempty"`
+	IsCoinbase   bool     `json:"is_coinbase"`
+	Sequence     uint32   `json:"sequence"`
+}
+
+// Vout is the Esplora representation of a transaction output
+type Vout struct {
+	ScriptPubKey        string `json:"scriptpubkey"`
+	ScriptPubKeyAsm     string `json:"scriptpubkey_asm"`
+	ScriptPubKeyType    string `json:"scriptpubkey_type"`
+	ScriptPubKeyAddress string `json:"scriptpubkey_address,omitempty"`
+	Value               int64  `json:"value"`
+}
+
+// Outspend is the Esplora spending status of a transaction output
+type Outspend struct {
+	Spent  bool    `json:"spent"`
+	Txid   string  `json:"txid,omitempty"`
+	Vin    *int64  `json:"vin,omitempty"`
+	Status *Status `json:"status,omitempty"`
+}
+
+// Utxo is the Esplora representation of an unspent output
+type Utxo struct {
+	Txid   string `json:"txid"`
+	Vout   uint32 `json:"vout"`
+	Status Status `json:"status"`
+	Value  int64  `json:"value"`
+}
+
+// newBlock converts the block
+func newBlock(block *wire.MsgBlock, blockCtx *btc.BlockContext, params *chaincfg.Params) *Block {
+	res := &Block{
+		ID:         block.BlockHash().String(),
+		Height:     blockCtx.Height,
+		Version:    block.Header.Version,
+		Timestamp:  block.Header.Timestamp.Unix(),
+		TxCount:    len(block.Transactions),
+		Size:       block.SerializeSize(),
+		Weight:     block.SerializeSizeStripped()*(blockchain.WitnessScaleFactor-1) + block.SerializeSize(),
+		MerkleRoot: block.Header.MerkleRoot.String(),
+		MedianTime: blockCtx.MedianTime,
+		Nonce:      block.Header.Nonce,
+		Bits:       block.Header.Bits,
+		Difficulty: btc.DifficultyRatio(block.Header.Bits, params),
+	}
+	if blockCtx.Height > 0 {
+		prevHash := block.Header.PrevBlock.String()
+		res.PreviousBlockHash = &prevHash
+	}
+	return res
+}
+
+// newStatus returns the confirmation status of a transaction in the block at the provided height, blocks which are not
+// canonical do not confirm their transactions
+func newStatus(canonical bool, height int64, hash string, timestamp int64) Status {
+	if !canonical {
+		return Status{}
+	}
+	blockTime := timestamp / 1e9
+	return Status{
+		Confirmed:   true,
+		BlockHeight: &height,
+		BlockHash:   &hash,
+		BlockTime:   &blockTime,
+	}
+}
+
+// newTransaction converts the indexed transaction, resolving its inputs' prevouts from the indexed outputs
+func newTransaction(indexedTx *btc.IndexedTx, params *chaincfg.Params) *Transaction {
+	trx := indexedTx.Tx
+	res := &Transaction{
+		Txid:     trx.TxHash().String(),
+		Version:  trx.Version,
+		Locktime: trx.LockTime,
+		Vin:      make([]Vin, len(trx.TxIn)),
+		Vout:     make([]Vout, len(trx.TxOut)),
+		Size:     trx.SerializeSize(),
+		Weight:   trx.SerializeSizeStripped()*(blockchain.WitnessScaleFactor-1) + trx.SerializeSize(),
+		Status: newStatus(indexedTx.Block.Canonical, indexedTx.Block.BlockNumber, indexedTx.Block.BlockHash,
+			indexedTx.Block.Timestamp),
+	}
+	coinbase := blockchain.IsCoinBaseTx(trx)
+	var inValue, outValue int64
+	resolved := true
+	for i, in := range trx.TxIn {
+		asm, _ := txscript.DisasmString(in.SignatureScript)
+		vin := Vin{
+			Txid:         in.PreviousOutPoint.Hash.String(),
+			Vout:         in.PreviousOutPoint.Index,
+			ScriptSig:    hex.EncodeToString(in.SignatureScript),
+			ScriptSigAsm: asm,
+			IsCoinbase:   coinbase,
+			Sequence:     in.Sequence,
+		}
+		for _, item := range in.Witness {
+			vin.Witness = append(vin.Witness, hex.EncodeToString(item))
+		}
+		if prevout, ok := indexedTx.Prevouts[int64(i)]; ok {
+			vout := newVout(prevout.PkScript, prevout.Value, params)
+			vin.Prevout = &vout
+			inValue += prevout.Value
+		} else if !coinbase {
+			resolved = false
+		}
+		res.Vin[i] = vin
+	}
+	for i, out := range trx.TxOut {
+		res.Vout[i] = newVout(out.PkScript, out.Value, params)
+		outValue += out.Value
+	}
+	if coinbase {
+		var fee int64
+		res.Fee = &fee
+	} else if resolved {
+		fee := inValue - outValue
+		res.Fee = &fee
+	} else if indexedTx.Block.Fee.Valid {
+		// Outputs which have not been indexed may have been fetched from the node when the transaction was indexed
+		fee := indexedTx.Block.Fee.Int64
+		res.Fee = &fee
+	}
+	return res
+}
+
+// newVout converts an output
+func newVout(pkScript []byte, value int64, params *chaincfg.Params) Vout {
+	asm, _ := txscript.DisasmString(pkScript)
+	vout := Vout{
+		ScriptPubKey:     hex.EncodeToString(pkScript),
+		ScriptPubKeyAsm:  asm,
+		ScriptPubKeyType: scriptType(pkScript),
+		Value:            value,
+	}
+	scriptClass, addresses, _, err := btc.ExtractPkScriptAddrs(pkScript, params)
+	if err == nil && len(addresses) == 1 && scriptClass != txscript.PubKeyTy {
+		vout.ScriptPubKeyAddress = addresses[0].EncodeAddress()
+	}
+	return vout
+}
+
+// scriptType returns the name Esplora gives to the class of the pk script
+func scriptType(pkScript []byte) string {
+	if len(pkScript) == 0 {
+		return "empty"
+	}
+	switch btc.GetScriptClass(pkScript) {
+	case txscript.PubKeyTy:
+		return "p2pk"
+	case txscript.PubKeyHashTy:
+		return "p2pkh"
+	case txscript.ScriptHashTy:
+		return "p2sh"
+	case txscript.WitnessV0PubKeyHashTy:
+		return "v0_p2wpkh"
+	case txscript.WitnessV0ScriptHashTy:
+		return "v0_p2wsh"
+	case txscript.MultiSigTy:
+		return "multisig"
+	case txscript.NullDataTy:
+		return "op_return"
+	case btc.WitnessV1TaprootTy:
+		return "v1_p2tr"
+	default:
+		return "unknown"
+	}
+}
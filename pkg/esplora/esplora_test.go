@@ -0,0 +1,187 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package esplora_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc/mocks"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/esplora"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// get requests the path and returns the response recorded for it
+func get(handler http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	return rec
+}
+
+// getJSON requests the path and decodes the json response into the out value
+func getJSON(handler http.Handler, path string, out interface{}) {
+	rec := get(handler, path)
+	Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+	Expect(json.Unmarshal(rec.Body.Bytes(), out)).To(Succeed())
+}
+
+// childPayload converts a block holding the transactions which extends the mock block
+func childPayload(txs ...*wire.MsgTx) btc.ConvertedPayload {
+	header := mocks.MockBlock.Header
+	header.PrevBlock = mocks.MockBlock.BlockHash()
+	header.Timestamp = header.Timestamp.Add(10 * time.Minute)
+	blockTxs := make([]*btcutil.Tx, len(txs))
+	for i, trx := range txs {
+		blockTxs[i] = btcutil.NewTx(trx)
+	}
+	payload, err := btc.NewPayloadConverter(&chaincfg.MainNetParams).Convert(btc.BlockPayload{
+		BlockHeight: mocks.MockBlockHeight + 1,
+		Header:      &header,
+		Txs:         blockTxs,
+	})
+	Expect(err).ToNot(HaveOccurred())
+	return payload.(btc.ConvertedPayload)
+}
+
+var _ = Describe("Esplora", func() {
+	var (
+		db        *postgres.DB
+		handler   http.Handler
+		blockHash = mocks.MockBlock.BlockHash().String()
+		spentTx   = mocks.MockBlock.Transactions[1]
+		txid      = spentTx.TxHash().String()
+		address   = mocks.MockTxsMetaData[1].TxOutputs[0].Addresses[0]
+		spend     *wire.MsgTx
+	)
+	BeforeEach(func() {
+		var err error
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		backend, err := btc.NewBtcBackend(db, &chaincfg.MainNetParams)
+		Expect(err).ToNot(HaveOccurred())
+		handler = esplora.NewHandler(backend)
+		_, err = btc.NewIPLDPublisherAndIndexer(db, nil, nil).Publish(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+		// spend the first output of the mock transaction, paying a 1000 satoshi fee
+		spend = wire.NewMsgTx(wire.TxVersion)
+		spend.AddTxIn(wire.NewTxIn(&wire.OutPoint{Hash: spentTx.TxHash(), Index: 0}, []byte{0x51}, nil))
+		spend.AddTxOut(wire.NewTxOut(spentTx.TxOut[0].Value-1000, mocks.MockBlock.Transactions[2].TxOut[0].PkScript))
+		_, err = btc.NewIPLDPublisherAndIndexer(db, nil, nil).Publish(childPayload(spend))
+		Expect(err).ToNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		btc.TearDownDB(db)
+	})
+
+	Describe("Blocks", func() {
+		It("Returns blocks by hash and the hashes of canonical blocks by height", func() {
+			var block esplora.Block
+			getJSON(handler, "/block/"+blockHash, &block)
+			Expect(block.ID).To(Equal(blockHash))
+			Expect(block.Height).To(Equal(mocks.MockBlockHeight))
+			Expect(block.TxCount).To(Equal(len(mocks.MockBlock.Transactions)))
+			Expect(block.Size).To(Equal(mocks.MockBlock.SerializeSize()))
+			Expect(block.MerkleRoot).To(Equal(mocks.MockBlock.Header.MerkleRoot.String()))
+			Expect(block.Bits).To(Equal(mocks.MockBlock.Header.Bits))
+			Expect(*block.PreviousBlockHash).To(Equal(mocks.MockBlock.Header.PrevBlock.String()))
+
+			rec := get(handler, fmt.Sprintf("/block-height/%d", mocks.MockBlockHeight))
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(Equal(blockHash))
+
+			Expect(get(handler, fmt.Sprintf("/block-height/%d", mocks.MockBlockHeight+2)).Code).To(Equal(http.StatusNotFound))
+			Expect(get(handler, "/block/nothex").Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("Transactions", func() {
+		It("Returns transactions with their prevouts, fees and confirmation status", func() {
+			var trx esplora.Transaction
+			getJSON(handler, "/tx/"+spend.TxHash().String(), &trx)
+			Expect(trx.Txid).To(Equal(spend.TxHash().String()))
+			Expect(trx.Vin).To(HaveLen(1))
+			Expect(trx.Vin[0].Txid).To(Equal(txid))
+			Expect(trx.Vin[0].Prevout).ToNot(BeNil())
+			Expect(trx.Vin[0].Prevout.Value).To(Equal(spentTx.TxOut[0].Value))
+			Expect(trx.Vin[0].Prevout.ScriptPubKeyAddress).To(Equal(address))
+			Expect(trx.Vin[0].Prevout.ScriptPubKeyType).To(Equal("p2pkh"))
+			Expect(*trx.Fee).To(Equal(int64(1000)))
+			Expect(trx.Status.Confirmed).To(BeTrue())
+			Expect(*trx.Status.BlockHeight).To(Equal(mocks.MockBlockHeight + 1))
+
+			// the outputs spent by the mock transaction are not indexed
+			getJSON(handler, "/tx/"+txid, &trx)
+			Expect(trx.Vin[0].Prevout).To(BeNil())
+			Expect(trx.Fee).To(BeNil())
+
+			Expect(get(handler, "/tx/0000000000000000000000000000000000000000000000000000000000000001").Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("Returns the spending status of transaction outputs", func() {
+			var outspends []esplora.Outspend
+			getJSON(handler, "/tx/"+txid+"/outspends", &outspends)
+			Expect(outspends).To(HaveLen(len(spentTx.TxOut)))
+			Expect(outspends[0].Spent).To(BeTrue())
+			Expect(outspends[0].Txid).To(Equal(spend.TxHash().String()))
+			Expect(*outspends[0].Vin).To(Equal(int64(0)))
+			Expect(*outspends[0].Status.BlockHeight).To(Equal(mocks.MockBlockHeight + 1))
+			Expect(outspends[1].Spent).To(BeFalse())
+		})
+	})
+
+	Describe("Addresses", func() {
+		It("Pages through the transactions paying to and spending from an address, newest first", func() {
+			var txs []esplora.Transaction
+			getJSON(handler, "/address/"+address+"/txs", &txs)
+			Expect(txs).To(HaveLen(2))
+			Expect(txs[0].Txid).To(Equal(spend.TxHash().String()))
+			Expect(txs[1].Txid).To(Equal(txid))
+
+			getJSON(handler, "/address/"+address+"/txs/chain/"+spend.TxHash().String(), &txs)
+			Expect(txs).To(HaveLen(1))
+			Expect(txs[0].Txid).To(Equal(txid))
+			getJSON(handler, "/address/"+address+"/txs/chain/"+txid, &txs)
+			Expect(txs).To(BeEmpty())
+
+			Expect(get(handler, "/address/notanaddress/txs").Code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("Lists the unspent outputs paying to an address", func() {
+			var utxos []esplora.Utxo
+			getJSON(handler, "/address/"+address+"/utxo", &utxos)
+			Expect(utxos).To(BeEmpty())
+
+			recipient := mocks.MockTxsMetaData[2].TxOutputs[0].Addresses[0]
+			getJSON(handler, "/address/"+recipient+"/utxo", &utxos)
+			Expect(utxos).To(HaveLen(2))
+			for _, utxo := range utxos {
+				Expect(utxo.Status.Confirmed).To(BeTrue())
+			}
+		})
+	})
+})
@@ -0,0 +1,296 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package esplora
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+)
+
+const (
+	// chainTxsPerPage is the number of confirmed transactions returned per page of address history, as in Esplora
+	chainTxsPerPage = 25
+	// maxUtxos bounds the number of unspent outputs returned for an address, as in Esplora
+	maxUtxos = 500
+)
+
+// httpError is an error answered with the provided status code and message
+type httpError struct {
+	status  int
+	message string
+}
+
+func (e *httpError) Error() string {
+	return e.message
+}
+
+var (
+	errNotFound         = &httpError{status: http.StatusNotFound, message: "Not found"}
+	errBlockNotFound    = &httpError{status: http.StatusNotFound, message: "Block not found"}
+	errTxNotFound       = &httpError{status: http.StatusNotFound, message: "Transaction not found"}
+	errInvalidHash      = &httpError{status: http.StatusBadRequest, message: "Invalid hex string"}
+	errInvalidHeight    = &httpError{status: http.StatusBadRequest, message: "Invalid block height"}
+	errInvalidAddress   = &httpError{status: http.StatusBadRequest, message: "Invalid Bitcoin address"}
+	errInvalidLastSeen  = &httpError{status: http.StatusBadRequest, message: "Invalid last seen txid"}
+	errTooManyUnspents  = &httpError{status: http.StatusBadRequest, message: "Too many unspent outputs"}
+	errMethodNotAllowed = &httpError{status: http.StatusMethodNotAllowed, message: "Method not allowed"}
+)
+
+// route answers a request for a resource from the path segments following the route's prefix
+type route func(segments []string) (interface{}, error)
+
+// Service answers Esplora REST requests from the indexed bitcoin data
+type Service struct {
+	backend *btc.Backend
+}
+
+// NewHandler returns a new http.Handler that answers the Esplora REST routes from the provided backend
+func NewHandler(backend *btc.Backend) http.Handler {
+	s := &Service{backend: backend}
+	mux := http.NewServeMux()
+	mux.Handle("/block/", s.handle("/block/", s.block))
+	mux.Handle("/block-height/", s.handle("/block-height/", s.blockHeight))
+	mux.Handle("/tx/", s.handle("/tx/", s.tx))
+	mux.Handle("/address/", s.handle("/address/", s.address))
+	return mux
+}
+
+// StartHTTPEndpoint starts the Esplora server on the provided endpoint, it returns the endpoint's listener
+func StartHTTPEndpoint(endpoint string, backend *btc.Backend) (net.Listener, error) {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, err
+	}
+	go rpc.NewHTTPServer(nil, nil, rpc.HTTPTimeouts{}, NewHandler(backend)).Serve(listener)
+	return listener, nil
+}
+
+// handle wraps the route in an http.Handler; strings are answered as plain text and everything else as json
+func (s *Service) handle(prefix string, r route) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var res interface{}
+		var err error
+		if req.Method != http.MethodGet {
+			err = errMethodNotAllowed
+		} else {
+			res, err = r(strings.Split(strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, prefix), "/"), "/"))
+		}
+		if err != nil {
+			httpErr, ok := err.(*httpError)
+			if !ok {
+				log.Errorf("esplora %s: %v", req.URL.Path, err)
+				httpErr = &httpError{status: http.StatusInternalServerError, message: err.Error()}
+			}
+			http.Error(w, httpErr.message, httpErr.status)
+			return
+		}
+		if text, ok := res.(string); ok {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(text))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			log.Errorf("esplora %s: %v", req.URL.Path, err)
+		}
+	})
+}
+
+// block answers /block/:hash
+func (s *Service) block(segments []string) (interface{}, error) {
+	if len(segments) != 1 {
+		return nil, errNotFound
+	}
+	hash, err := parseHash(segments[0])
+	if err != nil {
+		return nil, err
+	}
+	block, blockCtx, err := s.backend.BlockByHash(*hash)
+	if err == sql.ErrNoRows {
+		return nil, errBlockNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newBlock(block, blockCtx, s.backend.Params), nil
+}
+
+// blockHeight answers /block-height/:height with the hash of the canonical block at the height
+func (s *Service) blockHeight(segments []string) (interface{}, error) {
+	if len(segments) != 1 {
+		return nil, errNotFound
+	}
+	height, err := strconv.ParseInt(segments[0], 10, 64)
+	if err != nil || height < 0 {
+		return nil, errInvalidHeight
+	}
+	header, _, err := s.backend.HeaderByNumber(height)
+	if err == sql.ErrNoRows {
+		return nil, errBlockNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return header.BlockHash().String(), nil
+}
+
+// tx answers /tx/:txid and /tx/:txid/outspends
+func (s *Service) tx(segments []string) (interface{}, error) {
+	if len(segments) > 2 || (len(segments) == 2 && segments[1] != "outspends") {
+		return nil, errNotFound
+	}
+	hash, err := parseHash(segments[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 2 {
+		return s.outspends(*hash)
+	}
+	indexedTx, err := s.backend.IndexedTransaction(*hash)
+	if err == sql.ErrNoRows {
+		return nil, errTxNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newTransaction(indexedTx, s.backend.Params), nil
+}
+
+// outspends returns the spending status of each output of the transaction
+func (s *Service) outspends(hash chainhash.Hash) (interface{}, error) {
+	outspends, err := s.backend.Outspends(hash)
+	if err == sql.ErrNoRows {
+		return nil, errTxNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Outspend, len(outspends))
+	for i, outspend := range outspends {
+		if !outspend.TxHash.Valid {
+			continue
+		}
+		vin := outspend.InputIndex.Int64
+		status := newStatus(true, outspend.BlockNumber.Int64, outspend.BlockHash.String, outspend.Timestamp.Int64)
+		res[i] = Outspend{
+			Spent:  true,
+			Txid:   outspend.TxHash.String,
+			Vin:    &vin,
+			Status: &status,
+		}
+	}
+	return res, nil
+}
+
+// address answers /address/:address/txs, /address/:address/txs/chain[/:last_seen_txid] and /address/:address/utxo
+func (s *Service) address(segments []string) (interface{}, error) {
+	if len(segments) < 2 {
+		return nil, errNotFound
+	}
+	address, err := s.parseAddress(segments[0])
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(segments) == 2 && segments[1] == "utxo":
+		return s.utxos(address)
+	case len(segments) == 2 && segments[1] == "txs":
+		return s.addressTxs(address, nil)
+	case len(segments) == 3 && segments[1] == "txs" && segments[2] == "chain":
+		return s.addressTxs(address, nil)
+	case len(segments) == 4 && segments[1] == "txs" && segments[2] == "chain":
+		lastSeen, err := parseHash(segments[3])
+		if err != nil {
+			return nil, err
+		}
+		return s.addressTxs(address, lastSeen)
+	default:
+		return nil, errNotFound
+	}
+}
+
+// addressTxs returns a page of the confirmed transactions paying to or spending from the address, newest first
+func (s *Service) addressTxs(address string, lastSeen *chainhash.Hash) (interface{}, error) {
+	indexedTxs, err := s.backend.AddressTransactions(address, lastSeen, chainTxsPerPage)
+	if err == sql.ErrNoRows {
+		return nil, errInvalidLastSeen
+	}
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*Transaction, len(indexedTxs))
+	for i, indexedTx := range indexedTxs {
+		res[i] = newTransaction(indexedTx, s.backend.Params)
+	}
+	return res, nil
+}
+
+// utxos returns the outputs paying to the address which are unspent at the chain head
+func (s *Service) utxos(address string) (interface{}, error) {
+	head, err := s.backend.Retriever.RetrieveLastBlockNumber()
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := s.backend.UTXOs(head, []string{address}, 0, maxUtxos+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(utxos) > maxUtxos {
+		return nil, errTooManyUnspents
+	}
+	res := make([]Utxo, len(utxos))
+	for i, utxo := range utxos {
+		res[i] = Utxo{
+			Txid:   utxo.TxHash,
+			Vout:   uint32(utxo.Index),
+			Status: newStatus(true, utxo.BlockNumber, utxo.BlockHash, utxo.Timestamp),
+			Value:  utxo.Value,
+		}
+	}
+	return res, nil
+}
+
+// parseAddress decodes the address for the backend's network and returns it in the encoding it is indexed with
+func (s *Service) parseAddress(str string) (string, error) {
+	addr, err := btc.DecodeAddress(str, s.backend.Params)
+	if err != nil || !addr.IsForNet(s.backend.Params) {
+		return "", errInvalidAddress
+	}
+	return addr.EncodeAddress(), nil
+}
+
+// parseHash decodes a hex encoded block or transaction hash
+func parseHash(str string) (*chainhash.Hash, error) {
+	if len(str) != 2*chainhash.HashSize {
+		return nil, errInvalidHash
+	}
+	hash, err := chainhash.NewHashFromStr(str)
+	if err != nil {
+		return nil, errInvalidHash
+	}
+	return hash, nil
+}
@@ -18,7 +18,11 @@ package eth
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"math"
 	"math/big"
+	"time"
 
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
 
@@ -26,7 +30,9 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
 )
 
 // APIName is the namespace for the watcher's eth api
@@ -35,14 +41,25 @@ const APIName = "eth"
 // APIVersion is the version of the watcher's eth api
 const APIVersion = "0.0.1"
 
+// callTimeout bounds the execution time of eth_call
+const callTimeout = 5 * time.Second
+
 type PublicEthAPI struct {
-	B *Backend
+	B      *Backend
+	Events *EventSystem
+	Fees   *FeeOracle
+	// Client for the upstream node that calls the watcher cannot answer from its index are forwarded to, can be nil
+	rpc *rpc.Client
 }
 
 // NewPublicEthAPI creates a new PublicEthAPI with the provided underlying Backend
-func NewPublicEthAPI(b *Backend) *PublicEthAPI {
+// If an upstream client is provided, calls for data that has not been indexed are forwarded to it
+func NewPublicEthAPI(b *Backend, client *rpc.Client) *PublicEthAPI {
 	return &PublicEthAPI{
-		B: b,
+		B:      b,
+		Events: NewEventSystem(FilterTimeout),
+		Fees:   NewFeeOracle(b),
+		rpc:    client,
 	}
 }
 
@@ -52,29 +69,26 @@ func (pea *PublicEthAPI) BlockNumber() hexutil.Uint64 {
 	return hexutil.Uint64(number)
 }
 
+// GasPrice returns a gas price suggestion computed from the prices paid in the most recently indexed blocks
+func (pea *PublicEthAPI) GasPrice(ctx context.Context) (*hexutil.Big, error) {
+	price, err := pea.Fees.GasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(price), nil
+}
+
+// FeeHistory returns the gas used ratios of a range of indexed blocks, ending at newestBlock, along with the gas prices
+// paid at each of the reward percentiles of their gas used
+func (pea *PublicEthAPI) FeeHistory(ctx context.Context, blockCount hexutil.Uint64, newestBlock rpc.BlockNumber, rewardPercentiles []float64) (*FeeHistoryResult, error) {
+	return pea.Fees.FeeHistory(ctx, uint64(blockCount), newestBlock, rewardPercentiles)
+}
+
 // GetLogs returns logs matching the given argument that are stored within the state.
 //
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_getlogs
 func (pea *PublicEthAPI) GetLogs(ctx context.Context, crit ethereum.FilterQuery) ([]*types.Log, error) {
-	// Convert FilterQuery into ReceiptFilter
-	addrStrs := make([]string, len(crit.Addresses))
-	for i, addr := range crit.Addresses {
-		addrStrs[i] = addr.String()
-	}
-	topicStrSets := make([][]string, 4)
-	for i, topicSet := range crit.Topics {
-		if i > 3 {
-			// don't allow more than 4 topics
-			break
-		}
-		for _, topic := range topicSet {
-			topicStrSets[i] = append(topicStrSets[i], topic.String())
-		}
-	}
-	filter := ReceiptFilter{
-		LogAddresses: addrStrs,
-		Topics:       topicStrSets,
-	}
+	filter := receiptFilterFromQuery(crit)
 
 	// Begin tx
 	tx, err := pea.B.DB.Beginx()
@@ -125,25 +139,186 @@ func (pea *PublicEthAPI) GetLogs(ctx context.Context, crit ethereum.FilterQuery)
 		}
 		endingBlock = big.NewInt(endingBlockInt)
 	}
-	start := startingBlock.Int64()
-	end := endingBlock.Int64()
-	allRctCIDs := make([]ReceiptModel, 0)
-	for i := start; i <= end; i++ {
-		rctCIDs, err := pea.B.Retriever.RetrieveRctCIDs(tx, filter, i, nil, nil)
+	logs, err := pea.B.LogsInRange(tx, filter, startingBlock.Int64(), endingBlock.Int64())
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return logs, err // need to return err variable so that we return the err = tx.Commit() assignment in the defer
+}
+
+// receiptFilterFromQuery converts the addresses and topics of a FilterQuery into a ReceiptFilter
+func receiptFilterFromQuery(crit ethereum.FilterQuery) ReceiptFilter {
+	addrStrs := make([]string, len(crit.Addresses))
+	for i, addr := range crit.Addresses {
+		addrStrs[i] = addr.String()
+	}
+	topicStrSets := make([][]string, 4)
+	for i, topicSet := range crit.Topics {
+		if i > 3 {
+			// don't allow more than 4 topics
+			break
+		}
+		for _, topic := range topicSet {
+			topicStrSets[i] = append(topicStrSets[i], topic.String())
+		}
+	}
+	return ReceiptFilter{
+		LogAddresses: addrStrs,
+		Topics:       topicStrSets,
+	}
+}
+
+// NewBlockFilter creates a filter that collects the hashes of the blocks served by the watcher.
+// The hashes are drained with eth_getFilterChanges; the filter is uninstalled after FilterTimeout without a poll.
+//
+// https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_newblockfilter
+func (pea *PublicEthAPI) NewBlockFilter() rpc.ID {
+	return pea.Events.install(&filter{typ: blockFilter})
+}
+
+// NewFilter creates a filter that collects the logs matching the criteria from the blocks served by the watcher.
+// If the criteria reach back into blocks which are already indexed, the first eth_getFilterChanges call
+// also returns the matching logs from those blocks.
+// The filter is uninstalled after FilterTimeout without a poll.
+//
+// https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_newfilter
+func (pea *PublicEthAPI) NewFilter(crit ethereum.FilterQuery) (rpc.ID, error) {
+	head, err := pea.B.Retriever.RetrieveLastBlockNumber()
+	if err != nil {
+		return "", err
+	}
+	if crit.FromBlock != nil && crit.FromBlock.Sign() < 0 {
+		// "latest" and "pending" both start the filter at the current head
+		crit.FromBlock = big.NewInt(head + 1)
+	}
+	if crit.ToBlock != nil && crit.ToBlock.Sign() < 0 {
+		crit.ToBlock = nil
+	}
+	f := &filter{
+		typ:  logFilter,
+		crit: crit,
+	}
+	if crit.BlockHash != nil {
+		f.catchUp = &crit
+	} else if crit.FromBlock != nil && crit.FromBlock.Int64() <= head {
+		catchUp := crit
+		catchUp.ToBlock = big.NewInt(head)
+		if crit.ToBlock != nil && crit.ToBlock.Int64() < head {
+			catchUp.ToBlock = crit.ToBlock
+		}
+		f.catchUp = &catchUp
+	}
+	return pea.Events.install(f), nil
+}
+
+// GetFilterChanges returns the block hashes or logs collected by the filter since it was last polled.
+//
+// https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_getfilterchanges
+func (pea *PublicEthAPI) GetFilterChanges(ctx context.Context, id rpc.ID) (interface{}, error) {
+	var catchUpLogs []*types.Log
+	if _, catchUp, err := pea.Events.criteria(id); err == nil && catchUp != nil {
+		catchUpLogs, err = pea.GetLogs(ctx, *catchUp)
 		if err != nil {
 			return nil, err
 		}
-		allRctCIDs = append(allRctCIDs, rctCIDs...)
 	}
-	rctIPLDs, err := pea.B.Fetcher.FetchRcts(tx, allRctCIDs)
+	return pea.Events.changes(id, catchUpLogs)
+}
+
+// GetFilterLogs returns all of the indexed logs matching the criteria of the log filter with the given id.
+//
+// https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_getfilterlogs
+func (pea *PublicEthAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*types.Log, error) {
+	crit, _, err := pea.Events.criteria(id)
 	if err != nil {
 		return nil, err
 	}
-	if err := tx.Commit(); err != nil {
-		return nil, err
+	return pea.GetLogs(ctx, crit)
+}
+
+// UninstallFilter removes the filter with the given id, it returns false if there was no such filter.
+//
+// https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_uninstallfilter
+func (pea *PublicEthAPI) UninstallFilter(id rpc.ID) bool {
+	return pea.Events.uninstall(id)
+}
+
+// NewHeads sends a notification, with the header in geth's JSON encoding, each time a block is served by the watcher.
+//
+// https://github.com/ethereum/go-ethereum/wiki/RPC-PUB-SUB#newheads
+func (pea *PublicEthAPI) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
 	}
-	logs, err := extractLogsOfInterest(rctIPLDs, filter.Topics)
-	return logs, err // need to return err variable so that we return the err = tx.Commit() assignment in the defer
+	rpcSub := notifier.CreateSubscription()
+	sub := &subscription{
+		typ:     blockFilter,
+		headers: make(chan *types.Header, subscriptionBufferSize),
+	}
+	pea.Events.subscribe(rpcSub.ID, sub)
+
+	go func() {
+		defer pea.Events.unsubscribe(rpcSub.ID)
+		for {
+			select {
+			case header := <-sub.headers:
+				if err := notifier.Notify(rpcSub.ID, header); err != nil {
+					log.Errorf("failed to send eth header to subscription %s: %v", rpcSub.ID, err)
+					return
+				}
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// Logs sends a notification for each log matching the criteria in the blocks served by the watcher.
+// When a reorg replaces served blocks their logs are sent again with removed set to true.
+//
+// https://github.com/ethereum/go-ethereum/wiki/RPC-PUB-SUB#logs
+func (pea *PublicEthAPI) Logs(ctx context.Context, crit filters.FilterCriteria) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	query := ethereum.FilterQuery(crit)
+	if query.FromBlock != nil && query.FromBlock.Sign() < 0 {
+		query.FromBlock = nil
+	}
+	if query.ToBlock != nil && query.ToBlock.Sign() < 0 {
+		query.ToBlock = nil
+	}
+	rpcSub := notifier.CreateSubscription()
+	sub := &subscription{
+		typ:  logFilter,
+		crit: query,
+		logs: make(chan []*types.Log, subscriptionBufferSize),
+	}
+	pea.Events.subscribe(rpcSub.ID, sub)
+
+	go func() {
+		defer pea.Events.unsubscribe(rpcSub.ID)
+		for {
+			select {
+			case logs := <-sub.logs:
+				for _, l := range logs {
+					if err := notifier.Notify(rpcSub.ID, l); err != nil {
+						log.Errorf("failed to send eth log to subscription %s: %v", rpcSub.ID, err)
+						return
+					}
+				}
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
 }
 
 // GetHeaderByNumber returns the requested canonical block header.
@@ -154,6 +329,10 @@ func (pea *PublicEthAPI) GetHeaderByNumber(ctx context.Context, number rpc.Block
 	if header != nil && err == nil {
 		return pea.rpcMarshalHeader(header)
 	}
+	var res map[string]interface{}
+	if pea.proxyCall(ctx, &res, "eth_getHeaderByNumber", toBlockNumArg(number)) {
+		return res, nil
+	}
 	return nil, err
 }
 
@@ -167,6 +346,10 @@ func (pea *PublicEthAPI) GetBlockByNumber(ctx context.Context, number rpc.BlockN
 	if block != nil && err == nil {
 		return pea.rpcMarshalBlock(block, true, fullTx)
 	}
+	var res map[string]interface{}
+	if pea.proxyCall(ctx, &res, "eth_getBlockByNumber", toBlockNumArg(number), fullTx) {
+		return res, nil
+	}
 	return nil, err
 }
 
@@ -177,6 +360,155 @@ func (pea *PublicEthAPI) GetBlockByHash(ctx context.Context, hash common.Hash, f
 	if block != nil {
 		return pea.rpcMarshalBlock(block, true, fullTx)
 	}
+	var res map[string]interface{}
+	if pea.proxyCall(ctx, &res, "eth_getBlockByHash", hash, fullTx) {
+		return res, nil
+	}
+	return nil, err
+}
+
+// GetHeaderByHash returns the requested header by hash.
+func (pea *PublicEthAPI) GetHeaderByHash(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	header, err := pea.B.HeaderByHash(ctx, hash)
+	if err == nil {
+		return pea.rpcMarshalHeader(header)
+	}
+	var res map[string]interface{}
+	if pea.proxyCall(ctx, &res, "eth_getHeaderByHash", hash) {
+		return res, nil
+	}
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return nil, err
+}
+
+// GetUncleByBlockNumberAndIndex returns the uncle block for the given canonical block number and index.
+func (pea *PublicEthAPI) GetUncleByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) (map[string]interface{}, error) {
+	block, err := pea.B.BlockByNumber(ctx, blockNr)
+	if block != nil && err == nil {
+		return uncleByIndex(block, index)
+	}
+	var res map[string]interface{}
+	if pea.proxyCall(ctx, &res, "eth_getUncleByBlockNumberAndIndex", toBlockNumArg(blockNr), index) {
+		return res, nil
+	}
+	return nil, err
+}
+
+// GetUncleByBlockHashAndIndex returns the uncle block for the given block hash and index.
+func (pea *PublicEthAPI) GetUncleByBlockHashAndIndex(ctx context.Context, blockHash common.Hash, index hexutil.Uint) (map[string]interface{}, error) {
+	block, err := pea.B.BlockByHash(ctx, blockHash)
+	if block != nil && err == nil {
+		return uncleByIndex(block, index)
+	}
+	var res map[string]interface{}
+	if pea.proxyCall(ctx, &res, "eth_getUncleByBlockHashAndIndex", blockHash, index) {
+		return res, nil
+	}
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return nil, err
+}
+
+// uncleByIndex marshals the uncle at the given index of the block, or returns nil if there is no such uncle
+func uncleByIndex(block *types.Block, index hexutil.Uint) (map[string]interface{}, error) {
+	uncles := block.Uncles()
+	if index >= hexutil.Uint(len(uncles)) {
+		return nil, nil
+	}
+	return RPCMarshalBlock(types.NewBlockWithHeader(uncles[index]), false, false)
+}
+
+// GetUncleCountByBlockNumber returns number of uncles in the block for the given block number
+func (pea *PublicEthAPI) GetUncleCountByBlockNumber(ctx context.Context, blockNr rpc.BlockNumber) (*hexutil.Uint, error) {
+	block, err := pea.B.BlockByNumber(ctx, blockNr)
+	if block != nil && err == nil {
+		n := hexutil.Uint(len(block.Uncles()))
+		return &n, nil
+	}
+	var res *hexutil.Uint
+	if pea.proxyCall(ctx, &res, "eth_getUncleCountByBlockNumber", toBlockNumArg(blockNr)) {
+		return res, nil
+	}
+	return nil, err
+}
+
+// GetUncleCountByBlockHash returns number of uncles in the block for the given block hash
+func (pea *PublicEthAPI) GetUncleCountByBlockHash(ctx context.Context, blockHash common.Hash) (*hexutil.Uint, error) {
+	block, err := pea.B.BlockByHash(ctx, blockHash)
+	if block != nil && err == nil {
+		n := hexutil.Uint(len(block.Uncles()))
+		return &n, nil
+	}
+	var res *hexutil.Uint
+	if pea.proxyCall(ctx, &res, "eth_getUncleCountByBlockHash", blockHash) {
+		return res, nil
+	}
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return nil, err
+}
+
+// GetBlockTransactionCountByNumber returns the number of transactions in the block with the given block number.
+func (pea *PublicEthAPI) GetBlockTransactionCountByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*hexutil.Uint, error) {
+	block, err := pea.B.BlockByNumber(ctx, blockNr)
+	if block != nil && err == nil {
+		n := hexutil.Uint(len(block.Transactions()))
+		return &n, nil
+	}
+	var res *hexutil.Uint
+	if pea.proxyCall(ctx, &res, "eth_getBlockTransactionCountByNumber", toBlockNumArg(blockNr)) {
+		return res, nil
+	}
+	return nil, err
+}
+
+// GetBlockTransactionCountByHash returns the number of transactions in the block with the given hash.
+func (pea *PublicEthAPI) GetBlockTransactionCountByHash(ctx context.Context, blockHash common.Hash) (*hexutil.Uint, error) {
+	block, err := pea.B.BlockByHash(ctx, blockHash)
+	if block != nil && err == nil {
+		n := hexutil.Uint(len(block.Transactions()))
+		return &n, nil
+	}
+	var res *hexutil.Uint
+	if pea.proxyCall(ctx, &res, "eth_getBlockTransactionCountByHash", blockHash) {
+		return res, nil
+	}
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return nil, err
+}
+
+// GetTransactionByBlockNumberAndIndex returns the transaction for the given block number and index.
+func (pea *PublicEthAPI) GetTransactionByBlockNumberAndIndex(ctx context.Context, blockNr rpc.BlockNumber, index hexutil.Uint) (*RPCTransaction, error) {
+	block, err := pea.B.BlockByNumber(ctx, blockNr)
+	if block != nil && err == nil {
+		return newRPCTransactionFromBlockIndex(block, uint64(index)), nil
+	}
+	var res *RPCTransaction
+	if pea.proxyCall(ctx, &res, "eth_getTransactionByBlockNumberAndIndex", toBlockNumArg(blockNr), index) {
+		return res, nil
+	}
+	return nil, err
+}
+
+// GetTransactionByBlockHashAndIndex returns the transaction for the given block hash and index.
+func (pea *PublicEthAPI) GetTransactionByBlockHashAndIndex(ctx context.Context, blockHash common.Hash, index hexutil.Uint) (*RPCTransaction, error) {
+	block, err := pea.B.BlockByHash(ctx, blockHash)
+	if block != nil && err == nil {
+		return newRPCTransactionFromBlockIndex(block, uint64(index)), nil
+	}
+	var res *RPCTransaction
+	if pea.proxyCall(ctx, &res, "eth_getTransactionByBlockHashAndIndex", blockHash, index) {
+		return res, nil
+	}
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	return nil, err
 }
 
@@ -185,12 +517,272 @@ func (pea *PublicEthAPI) GetBlockByHash(ctx context.Context, hash common.Hash, f
 func (pea *PublicEthAPI) GetTransactionByHash(ctx context.Context, hash common.Hash) (*RPCTransaction, error) {
 	// Try to return an already finalized transaction
 	tx, blockHash, blockNumber, index, err := pea.B.GetTransaction(ctx, hash)
+	if tx != nil && err == nil {
+		return NewRPCTransaction(tx, blockHash, blockNumber, index), nil
+	}
+	var res *RPCTransaction
+	if pea.proxyCall(ctx, &res, "eth_getTransactionByHash", hash) {
+		return res, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	if tx != nil {
-		return NewRPCTransaction(tx, blockHash, blockNumber, index), nil
-	}
 	// Transaction unknown, return as such
 	return nil, nil
 }
+
+// GetTransactionReceipt returns the transaction receipt for the given transaction hash
+// eth ipfs-blockchain-watcher cannot currently handle pending/tx_pool txs
+func (pea *PublicEthAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	receipt, err := pea.localGetTransactionReceipt(ctx, hash)
+	if receipt != nil && err == nil {
+		return receipt, nil
+	}
+	var res map[string]interface{}
+	if pea.proxyCall(ctx, &res, "eth_getTransactionReceipt", hash) {
+		return res, nil
+	}
+	if err == sql.ErrNoRows {
+		// Transaction unknown, return as such
+		return nil, nil
+	}
+	return nil, err
+}
+
+// localGetTransactionReceipt builds the receipt for the given transaction hash from the index
+func (pea *PublicEthAPI) localGetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	_, blockHash, blockNumber, index, err := pea.B.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	receipts, txs, err := pea.B.GetReceipts(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if index >= uint64(len(receipts)) {
+		return nil, fmt.Errorf("receipt for transaction %s is not available", hash.Hex())
+	}
+	signer := types.MakeSigner(pea.B.Config, new(big.Int).SetUint64(blockNumber))
+	return RPCMarshalReceipt(receipts[index], txs[index], signer), nil
+}
+
+// GetBlockReceipts returns all of the transaction receipts for the given block number or hash
+func (pea *PublicEthAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	receipts, err := pea.localGetBlockReceipts(ctx, blockNrOrHash)
+	if receipts != nil && err == nil {
+		return receipts, nil
+	}
+	var res []map[string]interface{}
+	if pea.proxyCall(ctx, &res, "eth_getBlockReceipts", toBlockNumOrHashArg(blockNrOrHash)) {
+		return res, nil
+	}
+	if err == sql.ErrNoRows {
+		// Block unknown, return as such
+		return nil, nil
+	}
+	return nil, err
+}
+
+// localGetBlockReceipts builds the receipts for the given block number or hash from the index
+func (pea *PublicEthAPI) localGetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	blockHash, ok := blockNrOrHash.Hash()
+	if !ok {
+		header, err := pea.B.HeaderByNumberOrHash(ctx, blockNrOrHash)
+		if err != nil {
+			return nil, err
+		}
+		blockHash = header.Hash()
+	}
+	receipts, txs, err := pea.B.GetReceipts(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]map[string]interface{}, len(receipts))
+	for i, receipt := range receipts {
+		signer := types.MakeSigner(pea.B.Config, receipt.BlockNumber)
+		fields[i] = RPCMarshalReceipt(receipt, txs[i], signer)
+	}
+	return fields, nil
+}
+
+// GetBalance returns the amount of wei for the given address in the state of the
+// given block number. The rpc.LatestBlockNumber meta block number is also allowed.
+func (pea *PublicEthAPI) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	balance, err := pea.localGetBalance(ctx, address, blockNrOrHash)
+	if err == nil {
+		return balance, nil
+	}
+	var res *hexutil.Big
+	if pea.proxyCall(ctx, &res, "eth_getBalance", address, toBlockNumOrHashArg(blockNrOrHash)) {
+		return res, nil
+	}
+	return nil, err
+}
+
+// localGetBalance retrieves the balance of the given address from the index
+func (pea *PublicEthAPI) localGetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	account, err := pea.B.StateAccountByNumberOrHash(ctx, address, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return (*hexutil.Big)(common.Big0), nil
+	}
+	balance, ok := new(big.Int).SetString(account.Balance, 10)
+	if !ok {
+		return nil, fmt.Errorf("balance retrieved from Postgres cannot be converted to an integer: %s", account.Balance)
+	}
+	return (*hexutil.Big)(balance), nil
+}
+
+// GetTransactionCount returns the number of transactions the given address has sent for the given block number
+// eth ipfs-blockchain-watcher cannot currently handle pending/tx_pool txs
+func (pea *PublicEthAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Uint64, error) {
+	account, err := pea.B.StateAccountByNumberOrHash(ctx, address, blockNrOrHash)
+	if err != nil {
+		var res *hexutil.Uint64
+		if pea.proxyCall(ctx, &res, "eth_getTransactionCount", address, toBlockNumOrHashArg(blockNrOrHash)) {
+			return res, nil
+		}
+		return nil, err
+	}
+	var nonce uint64
+	if account != nil {
+		nonce = account.Nonce
+	}
+	return (*hexutil.Uint64)(&nonce), nil
+}
+
+// GetCode returns the code stored at the given address in the state for the given block number.
+func (pea *PublicEthAPI) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	code, err := pea.B.CodeByNumberOrHash(ctx, address, blockNrOrHash)
+	if err == nil {
+		return code, nil
+	}
+	var res hexutil.Bytes
+	if pea.proxyCall(ctx, &res, "eth_getCode", address, toBlockNumOrHashArg(blockNrOrHash)) {
+		return res, nil
+	}
+	return nil, err
+}
+
+// GetStorageAt returns the storage from the state at the given address, key and
+// block number. The rpc.LatestBlockNumber meta block number is also allowed.
+func (pea *PublicEthAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	value, err := pea.B.StorageByNumberOrHash(ctx, address, common.HexToHash(key), blockNrOrHash)
+	if err != nil {
+		var res hexutil.Bytes
+		if pea.proxyCall(ctx, &res, "eth_getStorageAt", address, key, toBlockNumOrHashArg(blockNrOrHash)) {
+			return res, nil
+		}
+		return nil, err
+	}
+	return value[:], nil
+}
+
+// AccountResult is the EIP-1186 proof of an account and its storage slots
+type AccountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []string        `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is the EIP-1186 proof of a storage slot
+type StorageResult struct {
+	Key   string       `json:"key"`
+	Value *hexutil.Big `json:"value"`
+	Proof []string     `json:"proof"`
+}
+
+// GetProof returns the Merkle-proof for a given account and optionally some storage keys.
+func (pea *PublicEthAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*AccountResult, error) {
+	proof, err := pea.B.GetProof(ctx, address, storageKeys, blockNrOrHash)
+	if err == nil {
+		return proof, nil
+	}
+	var res *AccountResult
+	if pea.proxyCall(ctx, &res, "eth_getProof", address, storageKeys, toBlockNumOrHashArg(blockNrOrHash)) {
+		return res, nil
+	}
+	return nil, err
+}
+
+// CallArgs represents the arguments for a call.
+type CallArgs struct {
+	From     *common.Address `json:"from"`
+	To       *common.Address `json:"to"`
+	Gas      *hexutil.Uint64 `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Value    *hexutil.Big    `json:"value"`
+	Data     *hexutil.Bytes  `json:"data"`
+}
+
+// toMessage converts the call arguments into an unsigned message
+// The sender defaults to the zero address, the gas price to zero and the gas allowance to half of the max uint64
+func (args CallArgs) toMessage() types.Message {
+	var addr common.Address
+	if args.From != nil {
+		addr = *args.From
+	}
+	gas := uint64(math.MaxUint64 / 2)
+	if args.Gas != nil {
+		gas = uint64(*args.Gas)
+	}
+	gasPrice := new(big.Int)
+	if args.GasPrice != nil {
+		gasPrice = args.GasPrice.ToInt()
+	}
+	value := new(big.Int)
+	if args.Value != nil {
+		value = args.Value.ToInt()
+	}
+	var data []byte
+	if args.Data != nil {
+		data = *args.Data
+	}
+	return types.NewMessage(addr, args.To, 0, value, gas, gasPrice, data, false)
+}
+
+// Call executes the given transaction on the state for the given block number or hash.
+// The state is resolved from the trie node IPLDs, and no changes are ever made to it.
+// The call is only proxied if the block or its state is not indexed, execution errors are returned as they are
+func (pea *PublicEthAPI) Call(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	result, _, _, err := pea.B.DoCall(ctx, args, blockNrOrHash, callTimeout)
+	if err == nil {
+		return result, nil
+	}
+	if _, ok := err.(*ExecutionError); ok {
+		return nil, err
+	}
+	var res hexutil.Bytes
+	if pea.proxyCall(ctx, &res, "eth_call", args, toBlockNumOrHashArg(blockNrOrHash)) {
+		return res, nil
+	}
+	return nil, err
+}
+
+// EstimateGas returns an estimate of the amount of gas needed to execute the
+// given transaction against the state of the given block number or hash (latest if omitted).
+// As with Call, the estimate is only proxied if the block or its state is not indexed
+func (pea *PublicEthAPI) EstimateGas(ctx context.Context, args CallArgs, blockNrOrHash *rpc.BlockNumberOrHash) (hexutil.Uint64, error) {
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	gas, err := pea.B.DoEstimateGas(ctx, args, bNrOrHash)
+	if err == nil {
+		return hexutil.Uint64(gas), nil
+	}
+	if _, ok := err.(*ExecutionError); ok {
+		return 0, err
+	}
+	var res hexutil.Uint64
+	if pea.proxyCall(ctx, &res, "eth_estimateGas", args, toBlockNumOrHashArg(bNrOrHash)) {
+		return res, nil
+	}
+	return 0, err
+}
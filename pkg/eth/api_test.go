@@ -18,6 +18,7 @@ package eth_test
 
 import (
 	"context"
+	"math/big"
 	"strconv"
 
 	"github.com/ethereum/go-ethereum"
@@ -25,7 +26,14 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/statediff"
+	"github.com/ethereum/go-ethereum/trie"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -100,8 +108,9 @@ var _ = Describe("API", func() {
 			Retriever: retriever,
 			Fetcher:   fetcher,
 			DB:        db,
+			Config:    params.MainnetChainConfig,
 		}
-		api = eth.NewPublicEthAPI(backend)
+		api = eth.NewPublicEthAPI(backend, nil)
 		_, err = indexAndPublisher.Publish(mocks.MockConvertedPayload)
 		Expect(err).ToNot(HaveOccurred())
 		uncles := mocks.MockBlock.Uncles()
@@ -132,6 +141,60 @@ var _ = Describe("API", func() {
 		})
 	})
 
+	Describe("GetTransactionReceipt", func() {
+		It("Retrieves a receipt by transaction hash with its derived fields", func() {
+			rct, err := api.GetTransactionReceipt(context.Background(), mocks.MockTransactions[1].Hash())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rct["blockHash"]).To(Equal(mocks.MockBlock.Hash()))
+			Expect(rct["blockNumber"]).To(Equal(hexutil.Uint64(1)))
+			Expect(rct["transactionHash"]).To(Equal(mocks.MockTransactions[1].Hash()))
+			Expect(rct["transactionIndex"]).To(Equal(hexutil.Uint64(1)))
+			Expect(rct["from"]).To(Equal(mocks.SenderAddr))
+			Expect(rct["to"]).To(Equal(&mocks.AnotherAddress))
+			Expect(rct["cumulativeGasUsed"]).To(Equal(hexutil.Uint64(100)))
+			Expect(rct["gasUsed"]).To(Equal(hexutil.Uint64(50)))
+			Expect(rct["contractAddress"]).To(BeNil())
+			Expect(rct["root"]).To(Equal(hexutil.Bytes(common.HexToHash("0x1").Bytes())))
+			logs, ok := rct["logs"].([]*types.Log)
+			Expect(ok).To(BeTrue())
+			Expect(len(logs)).To(Equal(1))
+			Expect(logs[0].Address).To(Equal(mocks.AnotherAddress))
+			Expect(logs[0].BlockHash).To(Equal(mocks.MockBlock.Hash()))
+			Expect(logs[0].TxHash).To(Equal(mocks.MockTransactions[1].Hash()))
+			Expect(logs[0].TxIndex).To(Equal(uint(1)))
+			Expect(logs[0].Index).To(Equal(uint(1)))
+		})
+		It("Sets the contract address for contract creations", func() {
+			rct, err := api.GetTransactionReceipt(context.Background(), mocks.MockTransactions[2].Hash())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rct["contractAddress"]).To(Equal(mocks.ContractAddress))
+			Expect(rct["to"]).To(BeNil())
+			Expect(rct["logs"]).To(Equal([]*types.Log{}))
+		})
+		It("Returns nil for unknown transactions", func() {
+			rct, err := api.GetTransactionReceipt(context.Background(), common.HexToHash("0x01"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rct).To(BeNil())
+		})
+	})
+
+	Describe("GetBlockReceipts", func() {
+		It("Retrieves all of the receipts for a block by number or hash", func() {
+			rcts, err := api.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(rcts)).To(Equal(3))
+			for i, rct := range rcts {
+				Expect(rct["transactionHash"]).To(Equal(mocks.MockTransactions[i].Hash()))
+				Expect(rct["transactionIndex"]).To(Equal(hexutil.Uint64(i)))
+			}
+			Expect(rcts[0]["gasUsed"]).To(Equal(hexutil.Uint64(50)))
+
+			rcts, err = api.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithHash(mocks.MockBlock.Hash(), false))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(rcts)).To(Equal(3))
+		})
+	})
+
 	Describe("GetBlockByNumber", func() {
 		It("Retrieves a block by number", func() {
 			// without full txs
@@ -182,6 +245,69 @@ var _ = Describe("API", func() {
 		})
 	})
 
+	Describe("GetHeaderByHash", func() {
+		It("Retrieves a header by hash", func() {
+			header, err := api.GetHeaderByHash(context.Background(), mocks.MockBlock.Hash())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(header).To(Equal(expectedHeader))
+		})
+		It("Returns nil for unknown hashes", func() {
+			header, err := api.GetHeaderByHash(context.Background(), common.HexToHash("0x01"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(header).To(BeNil())
+		})
+	})
+
+	Describe("GetBlockTransactionCount", func() {
+		It("Retrieves the number of transactions in a block by number or hash", func() {
+			count, err := api.GetBlockTransactionCountByNumber(context.Background(), rpc.BlockNumber(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*count).To(Equal(hexutil.Uint(3)))
+
+			count, err = api.GetBlockTransactionCountByHash(context.Background(), mocks.MockBlock.Hash())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*count).To(Equal(hexutil.Uint(3)))
+		})
+	})
+
+	Describe("GetTransactionByBlockNumberAndIndex/GetTransactionByBlockHashAndIndex", func() {
+		It("Retrieves a transaction by its position in a block", func() {
+			tx, err := api.GetTransactionByBlockNumberAndIndex(context.Background(), rpc.BlockNumber(1), 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx).To(Equal(expectedTransaction))
+
+			tx, err = api.GetTransactionByBlockHashAndIndex(context.Background(), mocks.MockBlock.Hash(), 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx).To(Equal(expectedTransaction))
+		})
+		It("Returns nil for indexes outside of the block", func() {
+			tx, err := api.GetTransactionByBlockNumberAndIndex(context.Background(), rpc.BlockNumber(1), 3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tx).To(BeNil())
+		})
+	})
+
+	Describe("Uncles", func() {
+		It("Retrieves the number of uncles in a block by number or hash", func() {
+			count, err := api.GetUncleCountByBlockNumber(context.Background(), rpc.BlockNumber(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*count).To(Equal(hexutil.Uint(len(mocks.MockBlock.Uncles()))))
+
+			count, err = api.GetUncleCountByBlockHash(context.Background(), mocks.MockBlock.Hash())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*count).To(Equal(hexutil.Uint(len(mocks.MockBlock.Uncles()))))
+		})
+		It("Returns nil for uncle indexes outside of the block", func() {
+			uncle, err := api.GetUncleByBlockNumberAndIndex(context.Background(), rpc.BlockNumber(1), 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uncle).To(BeNil())
+
+			uncle, err = api.GetUncleByBlockHashAndIndex(context.Background(), mocks.MockBlock.Hash(), 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uncle).To(BeNil())
+		})
+	})
+
 	Describe("GetBlockByHash", func() {
 		It("Retrieves a block by hash", func() {
 			// without full txs
@@ -579,4 +705,309 @@ var _ = Describe("API", func() {
 			Expect(logs).To(Equal([]*types.Log{mocks.MockLog1, mocks.MockLog2}))
 		})
 	})
+
+	Describe("GetLogs limits", func() {
+		It("Throws an error if the block range exceeds the maximum", func() {
+			backend.LogLimits = eth.LogFilterLimits{MaxRange: 1}
+			crit := ethereum.FilterQuery{
+				FromBlock: big.NewInt(0),
+				ToBlock:   mocks.MockBlock.Number(),
+			}
+			_, err := api.GetLogs(context.Background(), crit)
+			Expect(err).To(HaveOccurred())
+
+			crit.FromBlock = mocks.MockBlock.Number()
+			logs, err := api.GetLogs(context.Background(), crit)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(logs).To(Equal([]*types.Log{mocks.MockLog1, mocks.MockLog2}))
+		})
+
+		It("Throws an error if the query returns more than the maximum number of results", func() {
+			backend.LogLimits = eth.LogFilterLimits{MaxResults: 1}
+			crit := ethereum.FilterQuery{
+				FromBlock: mocks.MockBlock.Number(),
+				ToBlock:   mocks.MockBlock.Number(),
+			}
+			_, err := api.GetLogs(context.Background(), crit)
+			Expect(err).To(MatchError("query returned more than 1 results"))
+
+			crit.Addresses = []common.Address{mocks.AnotherAddress}
+			logs, err := api.GetLogs(context.Background(), crit)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(logs).To(Equal([]*types.Log{mocks.MockLog2}))
+		})
+	})
+
+	Describe("GetBalance", func() {
+		It("Retrieves the balance of an account at the provided block", func() {
+			bal, err := api.GetBalance(context.Background(), mocks.AccountAddresss, rpc.BlockNumberOrHashWithNumber(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bal).To(Equal((*hexutil.Big)(big.NewInt(1000))))
+
+			bal, err = api.GetBalance(context.Background(), mocks.AccountAddresss, rpc.BlockNumberOrHashWithHash(mocks.MockBlock.Hash(), false))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bal).To(Equal((*hexutil.Big)(big.NewInt(1000))))
+
+			bal, err = api.GetBalance(context.Background(), mocks.ContractAddress, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bal).To(Equal((*hexutil.Big)(big.NewInt(0))))
+		})
+		It("Returns a zero balance for accounts that do not exist at the provided block", func() {
+			bal, err := api.GetBalance(context.Background(), mocks.Address, rpc.BlockNumberOrHashWithNumber(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bal).To(Equal((*hexutil.Big)(big.NewInt(0))))
+		})
+		It("Throws an error for blocks that are not indexed", func() {
+			_, err := api.GetBalance(context.Background(), mocks.AccountAddresss, rpc.BlockNumberOrHashWithNumber(0))
+			Expect(err).To(HaveOccurred())
+
+			_, err = api.GetBalance(context.Background(), mocks.AccountAddresss, rpc.BlockNumberOrHashWithNumber(2))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("GetTransactionCount", func() {
+		It("Retrieves the nonce of an account at the provided block", func() {
+			nonce, err := api.GetTransactionCount(context.Background(), mocks.ContractAddress, rpc.BlockNumberOrHashWithNumber(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*nonce).To(Equal(hexutil.Uint64(1)))
+
+			nonce, err = api.GetTransactionCount(context.Background(), mocks.AccountAddresss, rpc.BlockNumberOrHashWithNumber(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*nonce).To(Equal(hexutil.Uint64(0)))
+		})
+	})
+
+	Describe("GetCode", func() {
+		It("Returns empty code for accounts without code", func() {
+			code, err := api.GetCode(context.Background(), mocks.AccountAddresss, rpc.BlockNumberOrHashWithNumber(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(code).To(BeEmpty())
+		})
+		It("Retrieves the code of a contract at the provided block", func() {
+			code, err := api.GetCode(context.Background(), mocks.ContractAddress, rpc.BlockNumberOrHashWithNumber(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(code).To(Equal(hexutil.Bytes(mocks.ContractCode)))
+		})
+		It("Throws an error if the contract code was never published", func() {
+			unpublishedCodeHash := crypto.Keccak256Hash([]byte("unpublished code"))
+			account, err := rlp.EncodeToBytes(state.Account{
+				Nonce:    1,
+				Balance:  big.NewInt(0),
+				CodeHash: unpublishedCodeHash.Bytes(),
+				Root:     common.HexToHash(mocks.ContractRoot),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			leafNode, err := rlp.EncodeToBytes([]interface{}{
+				mocks.ContractPartialPath,
+				account,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			block := types.NewBlockWithHeader(&types.Header{
+				ParentHash: mocks.MockBlock.Hash(),
+				Number:     big.NewInt(2),
+				Difficulty: big.NewInt(5000000),
+				Extra:      []byte{},
+			})
+			_, err = indexAndPublisher.Publish(eth.ConvertedPayload{
+				TotalDifficulty: big.NewInt(10000000),
+				Block:           block,
+				StateNodes: []eth.TrieNode{
+					{
+						LeafKey: common.BytesToHash(mocks.ContractLeafKey),
+						Path:    []byte{'\x06'},
+						Value:   leafNode,
+						Type:    statediff.Leaf,
+					},
+				},
+				StorageNodes: make(map[string][]eth.TrieNode),
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = api.GetCode(context.Background(), mocks.ContractAddress, rpc.BlockNumberOrHashWithNumber(2))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("GetStorageAt", func() {
+		It("Retrieves the value of a storage slot at the provided block", func() {
+			val, err := api.GetStorageAt(context.Background(), mocks.ContractAddress, "0x0", rpc.BlockNumberOrHashWithNumber(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(val).To(Equal(hexutil.Bytes(common.BytesToHash(mocks.StorageValue).Bytes())))
+
+			val, err = api.GetStorageAt(context.Background(), mocks.ContractAddress, "0x1", rpc.BlockNumberOrHashWithNumber(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(val).To(Equal(hexutil.Bytes(common.Hash{}.Bytes())))
+		})
+		It("Throws an error for blocks that are not indexed", func() {
+			_, err := api.GetStorageAt(context.Background(), mocks.ContractAddress, "0x0", rpc.BlockNumberOrHashWithNumber(0))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("State execution and proofs", func() {
+		var stateBlock *types.Block
+		BeforeEach(func() {
+			stateRoot := publishTestState(db)
+			stateBlock = types.NewBlockWithHeader(&types.Header{
+				ParentHash: mocks.MockBlock.Hash(),
+				Number:     big.NewInt(2),
+				Root:       stateRoot,
+				Difficulty: big.NewInt(5000000),
+				GasLimit:   8000000,
+				Extra:      []byte{},
+			})
+			_, err := indexAndPublisher.Publish(eth.ConvertedPayload{
+				TotalDifficulty: big.NewInt(10000000),
+				Block:           stateBlock,
+				StorageNodes:    make(map[string][]eth.TrieNode),
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		Describe("Call", func() {
+			It("Executes a call against the state of the provided block", func() {
+				res, err := api.Call(context.Background(), eth.CallArgs{
+					From: &mocks.AccountAddresss,
+					To:   &mocks.ContractAddress,
+				}, rpc.BlockNumberOrHashWithNumber(2))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(common.BytesToHash(res)).To(Equal(slotZeroValue))
+
+				res, err = api.Call(context.Background(), eth.CallArgs{
+					To: &mocks.ContractAddress,
+				}, rpc.BlockNumberOrHashWithHash(stateBlock.Hash(), false))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(common.BytesToHash(res)).To(Equal(slotZeroValue))
+			})
+			It("Returns empty data for accounts without code at the provided block", func() {
+				res, err := api.Call(context.Background(), eth.CallArgs{
+					To: &mocks.ContractAddress,
+				}, rpc.BlockNumberOrHashWithNumber(1))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res).To(BeEmpty())
+			})
+		})
+
+		Describe("EstimateGas", func() {
+			It("Estimates the gas needed to execute a call", func() {
+				gas, err := api.EstimateGas(context.Background(), eth.CallArgs{
+					To: &mocks.ContractAddress,
+				}, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(uint64(gas) > params.TxGas).To(BeTrue())
+
+				gas, err = api.EstimateGas(context.Background(), eth.CallArgs{
+					To:    &mocks.AnotherAddress,
+					Value: (*hexutil.Big)(big.NewInt(1)),
+					From:  &mocks.AccountAddresss,
+				}, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(uint64(gas)).To(Equal(params.TxGas))
+			})
+			It("Throws an error if the call cannot succeed", func() {
+				_, err := api.EstimateGas(context.Background(), eth.CallArgs{
+					To:    &mocks.AnotherAddress,
+					Value: (*hexutil.Big)(big.NewInt(1)),
+				}, nil)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Describe("Call and EstimateGas with an upstream node", func() {
+			var (
+				client     *rpc.Client
+				proxiedAPI *eth.PublicEthAPI
+			)
+			BeforeEach(func() {
+				server := rpc.NewServer()
+				err := server.RegisterName(eth.APIName, new(upstreamEthAPI))
+				Expect(err).ToNot(HaveOccurred())
+				client = rpc.DialInProc(server)
+				proxiedAPI = eth.NewPublicEthAPI(backend, client)
+			})
+			AfterEach(func() {
+				client.Close()
+			})
+			It("Returns errors executing the call against the indexed state instead of forwarding it", func() {
+				gas := hexutil.Uint64(1)
+				res, err := proxiedAPI.Call(context.Background(), eth.CallArgs{
+					To:  &mocks.ContractAddress,
+					Gas: &gas,
+				}, rpc.BlockNumberOrHashWithNumber(2))
+				Expect(err).To(HaveOccurred())
+				_, ok := err.(*eth.ExecutionError)
+				Expect(ok).To(BeTrue())
+				Expect(res).To(BeNil())
+
+				estimate, err := proxiedAPI.EstimateGas(context.Background(), eth.CallArgs{
+					To:    &mocks.AnotherAddress,
+					Value: (*hexutil.Big)(big.NewInt(1)),
+				}, nil)
+				Expect(err).To(HaveOccurred())
+				_, ok = err.(*eth.ExecutionError)
+				Expect(ok).To(BeTrue())
+				Expect(estimate).To(BeZero())
+			})
+			It("Forwards calls against blocks that are not indexed", func() {
+				res, err := proxiedAPI.Call(context.Background(), eth.CallArgs{
+					To: &mocks.ContractAddress,
+				}, rpc.BlockNumberOrHashWithNumber(100))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res).To(Equal(upstreamCallResult))
+
+				unindexed := rpc.BlockNumberOrHashWithNumber(100)
+				estimate, err := proxiedAPI.EstimateGas(context.Background(), eth.CallArgs{
+					To: &mocks.ContractAddress,
+				}, &unindexed)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(uint64(estimate)).To(Equal(params.TxGas))
+			})
+		})
+
+		Describe("GetProof", func() {
+			It("Builds account and storage proofs that verify against the block's state root", func() {
+				res, err := api.GetProof(context.Background(), mocks.ContractAddress, []string{"0x0", "0x1"}, rpc.BlockNumberOrHashWithNumber(2))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res.CodeHash).To(Equal(crypto.Keccak256Hash(slotZeroReturnCode)))
+
+				accountRLP, _, err := trie.VerifyProof(stateBlock.Root(), crypto.Keccak256(mocks.ContractAddress.Bytes()), proofDB(res.AccountProof))
+				Expect(err).ToNot(HaveOccurred())
+				var account state.Account
+				err = rlp.DecodeBytes(accountRLP, &account)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(account.Root).To(Equal(res.StorageHash))
+
+				Expect(len(res.StorageProof)).To(Equal(2))
+				Expect(res.StorageProof[0].Key).To(Equal("0x0"))
+				Expect(res.StorageProof[0].Value.ToInt()).To(Equal(slotZeroValue.Big()))
+				_, _, err = trie.VerifyProof(res.StorageHash, crypto.Keccak256(common.Hash{}.Bytes()), proofDB(res.StorageProof[0].Proof))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res.StorageProof[1].Value.ToInt().Sign()).To(Equal(0))
+				value, _, err := trie.VerifyProof(res.StorageHash, crypto.Keccak256(common.HexToHash("0x1").Bytes()), proofDB(res.StorageProof[1].Proof))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(value).To(BeNil())
+			})
+			It("Proves the absence of accounts", func() {
+				res, err := api.GetProof(context.Background(), mocks.AnotherAddress, []string{"0x0"}, rpc.BlockNumberOrHashWithNumber(2))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(res.Balance.ToInt().Sign()).To(Equal(0))
+				Expect(res.StorageHash).To(Equal(types.EmptyRootHash))
+				Expect(res.StorageProof[0].Proof).To(BeEmpty())
+				value, _, err := trie.VerifyProof(stateBlock.Root(), crypto.Keccak256(mocks.AnotherAddress.Bytes()), proofDB(res.AccountProof))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(value).To(BeNil())
+			})
+		})
+	})
 })
+
+// proofDB loads hex encoded proof nodes into a database keyed by their hashes
+func proofDB(proof []string) *memorydb.Database {
+	db := memorydb.New()
+	for _, node := range proof {
+		raw := common.FromHex(node)
+		Expect(db.Put(crypto.Keccak256(raw), raw)).To(Succeed())
+	}
+	return db
+}
@@ -18,36 +18,63 @@ package eth
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
+	"strconv"
+	"time"
 
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/jmoiron/sqlx"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/ipfs"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/ipfs/ipld"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
 )
 
 var (
 	errPendingBlockNumber = errors.New("pending block number not supported")
+	emptyCodeHash         = crypto.Keccak256Hash(nil)
 )
 
+// logsPageSize is the number of receipts retrieved and decoded at a time when collecting the logs of a block range
+const logsPageSize = 1000
+
+// LogFilterLimits bounds the block range and number of results of a log query, a zero value disables the limit
+type LogFilterLimits struct {
+	MaxRange   int64
+	MaxResults int
+}
+
 type Backend struct {
 	Retriever *CIDRetriever
 	Fetcher   *IPLDPGFetcher
 	DB        *postgres.DB
+	Config    *params.ChainConfig
+	LogLimits LogFilterLimits
 }
 
-func NewEthBackend(db *postgres.DB) (*Backend, error) {
+func NewEthBackend(db *postgres.DB, logLimits LogFilterLimits, config *params.ChainConfig) (*Backend, error) {
 	r := NewCIDRetriever(db)
 	return &Backend{
 		Retriever: r,
+		Fetcher:   NewIPLDPGFetcher(db),
 		DB:        db,
+		Config:    config,
+		LogLimits: logLimits,
 	}, nil
 }
 
@@ -80,7 +107,7 @@ func (b *Backend) HeaderByNumber(ctx context.Context, blockNumber rpc.BlockNumbe
 		}
 	}()
 
-	// Retrieve the CIDs for headers at this height
+	// Retrieve the CIDs for the canonical header at this height
 	headerCids, err := b.Retriever.RetrieveHeaderCIDs(tx, number)
 	if err != nil {
 		return nil, err
@@ -94,7 +121,7 @@ func (b *Backend) HeaderByNumber(ctx context.Context, blockNumber rpc.BlockNumbe
 	if err != nil {
 		return nil, err
 	}
-	// Decode the first header at this block height and return it
+	// Decode the canonical header at this block height and return it
 	// We throw an error in FetchHeaders() if the number of headers does not match the number of CIDs and we already
 	// confirmed the number of CIDs is greater than 0 so there is no need to bound check the slice before accessing
 	var header types.Header
@@ -157,9 +184,117 @@ func (b *Backend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types.Log
 	return logs, err
 }
 
+// LogsInRange returns the canonical logs between the provided blockheights, inclusive, that conform to the filter
+// The receipts are retrieved in pages ordered by block number and transaction index so that the logs
+// are returned in chain order; the query is aborted as soon as it exceeds the configured limits
+func (b *Backend) LogsInRange(tx *sqlx.Tx, filter ReceiptFilter, startingBlock, endingBlock int64) ([]*types.Log, error) {
+	if b.LogLimits.MaxRange > 0 && endingBlock-startingBlock+1 > b.LogLimits.MaxRange {
+		return nil, fmt.Errorf("block range exceeds the maximum of %d blocks", b.LogLimits.MaxRange)
+	}
+	logs := make([]*types.Log, 0)
+	afterBlock, afterIndex := startingBlock, int64(-1)
+	for {
+		rctCIDs, err := b.Retriever.RetrieveRctCIDsInRange(tx, filter, startingBlock, endingBlock, afterBlock, afterIndex, logsPageSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(rctCIDs) == 0 {
+			return logs, nil
+		}
+		page := make([]ReceiptModel, len(rctCIDs))
+		for i, rctCID := range rctCIDs {
+			page[i] = rctCID.ReceiptModel
+		}
+		rctIPLDs, err := b.Fetcher.FetchRcts(tx, page)
+		if err != nil {
+			return nil, err
+		}
+		pageLogs, err := extractLogsOfInterest(rctIPLDs, filter.Topics)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, pageLogs...)
+		if b.LogLimits.MaxResults > 0 && len(logs) > b.LogLimits.MaxResults {
+			return nil, fmt.Errorf("query returned more than %d results", b.LogLimits.MaxResults)
+		}
+		if len(rctCIDs) < logsPageSize {
+			return logs, nil
+		}
+		last := rctCIDs[len(rctCIDs)-1]
+		afterBlock, afterIndex = last.BlockNumber, last.Index
+	}
+}
+
+// DerivedLogsInRange returns the canonical logs between the provided blockheights, inclusive, that match the criteria
+// Unlike LogsInRange the logs carry their block, transaction and index fields, which are derived from the receipts of
+// every block that holds a match
+func (b *Backend) DerivedLogsInRange(ctx context.Context, crit ethereum.FilterQuery, startingBlock, endingBlock int64) ([]*types.Log, error) {
+	if b.LogLimits.MaxRange > 0 && endingBlock-startingBlock+1 > b.LogLimits.MaxRange {
+		return nil, fmt.Errorf("block range exceeds the maximum of %d blocks", b.LogLimits.MaxRange)
+	}
+	hashes, err := b.blockHashesWithReceipts(receiptFilterFromQuery(crit), startingBlock, endingBlock)
+	if err != nil {
+		return nil, err
+	}
+	logs := make([]*types.Log, 0)
+	for _, hash := range hashes {
+		receipts, _, err := b.GetReceipts(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		for _, receipt := range receipts {
+			logs = append(logs, FilterLogs(receipt.Logs, crit)...)
+		}
+		if b.LogLimits.MaxResults > 0 && len(logs) > b.LogLimits.MaxResults {
+			return nil, fmt.Errorf("query returned more than %d results", b.LogLimits.MaxResults)
+		}
+	}
+	return logs, nil
+}
+
+// blockHashesWithReceipts returns the hashes of the canonical blocks between the provided blockheights, inclusive,
+// that hold receipts which conform to the filter, in ascending order
+func (b *Backend) blockHashesWithReceipts(filter ReceiptFilter, startingBlock, endingBlock int64) ([]common.Hash, error) {
+	// Begin tx
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	hashes := make([]common.Hash, 0)
+	afterBlock, afterIndex := startingBlock, int64(-1)
+	var rctCIDs []RangeReceiptModel
+	for {
+		rctCIDs, err = b.Retriever.RetrieveRctCIDsInRange(tx, filter, startingBlock, endingBlock, afterBlock, afterIndex, logsPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, rctCID := range rctCIDs {
+			hash := common.HexToHash(rctCID.BlockHash)
+			if len(hashes) == 0 || hashes[len(hashes)-1] != hash {
+				hashes = append(hashes, hash)
+			}
+		}
+		if len(rctCIDs) < logsPageSize {
+			return hashes, err
+		}
+		last := rctCIDs[len(rctCIDs)-1]
+		afterBlock, afterIndex = last.BlockNumber, last.Index
+	}
+}
+
 // BlockByNumber returns the requested canonical block.
-// Since the ipfs-blockchain-watcher database can contain forked blocks, it is recommended to fetch BlockByHash as
-// fetching by number can return non-deterministic results (returns the first block found at that height)
+// The ipfs-blockchain-watcher database can contain forked blocks, only the block marked canonical at that height is returned
 func (b *Backend) BlockByNumber(ctx context.Context, blockNumber rpc.BlockNumber) (*types.Block, error) {
 	var err error
 	number := blockNumber.Int64()
@@ -323,12 +458,303 @@ func (b *Backend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Blo
 	return types.NewBlock(&header, transactions, uncles, receipts), err
 }
 
+// GetReceipts retrieves the receipts for the block with the provided hash, along with the transactions they belong to
+// The derived receipt fields (tx hash, block location, gas used, contract address and log indices) are rebuilt from
+// the receipt cids and receipt IPLDs
+func (b *Backend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, types.Transactions, error) {
+	// Retrieve all the CIDs for the block
+	headerCID, _, txCIDs, rctCIDs, err := b.Retriever.RetrieveBlockByHash(hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(txCIDs) != len(rctCIDs) {
+		return nil, nil, fmt.Errorf("block %s has %d transaction cids but %d receipt cids", hash.Hex(), len(txCIDs), len(rctCIDs))
+	}
+	blockNumber, err := strconv.ParseUint(headerCID.BlockNumber, 10, 64)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Begin tx
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	// Fetch and decode the transaction IPLDs
+	txIPLDs, err := b.Fetcher.FetchTrxs(tx, txCIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	transactions := make(types.Transactions, len(txIPLDs))
+	for i, txIPLD := range txIPLDs {
+		transactions[i] = new(types.Transaction)
+		if err = rlp.DecodeBytes(txIPLD.Data, transactions[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	// Fetch and decode the receipt IPLDs
+	rctIPLDs, err := b.Fetcher.FetchRcts(tx, rctCIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	receipts := make(types.Receipts, len(rctIPLDs))
+	for i, rctIPLD := range rctIPLDs {
+		receipts[i] = new(types.Receipt)
+		if err = rlp.DecodeBytes(rctIPLD.Data, receipts[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	deriveReceiptFields(receipts, transactions, rctCIDs, hash, blockNumber)
+	return receipts, transactions, err
+}
+
+// deriveReceiptFields fills in the receipt fields that are not part of the consensus encoding
+// The receipts, transactions and receipt cids must all be in transaction index order
+func deriveReceiptFields(receipts types.Receipts, transactions types.Transactions, rctCIDs []ReceiptModel, hash common.Hash, number uint64) {
+	var logIndex uint
+	for i, receipt := range receipts {
+		receipt.TxHash = transactions[i].Hash()
+		receipt.BlockHash = hash
+		receipt.BlockNumber = new(big.Int).SetUint64(number)
+		receipt.TransactionIndex = uint(i)
+		// The contract address was derived from the sender and nonce when the receipt was indexed
+		if rctCIDs[i].Contract != "" {
+			receipt.ContractAddress = common.HexToAddress(rctCIDs[i].Contract)
+		}
+		if i == 0 {
+			receipt.GasUsed = receipt.CumulativeGasUsed
+		} else {
+			receipt.GasUsed = receipt.CumulativeGasUsed - receipts[i-1].CumulativeGasUsed
+		}
+		for _, log := range receipt.Logs {
+			log.BlockNumber = number
+			log.BlockHash = hash
+			log.TxHash = receipt.TxHash
+			log.TxIndex = uint(i)
+			log.Index = logIndex
+			logIndex++
+		}
+	}
+}
+
+// HeaderByHash gets the header for the provided block hash
+func (b *Backend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	// Begin tx
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	headerCID, err := b.Retriever.RetrieveHeaderCIDByHash(tx, hash)
+	if err != nil {
+		return nil, err
+	}
+	headerIPLD, err := b.Fetcher.FetchHeader(tx, headerCID)
+	if err != nil {
+		return nil, err
+	}
+	var header types.Header
+	err = rlp.DecodeBytes(headerIPLD.Data, &header)
+	return &header, err
+}
+
+// HeaderByNumberOrHash gets the canonical header for the provided block number or hash
+func (b *Backend) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	number, err := b.NormalizeBlockNumberOrHash(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return b.HeaderByNumber(ctx, rpc.BlockNumber(number))
+}
+
+// Engine satisfies the core.ChainContext interface
+// No consensus engine is available, so the block author must always be passed explicitly when building an EVM context
+func (b *Backend) Engine() consensus.Engine {
+	return nil
+}
+
+// GetHeader satisfies the core.ChainContext interface, it is used to look up block hashes for the BLOCKHASH opcode
+// It returns nil if the header is not available
+func (b *Backend) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header, err := b.HeaderByHash(context.Background(), hash)
+	if err != nil || header.Number.Uint64() != number {
+		return nil
+	}
+	return header
+}
+
+// ExecutionError is returned when a call message cannot be executed against the indexed state (e.g. its intrinsic gas is
+// too low, it runs out of gas or it times out), as opposed to an error encountered while resolving that state
+// The upstream node would return the same error, so calls that fail with it are not proxied
+type ExecutionError struct {
+	Err error
+}
+
+func (e *ExecutionError) Error() string {
+	return e.Err.Error()
+}
+
+// DoCall executes the call message against the IPLD-backed state as of the provided block
+// It returns the return data, the gas used, and whether or not the execution failed (e.g. was reverted)
+// Errors applying the message are returned as an *ExecutionError
+func (b *Backend) DoCall(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, timeout time.Duration) ([]byte, uint64, bool, error) {
+	header, err := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	res, err := b.doCall(ctx, args, header, timeout)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if res.Err != nil {
+		return res.ReturnData, res.UsedGas, res.Failed, &ExecutionError{Err: res.Err}
+	}
+	return res.ReturnData, res.UsedGas, res.Failed, nil
+}
+
+// DoEstimateGas binary searches for the lowest gas allowance with which the call message executes successfully
+// If the message cannot execute successfully even with the highest allowance an *ExecutionError is returned
+func (b *Backend) DoEstimateGas(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash) (uint64, error) {
+	header, err := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		lo  = params.TxGas - 1
+		hi  = header.GasLimit
+		cap uint64
+	)
+	if args.Gas != nil && uint64(*args.Gas) >= params.TxGas {
+		hi = uint64(*args.Gas)
+	}
+	cap = hi
+
+	// Create a helper to check if a gas allowance results in an executable transaction
+	executable := func(gas uint64) (bool, error) {
+		args.Gas = (*hexutil.Uint64)(&gas)
+		res, err := b.doCall(ctx, args, header, 0)
+		if err != nil {
+			return false, err
+		}
+		return res.Err == nil && !res.Failed, nil
+	}
+	// Execute the binary search and hone in on an executable gas limit
+	for lo+1 < hi {
+		mid := (hi + lo) / 2
+		ok, err := executable(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	// Reject the transaction as invalid if it still fails at the highest allowance
+	if hi == cap {
+		ok, err := executable(hi)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			return 0, &ExecutionError{Err: fmt.Errorf("gas required exceeds allowance (%d) or always failing transaction", cap)}
+		}
+	}
+	return hi, nil
+}
+
+// callResult holds the outcome of applying a call message
+// Err holds any error returned while applying the message (e.g. intrinsic gas too low), as opposed to an error
+// encountered while resolving the state the message is applied to
+type callResult struct {
+	ReturnData []byte
+	UsedGas    uint64
+	Failed     bool
+	Err        error
+}
+
+// doCall applies the call message on top of the state as of the provided header
+func (b *Backend) doCall(ctx context.Context, args CallArgs, header *types.Header, timeout time.Duration) (*callResult, error) {
+	// Begin tx
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	state := NewIPLDStateDB(tx, header.Root)
+	msg := args.toMessage()
+
+	// Setup context so it may be cancelled when the call has completed
+	// or, in case of unmetered gas, setup a context with a timeout
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	evm := vm.NewEVM(core.NewEVMContext(msg, header, b, &header.Coinbase), state, b.Config, vm.Config{})
+	// Wait for the context to be done and cancel the evm, even if the EVM has finished cancelling may be done (repeatedly)
+	go func() {
+		<-ctx.Done()
+		evm.Cancel()
+	}()
+
+	// Setup the gas pool (also for unmetered requests) and apply the message
+	gp := new(core.GasPool).AddGas(math.MaxUint64)
+	res := new(callResult)
+	res.ReturnData, res.UsedGas, res.Failed, res.Err = core.ApplyMessage(evm, msg, gp)
+	if err = state.Error(); err != nil {
+		return nil, err
+	}
+	// If the timer caused an abort, return an appropriate error message
+	if evm.Cancelled() {
+		err = &ExecutionError{Err: fmt.Errorf("execution aborted (timeout = %v)", timeout)}
+		return nil, err
+	}
+	return res, err
+}
+
 // GetTransaction retrieves a tx by hash
 // It also returns the blockhash, blocknumber, and tx index associated with the transaction
 func (b *Backend) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error) {
 	pgStr := `SELECT transaction_cids.mh_key, transaction_cids.index, header_cids.block_hash, header_cids.block_number
 			FROM eth.transaction_cids, eth.header_cids
 			WHERE transaction_cids.header_id = header_cids.id
+			AND header_cids.canonical = true
 			AND transaction_cids.tx_hash = $1`
 	var txCIDWithHeaderInfo struct {
 		MhKey       string `db:"mh_key"`
@@ -370,6 +796,334 @@ func (b *Backend) GetTransaction(ctx context.Context, txHash common.Hash) (*type
 	return &transaction, common.HexToHash(txCIDWithHeaderInfo.BlockHash), uint64(txCIDWithHeaderInfo.BlockNumber), uint64(txCIDWithHeaderInfo.Index), err
 }
 
+// NormalizeBlockNumberOrHash resolves the provided block number or hash into the height of a canonical block
+// It returns an error if no canonical header is indexed at the height, e.g. above the indexed head or in a gap, rather
+// than letting state lookups fall back to the last diff indexed below it
+func (b *Backend) NormalizeBlockNumberOrHash(blockNrOrHash rpc.BlockNumberOrHash) (int64, error) {
+	if blockNr, ok := blockNrOrHash.Number(); ok {
+		switch blockNr {
+		case rpc.PendingBlockNumber:
+			return 0, errPendingBlockNumber
+		case rpc.LatestBlockNumber:
+			return b.Retriever.RetrieveLastBlockNumber()
+		default:
+			number := blockNr.Int64()
+			_, err := b.Retriever.RetrieveCanonicalBlockHash(number)
+			if err == sql.ErrNoRows {
+				return 0, fmt.Errorf("header at block %d is not available", number)
+			}
+			return number, err
+		}
+	}
+	hash, ok := blockNrOrHash.Hash()
+	if !ok {
+		return 0, errors.New("invalid arguments; neither block nor hash specified")
+	}
+	pgStr := `SELECT block_number, canonical FROM eth.header_cids
+			WHERE block_hash = $1`
+	var header HeaderModel
+	if err := b.DB.Get(&header, pgStr, hash.String()); err != nil {
+		return 0, err
+	}
+	if !header.Canonical {
+		return 0, fmt.Errorf("hash %s is not currently canonical", hash.String())
+	}
+	return strconv.ParseInt(header.BlockNumber, 10, 64)
+}
+
+// StateAccountByNumberOrHash returns the state account for the address as of the provided block
+// It returns nil if the account does not exist at that block
+func (b *Backend) StateAccountByNumberOrHash(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*StateAccountModel, error) {
+	number, err := b.NormalizeBlockNumberOrHash(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Begin tx
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	account, err := b.stateAccount(tx, address, number)
+	return account, err
+}
+
+// CodeByNumberOrHash returns the contract code for the address as of the provided block
+func (b *Backend) CodeByNumberOrHash(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) ([]byte, error) {
+	number, err := b.NormalizeBlockNumberOrHash(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Begin tx
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	account, err := b.stateAccount(tx, address, number)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return []byte{}, nil
+	}
+	code, err := fetchCode(tx, common.BytesToHash(account.CodeHash))
+	return code, err
+}
+
+// StorageByNumberOrHash returns the value held in the storage slot of the address as of the provided block
+func (b *Backend) StorageByNumberOrHash(ctx context.Context, address common.Address, slot common.Hash, blockNrOrHash rpc.BlockNumberOrHash) (common.Hash, error) {
+	number, err := b.NormalizeBlockNumberOrHash(blockNrOrHash)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	// Begin tx
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	value, err := b.storage(tx, address, slot, number)
+	return value, err
+}
+
+// GetProof builds the EIP-1186 Merkle proofs for the account and its storage slots as of the provided block
+// The proofs are assembled by walking from the header's state root, and then the account's storage root,
+// through the trie node IPLDs in public.blocks
+func (b *Backend) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*AccountResult, error) {
+	header, err := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Begin tx
+	tx, err := b.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	leaf, accountProof, err := resolveTrieLeaf(tx, header.Root, crypto.Keccak256(address.Bytes()), decodeStateTrieNode)
+	if err != nil {
+		return nil, err
+	}
+	result := &AccountResult{
+		Address:      address,
+		AccountProof: toHexSlice(accountProof),
+		Balance:      (*hexutil.Big)(new(big.Int)),
+		StorageHash:  types.EmptyRootHash,
+		StorageProof: make([]StorageResult, len(storageKeys)),
+	}
+	if leaf != nil {
+		account, ok := leaf.(*ipld.EthAccountSnapshot)
+		if !ok {
+			err = fmt.Errorf("unexpected state leaf value type %T for address %s", leaf, address.Hex())
+			return nil, err
+		}
+		result.Balance = (*hexutil.Big)(account.Balance)
+		result.CodeHash = common.BytesToHash(account.CodeHash)
+		result.Nonce = hexutil.Uint64(account.Nonce)
+		result.StorageHash = common.BytesToHash(account.Root)
+	}
+	for i, key := range storageKeys {
+		// If the account does not exist its storage root is empty, and every slot proof is empty
+		var (
+			storageLeaf  interface{}
+			storageProof [][]byte
+			value        common.Hash
+		)
+		storageLeaf, storageProof, err = resolveTrieLeaf(tx, result.StorageHash, crypto.Keccak256(common.HexToHash(key).Bytes()), decodeStorageTrieNode)
+		if err != nil {
+			return nil, err
+		}
+		value, err = decodeStorageValue(storageLeaf)
+		if err != nil {
+			return nil, err
+		}
+		result.StorageProof[i] = StorageResult{
+			Key:   key,
+			Value: (*hexutil.Big)(value.Big()),
+			Proof: toHexSlice(storageProof),
+		}
+	}
+	return result, err
+}
+
+// toHexSlice hex encodes each of the provided byte slices
+func toHexSlice(b [][]byte) []string {
+	r := make([]string, len(b))
+	for i := range b {
+		r[i] = hexutil.Encode(b[i])
+	}
+	return r
+}
+
+// stateAccount finds the latest diff for the account's state leaf at or below the given canonical block height
+// The account does not exist if there is no such leaf, or if another node has since been written at the leaf's path
+// without a newer leaf for the account (the account has been deleted)
+func (b *Backend) stateAccount(tx *sqlx.Tx, address common.Address, number int64) (*StateAccountModel, error) {
+	leafKey := crypto.Keccak256Hash(address.Bytes())
+	pgStr := `SELECT state_cids.id, state_cids.state_path, header_cids.block_number
+			FROM eth.state_cids INNER JOIN eth.header_cids ON (state_cids.header_id = header_cids.id)
+			WHERE state_cids.state_leaf_key = $1
+			AND header_cids.block_number <= $2
+			AND header_cids.canonical = true
+			ORDER BY header_cids.block_number DESC LIMIT 1`
+	var leaf struct {
+		ID          int64  `db:"id"`
+		Path        []byte `db:"state_path"`
+		BlockNumber int64  `db:"block_number"`
+	}
+	err := tx.Get(&leaf, pgStr, leafKey.String(), number)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	pgStr = `SELECT EXISTS(SELECT 1
+			FROM eth.state_cids INNER JOIN eth.header_cids ON (state_cids.header_id = header_cids.id)
+			WHERE state_cids.state_path = $1
+			AND header_cids.block_number > $2
+			AND header_cids.block_number <= $3
+			AND header_cids.canonical = true)`
+	var deleted bool
+	if err := tx.Get(&deleted, pgStr, leaf.Path, leaf.BlockNumber, number); err != nil {
+		return nil, err
+	}
+	if deleted {
+		return nil, nil
+	}
+	account := new(StateAccountModel)
+	pgStr = `SELECT * FROM eth.state_accounts WHERE state_id = $1`
+	return account, tx.Get(account, pgStr, leaf.ID)
+}
+
+// storage finds the latest diff for the storage leaf at or below the given canonical block height
+// The slot is empty if the account does not exist, if there is no such leaf, or if another node has
+// since been written at the leaf's path in the account's storage trie without a newer leaf for the slot
+func (b *Backend) storage(tx *sqlx.Tx, address common.Address, slot common.Hash, number int64) (common.Hash, error) {
+	account, err := b.stateAccount(tx, address, number)
+	if err != nil || account == nil {
+		return common.Hash{}, err
+	}
+	stateLeafKey := crypto.Keccak256Hash(address.Bytes())
+	storageLeafKey := crypto.Keccak256Hash(slot.Bytes())
+	pgStr := `SELECT storage_cids.storage_path, storage_cids.mh_key, header_cids.block_number
+			FROM eth.storage_cids, eth.state_cids, eth.header_cids
+			WHERE storage_cids.state_id = state_cids.id
+			AND state_cids.header_id = header_cids.id
+			AND state_cids.state_leaf_key = $1
+			AND storage_cids.storage_leaf_key = $2
+			AND header_cids.block_number <= $3
+			AND header_cids.canonical = true
+			ORDER BY header_cids.block_number DESC LIMIT 1`
+	var leaf struct {
+		Path        []byte `db:"storage_path"`
+		MhKey       string `db:"mh_key"`
+		BlockNumber int64  `db:"block_number"`
+	}
+	err = tx.Get(&leaf, pgStr, stateLeafKey.String(), storageLeafKey.String(), number)
+	if err == sql.ErrNoRows {
+		return common.Hash{}, nil
+	}
+	if err != nil {
+		return common.Hash{}, err
+	}
+	pgStr = `SELECT EXISTS(SELECT 1
+			FROM eth.storage_cids, eth.state_cids, eth.header_cids
+			WHERE storage_cids.state_id = state_cids.id
+			AND state_cids.header_id = header_cids.id
+			AND state_cids.state_leaf_key = $1
+			AND storage_cids.storage_path = $2
+			AND header_cids.block_number > $3
+			AND header_cids.block_number <= $4
+			AND header_cids.canonical = true)`
+	var deleted bool
+	if err := tx.Get(&deleted, pgStr, stateLeafKey.String(), leaf.Path, leaf.BlockNumber, number); err != nil {
+		return common.Hash{}, err
+	}
+	if deleted {
+		return common.Hash{}, nil
+	}
+	leafRLP, err := shared.FetchIPLDByMhKey(tx, leaf.MhKey)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var nodeElements []interface{}
+	if err := rlp.DecodeBytes(leafRLP, &nodeElements); err != nil {
+		return common.Hash{}, err
+	}
+	if len(nodeElements) != 2 {
+		return common.Hash{}, fmt.Errorf("eth storage leaf node rlp expected to decode into two elements, got %d", len(nodeElements))
+	}
+	valueRLP, ok := nodeElements[1].([]byte)
+	if !ok {
+		return common.Hash{}, errors.New("eth storage leaf node value is not a byte string")
+	}
+	_, value, _, err := rlp.Split(valueRLP)
+	return common.BytesToHash(value), err
+}
+
+// fetchCode returns the contract code for the provided code hash
+// Contract code is stored in the public.blocks table as a raw IPLD keyed by the keccak256 hash of the code
+func fetchCode(tx *sqlx.Tx, codeHash common.Hash) ([]byte, error) {
+	if codeHash == emptyCodeHash || codeHash == (common.Hash{}) {
+		return []byte{}, nil
+	}
+	mhKey, err := shared.MultihashKeyFromKeccak256(codeHash)
+	if err != nil {
+		return nil, err
+	}
+	code, err := shared.FetchIPLDByMhKey(tx, mhKey)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("code for hash %s is not available", codeHash.Hex())
+	}
+	return code, err
+}
+
 // extractLogsOfInterest returns logs from the receipt IPLD
 func extractLogsOfInterest(rctIPLDs []ipfs.BlockModel, wantedTopics [][]string) ([]*types.Log, error) {
 	var logs []*types.Log
@@ -505,6 +1259,39 @@ func RPCMarshalBlock(block *types.Block, inclTx bool, fullTx bool) (map[string]i
 	return fields, nil
 }
 
+// RPCMarshalReceipt converts the given receipt, with its derived fields filled in, to the RPC output
+// The signer is used to recover the sender of the transaction the receipt belongs to
+func RPCMarshalReceipt(receipt *types.Receipt, tx *types.Transaction, signer types.Signer) map[string]interface{} {
+	from, _ := types.Sender(signer, tx)
+	fields := map[string]interface{}{
+		"blockHash":         receipt.BlockHash,
+		"blockNumber":       hexutil.Uint64(receipt.BlockNumber.Uint64()),
+		"transactionHash":   receipt.TxHash,
+		"transactionIndex":  hexutil.Uint64(receipt.TransactionIndex),
+		"from":              from,
+		"to":                tx.To(),
+		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
+		"cumulativeGasUsed": hexutil.Uint64(receipt.CumulativeGasUsed),
+		"contractAddress":   nil,
+		"logs":              receipt.Logs,
+		"logsBloom":         receipt.Bloom,
+	}
+	// Assign receipt status or post state
+	if len(receipt.PostState) > 0 {
+		fields["root"] = hexutil.Bytes(receipt.PostState)
+	} else {
+		fields["status"] = hexutil.Uint(receipt.Status)
+	}
+	if receipt.Logs == nil {
+		fields["logs"] = []*types.Log{}
+	}
+	// If the ContractAddress is 20 0x0 bytes, assume it is not a contract creation
+	if receipt.ContractAddress != (common.Address{}) {
+		fields["contractAddress"] = receipt.ContractAddress
+	}
+	return fields
+}
+
 // NewRPCTransactionFromBlockHash returns a transaction that will serialize to the RPC representation.
 func NewRPCTransactionFromBlockHash(b *types.Block, hash common.Hash) *RPCTransaction {
 	for idx, tx := range b.Transactions() {
@@ -22,6 +22,7 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
@@ -51,13 +52,20 @@ func (ecr *CIDRetriever) RetrieveFirstBlockNumber() (int64, error) {
 	return blockNumber, err
 }
 
-// RetrieveLastBlockNumber is used to retrieve the latest block number in the db
+// RetrieveLastBlockNumber is used to retrieve the latest canonical block number in the db
 func (ecr *CIDRetriever) RetrieveLastBlockNumber() (int64, error) {
 	var blockNumber int64
-	err := ecr.db.Get(&blockNumber, "SELECT block_number FROM eth.header_cids ORDER BY block_number DESC LIMIT 1 ")
+	err := ecr.db.Get(&blockNumber, "SELECT block_number FROM eth.header_cids WHERE canonical = true ORDER BY block_number DESC LIMIT 1 ")
 	return blockNumber, err
 }
 
+// RetrieveCanonicalBlockHash is used to retrieve the hash of the canonical block at the provided height
+func (ecr *CIDRetriever) RetrieveCanonicalBlockHash(blockNumber int64) (common.Hash, error) {
+	var blockHash string
+	err := ecr.db.Get(&blockHash, "SELECT block_hash FROM eth.header_cids WHERE block_number = $1 AND canonical = true", blockNumber)
+	return common.HexToHash(blockHash), err
+}
+
 // Retrieve is used to retrieve all of the CIDs which conform to the passed StreamFilters
 func (ecr *CIDRetriever) Retrieve(filter shared.SubscriptionSettings, blockNumber int64) ([]shared.CIDsForFetching, bool, error) {
 	streamFilter, ok := filter.(*SubscriptionSettings)
@@ -160,12 +168,13 @@ func (ecr *CIDRetriever) Retrieve(filter shared.SubscriptionSettings, blockNumbe
 	return cws, empty, err
 }
 
-// RetrieveHeaderCIDs retrieves and returns all of the header cids at the provided blockheight
+// RetrieveHeaderCIDs retrieves and returns the canonical header cids at the provided blockheight
 func (ecr *CIDRetriever) RetrieveHeaderCIDs(tx *sqlx.Tx, blockNumber int64) ([]HeaderModel, error) {
 	log.Debug("retrieving header cids for block ", blockNumber)
 	headers := make([]HeaderModel, 0)
 	pgStr := `SELECT * FROM eth.header_cids
-				WHERE block_number = $1`
+				WHERE block_number = $1
+				AND canonical = true`
 	return headers, tx.Select(&headers, pgStr, blockNumber)
 }
 
@@ -219,67 +228,9 @@ func (ecr *CIDRetriever) RetrieveRctCIDsByHeaderID(tx *sqlx.Tx, rctFilter Receip
 			AND header_cids.id = $1`
 	id := 2
 	args = append(args, headerID)
-	if len(rctFilter.LogAddresses) > 0 {
-		// Filter on log contract addresses if there are any
-		pgStr += fmt.Sprintf(` AND ((receipt_cids.log_contracts && $%d::VARCHAR(66)[]`, id)
-		args = append(args, pq.Array(rctFilter.LogAddresses))
-		id++
-		// Filter on topics if there are any
-		if hasTopics(rctFilter.Topics) {
-			pgStr += " AND ("
-			first := true
-			for i, topicSet := range rctFilter.Topics {
-				if i < 4 && len(topicSet) > 0 {
-					if first {
-						pgStr += fmt.Sprintf(`receipt_cids.topic%ds && $%d::VARCHAR(66)[]`, i, id)
-						first = false
-					} else {
-						pgStr += fmt.Sprintf(` AND receipt_cids.topic%ds && $%d::VARCHAR(66)[]`, i, id)
-					}
-					args = append(args, pq.Array(topicSet))
-					id++
-				}
-			}
-			pgStr += ")"
-		}
-		pgStr += ")"
-		// Filter on txIDs if there are any and we are matching txs
-		if rctFilter.MatchTxs && len(trxIds) > 0 {
-			pgStr += fmt.Sprintf(` OR receipt_cids.tx_id = ANY($%d::INTEGER[])`, id)
-			args = append(args, pq.Array(trxIds))
-		}
-		pgStr += ")"
-	} else { // If there are no contract addresses to filter on
-		// Filter on topics if there are any
-		if hasTopics(rctFilter.Topics) {
-			pgStr += " AND (("
-			first := true
-			for i, topicSet := range rctFilter.Topics {
-				if i < 4 && len(topicSet) > 0 {
-					if first {
-						pgStr += fmt.Sprintf(`receipt_cids.topic%ds && $%d::VARCHAR(66)[]`, i, id)
-						first = false
-					} else {
-						pgStr += fmt.Sprintf(` AND receipt_cids.topic%ds && $%d::VARCHAR(66)[]`, i, id)
-					}
-					args = append(args, pq.Array(topicSet))
-					id++
-				}
-			}
-			pgStr += ")"
-			// Filter on txIDs if there are any and we are matching txs
-			if rctFilter.MatchTxs && len(trxIds) > 0 {
-				pgStr += fmt.Sprintf(` OR receipt_cids.tx_id = ANY($%d::INTEGER[])`, id)
-				args = append(args, pq.Array(trxIds))
-			}
-			pgStr += ")"
-		} else if rctFilter.MatchTxs && len(trxIds) > 0 {
-			// If there are no contract addresses or topics to filter on,
-			// Filter on txIDs if there are any and we are matching txs
-			pgStr += fmt.Sprintf(` AND receipt_cids.tx_id = ANY($%d::INTEGER[])`, id)
-			args = append(args, pq.Array(trxIds))
-		}
-	}
+	conditions, conditionArgs := receiptFilterConditions(rctFilter, trxIds, id)
+	pgStr += conditions
+	args = append(args, conditionArgs...)
 	pgStr += ` ORDER BY transaction_cids.index`
 	receiptCids := make([]ReceiptModel, 0)
 	return receiptCids, tx.Select(&receiptCids, pgStr, args...)
@@ -306,7 +257,23 @@ func (ecr *CIDRetriever) RetrieveRctCIDs(tx *sqlx.Tx, rctFilter ReceiptFilter, b
 		pgStr += fmt.Sprintf(` AND header_cids.block_hash = $%d`, id)
 		args = append(args, blockHash.String())
 		id++
+	} else {
+		// Without an explicit block hash we only return receipts from the canonical chain
+		pgStr += ` AND header_cids.canonical = true`
 	}
+	conditions, conditionArgs := receiptFilterConditions(rctFilter, trxIds, id)
+	pgStr += conditions
+	args = append(args, conditionArgs...)
+	pgStr += ` ORDER BY transaction_cids.index`
+	receiptCids := make([]ReceiptModel, 0)
+	return receiptCids, tx.Select(&receiptCids, pgStr, args...)
+}
+
+// receiptFilterConditions returns the conditions, and their arguments, that restrict a receipt_cids query to the receipts
+// that conform to the filter parameters or correspond to the provided tx ids, the first argument is numbered id
+func receiptFilterConditions(rctFilter ReceiptFilter, trxIds []int64, id int) (string, []interface{}) {
+	pgStr := ""
+	args := make([]interface{}, 0)
 	if len(rctFilter.LogAddresses) > 0 {
 		// Filter on log contract addresses if there are any
 		pgStr += fmt.Sprintf(` AND ((receipt_cids.log_contracts && $%d::VARCHAR(66)[]`, id)
@@ -368,11 +335,84 @@ func (ecr *CIDRetriever) RetrieveRctCIDs(tx *sqlx.Tx, rctFilter ReceiptFilter, b
 			args = append(args, pq.Array(trxIds))
 		}
 	}
-	pgStr += ` ORDER BY transaction_cids.index`
-	receiptCids := make([]ReceiptModel, 0)
+	return pgStr, args
+}
+
+// RetrieveRctCIDsInRange retrieves and returns a page of the canonical rct cids between the provided blockheights, inclusive,
+// that conform to the provided filter parameters
+// Receipts are ordered by block number and transaction index, the page begins after the receipt at the provided position
+// and holds at most limit receipts
+func (ecr *CIDRetriever) RetrieveRctCIDsInRange(tx *sqlx.Tx, rctFilter ReceiptFilter, startingBlock, endingBlock, afterBlock, afterIndex int64, limit int) ([]RangeReceiptModel, error) {
+	log.Debugf("retrieving receipt cids for blocks %d to %d", startingBlock, endingBlock)
+	args := make([]interface{}, 0, 9)
+	pgStr := `SELECT receipt_cids.id, receipt_cids.tx_id, receipt_cids.cid, receipt_cids.mh_key,
+ 			receipt_cids.contract, receipt_cids.contract_hash, receipt_cids.topic0s, receipt_cids.topic1s,
+			receipt_cids.topic2s, receipt_cids.topic3s, receipt_cids.log_contracts,
+			header_cids.block_hash, header_cids.block_number, transaction_cids.index
+ 			FROM eth.receipt_cids, eth.transaction_cids, eth.header_cids
+			WHERE receipt_cids.tx_id = transaction_cids.id
+			AND transaction_cids.header_id = header_cids.id
+			AND header_cids.canonical = true
+			AND header_cids.block_number BETWEEN $1 AND $2
+			AND (header_cids.block_number, transaction_cids.index) > ($3, $4)`
+	args = append(args, startingBlock, endingBlock, afterBlock, afterIndex)
+	pgStr += bloomFilterConditions(rctFilter)
+	conditions, conditionArgs := receiptFilterConditions(rctFilter, nil, 5)
+	pgStr += conditions
+	args = append(args, conditionArgs...)
+	pgStr += fmt.Sprintf(` ORDER BY header_cids.block_number, transaction_cids.index LIMIT %d`, limit)
+	receiptCids := make([]RangeReceiptModel, 0)
 	return receiptCids, tx.Select(&receiptCids, pgStr, args...)
 }
 
+// bloomFilterConditions returns the conditions that restrict a query to the headers whose logs bloom can contain logs
+// that conform to the filter parameters
+// A header passes if its bloom holds any of the addresses and, at each topic position, any of the topics
+func bloomFilterConditions(rctFilter ReceiptFilter) string {
+	pgStr := ""
+	if len(rctFilter.LogAddresses) > 0 {
+		values := make([][]byte, len(rctFilter.LogAddresses))
+		for i, addr := range rctFilter.LogAddresses {
+			values[i] = common.HexToAddress(addr).Bytes()
+		}
+		pgStr += bloomContainsAny(values)
+	}
+	for i, topicSet := range rctFilter.Topics {
+		if i > 3 || len(topicSet) == 0 {
+			continue
+		}
+		values := make([][]byte, len(topicSet))
+		for j, topic := range topicSet {
+			values[j] = common.HexToHash(topic).Bytes()
+		}
+		pgStr += bloomContainsAny(values)
+	}
+	return pgStr
+}
+
+// bloomContainsAny returns a condition that checks the header's logs bloom for the bits set by any of the values
+// The bit positions are derived as in go-ethereum's bloom9: three 11 bit indexes from the keccak256 hash of the value
+func bloomContainsAny(values [][]byte) string {
+	pgStr := " AND ("
+	for i, value := range values {
+		if i > 0 {
+			pgStr += " OR "
+		}
+		hash := crypto.Keccak256(value)
+		pgStr += "("
+		for j := 0; j < 6; j += 2 {
+			if j > 0 {
+				pgStr += " AND "
+			}
+			bit := (uint(hash[j])<<8 | uint(hash[j+1])) & 2047
+			mask := 1 << (bit % 8)
+			pgStr += fmt.Sprintf("get_byte(header_cids.bloom, %d) & %d = %d", types.BloomByteLength-1-bit/8, mask, mask)
+		}
+		pgStr += ")"
+	}
+	return pgStr + ")"
+}
+
 func hasTopics(topics [][]string) bool {
 	for _, topicSet := range topics {
 		if len(topicSet) > 0 {
@@ -22,6 +22,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/jmoiron/sqlx"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -407,6 +408,60 @@ var _ = Describe("Retriever", func() {
 		})
 	})
 
+	Describe("RetrieveRctCIDsInRange", func() {
+		var tx *sqlx.Tx
+		BeforeEach(func() {
+			_, err := repo.Publish(mocks.MockConvertedPayload)
+			Expect(err).ToNot(HaveOccurred())
+			tx, err = db.Beginx()
+			Expect(err).ToNot(HaveOccurred())
+		})
+		AfterEach(func() {
+			shared.Rollback(tx)
+		})
+
+		It("Pages through the receipts of the range in chain order", func() {
+			rcts, err := retriever.RetrieveRctCIDsInRange(tx, eth.ReceiptFilter{}, 0, 10, 0, -1, 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(rcts)).To(Equal(2))
+			Expect(rcts[0].CID).To(Equal(mocks.Rct1CID.String()))
+			Expect(rcts[0].BlockNumber).To(Equal(mocks.MockBlock.Number().Int64()))
+			Expect(rcts[0].Index).To(Equal(int64(0)))
+			Expect(rcts[1].CID).To(Equal(mocks.Rct2CID.String()))
+
+			rcts, err = retriever.RetrieveRctCIDsInRange(tx, eth.ReceiptFilter{}, 0, 10, rcts[1].BlockNumber, rcts[1].Index, 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(rcts)).To(Equal(1))
+			Expect(rcts[0].CID).To(Equal(mocks.Rct3CID.String()))
+
+			rcts, err = retriever.RetrieveRctCIDsInRange(tx, eth.ReceiptFilter{}, 2, 10, 2, -1, 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(rcts)).To(Equal(0))
+		})
+
+		It("Applies the receipt filter and the header bloom", func() {
+			rcts, err := retriever.RetrieveRctCIDsInRange(tx, eth.ReceiptFilter{
+				LogAddresses: []string{mocks.AnotherAddress.String()},
+			}, 0, 10, 0, -1, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(rcts)).To(Equal(1))
+			Expect(rcts[0].CID).To(Equal(mocks.Rct2CID.String()))
+
+			rcts, err = retriever.RetrieveRctCIDsInRange(tx, eth.ReceiptFilter{
+				Topics: [][]string{{"0x0000000000000000000000000000000000000000000000000000000000000004"}},
+			}, 0, 10, 0, -1, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(rcts)).To(Equal(1))
+			Expect(rcts[0].CID).To(Equal(mocks.Rct1CID.String()))
+
+			rcts, err = retriever.RetrieveRctCIDsInRange(tx, eth.ReceiptFilter{
+				LogAddresses: []string{common.HexToAddress("0xbadbadbad").String()},
+			}, 0, 10, 0, -1, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(rcts)).To(Equal(0))
+		})
+	})
+
 	Describe("RetrieveFirstBlockNumber", func() {
 		It("Throws an error if there are no blocks in the database", func() {
 			_, err := retriever.RetrieveFirstBlockNumber()
@@ -130,6 +130,12 @@ func (pc *PayloadConverter) Convert(payload shared.RawChainData) (shared.Convert
 	if err := rlp.DecodeBytes(stateDiffPayload.StateObjectRlp, stateDiff); err != nil {
 		return nil, err
 	}
+	for _, code := range stateDiff.CodeAndCodeHashes {
+		convertedPayload.Code = append(convertedPayload.Code, CodeAndCodeHash{
+			Hash: code.Hash,
+			Code: code.Code,
+		})
+	}
 	for _, stateNode := range stateDiff.Nodes {
 		statePath := common.Bytes2Hex(stateNode.Path)
 		convertedPayload.StateNodes = append(convertedPayload.StateNodes, TrieNode{
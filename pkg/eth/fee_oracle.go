@@ -0,0 +1,340 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+const (
+	// gasPriceBlocks is the number of recent blocks sampled by eth_gasPrice
+	gasPriceBlocks = 20
+	// gasPricePercentile is the percentile of the sampled block prices returned by eth_gasPrice
+	gasPricePercentile = 60
+	// maxFeeHistory is the greatest number of blocks a single eth_feeHistory call covers
+	maxFeeHistory = 1024
+	// feeCacheDepth is the number of recent block heights whose fee data is kept in memory
+	feeCacheDepth = 1024
+)
+
+var (
+	// defaultGasPrice is returned by eth_gasPrice until a sampled block holds a transaction
+	defaultGasPrice = big.NewInt(params.GWei)
+	// maxGasPrice caps the price returned by eth_gasPrice
+	maxGasPrice = big.NewInt(500 * params.GWei)
+)
+
+// FeeHistoryResult is the response to eth_feeHistory
+// Base fees are zero since the indexed chain predates EIP-1559, so rewards are the full gas prices paid
+type FeeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
+	BaseFee      []*hexutil.Big   `json:"baseFeePerGas,omitempty"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+// txFee is the gas price paid and the gas used by a single transaction
+type txFee struct {
+	gasPrice *big.Int
+	gasUsed  uint64
+}
+
+// blockFees is the fee data of a single block
+type blockFees struct {
+	height   int64
+	gasUsed  uint64
+	gasLimit uint64
+	// txs are the transactions of the block ordered by ascending gas price
+	txs []txFee
+	// minPrice is the lowest gas price paid by a transaction not sent by the miner, nil if there is no such transaction
+	minPrice *big.Int
+}
+
+// FeeOracle answers eth_gasPrice and eth_feeHistory from the indexed transactions and receipts
+// The fee data of recent blocks is cached as they are synced, older blocks are read from the database when requested
+// The cache is keyed by block hash, since synced blocks are not necessarily canonical, and the canonical block at a
+// height is looked up in the database when its fee data is requested
+// It satisfies the shared.PayloadListener interface
+type FeeOracle struct {
+	sync.RWMutex
+	backend *Backend
+	blocks  map[common.Hash]*blockFees
+	// head is the greatest height cached
+	head int64
+	// lastHead and lastPrice memoize the eth_gasPrice result for the current chain head
+	lastHead  int64
+	lastPrice *big.Int
+}
+
+// NewFeeOracle returns a new FeeOracle which reads the blocks it has not cached through the provided Backend
+func NewFeeOracle(b *Backend) *FeeOracle {
+	return &FeeOracle{
+		backend:   b,
+		blocks:    make(map[common.Hash]*blockFees),
+		lastHead:  -1,
+		lastPrice: defaultGasPrice,
+	}
+}
+
+// Notify satisfies the shared.PayloadListener interface
+// It caches the fee data of a newly synced block
+func (fo *FeeOracle) Notify(payload shared.ConvertedData) {
+	ethPayload, ok := payload.(ConvertedPayload)
+	if !ok {
+		log.Errorf("eth fee oracle: expected payload type %T got %T", ConvertedPayload{}, payload)
+		return
+	}
+	fees, err := fo.newBlockFees(ethPayload.Block, ethPayload.Receipts)
+	if err != nil {
+		log.Errorf("eth fee oracle: unable to compute the fees of block %d: %v", ethPayload.Height(), err)
+		return
+	}
+	fo.store(ethPayload.Block.Hash(), fees)
+}
+
+// NotifyReorg satisfies the shared.PayloadListener interface
+// It evicts the fee data cached at and above the fork height
+func (fo *FeeOracle) NotifyReorg(forkHeight int64) {
+	fo.Lock()
+	defer fo.Unlock()
+	for hash, fees := range fo.blocks {
+		if fees.height >= forkHeight {
+			delete(fo.blocks, hash)
+		}
+	}
+	fo.lastHead = -1
+}
+
+// GasPrice returns the configured percentile of the lowest gas prices paid in each of the most recent blocks
+// Blocks without transactions are skipped; if none of the sampled blocks hold a transaction the last computed price is returned
+func (fo *FeeOracle) GasPrice(ctx context.Context) (*big.Int, error) {
+	head, err := fo.backend.Retriever.RetrieveLastBlockNumber()
+	if err != nil {
+		return nil, err
+	}
+	fo.RLock()
+	lastHead, lastPrice := fo.lastHead, fo.lastPrice
+	fo.RUnlock()
+	if head == lastHead {
+		return lastPrice, nil
+	}
+	prices := make([]*big.Int, 0, gasPriceBlocks)
+	for height := head; height >= 0 && height > head-gasPriceBlocks; height-- {
+		fees, err := fo.fees(ctx, height)
+		if err != nil {
+			log.Warnf("eth fee oracle: skipping block %d: %v", height, err)
+			continue
+		}
+		if fees.minPrice != nil {
+			prices = append(prices, fees.minPrice)
+		}
+	}
+	price := lastPrice
+	if len(prices) > 0 {
+		sort.Slice(prices, func(i, j int) bool { return prices[i].Cmp(prices[j]) < 0 })
+		price = prices[(len(prices)-1)*gasPricePercentile/100]
+	}
+	if price.Cmp(maxGasPrice) > 0 {
+		price = new(big.Int).Set(maxGasPrice)
+	}
+	fo.Lock()
+	fo.lastHead, fo.lastPrice = head, price
+	fo.Unlock()
+	return price, nil
+}
+
+// FeeHistory returns the gas used ratios of up to blockCount blocks ending at the newest block, along with the
+// gas prices paid at each of the reward percentiles, weighted by the gas used by each transaction
+func (fo *FeeOracle) FeeHistory(ctx context.Context, blockCount uint64, newestBlock rpc.BlockNumber, rewardPercentiles []float64) (*FeeHistoryResult, error) {
+	for i, p := range rewardPercentiles {
+		if p < 0 || p > 100 {
+			return nil, fmt.Errorf("invalid reward percentile %f", p)
+		}
+		if i > 0 && p < rewardPercentiles[i-1] {
+			return nil, fmt.Errorf("invalid reward percentile %f, percentiles must be in ascending order", p)
+		}
+	}
+	newest := newestBlock.Int64()
+	switch newestBlock {
+	case rpc.PendingBlockNumber:
+		return nil, errPendingBlockNumber
+	case rpc.LatestBlockNumber:
+		var err error
+		newest, err = fo.backend.Retriever.RetrieveLastBlockNumber()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if blockCount > maxFeeHistory {
+		blockCount = maxFeeHistory
+	}
+	if blockCount > uint64(newest+1) {
+		blockCount = uint64(newest + 1)
+	}
+	oldest := newest + 1 - int64(blockCount)
+	result := &FeeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(big.NewInt(oldest)),
+		GasUsedRatio: make([]float64, blockCount),
+	}
+	if blockCount == 0 {
+		return result, nil
+	}
+	result.BaseFee = make([]*hexutil.Big, blockCount+1)
+	for i := range result.BaseFee {
+		result.BaseFee[i] = (*hexutil.Big)(new(big.Int))
+	}
+	if len(rewardPercentiles) > 0 {
+		result.Reward = make([][]*hexutil.Big, blockCount)
+	}
+	for i := range result.GasUsedRatio {
+		fees, err := fo.fees(ctx, oldest+int64(i))
+		if err != nil {
+			return nil, err
+		}
+		if fees.gasLimit > 0 {
+			result.GasUsedRatio[i] = float64(fees.gasUsed) / float64(fees.gasLimit)
+		}
+		if result.Reward != nil {
+			result.Reward[i] = fees.rewards(rewardPercentiles)
+		}
+	}
+	return result, nil
+}
+
+// fees returns the fee data of the canonical block at the provided height, reading and caching it if needed
+func (fo *FeeOracle) fees(ctx context.Context, height int64) (*blockFees, error) {
+	hash, err := fo.backend.Retriever.RetrieveCanonicalBlockHash(height)
+	if err != nil {
+		return nil, err
+	}
+	fo.RLock()
+	fees, ok := fo.blocks[hash]
+	fo.RUnlock()
+	if ok {
+		return fees, nil
+	}
+	block, err := fo.backend.BlockByHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	receipts, _, err := fo.backend.GetReceipts(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	fees, err = fo.newBlockFees(block, receipts)
+	if err != nil {
+		return nil, err
+	}
+	fo.store(hash, fees)
+	return fees, nil
+}
+
+// store caches the fee data of a block, evicting the blocks whose heights fall behind the cache depth
+func (fo *FeeOracle) store(hash common.Hash, fees *blockFees) {
+	fo.Lock()
+	defer fo.Unlock()
+	if fees.height <= fo.head-feeCacheDepth {
+		return
+	}
+	fo.blocks[hash] = fees
+	if fees.height > fo.head {
+		fo.head = fees.height
+		for h, cached := range fo.blocks {
+			if cached.height <= fo.head-feeCacheDepth {
+				delete(fo.blocks, h)
+			}
+		}
+	}
+}
+
+// newBlockFees computes the fee data of a block from its transactions and receipts
+func (fo *FeeOracle) newBlockFees(block *types.Block, receipts types.Receipts) (*blockFees, error) {
+	transactions := block.Transactions()
+	if len(transactions) != len(receipts) {
+		return nil, fmt.Errorf("block %s has %d transactions but %d receipts", block.Hash().Hex(), len(transactions), len(receipts))
+	}
+	config := fo.backend.Config
+	if config == nil {
+		config = params.MainnetChainConfig
+	}
+	signer := types.MakeSigner(config, block.Number())
+	fees := &blockFees{
+		height:   block.Number().Int64(),
+		gasUsed:  block.GasUsed(),
+		gasLimit: block.GasLimit(),
+		txs:      make([]txFee, len(transactions)),
+	}
+	var cumulativeGasUsed uint64
+	for i, trx := range transactions {
+		// Gas used is derived from the cumulative gas used, which is all the consensus encoding of a receipt holds
+		fee := txFee{gasPrice: trx.GasPrice()}
+		if receipts[i].CumulativeGasUsed > cumulativeGasUsed {
+			fee.gasUsed = receipts[i].CumulativeGasUsed - cumulativeGasUsed
+			cumulativeGasUsed = receipts[i].CumulativeGasUsed
+		}
+		fees.txs[i] = fee
+		sender, err := types.Sender(signer, trx)
+		if err != nil {
+			return nil, err
+		}
+		if sender == block.Coinbase() {
+			continue
+		}
+		if fees.minPrice == nil || fee.gasPrice.Cmp(fees.minPrice) < 0 {
+			fees.minPrice = fee.gasPrice
+		}
+	}
+	sort.SliceStable(fees.txs, func(i, j int) bool { return fees.txs[i].gasPrice.Cmp(fees.txs[j].gasPrice) < 0 })
+	return fees, nil
+}
+
+// rewards returns the gas price at each percentile of the gas used in the block
+// The transactions are walked in ascending price order until their summed gas used reaches the percentile's share of
+// the block's gas used
+func (bf *blockFees) rewards(percentiles []float64) []*hexutil.Big {
+	rewards := make([]*hexutil.Big, len(percentiles))
+	if len(bf.txs) == 0 {
+		for i := range rewards {
+			rewards[i] = (*hexutil.Big)(new(big.Int))
+		}
+		return rewards
+	}
+	txIndex := 0
+	sumGasUsed := bf.txs[0].gasUsed
+	for i, p := range percentiles {
+		threshold := uint64(float64(bf.gasUsed) * p / 100)
+		for sumGasUsed < threshold && txIndex < len(bf.txs)-1 {
+			txIndex++
+			sumGasUsed += bf.txs[txIndex].gasUsed
+		}
+		rewards[i] = (*hexutil.Big)(new(big.Int).Set(bf.txs[txIndex].gasPrice))
+	}
+	return rewards
+}
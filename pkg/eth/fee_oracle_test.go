@@ -0,0 +1,196 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth/mocks"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// feePayload builds a payload for a block at the provided height and total difficulty holding a signed transaction for
+// each of the gas prices, each using the corresponding amount of gas
+func feePayload(height int64, td int64, gasLimit uint64, gasPrices []int64, gasUsed []uint64) eth.ConvertedPayload {
+	key, err := crypto.GenerateKey()
+	Expect(err).ToNot(HaveOccurred())
+	signer := types.MakeSigner(params.MainnetChainConfig, big.NewInt(height))
+	transactions := make(types.Transactions, len(gasPrices))
+	receipts := make(types.Receipts, len(gasPrices))
+	txMetaData := make([]eth.TxModel, len(gasPrices))
+	var cumulativeGasUsed uint64
+	for i, price := range gasPrices {
+		trx := types.NewTransaction(uint64(i), mocks.Address, big.NewInt(0), gasUsed[i], big.NewInt(price), nil)
+		transactions[i], err = types.SignTx(trx, signer, key)
+		Expect(err).ToNot(HaveOccurred())
+		cumulativeGasUsed += gasUsed[i]
+		receipts[i] = types.NewReceipt(nil, false, cumulativeGasUsed)
+		txMetaData[i] = eth.TxModel{
+			Index:  int64(i),
+			TxHash: transactions[i].Hash().String(),
+			Src:    crypto.PubkeyToAddress(key.PublicKey).Hex(),
+			Dst:    mocks.Address.Hex(),
+		}
+	}
+	header := &types.Header{
+		Number:     big.NewInt(height),
+		Difficulty: big.NewInt(1),
+		GasLimit:   gasLimit,
+		GasUsed:    cumulativeGasUsed,
+		Coinbase:   common.HexToAddress("0x01"),
+	}
+	return eth.ConvertedPayload{
+		TotalDifficulty: big.NewInt(td),
+		Block:           types.NewBlock(header, transactions, nil, receipts),
+		Receipts:        receipts,
+		TxMetaData:      txMetaData,
+		ReceiptMetaData: make([]eth.ReceiptModel, len(gasPrices)),
+		StorageNodes:    make(map[string][]eth.TrieNode),
+	}
+}
+
+// syncFeePayload indexes the payload and notifies the oracle of it, as the watcher does when it syncs a block
+func syncFeePayload(db *postgres.DB, oracle *eth.FeeOracle, payload eth.ConvertedPayload) {
+	_, err := eth.NewIPLDPublisherAndIndexer(db).Publish(payload)
+	Expect(err).ToNot(HaveOccurred())
+	oracle.Notify(payload)
+}
+
+func hexBigs(values ...int64) []*hexutil.Big {
+	bigs := make([]*hexutil.Big, len(values))
+	for i, v := range values {
+		bigs[i] = (*hexutil.Big)(big.NewInt(v))
+	}
+	return bigs
+}
+
+var _ = Describe("FeeOracle", func() {
+	var ctx = context.Background()
+
+	Describe("FeeHistory", func() {
+		var (
+			db     *postgres.DB
+			oracle *eth.FeeOracle
+		)
+		BeforeEach(func() {
+			var err error
+			db, err = shared.SetupDB()
+			Expect(err).ToNot(HaveOccurred())
+			backend, err := eth.NewEthBackend(db, eth.LogFilterLimits{}, params.MainnetChainConfig)
+			Expect(err).ToNot(HaveOccurred())
+			oracle = eth.NewFeeOracle(backend)
+			syncFeePayload(db, oracle, feePayload(0, 1, 100000, nil, nil))
+			syncFeePayload(db, oracle, feePayload(1, 2, 100000, []int64{30, 10, 20}, []uint64{21000, 42000, 21000}))
+			syncFeePayload(db, oracle, feePayload(2, 3, 100000, nil, nil))
+		})
+		AfterEach(func() {
+			eth.TearDownDB(db)
+		})
+
+		It("Returns the gas used ratios and gas weighted reward percentiles of synced blocks", func() {
+			history, err := oracle.FeeHistory(ctx, 2, rpc.BlockNumber(2), []float64{0, 50, 60, 100})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history.OldestBlock.ToInt().Int64()).To(Equal(int64(1)))
+			Expect(history.GasUsedRatio).To(Equal([]float64{0.84, 0}))
+			Expect(history.Reward).To(Equal([][]*hexutil.Big{hexBigs(10, 10, 20, 30), hexBigs(0, 0, 0, 0)}))
+			Expect(history.BaseFee).To(Equal(hexBigs(0, 0, 0)))
+		})
+
+		It("Limits the block count to the blocks at and below the newest block", func() {
+			history, err := oracle.FeeHistory(ctx, 5, rpc.BlockNumber(1), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history.OldestBlock.ToInt().Int64()).To(Equal(int64(0)))
+			Expect(history.GasUsedRatio).To(HaveLen(2))
+		})
+
+		It("Rejects out of range or unordered reward percentiles", func() {
+			_, err := oracle.FeeHistory(ctx, 1, rpc.BlockNumber(1), []float64{101})
+			Expect(err).To(HaveOccurred())
+			_, err = oracle.FeeHistory(ctx, 1, rpc.BlockNumber(1), []float64{50, 10})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Indexed blocks", func() {
+		var (
+			db     *postgres.DB
+			oracle *eth.FeeOracle
+		)
+		BeforeEach(func() {
+			var err error
+			db, err = shared.SetupDB()
+			Expect(err).ToNot(HaveOccurred())
+			backend, err := eth.NewEthBackend(db, eth.LogFilterLimits{}, params.MainnetChainConfig)
+			Expect(err).ToNot(HaveOccurred())
+			oracle = eth.NewFeeOracle(backend)
+			_, err = eth.NewIPLDPublisherAndIndexer(db).Publish(mocks.MockConvertedPayload)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		AfterEach(func() {
+			eth.TearDownDB(db)
+		})
+
+		It("Suggests the lowest gas price of the indexed blocks", func() {
+			price, err := oracle.GasPrice(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(price.Int64()).To(Equal(int64(100)))
+		})
+
+		It("Reads the fees of blocks it has not synced from the database", func() {
+			history, err := oracle.FeeHistory(ctx, 1, rpc.LatestBlockNumber, []float64{50})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history.OldestBlock.ToInt()).To(Equal(mocks.BlockNumber))
+			Expect(history.Reward).To(Equal([][]*hexutil.Big{hexBigs(100)}))
+		})
+
+		It("Returns the fees of the canonical block when a competing block at the same height is synced", func() {
+			height := mocks.BlockNumber.Int64() + 1
+			syncFeePayload(db, oracle, feePayload(height, 10000000, 100000, []int64{10}, []uint64{21000}))
+			syncFeePayload(db, oracle, feePayload(height, 9000000, 100000, []int64{20}, []uint64{21000}))
+			history, err := oracle.FeeHistory(ctx, 1, rpc.BlockNumber(height), []float64{50})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history.Reward).To(Equal([][]*hexutil.Big{hexBigs(10)}))
+
+			// The competing block becomes canonical once a heavier block is built on it
+			competing := feePayload(height, 9000000, 100000, []int64{30}, []uint64{21000})
+			syncFeePayload(db, oracle, competing)
+			child := feePayload(height+1, 20000000, 100000, nil, nil)
+			child.Block = types.NewBlockWithHeader(&types.Header{
+				Number:     big.NewInt(height + 1),
+				ParentHash: competing.Block.Hash(),
+				Difficulty: big.NewInt(1),
+				GasLimit:   100000,
+			})
+			syncFeePayload(db, oracle, child)
+			history, err = oracle.FeeHistory(ctx, 1, rpc.BlockNumber(height), []float64{50})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history.Reward).To(Equal([][]*hexutil.Big{hexBigs(30)}))
+		})
+	})
+})
@@ -0,0 +1,314 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+const (
+	// FilterTimeout is the period of inactivity after which a polling filter is uninstalled
+	FilterTimeout = 5 * time.Minute
+	// recentLogsDepth is the number of recently served block heights whose logs are remembered
+	// so that they can be reported as removed when a reorg replaces their blocks
+	recentLogsDepth = 256
+	// subscriptionBufferSize is the number of events buffered for a subscription, further events are dropped
+	subscriptionBufferSize = 2000
+)
+
+var errFilterNotFound = errors.New("filter not found")
+
+type filterType int
+
+const (
+	blockFilter filterType = iota
+	logFilter
+)
+
+// filter is a polling filter installed by eth_newBlockFilter or eth_newFilter
+type filter struct {
+	typ  filterType
+	crit ethereum.FilterQuery
+	// catchUp is the historical query the next poll of a log filter answers from the database
+	catchUp *ethereum.FilterQuery
+	hashes  []common.Hash
+	logs    []*types.Log
+	timer   *time.Timer
+}
+
+// subscription is an eth_subscribe subscription to new headers or logs
+type subscription struct {
+	typ     filterType
+	crit    ethereum.FilterQuery
+	headers chan *types.Header
+	logs    chan []*types.Log
+}
+
+// EventSystem hands the blocks and logs served by the watcher to the polling filters and subscriptions of the eth api
+// It satisfies the shared.PayloadListener interface
+type EventSystem struct {
+	sync.Mutex
+	filters       map[rpc.ID]*filter
+	subscriptions map[rpc.ID]*subscription
+	recentLogs    map[int64][]*types.Log
+	timeout       time.Duration
+}
+
+// NewEventSystem returns a new EventSystem whose filters are uninstalled after the provided period of inactivity
+func NewEventSystem(timeout time.Duration) *EventSystem {
+	return &EventSystem{
+		filters:       make(map[rpc.ID]*filter),
+		subscriptions: make(map[rpc.ID]*subscription),
+		recentLogs:    make(map[int64][]*types.Log),
+		timeout:       timeout,
+	}
+}
+
+// Notify satisfies the shared.PayloadListener interface
+// It hands the header and logs of a newly served payload to the installed filters and subscriptions
+func (es *EventSystem) Notify(payload shared.ConvertedData) {
+	ethPayload, ok := payload.(ConvertedPayload)
+	if !ok {
+		log.Errorf("eth event system: expected payload type %T got %T", ConvertedPayload{}, payload)
+		return
+	}
+	height := ethPayload.Height()
+	logs := make([]*types.Log, 0)
+	for _, receipt := range ethPayload.Receipts {
+		logs = append(logs, receipt.Logs...)
+	}
+	es.Lock()
+	defer es.Unlock()
+	for h := range es.recentLogs {
+		if h >= height || h <= height-recentLogsDepth {
+			delete(es.recentLogs, h)
+		}
+	}
+	es.recentLogs[height] = logs
+	for _, f := range es.filters {
+		switch f.typ {
+		case blockFilter:
+			f.hashes = append(f.hashes, ethPayload.Block.Hash())
+		case logFilter:
+			f.logs = append(f.logs, FilterLogs(logs, f.crit)...)
+		}
+	}
+	for id, sub := range es.subscriptions {
+		switch sub.typ {
+		case blockFilter:
+			sub.sendHeader(id, ethPayload.Block.Header())
+		case logFilter:
+			sub.sendLogs(id, logs)
+		}
+	}
+}
+
+// NotifyReorg satisfies the shared.PayloadListener interface
+// It hands copies of the logs served at and above the fork height, marked as removed, to the installed log filters
+// and log subscriptions
+func (es *EventSystem) NotifyReorg(forkHeight int64) {
+	es.Lock()
+	defer es.Unlock()
+	removed := make([]*types.Log, 0)
+	for h, logs := range es.recentLogs {
+		if h < forkHeight {
+			continue
+		}
+		for _, l := range logs {
+			removedLog := *l
+			removedLog.Removed = true
+			removed = append(removed, &removedLog)
+		}
+		delete(es.recentLogs, h)
+	}
+	for _, f := range es.filters {
+		if f.typ == logFilter {
+			f.logs = append(f.logs, FilterLogs(removed, f.crit)...)
+		}
+	}
+	for id, sub := range es.subscriptions {
+		if sub.typ == logFilter {
+			sub.sendLogs(id, removed)
+		}
+	}
+}
+
+// install registers a new filter and starts its inactivity timer
+func (es *EventSystem) install(f *filter) rpc.ID {
+	id := rpc.NewID()
+	f.timer = time.AfterFunc(es.timeout, func() {
+		es.uninstall(id)
+	})
+	es.Lock()
+	es.filters[id] = f
+	es.Unlock()
+	return id
+}
+
+// uninstall removes the filter with the provided id, it returns false if there is no such filter
+func (es *EventSystem) uninstall(id rpc.ID) bool {
+	es.Lock()
+	defer es.Unlock()
+	f, ok := es.filters[id]
+	if !ok {
+		return false
+	}
+	f.timer.Stop()
+	delete(es.filters, id)
+	return true
+}
+
+// subscribe registers a new subscription
+func (es *EventSystem) subscribe(id rpc.ID, sub *subscription) {
+	es.Lock()
+	es.subscriptions[id] = sub
+	es.Unlock()
+}
+
+// unsubscribe removes the subscription with the provided id
+func (es *EventSystem) unsubscribe(id rpc.ID) {
+	es.Lock()
+	delete(es.subscriptions, id)
+	es.Unlock()
+}
+
+// sendHeader hands the header to a newHeads subscription without blocking the Serve process
+func (sub *subscription) sendHeader(id rpc.ID, header *types.Header) {
+	select {
+	case sub.headers <- header:
+	default:
+		log.Infof("unable to send header to eth subscription %s; channel is full", id)
+	}
+}
+
+// sendLogs hands the logs matching its criteria to a logs subscription without blocking the Serve process
+func (sub *subscription) sendLogs(id rpc.ID, logs []*types.Log) {
+	matched := FilterLogs(logs, sub.crit)
+	if len(matched) == 0 {
+		return
+	}
+	select {
+	case sub.logs <- matched:
+	default:
+		log.Infof("unable to send logs to eth subscription %s; channel is full", id)
+	}
+}
+
+// criteria returns the criteria and pending catch up query of the log filter with the provided id
+func (es *EventSystem) criteria(id rpc.ID) (ethereum.FilterQuery, *ethereum.FilterQuery, error) {
+	es.Lock()
+	defer es.Unlock()
+	f, ok := es.filters[id]
+	if !ok || f.typ != logFilter {
+		return ethereum.FilterQuery{}, nil, errFilterNotFound
+	}
+	return f.crit, f.catchUp, nil
+}
+
+// changes drains the filter with the provided id and resets its inactivity timer
+// Block filters return the hashes of the blocks served since the last poll; log filters return the provided
+// catch up logs followed by the matching logs served since the last poll
+func (es *EventSystem) changes(id rpc.ID, catchUpLogs []*types.Log) (interface{}, error) {
+	es.Lock()
+	defer es.Unlock()
+	f, ok := es.filters[id]
+	if !ok {
+		return nil, errFilterNotFound
+	}
+	f.timer.Reset(es.timeout)
+	switch f.typ {
+	case blockFilter:
+		hashes := f.hashes
+		f.hashes = nil
+		if hashes == nil {
+			return []common.Hash{}, nil
+		}
+		return hashes, nil
+	default:
+		logs := append(make([]*types.Log, 0, len(catchUpLogs)+len(f.logs)), catchUpLogs...)
+		logs = append(logs, f.logs...)
+		f.logs = nil
+		f.catchUp = nil
+		return logs, nil
+	}
+}
+
+// FilterLogs returns the logs which fall within the block range and match the addresses and topics of the criteria
+func FilterLogs(logs []*types.Log, crit ethereum.FilterQuery) []*types.Log {
+	filtered := make([]*types.Log, 0)
+	for _, l := range logs {
+		if crit.BlockHash != nil && l.BlockHash != *crit.BlockHash {
+			continue
+		}
+		if crit.FromBlock != nil && crit.FromBlock.Sign() >= 0 && crit.FromBlock.Uint64() > l.BlockNumber {
+			continue
+		}
+		if crit.ToBlock != nil && crit.ToBlock.Sign() >= 0 && crit.ToBlock.Uint64() < l.BlockNumber {
+			continue
+		}
+		if len(crit.Addresses) > 0 && !containsAddress(crit.Addresses, l.Address) {
+			continue
+		}
+		if !matchTopics(crit.Topics, l.Topics) {
+			continue
+		}
+		filtered = append(filtered, l)
+	}
+	return filtered
+}
+
+func containsAddress(addresses []common.Address, address common.Address) bool {
+	for _, addr := range addresses {
+		if addr == address {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTopics checks the topics of a log against positional topic sets, an empty set matches any topic
+func matchTopics(topicSets [][]common.Hash, topics []common.Hash) bool {
+	if len(topicSets) > len(topics) {
+		return false
+	}
+	for i, set := range topicSets {
+		if len(set) == 0 {
+			continue
+		}
+		match := false
+		for _, topic := range set {
+			if topic == topics[i] {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,218 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth/mocks"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+var _ = Describe("Polling filters", func() {
+	var (
+		db  *postgres.DB
+		api *eth.PublicEthAPI
+		ctx = context.Background()
+	)
+	BeforeEach(func() {
+		var err error
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		api = eth.NewPublicEthAPI(&eth.Backend{
+			Retriever: eth.NewCIDRetriever(db),
+			Fetcher:   eth.NewIPLDPGFetcher(db),
+			DB:        db,
+			Config:    params.MainnetChainConfig,
+		}, nil)
+		_, err = eth.NewIPLDPublisherAndIndexer(db).Publish(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	Describe("NewBlockFilter", func() {
+		It("Collects the hashes of served blocks until they are polled", func() {
+			id := api.NewBlockFilter()
+			changes, err := api.GetFilterChanges(ctx, id)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changes).To(Equal([]common.Hash{}))
+
+			api.Events.Notify(mocks.MockConvertedPayload)
+			changes, err = api.GetFilterChanges(ctx, id)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changes).To(Equal([]common.Hash{mocks.MockBlock.Hash()}))
+
+			changes, err = api.GetFilterChanges(ctx, id)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changes).To(Equal([]common.Hash{}))
+		})
+	})
+
+	Describe("NewFilter", func() {
+		It("Collects the served logs that match the criteria", func() {
+			id, err := api.NewFilter(ethereum.FilterQuery{
+				Topics: [][]common.Hash{{common.HexToHash("0x04")}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			api.Events.Notify(mocks.MockConvertedPayload)
+			changes, err := api.GetFilterChanges(ctx, id)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changes).To(Equal([]*types.Log{mocks.MockLog1}))
+
+			changes, err = api.GetFilterChanges(ctx, id)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changes).To(Equal([]*types.Log{}))
+		})
+
+		It("Catches up on indexed logs within the criteria's range on the first poll", func() {
+			id, err := api.NewFilter(ethereum.FilterQuery{
+				FromBlock: mocks.MockBlock.Number(),
+				Topics:    [][]common.Hash{{common.HexToHash("0x05")}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			changes, err := api.GetFilterChanges(ctx, id)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changes).To(Equal([]*types.Log{mocks.MockLog2}))
+
+			changes, err = api.GetFilterChanges(ctx, id)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changes).To(Equal([]*types.Log{}))
+		})
+
+		It("Reports the logs of reorged blocks as removed", func() {
+			id, err := api.NewFilter(ethereum.FilterQuery{})
+			Expect(err).ToNot(HaveOccurred())
+			api.Events.Notify(mocks.MockConvertedPayload)
+			changes, err := api.GetFilterChanges(ctx, id)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changes).To(HaveLen(2))
+
+			api.Events.NotifyReorg(mocks.MockBlock.Number().Int64())
+			changes, err = api.GetFilterChanges(ctx, id)
+			Expect(err).ToNot(HaveOccurred())
+			removed, ok := changes.([]*types.Log)
+			Expect(ok).To(BeTrue())
+			Expect(removed).To(HaveLen(2))
+			for _, l := range removed {
+				Expect(l.Removed).To(BeTrue())
+			}
+			Expect(mocks.MockLog1.Removed).To(BeFalse())
+		})
+	})
+
+	Describe("GetFilterLogs", func() {
+		It("Retrieves all indexed logs matching the filter's criteria", func() {
+			id, err := api.NewFilter(ethereum.FilterQuery{
+				FromBlock: mocks.MockBlock.Number(),
+				ToBlock:   mocks.MockBlock.Number(),
+				Topics:    [][]common.Hash{{common.HexToHash("0x04")}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			logs, err := api.GetFilterLogs(ctx, id)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(logs).To(Equal([]*types.Log{mocks.MockLog1}))
+		})
+
+		It("Throws an error for block filters", func() {
+			_, err := api.GetFilterLogs(ctx, api.NewBlockFilter())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("UninstallFilter", func() {
+		It("Removes the filter", func() {
+			id := api.NewBlockFilter()
+			Expect(api.UninstallFilter(id)).To(BeTrue())
+			Expect(api.UninstallFilter(id)).To(BeFalse())
+			_, err := api.GetFilterChanges(ctx, id)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Removes filters that have not been polled within the timeout", func() {
+			api.Events = eth.NewEventSystem(10 * time.Millisecond)
+			id := api.NewBlockFilter()
+			time.Sleep(50 * time.Millisecond)
+			_, err := api.GetFilterChanges(ctx, id)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("Subscriptions", func() {
+	var (
+		api    *eth.PublicEthAPI
+		client *rpc.Client
+		ctx    = context.Background()
+	)
+	BeforeEach(func() {
+		api = eth.NewPublicEthAPI(&eth.Backend{}, nil)
+		server := rpc.NewServer()
+		err := server.RegisterName(eth.APIName, api)
+		Expect(err).ToNot(HaveOccurred())
+		client = rpc.DialInProc(server)
+	})
+	AfterEach(func() {
+		client.Close()
+	})
+
+	It("Sends the header of each served block to newHeads subscriptions", func() {
+		headers := make(chan *types.Header, 1)
+		sub, err := client.EthSubscribe(ctx, headers, "newHeads")
+		Expect(err).ToNot(HaveOccurred())
+		defer sub.Unsubscribe()
+
+		api.Events.Notify(mocks.MockConvertedPayload)
+		var header *types.Header
+		Eventually(headers).Should(Receive(&header))
+		Expect(header.Hash()).To(Equal(mocks.MockBlock.Hash()))
+	})
+
+	It("Sends the served logs matching the criteria to logs subscriptions, and sends them again as removed on reorg", func() {
+		logs := make(chan types.Log, 2)
+		sub, err := client.EthSubscribe(ctx, logs, "logs", map[string]interface{}{
+			"address": mocks.AnotherAddress,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer sub.Unsubscribe()
+
+		api.Events.Notify(mocks.MockConvertedPayload)
+		var l types.Log
+		Eventually(logs).Should(Receive(&l))
+		Expect(l.Address).To(Equal(mocks.AnotherAddress))
+		Expect(l.Topics).To(Equal(mocks.MockLog2.Topics))
+		Expect(l.Removed).To(BeFalse())
+
+		api.Events.NotifyReorg(mocks.MockBlock.Number().Int64())
+		Eventually(logs).Should(Receive(&l))
+		Expect(l.Address).To(Equal(mocks.AnotherAddress))
+		Expect(l.Removed).To(BeTrue())
+		Consistently(logs).ShouldNot(Receive())
+	})
+})
@@ -17,7 +17,10 @@
 package eth
 
 import (
+	"database/sql"
 	"fmt"
+	"math/big"
+	"strconv"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/jmoiron/sqlx"
@@ -31,6 +34,9 @@ var (
 	nullHash = common.HexToHash("0x0000000000000000000000000000000000000000000000000000000000000000")
 )
 
+// canonicalLockID is the advisory lock key used to serialize updates to the canonical eth chain
+const canonicalLockID = 8545
+
 // Indexer satisfies the Indexer interface for ethereum
 type CIDIndexer struct {
 	db *postgres.DB
@@ -84,6 +90,11 @@ func (in *CIDIndexer) Index(cids shared.CIDsForIndexing) error {
 	err = in.indexStateAndStorageCIDs(tx, cidPayload, headerID)
 	if err != nil {
 		log.Error("eth indexer error when indexing state and storage nodes")
+		return err
+	}
+	err = in.markCanonical(tx, cidPayload.HeaderCID, headerID)
+	if err != nil {
+		log.Error("eth indexer error when marking the canonical chain")
 	}
 	return err
 }
@@ -99,6 +110,117 @@ func (in *CIDIndexer) indexHeaderCID(tx *sqlx.Tx, header HeaderModel) (int64, er
 	return headerID, err
 }
 
+// markCanonical decides whether or not the header belongs to the canonical chain, the chain with the greatest total difficulty
+// If it does, the header is marked canonical and we walk back along parent_hash, marking its ancestors canonical and
+// the competing headers at their heights non-canonical, until we reach a header which is already canonical (the fork point)
+func (in *CIDIndexer) markCanonical(tx *sqlx.Tx, header HeaderModel, headerID int64) error {
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, canonicalLockID); err != nil {
+		return err
+	}
+	canonical, orphanDescendants, err := in.isCanonical(tx, header, headerID)
+	if err != nil {
+		return err
+	}
+	if !canonical {
+		_, err := tx.Exec(`UPDATE eth.header_cids SET canonical = false WHERE id = $1`, headerID)
+		return err
+	}
+	blockNumber, err := strconv.ParseInt(header.BlockNumber, 10, 64)
+	if err != nil {
+		return err
+	}
+	if orphanDescendants {
+		// The header is the head of a heavier chain, all canonical headers above it are orphaned
+		if _, err := tx.Exec(`UPDATE eth.header_cids SET canonical = false WHERE block_number > $1 AND canonical = true`, blockNumber); err != nil {
+			return err
+		}
+	}
+	id, parentHash := headerID, header.ParentHash
+	for {
+		if _, err := tx.Exec(`UPDATE eth.header_cids SET canonical = (id = $1) WHERE block_number = $2`, id, blockNumber); err != nil {
+			return err
+		}
+		parent := new(HeaderModel)
+		err := tx.Get(parent, `SELECT id, parent_hash, canonical FROM eth.header_cids
+								WHERE block_number = $1 AND block_hash = $2`, blockNumber-1, parentHash)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if parent.Canonical {
+			return nil
+		}
+		id, parentHash = parent.ID, parent.ParentHash
+		blockNumber--
+	}
+}
+
+// isCanonical returns whether or not the header belongs to the canonical chain and, if it does,
+// whether the canonical headers above it now belong to an orphaned chain
+// Total difficulty stops growing after the merge, so a header at or above the canonical tip with the same total difficulty
+// is taken to be the node's new head: it may extend the tip, follow a gap in the indexed headers, or switch branches
+func (in *CIDIndexer) isCanonical(tx *sqlx.Tx, header HeaderModel, headerID int64) (bool, bool, error) {
+	tip := new(HeaderModel)
+	err := tx.Get(tip, `SELECT block_number, td FROM eth.header_cids
+						WHERE canonical = true AND id <> $1
+						ORDER BY block_number DESC LIMIT 1`, headerID)
+	if err == sql.ErrNoRows {
+		return true, false, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+	blockNumber, err := strconv.ParseInt(header.BlockNumber, 10, 64)
+	if err != nil {
+		return false, false, err
+	}
+	tipNumber, err := strconv.ParseInt(tip.BlockNumber, 10, 64)
+	if err != nil {
+		return false, false, err
+	}
+	td, ok := new(big.Int).SetString(header.TotalDifficulty, 10)
+	if !ok {
+		return false, false, fmt.Errorf("eth indexer unable to parse header total difficulty %s", header.TotalDifficulty)
+	}
+	tipTD, ok := new(big.Int).SetString(tip.TotalDifficulty, 10)
+	if !ok {
+		return false, false, fmt.Errorf("eth indexer unable to parse canonical tip total difficulty %s", tip.TotalDifficulty)
+	}
+	heavier := td.Cmp(tipTD) > 0
+	var childParentHashes []string
+	if err := tx.Select(&childParentHashes, `SELECT parent_hash FROM eth.header_cids
+											WHERE block_number = $1 AND canonical = true`, blockNumber+1); err != nil {
+		return false, false, err
+	}
+	if len(childParentHashes) > 0 {
+		// The canonical chain already extends above this height; the header is canonical if it is the parent of the canonical child
+		// or if it is the head of a chain heavier than the current canonical chain
+		for _, parentHash := range childParentHashes {
+			if parentHash == header.BlockHash {
+				return true, false, nil
+			}
+		}
+		return heavier, heavier, nil
+	}
+	// A header at or above the canonical tip is the new head unless its chain is lighter, markCanonical then walks its
+	// parents back to the fork point
+	if blockNumber >= tipNumber {
+		return td.Cmp(tipTD) >= 0, false, nil
+	}
+	// Below the tip and above a gap, the header is canonical unless it competes with a canonical header
+	var competing bool
+	if err := tx.Get(&competing, `SELECT EXISTS(SELECT 1 FROM eth.header_cids
+								WHERE block_number = $1 AND canonical = true AND id <> $2)`, blockNumber, headerID); err != nil {
+		return false, false, err
+	}
+	if !competing {
+		return true, false, nil
+	}
+	return heavier, heavier, nil
+}
+
 func (in *CIDIndexer) indexUncleCID(tx *sqlx.Tx, uncle UncleModel, headerID int64) error {
 	_, err := tx.Exec(`INSERT INTO eth.uncle_cids (block_hash, header_id, parent_hash, cid, reward, mh_key) VALUES ($1, $2, $3, $4, $5, $6)
 								ON CONFLICT (header_id, block_hash) DO UPDATE SET (parent_hash, cid, reward, mh_key) = ($3, $4, $5, $6)`,
@@ -133,5 +133,138 @@ var _ = Describe("Indexer", func() {
 				Path:       []byte{},
 			}))
 		})
+
+		It("Marks the heaviest chain of headers as canonical", func() {
+			err = repo.Index(mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			pgStr := `SELECT block_hash FROM eth.header_cids
+				WHERE block_number = $1
+				AND canonical = true`
+			canonicalHashes := make([]string, 0)
+			err = db.Select(&canonicalHashes, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonicalHashes).To(Equal([]string{mocks.MockCIDPayload.HeaderCID.BlockHash}))
+
+			// a competing header at the same height with the same total difficulty is the node's new head
+			forkHeader := mocks.MockCIDPayload.HeaderCID
+			forkHeader.BlockHash = common.HexToHash("0x01").String()
+			err = repo.Index(&eth.CIDPayload{HeaderCID: forkHeader})
+			Expect(err).ToNot(HaveOccurred())
+			canonicalHashes = make([]string, 0)
+			err = db.Select(&canonicalHashes, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonicalHashes).To(Equal([]string{forkHeader.BlockHash}))
+
+			// a competing header with a greater total difficulty replaces it
+			heavyHeader := forkHeader
+			heavyHeader.BlockHash = common.HexToHash("0x04").String()
+			heavyHeader.TotalDifficulty = "6000000"
+			err = repo.Index(&eth.CIDPayload{HeaderCID: heavyHeader})
+			Expect(err).ToNot(HaveOccurred())
+			canonicalHashes = make([]string, 0)
+			err = db.Select(&canonicalHashes, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonicalHashes).To(Equal([]string{heavyHeader.BlockHash}))
+
+			// a lighter header above the canonical tip does not replace it
+			lightHeader := mocks.MockCIDPayload.HeaderCID
+			lightHeader.BlockNumber = "2"
+			lightHeader.BlockHash = common.HexToHash("0x05").String()
+			lightHeader.ParentHash = forkHeader.BlockHash
+			lightHeader.TotalDifficulty = "5500000"
+			err = repo.Index(&eth.CIDPayload{HeaderCID: lightHeader})
+			Expect(err).ToNot(HaveOccurred())
+			canonicalHashes = make([]string, 0)
+			err = db.Select(&canonicalHashes, pgStr, 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonicalHashes).To(BeEmpty())
+
+			// extending the original header makes its chain canonical again
+			childHeader := mocks.MockCIDPayload.HeaderCID
+			childHeader.BlockNumber = "2"
+			childHeader.BlockHash = common.HexToHash("0x02").String()
+			childHeader.ParentHash = mocks.MockCIDPayload.HeaderCID.BlockHash
+			childHeader.TotalDifficulty = "2000000000"
+			err = repo.Index(&eth.CIDPayload{HeaderCID: childHeader})
+			Expect(err).ToNot(HaveOccurred())
+			canonicalHashes = make([]string, 0)
+			err = db.Select(&canonicalHashes, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonicalHashes).To(Equal([]string{mocks.MockCIDPayload.HeaderCID.BlockHash}))
+			canonicalHashes = make([]string, 0)
+			err = db.Select(&canonicalHashes, pgStr, 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonicalHashes).To(Equal([]string{childHeader.BlockHash}))
+
+			// a stale header below the canonical tip is not canonical
+			staleHeader := forkHeader
+			staleHeader.BlockHash = common.HexToHash("0x03").String()
+			err = repo.Index(&eth.CIDPayload{HeaderCID: staleHeader})
+			Expect(err).ToNot(HaveOccurred())
+			canonicalHashes = make([]string, 0)
+			err = db.Select(&canonicalHashes, pgStr, 1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(canonicalHashes).To(Equal([]string{mocks.MockCIDPayload.HeaderCID.BlockHash}))
+		})
+
+		It("Follows the streamed head when total difficulty no longer grows", func() {
+			err = repo.Index(mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			pgStr := `SELECT block_hash FROM eth.header_cids
+				WHERE block_number = $1
+				AND canonical = true`
+			expectCanonical := func(blockNumber int64, hash string) {
+				canonicalHashes := make([]string, 0)
+				err := db.Select(&canonicalHashes, pgStr, blockNumber)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(canonicalHashes).To(Equal([]string{hash}))
+			}
+
+			// after the merge total difficulty no longer grows, so every header has the same total difficulty
+			childHeader := mocks.MockCIDPayload.HeaderCID
+			childHeader.BlockNumber = "2"
+			childHeader.BlockHash = common.HexToHash("0x02").String()
+			childHeader.ParentHash = mocks.MockCIDPayload.HeaderCID.BlockHash
+			err = repo.Index(&eth.CIDPayload{HeaderCID: childHeader})
+			Expect(err).ToNot(HaveOccurred())
+			expectCanonical(2, childHeader.BlockHash)
+
+			// the node switching to a competing branch at the same height
+			siblingHeader := childHeader
+			siblingHeader.BlockHash = common.HexToHash("0x03").String()
+			err = repo.Index(&eth.CIDPayload{HeaderCID: siblingHeader})
+			Expect(err).ToNot(HaveOccurred())
+			expectCanonical(2, siblingHeader.BlockHash)
+
+			// and back to the original branch, whose new head is walked back to the fork point
+			grandchildHeader := childHeader
+			grandchildHeader.BlockNumber = "3"
+			grandchildHeader.BlockHash = common.HexToHash("0x04").String()
+			grandchildHeader.ParentHash = childHeader.BlockHash
+			err = repo.Index(&eth.CIDPayload{HeaderCID: grandchildHeader})
+			Expect(err).ToNot(HaveOccurred())
+			expectCanonical(3, grandchildHeader.BlockHash)
+			expectCanonical(2, childHeader.BlockHash)
+			expectCanonical(1, mocks.MockCIDPayload.HeaderCID.BlockHash)
+
+			// a header indexed above a gap, e.g. out of order by another worker, is the new head
+			gapHeader := childHeader
+			gapHeader.BlockNumber = "5"
+			gapHeader.BlockHash = common.HexToHash("0x06").String()
+			gapHeader.ParentHash = common.HexToHash("0x05").String()
+			err = repo.Index(&eth.CIDPayload{HeaderCID: gapHeader})
+			Expect(err).ToNot(HaveOccurred())
+			expectCanonical(5, gapHeader.BlockHash)
+
+			// and the header filling the gap joins the canonical chain below it
+			fillHeader := childHeader
+			fillHeader.BlockNumber = "4"
+			fillHeader.BlockHash = gapHeader.ParentHash
+			fillHeader.ParentHash = grandchildHeader.BlockHash
+			err = repo.Index(&eth.CIDPayload{HeaderCID: fillHeader})
+			Expect(err).ToNot(HaveOccurred())
+			expectCanonical(4, fillHeader.BlockHash)
+			expectCanonical(5, gapHeader.BlockHash)
+		})
 	})
 })
@@ -0,0 +1,494 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ipfs/go-cid"
+	"github.com/jmoiron/sqlx"
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/ipfs/ipld"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// IPLDStateDB satisfies the vm.StateDB interface
+var _ vm.StateDB = &IPLDStateDB{}
+
+// IPLDStateDB is a vm.StateDB backed by the trie node IPLDs in Postgres
+// Account and storage trie nodes are lazily resolved from public.blocks, starting at the state root of a header,
+// and every modification made during execution is held in an in-memory overlay that is never written back
+type IPLDStateDB struct {
+	tx        *sqlx.Tx
+	stateRoot common.Hash
+
+	// Accounts resolved or created so far; a nil entry records that the account does not exist
+	accounts map[common.Address]*ipldStateObject
+
+	refund  uint64
+	logs    []*types.Log
+	journal []func()
+
+	// The first error encountered while resolving state, since the vm.StateDB methods cannot return one
+	err error
+}
+
+// ipldStateObject is the in-memory representation of an account during execution
+type ipldStateObject struct {
+	nonce    uint64
+	balance  *big.Int
+	root     common.Hash
+	codeHash common.Hash
+	code     []byte
+
+	committedStorage map[common.Hash]common.Hash
+	dirtyStorage     map[common.Hash]common.Hash
+	suicided         bool
+}
+
+// NewIPLDStateDB creates a new IPLDStateDB for the state trie with the provided root
+// The provided Postgres tx is used for all node lookups and must outlive the IPLDStateDB
+func NewIPLDStateDB(tx *sqlx.Tx, stateRoot common.Hash) *IPLDStateDB {
+	return &IPLDStateDB{
+		tx:        tx,
+		stateRoot: stateRoot,
+		accounts:  make(map[common.Address]*ipldStateObject),
+	}
+}
+
+// Error returns the first error encountered while resolving state
+func (s *IPLDStateDB) Error() error {
+	return s.err
+}
+
+// Logs returns the logs emitted during execution
+func (s *IPLDStateDB) Logs() []*types.Log {
+	return s.logs
+}
+
+func (s *IPLDStateDB) setError(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// getStateObject returns the account at the address, resolving it from the state trie if it has not been seen yet
+// It returns nil if the account does not exist
+func (s *IPLDStateDB) getStateObject(addr common.Address) *ipldStateObject {
+	if obj, ok := s.accounts[addr]; ok {
+		return obj
+	}
+	value, _, err := resolveTrieLeaf(s.tx, s.stateRoot, crypto.Keccak256(addr.Bytes()), decodeStateTrieNode)
+	if err != nil {
+		s.setError(err)
+		return nil
+	}
+	var obj *ipldStateObject
+	if value != nil {
+		snapshot, ok := value.(*ipld.EthAccountSnapshot)
+		if !ok {
+			s.setError(fmt.Errorf("unexpected state leaf value type %T for address %s", value, addr.Hex()))
+			return nil
+		}
+		obj = &ipldStateObject{
+			nonce:            snapshot.Nonce,
+			balance:          new(big.Int).Set(snapshot.Balance),
+			root:             common.BytesToHash(snapshot.Root),
+			codeHash:         common.BytesToHash(snapshot.CodeHash),
+			committedStorage: make(map[common.Hash]common.Hash),
+			dirtyStorage:     make(map[common.Hash]common.Hash),
+		}
+	}
+	s.accounts[addr] = obj
+	return obj
+}
+
+// getOrNewStateObject returns the account at the address, creating it if it does not exist
+func (s *IPLDStateDB) getOrNewStateObject(addr common.Address) *ipldStateObject {
+	obj := s.getStateObject(addr)
+	if obj == nil {
+		obj = s.createObject(addr)
+	}
+	return obj
+}
+
+// createObject creates a new, empty account at the address, replacing any existing one
+func (s *IPLDStateDB) createObject(addr common.Address) *ipldStateObject {
+	prev, known := s.accounts[addr]
+	s.journal = append(s.journal, func() {
+		if known {
+			s.accounts[addr] = prev
+		} else {
+			delete(s.accounts, addr)
+		}
+	})
+	obj := &ipldStateObject{
+		balance:          new(big.Int),
+		root:             types.EmptyRootHash,
+		codeHash:         emptyCodeHash,
+		code:             []byte{},
+		committedStorage: make(map[common.Hash]common.Hash),
+		dirtyStorage:     make(map[common.Hash]common.Hash),
+	}
+	s.accounts[addr] = obj
+	return obj
+}
+
+// resolveTrieLeaf walks the trie with the provided root along the nibbles of the provided (hashed) key,
+// fetching each node from public.blocks by its multihash key
+// It returns the decoded leaf value, or nil if the key is not present in the trie, along with the RLP of each node
+// fetched along the way; these form the Merkle proof of the value (or of its absence)
+func resolveTrieLeaf(tx *sqlx.Tx, root common.Hash, key []byte, decode trieNodeDecoder) (interface{}, [][]byte, error) {
+	if root == types.EmptyRootHash || root == (common.Hash{}) {
+		return nil, nil, nil
+	}
+	mh, err := multihash.Encode(root.Bytes(), multihash.KECCAK_256)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := cid.NewCidV1(decode.codec, mh)
+	nibbles := keyToNibbles(key)
+	var proof [][]byte
+	for {
+		raw, err := shared.FetchIPLDByMhKey(tx, shared.MultihashKeyFromCID(c))
+		if err == sql.ErrNoRows {
+			return nil, nil, fmt.Errorf("trie node %s is not available", c.String())
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		proof = append(proof, raw)
+		node, err := decode.decode(c, raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		value, next, rest, err := node.Lookup(nibbles)
+		if err != nil || next == nil {
+			return value, proof, err
+		}
+		c, nibbles = *next, rest
+	}
+}
+
+// decodeStorageValue decodes the value held in a storage leaf
+func decodeStorageValue(leaf interface{}) (common.Hash, error) {
+	if leaf == nil {
+		return common.Hash{}, nil
+	}
+	valueRLP, ok := leaf.([]byte)
+	if !ok {
+		return common.Hash{}, fmt.Errorf("unexpected storage leaf value type %T", leaf)
+	}
+	_, content, _, err := rlp.Split(valueRLP)
+	return common.BytesToHash(content), err
+}
+
+// trieNodeDecoder pairs a trie codec with the function used to decode its nodes
+type trieNodeDecoder struct {
+	codec  uint64
+	decode func(cid.Cid, []byte) (*ipld.TrieNode, error)
+}
+
+var (
+	decodeStateTrieNode = trieNodeDecoder{
+		codec: ipld.MEthStateTrie,
+		decode: func(c cid.Cid, b []byte) (*ipld.TrieNode, error) {
+			node, err := ipld.DecodeEthStateTrie(c, b)
+			if err != nil {
+				return nil, err
+			}
+			return node.TrieNode, nil
+		},
+	}
+	decodeStorageTrieNode = trieNodeDecoder{
+		codec: ipld.MEthStorageTrie,
+		decode: func(c cid.Cid, b []byte) (*ipld.TrieNode, error) {
+			node, err := ipld.DecodeEthStorageTrie(c, b)
+			if err != nil {
+				return nil, err
+			}
+			return node.TrieNode, nil
+		},
+	}
+)
+
+// keyToNibbles expands a trie key into its nibbles
+func keyToNibbles(key []byte) []byte {
+	nibbles := make([]byte, len(key)*2)
+	for i, b := range key {
+		nibbles[i*2] = b / 16
+		nibbles[i*2+1] = b % 16
+	}
+	return nibbles
+}
+
+// CreateAccount explicitly creates a state object, carrying over the balance of any previous account at the address
+func (s *IPLDStateDB) CreateAccount(addr common.Address) {
+	prev := s.getStateObject(addr)
+	obj := s.createObject(addr)
+	if prev != nil {
+		obj.balance.Set(prev.balance)
+	}
+}
+
+// SubBalance subtracts amount from the account associated with addr
+func (s *IPLDStateDB) SubBalance(addr common.Address, amount *big.Int) {
+	if amount.Sign() == 0 {
+		return
+	}
+	obj := s.getOrNewStateObject(addr)
+	s.setBalance(obj, new(big.Int).Sub(obj.balance, amount))
+}
+
+// AddBalance adds amount to the account associated with addr
+func (s *IPLDStateDB) AddBalance(addr common.Address, amount *big.Int) {
+	obj := s.getOrNewStateObject(addr)
+	s.setBalance(obj, new(big.Int).Add(obj.balance, amount))
+}
+
+func (s *IPLDStateDB) setBalance(obj *ipldStateObject, amount *big.Int) {
+	prev := obj.balance
+	s.journal = append(s.journal, func() { obj.balance = prev })
+	obj.balance = amount
+}
+
+// GetBalance retrieves the balance from the given address or 0 if object not found
+func (s *IPLDStateDB) GetBalance(addr common.Address) *big.Int {
+	if obj := s.getStateObject(addr); obj != nil {
+		return new(big.Int).Set(obj.balance)
+	}
+	return new(big.Int)
+}
+
+// GetNonce retrieves the nonce from the given address or 0 if object not found
+func (s *IPLDStateDB) GetNonce(addr common.Address) uint64 {
+	if obj := s.getStateObject(addr); obj != nil {
+		return obj.nonce
+	}
+	return 0
+}
+
+// SetNonce sets the nonce of the account associated with addr
+func (s *IPLDStateDB) SetNonce(addr common.Address, nonce uint64) {
+	obj := s.getOrNewStateObject(addr)
+	prev := obj.nonce
+	s.journal = append(s.journal, func() { obj.nonce = prev })
+	obj.nonce = nonce
+}
+
+// GetCodeHash retrieves the code hash from the given address or the zero hash if object not found
+func (s *IPLDStateDB) GetCodeHash(addr common.Address) common.Hash {
+	if obj := s.getStateObject(addr); obj != nil {
+		return obj.codeHash
+	}
+	return common.Hash{}
+}
+
+// GetCode retrieves the code from the given address, fetching it from public.blocks by its code hash
+func (s *IPLDStateDB) GetCode(addr common.Address) []byte {
+	obj := s.getStateObject(addr)
+	if obj == nil {
+		return nil
+	}
+	if obj.code == nil {
+		code, err := fetchCode(s.tx, obj.codeHash)
+		if err != nil {
+			s.setError(err)
+			return nil
+		}
+		obj.code = code
+	}
+	return obj.code
+}
+
+// SetCode sets the code of the account associated with addr
+func (s *IPLDStateDB) SetCode(addr common.Address, code []byte) {
+	obj := s.getOrNewStateObject(addr)
+	prevCode, prevHash := obj.code, obj.codeHash
+	s.journal = append(s.journal, func() { obj.code, obj.codeHash = prevCode, prevHash })
+	obj.code = code
+	obj.codeHash = crypto.Keccak256Hash(code)
+}
+
+// GetCodeSize retrieves the size of the code at the given address
+func (s *IPLDStateDB) GetCodeSize(addr common.Address) int {
+	return len(s.GetCode(addr))
+}
+
+// AddRefund adds gas to the refund counter
+func (s *IPLDStateDB) AddRefund(gas uint64) {
+	prev := s.refund
+	s.journal = append(s.journal, func() { s.refund = prev })
+	s.refund += gas
+}
+
+// SubRefund removes gas from the refund counter
+// This method will panic if the refund counter goes below zero
+func (s *IPLDStateDB) SubRefund(gas uint64) {
+	if gas > s.refund {
+		panic("refund counter below zero")
+	}
+	prev := s.refund
+	s.journal = append(s.journal, func() { s.refund = prev })
+	s.refund -= gas
+}
+
+// GetRefund returns the current value of the refund counter
+func (s *IPLDStateDB) GetRefund() uint64 {
+	return s.refund
+}
+
+// GetCommittedState retrieves a value from the storage trie of the given account as of the state root
+func (s *IPLDStateDB) GetCommittedState(addr common.Address, key common.Hash) common.Hash {
+	obj := s.getStateObject(addr)
+	if obj == nil {
+		return common.Hash{}
+	}
+	if value, ok := obj.committedStorage[key]; ok {
+		return value
+	}
+	leaf, _, err := resolveTrieLeaf(s.tx, obj.root, crypto.Keccak256(key.Bytes()), decodeStorageTrieNode)
+	if err != nil {
+		s.setError(err)
+		return common.Hash{}
+	}
+	value, err := decodeStorageValue(leaf)
+	if err != nil {
+		s.setError(err)
+		return common.Hash{}
+	}
+	obj.committedStorage[key] = value
+	return value
+}
+
+// GetState retrieves a value from the given account's storage, including any modifications made during execution
+func (s *IPLDStateDB) GetState(addr common.Address, key common.Hash) common.Hash {
+	obj := s.getStateObject(addr)
+	if obj == nil {
+		return common.Hash{}
+	}
+	if value, ok := obj.dirtyStorage[key]; ok {
+		return value
+	}
+	return s.GetCommittedState(addr, key)
+}
+
+// SetState sets a value in the given account's storage
+func (s *IPLDStateDB) SetState(addr common.Address, key, value common.Hash) {
+	obj := s.getOrNewStateObject(addr)
+	prev, dirty := obj.dirtyStorage[key]
+	s.journal = append(s.journal, func() {
+		if dirty {
+			obj.dirtyStorage[key] = prev
+		} else {
+			delete(obj.dirtyStorage, key)
+		}
+	})
+	obj.dirtyStorage[key] = value
+}
+
+// Suicide marks the given account as suicided and clears its balance
+// The account's state object is still available until the execution is finished
+func (s *IPLDStateDB) Suicide(addr common.Address) bool {
+	obj := s.getStateObject(addr)
+	if obj == nil {
+		return false
+	}
+	prevSuicided, prevBalance := obj.suicided, obj.balance
+	s.journal = append(s.journal, func() { obj.suicided, obj.balance = prevSuicided, prevBalance })
+	obj.suicided = true
+	obj.balance = new(big.Int)
+	return true
+}
+
+// HasSuicided returns whether the given account has been marked as suicided
+func (s *IPLDStateDB) HasSuicided(addr common.Address) bool {
+	if obj := s.getStateObject(addr); obj != nil {
+		return obj.suicided
+	}
+	return false
+}
+
+// Exist reports whether the given account exists in state
+// Notably this also returns true for suicided accounts
+func (s *IPLDStateDB) Exist(addr common.Address) bool {
+	return s.getStateObject(addr) != nil
+}
+
+// Empty returns whether the given account is empty, according to EIP161 (balance = nonce = code = 0)
+func (s *IPLDStateDB) Empty(addr common.Address) bool {
+	obj := s.getStateObject(addr)
+	return obj == nil || (obj.nonce == 0 && obj.balance.Sign() == 0 && obj.codeHash == emptyCodeHash)
+}
+
+// Snapshot returns an identifier for the current revision of the state
+func (s *IPLDStateDB) Snapshot() int {
+	return len(s.journal)
+}
+
+// RevertToSnapshot reverts all state changes made since the given revision
+func (s *IPLDStateDB) RevertToSnapshot(revid int) {
+	if revid < 0 || revid > len(s.journal) {
+		panic(fmt.Errorf("revision id %v cannot be reverted", revid))
+	}
+	for i := len(s.journal) - 1; i >= revid; i-- {
+		s.journal[i]()
+	}
+	s.journal = s.journal[:revid]
+}
+
+// AddLog adds a log emitted during execution
+func (s *IPLDStateDB) AddLog(log *types.Log) {
+	prev := len(s.logs)
+	s.journal = append(s.journal, func() { s.logs = s.logs[:prev] })
+	log.Index = uint(prev)
+	s.logs = append(s.logs, log)
+}
+
+// AddPreimage is a no-op, preimages are not recorded
+func (s *IPLDStateDB) AddPreimage(hash common.Hash, preimage []byte) {}
+
+// ForEachStorage iterates over the storage slots of the given account that have been read or written during execution
+// Storage tries are resolved lazily, so slots that have not been touched are not visited
+func (s *IPLDStateDB) ForEachStorage(addr common.Address, cb func(key, value common.Hash) bool) error {
+	obj := s.getStateObject(addr)
+	if obj == nil {
+		return s.err
+	}
+	for key, value := range obj.dirtyStorage {
+		if !cb(key, value) {
+			return nil
+		}
+	}
+	for key, value := range obj.committedStorage {
+		if _, dirty := obj.dirtyStorage[key]; dirty {
+			continue
+		}
+		if !cb(key, value) {
+			return nil
+		}
+	}
+	return nil
+}
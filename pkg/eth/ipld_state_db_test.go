@@ -0,0 +1,163 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth/mocks"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+var (
+	// runtime code that returns the value held in storage slot 0
+	// PUSH1 0 SLOAD PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	slotZeroReturnCode = common.Hex2Bytes("60005460005260206000f3")
+	slotZeroValue      = common.HexToHash("0x2a")
+)
+
+// publishTestState builds a state trie in memory and copies all of its nodes, and the contract code, into public.blocks
+// AccountAddresss holds 1000 wei with a nonce of 3, ContractAddress holds code returning its slot 0, and 32 further
+// accounts fill out the upper levels of the trie
+func publishTestState(db *postgres.DB) common.Hash {
+	diskdb := rawdb.NewMemoryDatabase()
+	sdb := state.NewDatabase(diskdb)
+	s, err := state.New(common.Hash{}, sdb)
+	Expect(err).ToNot(HaveOccurred())
+	s.SetBalance(mocks.AccountAddresss, big.NewInt(1000))
+	s.SetNonce(mocks.AccountAddresss, 3)
+	s.SetCode(mocks.ContractAddress, slotZeroReturnCode)
+	s.SetState(mocks.ContractAddress, common.Hash{}, slotZeroValue)
+	for i := int64(0); i < 32; i++ {
+		s.SetBalance(common.BigToAddress(big.NewInt(i+1)), big.NewInt(i+1))
+	}
+	stateRoot, err := s.Commit(true)
+	Expect(err).ToNot(HaveOccurred())
+	err = sdb.TrieDB().Commit(stateRoot, false)
+	Expect(err).ToNot(HaveOccurred())
+
+	tx, err := db.Beginx()
+	Expect(err).ToNot(HaveOccurred())
+	it := diskdb.NewIterator()
+	defer it.Release()
+	for it.Next() {
+		if len(it.Key()) != common.HashLength {
+			continue
+		}
+		mhKey, err := shared.MultihashKeyFromKeccak256(common.BytesToHash(it.Key()))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = tx.Exec(`INSERT INTO public.blocks (key, data) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING`, mhKey, it.Value())
+		Expect(err).ToNot(HaveOccurred())
+	}
+	err = tx.Commit()
+	Expect(err).ToNot(HaveOccurred())
+	return stateRoot
+}
+
+var _ = Describe("IPLDStateDB", func() {
+	var (
+		db        *postgres.DB
+		tx        *sqlx.Tx
+		stateRoot common.Hash
+		stateDB   *eth.IPLDStateDB
+	)
+	BeforeEach(func() {
+		var err error
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+
+		stateRoot = publishTestState(db)
+		tx, err = db.Beginx()
+		Expect(err).ToNot(HaveOccurred())
+		stateDB = eth.NewIPLDStateDB(tx, stateRoot)
+	})
+	AfterEach(func() {
+		shared.Rollback(tx)
+		eth.TearDownDB(db)
+	})
+
+	It("Lazily resolves accounts, code and storage from the trie node IPLDs", func() {
+		Expect(stateDB.Exist(mocks.AccountAddresss)).To(BeTrue())
+		Expect(stateDB.GetBalance(mocks.AccountAddresss).Int64()).To(Equal(int64(1000)))
+		Expect(stateDB.GetNonce(mocks.AccountAddresss)).To(Equal(uint64(3)))
+		Expect(stateDB.GetBalance(common.BigToAddress(big.NewInt(17))).Int64()).To(Equal(int64(17)))
+		Expect(stateDB.GetCodeHash(mocks.ContractAddress)).To(Equal(crypto.Keccak256Hash(slotZeroReturnCode)))
+		Expect(stateDB.GetCode(mocks.ContractAddress)).To(Equal(slotZeroReturnCode))
+		Expect(stateDB.GetState(mocks.ContractAddress, common.Hash{})).To(Equal(slotZeroValue))
+		Expect(stateDB.GetState(mocks.ContractAddress, common.HexToHash("0x01"))).To(Equal(common.Hash{}))
+		Expect(stateDB.Error()).ToNot(HaveOccurred())
+	})
+
+	It("Treats accounts missing from the trie as empty", func() {
+		Expect(stateDB.Exist(mocks.AnotherAddress)).To(BeFalse())
+		Expect(stateDB.Empty(mocks.AnotherAddress)).To(BeTrue())
+		Expect(stateDB.GetBalance(mocks.AnotherAddress).Int64()).To(Equal(int64(0)))
+		Expect(stateDB.GetCode(mocks.AnotherAddress)).To(BeEmpty())
+		Expect(stateDB.Error()).ToNot(HaveOccurred())
+	})
+
+	It("Reverts in-memory modifications to a snapshot", func() {
+		snapshot := stateDB.Snapshot()
+		stateDB.AddBalance(mocks.AccountAddresss, big.NewInt(1))
+		stateDB.SetState(mocks.ContractAddress, common.Hash{}, common.HexToHash("0x01"))
+		stateDB.CreateAccount(mocks.AnotherAddress)
+		Expect(stateDB.GetBalance(mocks.AccountAddresss).Int64()).To(Equal(int64(1001)))
+		Expect(stateDB.GetState(mocks.ContractAddress, common.Hash{})).To(Equal(common.HexToHash("0x01")))
+		Expect(stateDB.GetCommittedState(mocks.ContractAddress, common.Hash{})).To(Equal(slotZeroValue))
+		Expect(stateDB.Exist(mocks.AnotherAddress)).To(BeTrue())
+
+		stateDB.RevertToSnapshot(snapshot)
+		Expect(stateDB.GetBalance(mocks.AccountAddresss).Int64()).To(Equal(int64(1000)))
+		Expect(stateDB.GetState(mocks.ContractAddress, common.Hash{})).To(Equal(slotZeroValue))
+		Expect(stateDB.Exist(mocks.AnotherAddress)).To(BeFalse())
+	})
+
+	It("Executes contract calls against the resolved state", func() {
+		ctx := vm.Context{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			BlockNumber: big.NewInt(1),
+			GasLimit:    params.GenesisGasLimit,
+			Difficulty:  big.NewInt(1),
+			GasPrice:    big.NewInt(0),
+		}
+		evm := vm.NewEVM(ctx, stateDB, params.MainnetChainConfig, vm.Config{})
+		ret, _, err := evm.Call(vm.AccountRef(mocks.AccountAddresss), mocks.ContractAddress, nil, 100000, big.NewInt(0))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(common.BytesToHash(ret)).To(Equal(slotZeroValue))
+		Expect(stateDB.Error()).ToNot(HaveOccurred())
+	})
+
+	It("Records an error if a trie node is not available", func() {
+		stateDB = eth.NewIPLDStateDB(tx, common.HexToHash("0x01"))
+		Expect(stateDB.Exist(mocks.AccountAddresss)).To(BeFalse())
+		Expect(stateDB.Error()).To(HaveOccurred())
+	})
+})
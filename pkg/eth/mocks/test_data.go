@@ -240,7 +240,8 @@ var (
 
 	nonce1             = uint64(1)
 	ContractRoot       = "0x821e2556a290c86405f8160a2d662042a431ba456b9db265c79bb837c04be5f0"
-	ContractCodeHash   = common.HexToHash("0x753f98a8d4328b15636e46f66f2cb4bc860100aa17967cc145fcd17d1d4710ea")
+	ContractCode       = common.Hex2Bytes("602a60005260206000f3")
+	ContractCodeHash   = crypto.Keccak256Hash(ContractCode)
 	contractPath       = common.Bytes2Hex([]byte{'\x06'})
 	ContractLeafKey    = testhelpers.AddressToLeafKey(ContractAddress)
 	ContractAccount, _ = rlp.EncodeToBytes(state.Account{
@@ -301,6 +302,18 @@ var (
 		BlockNumber: new(big.Int).Set(BlockNumber),
 		BlockHash:   MockBlock.Hash(),
 		Nodes:       StateDiffs,
+		CodeAndCodeHashes: []statediff.CodeAndCodeHash{
+			{
+				Hash: ContractCodeHash,
+				Code: ContractCode,
+			},
+		},
+	}
+	MockCode = []eth.CodeAndCodeHash{
+		{
+			Hash: ContractCodeHash,
+			Code: ContractCode,
+		},
 	}
 	MockStateDiffBytes, _ = rlp.EncodeToBytes(MockStateDiff)
 	MockStateNodes        = []eth.TrieNode{
@@ -360,6 +373,7 @@ var (
 		ReceiptMetaData: MockRctMeta,
 		StorageNodes:    MockStorageNodes,
 		StateNodes:      MockStateNodes,
+		Code:            MockCode,
 	}
 
 	MockCIDPayload = &eth.CIDPayload{
@@ -430,6 +444,7 @@ var (
 			Bloom:           MockBlock.Bloom().Bytes(),
 			Timestamp:       MockBlock.Time(),
 			TimesValidated:  1,
+			Canonical:       true,
 		},
 		Transactions: MockTrxMetaPostPublsh,
 		Receipts:     MockRctMetaPostPublish,
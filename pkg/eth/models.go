@@ -36,6 +36,7 @@ type HeaderModel struct {
 	Bloom           []byte `db:"bloom"`
 	Timestamp       uint64 `db:"timestamp"`
 	TimesValidated  int64  `db:"times_validated"`
+	Canonical       bool   `db:"canonical"`
 }
 
 // UncleModel is the db model for eth.uncle_cids
@@ -76,6 +77,15 @@ type ReceiptModel struct {
 	Topic3s      pq.StringArray `db:"topic3s"`
 }
 
+// RangeReceiptModel is a ReceiptModel along with the block and index of its transaction,
+// the position used to page through the receipts of a block range
+type RangeReceiptModel struct {
+	ReceiptModel
+	BlockHash   string `db:"block_hash"`
+	BlockNumber int64  `db:"block_number"`
+	Index       int64  `db:"index"`
+}
+
 // StateNodeModel is the db model for eth.state_cids
 type StateNodeModel struct {
 	ID       int64  `db:"id"`
@@ -0,0 +1,45 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth"
+)
+
+var _ = Describe("Params", func() {
+	Describe("ChainConfig", func() {
+		It("Resolves the chain config of the network with the chain id, defaulting to mainnet", func() {
+			for chainID, config := range map[uint64]*params.ChainConfig{
+				0: params.MainnetChainConfig,
+				1: params.MainnetChainConfig,
+				3: params.TestnetChainConfig,
+				4: params.RinkebyChainConfig,
+				5: params.GoerliChainConfig,
+			} {
+				resolved, err := eth.ChainConfig(chainID)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resolved).To(Equal(config))
+			}
+			_, err := eth.ChainConfig(1337)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -54,6 +54,7 @@ func NewPayloadFetcher(bc BatchClient, timeout time.Duration) *PayloadFetcher {
 			IncludeBlock:             true,
 			IntermediateStateNodes:   true,
 			IntermediateStorageNodes: true,
+			IncludeCode:              true,
 		},
 	}
 }
@@ -0,0 +1,66 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// proxyCall forwards a call the watcher could not answer from its index to the upstream node, if one is configured
+// It returns whether or not the upstream node answered the call, if it did the answer is decoded into result
+func (pea *PublicEthAPI) proxyCall(ctx context.Context, result interface{}, method string, args ...interface{}) bool {
+	if pea.rpc == nil {
+		return false
+	}
+	if err := pea.rpc.CallContext(ctx, result, method, args...); err != nil {
+		log.Errorf("upstream node failed to answer proxied %s call: %v", method, err)
+		shared.RecordProxyError(method)
+		return false
+	}
+	shared.RecordProxiedCall(shared.ProxyUnavailable, method)
+	return true
+}
+
+// toBlockNumArg encodes a block number the way it is expected as a json-rpc argument
+func toBlockNumArg(number rpc.BlockNumber) string {
+	switch number {
+	case rpc.LatestBlockNumber:
+		return "latest"
+	case rpc.PendingBlockNumber:
+		return "pending"
+	default:
+		return hexutil.EncodeUint64(uint64(number.Int64()))
+	}
+}
+
+// toBlockNumOrHashArg encodes a block number or hash the way it is expected as a json-rpc argument
+func toBlockNumOrHashArg(blockNrOrHash rpc.BlockNumberOrHash) interface{} {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		return map[string]interface{}{
+			"blockHash":        hash,
+			"requireCanonical": blockNrOrHash.RequireCanonical,
+		}
+	}
+	number, _ := blockNrOrHash.Number()
+	return toBlockNumArg(number)
+}
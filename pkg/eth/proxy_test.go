@@ -0,0 +1,115 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package eth_test
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth/mocks"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// upstreamEthAPI stands in for the eth api of the upstream node
+type upstreamEthAPI struct{}
+
+func (api *upstreamEthAPI) GetBlockByNumber(number rpc.BlockNumber, fullTx bool) map[string]interface{} {
+	return map[string]interface{}{
+		"number": hexutil.EncodeUint64(uint64(number)),
+	}
+}
+
+// upstreamBalance is the balance of every account at every block according to the upstream node
+var upstreamBalance = (*hexutil.Big)(big.NewInt(42))
+
+func (api *upstreamEthAPI) GetBalance(address common.Address, blockNrOrHash rpc.BlockNumberOrHash) *hexutil.Big {
+	return upstreamBalance
+}
+
+// upstreamCallResult is the return data of every call executed by the upstream node
+var upstreamCallResult = hexutil.Bytes{0x01}
+
+func (api *upstreamEthAPI) Call(args eth.CallArgs, blockNrOrHash rpc.BlockNumberOrHash) hexutil.Bytes {
+	return upstreamCallResult
+}
+
+func (api *upstreamEthAPI) EstimateGas(args eth.CallArgs, blockNrOrHash rpc.BlockNumberOrHash) hexutil.Uint64 {
+	return hexutil.Uint64(params.TxGas)
+}
+
+var _ = Describe("Upstream proxy", func() {
+	var (
+		db     *postgres.DB
+		client *rpc.Client
+		api    *eth.PublicEthAPI
+		ctx    = context.Background()
+	)
+	BeforeEach(func() {
+		var err error
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		server := rpc.NewServer()
+		err = server.RegisterName(eth.APIName, new(upstreamEthAPI))
+		Expect(err).ToNot(HaveOccurred())
+		client = rpc.DialInProc(server)
+		api = eth.NewPublicEthAPI(&eth.Backend{
+			Retriever: eth.NewCIDRetriever(db),
+			Fetcher:   eth.NewIPLDPGFetcher(db),
+			DB:        db,
+			Config:    params.MainnetChainConfig,
+		}, client)
+		_, err = eth.NewIPLDPublisherAndIndexer(db).Publish(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		client.Close()
+		eth.TearDownDB(db)
+	})
+
+	It("Answers indexed blocks from the index", func() {
+		block, err := api.GetBlockByNumber(ctx, rpc.BlockNumber(mocks.MockBlock.Number().Int64()), false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(block["hash"]).To(Equal(mocks.MockBlock.Hash()))
+	})
+
+	It("Forwards calls for blocks that are not indexed to the upstream node", func() {
+		block, err := api.GetBlockByNumber(ctx, rpc.BlockNumber(100), false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(block["number"]).To(Equal("0x64"))
+	})
+
+	It("Forwards state calls for blocks above the indexed head to the upstream node", func() {
+		balance, err := api.GetBalance(ctx, mocks.AccountAddresss, rpc.BlockNumberOrHashWithNumber(100))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(balance).To(Equal(upstreamBalance))
+	})
+
+	It("Returns the watcher's error if the upstream node cannot answer either", func() {
+		block, err := api.GetBlockByHash(ctx, common.HexToHash("0x01"), false)
+		Expect(err).To(HaveOccurred())
+		Expect(block).To(BeNil())
+	})
+})
@@ -152,8 +152,21 @@ func (pub *IPLDPublisherAndIndexer) Publish(payload shared.ConvertedData) (share
 		}
 	}
 
+	// Publish contract code, keyed by its keccak256 hash so it can be found from account code hashes
+	for _, code := range ipldPayload.Code {
+		if _, err := shared.PublishRaw(tx, ipld.RawBinary, multihash.KECCAK_256, code.Code); err != nil {
+			return nil, err
+		}
+	}
+
 	// Publish and index state and storage
 	err = pub.publishAndIndexStateAndStorage(tx, ipldPayload, headerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update the canonical chain
+	err = pub.indexer.markCanonical(tx, header, headerID)
 
 	// This IPLDPublisher does both publishing and indexing, we do not need to pass anything forward to the indexer
 	return nil, err // return err variable explicitly so that we return the err = tx.Commit() assignment in the defer
@@ -24,6 +24,9 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/statediff"
+	"github.com/ipfs/go-cid"
+	dag "github.com/ipfs/go-merkledag"
+	"github.com/multiformats/go-multihash"
 
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/ipfs"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/ipfs/dag_putters"
@@ -40,6 +43,7 @@ type IPLDPublisher struct {
 	ReceiptTriePutter     ipfs.DagPutter
 	StatePutter           ipfs.DagPutter
 	StoragePutter         ipfs.DagPutter
+	CodePutter            ipfs.DagPutter
 }
 
 // NewIPLDPublisher creates a pointer to a new IPLDPublisher which satisfies the IPLDPublisher interface
@@ -56,6 +60,7 @@ func NewIPLDPublisher(ipfsPath string) (*IPLDPublisher, error) {
 		ReceiptTriePutter:     dag_putters.NewEthRctTrieDagPutter(node),
 		StatePutter:           dag_putters.NewEthStateDagPutter(node),
 		StoragePutter:         dag_putters.NewEthStorageDagPutter(node),
+		CodePutter:            dag_putters.NewRawDagPutter(node),
 	}, nil
 }
 
@@ -134,6 +139,11 @@ func (pub *IPLDPublisher) Publish(payload shared.ConvertedData) (shared.CIDsForI
 		return nil, err
 	}
 
+	// Process and publish contract code
+	if err := pub.publishCode(ipldPayload.Code); err != nil {
+		return nil, err
+	}
+
 	// Package CIDs and their metadata into a single struct
 	return &CIDPayload{
 		HeaderCID:       header,
@@ -253,6 +263,25 @@ func (pub *IPLDPublisher) publishStateNodes(stateNodes []TrieNode) ([]StateNodeM
 	return stateNodeCids, stateAccounts, nil
 }
 
+func (pub *IPLDPublisher) publishCode(codes []CodeAndCodeHash) error {
+	for _, code := range codes {
+		// Code is keyed by its keccak256 hash, the same multihash that account code hashes reference
+		node, err := dag.NewRawNodeWPrefix(code.Code, cid.Prefix{
+			Version:  1,
+			Codec:    ipld.RawBinary,
+			MhType:   multihash.KECCAK_256,
+			MhLength: -1,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := pub.CodePutter.DagPut(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (pub *IPLDPublisher) publishStorageNodes(storageNodes map[string][]TrieNode) (map[string][]StorageNodeModel, error) {
 	storageLeafCids := make(map[string][]StorageNodeModel)
 	for path, storageTrie := range storageNodes {
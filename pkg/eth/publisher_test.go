@@ -34,6 +34,7 @@ var (
 	mockRctTrieDagPutter *mocks2.DagPutter
 	mockStateDagPutter   *mocks2.MappedDagPutter
 	mockStorageDagPutter *mocks2.MappedDagPutter
+	mockCodeDagPutter    *mocks2.DagPutter
 )
 
 var _ = Describe("Publisher", func() {
@@ -45,6 +46,7 @@ var _ = Describe("Publisher", func() {
 		mockRctTrieDagPutter = new(mocks2.DagPutter)
 		mockStateDagPutter = new(mocks2.MappedDagPutter)
 		mockStorageDagPutter = new(mocks2.MappedDagPutter)
+		mockCodeDagPutter = new(mocks2.DagPutter)
 	})
 
 	Describe("Publish", func() {
@@ -77,6 +79,7 @@ var _ = Describe("Publisher", func() {
 				ReceiptTriePutter:     mockRctTrieDagPutter,
 				StatePutter:           mockStateDagPutter,
 				StoragePutter:         mockStorageDagPutter,
+				CodePutter:            mockCodeDagPutter,
 			}
 			payload, err := publisher.Publish(mocks.MockConvertedPayload)
 			Expect(err).ToNot(HaveOccurred())
@@ -100,6 +103,7 @@ var _ = Describe("Publisher", func() {
 			Expect(cidPayload.StateNodeCIDs[0]).To(Equal(mocks.MockCIDPayload.StateNodeCIDs[0]))
 			Expect(cidPayload.StateNodeCIDs[1]).To(Equal(mocks.MockCIDPayload.StateNodeCIDs[1]))
 			Expect(cidPayload.StorageNodeCIDs).To(Equal(mocks.MockCIDPayload.StorageNodeCIDs))
+			Expect(mockCodeDagPutter.PassedNode.RawData()).To(Equal(mocks.ContractCode))
 		})
 	})
 })
@@ -51,6 +51,7 @@ func NewPayloadStreamer(client StreamClient) *PayloadStreamer {
 			IncludeReceipts:          true,
 			IntermediateStorageNodes: true,
 			IntermediateStateNodes:   true,
+			IncludeCode:              true,
 		},
 	}
 }
@@ -37,6 +37,7 @@ type ConvertedPayload struct {
 	ReceiptMetaData []ReceiptModel
 	StateNodes      []TrieNode
 	StorageNodes    map[string][]TrieNode
+	Code            []CodeAndCodeHash
 }
 
 // Height satisfies the StreamedIPLDs interface
@@ -44,6 +45,16 @@ func (i ConvertedPayload) Height() int64 {
 	return i.Block.Number().Int64()
 }
 
+// Hash satisfies the StreamedIPLDs interface
+func (i ConvertedPayload) Hash() string {
+	return i.Block.Hash().String()
+}
+
+// ParentHash satisfies the StreamedIPLDs interface
+func (i ConvertedPayload) ParentHash() string {
+	return i.Block.ParentHash().String()
+}
+
 // Trie struct used to flag node as leaf or not
 type TrieNode struct {
 	Path    []byte
@@ -52,6 +63,12 @@ type TrieNode struct {
 	Type    statediff.NodeType
 }
 
+// CodeAndCodeHash holds contract bytecode deployed in a block alongside its keccak256 hash
+type CodeAndCodeHash struct {
+	Hash common.Hash
+	Code []byte
+}
+
 // CIDPayload is a struct to hold all the CIDs and their associated meta data for indexing in Postgres
 // Returned by IPLDPublisher
 // Passed to CIDIndexer
@@ -0,0 +1,1005 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package graphql serves the Ethereum data indexed by the watcher over the EIP-1767 GraphQL schema
+// Every object is resolved lazily through the eth Backend, from the CIDs indexed in Postgres and the IPLDs they reference
+package graphql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth"
+)
+
+// callTimeout is the execution limit of the call and estimateGas accessors
+const callTimeout = 5 * time.Second
+
+var (
+	errBlockInvariant = errors.New("block objects must be instantiated with at least one of num or hash")
+	errNotSupported   = errors.New("not supported by the ipfs-blockchain-watcher")
+)
+
+// Account represents an Ethereum account at a particular block.
+type Account struct {
+	backend       *eth.Backend
+	address       common.Address
+	blockNrOrHash rpc.BlockNumberOrHash
+}
+
+func (a *Account) Address(ctx context.Context) (common.Address, error) {
+	return a.address, nil
+}
+
+func (a *Account) Balance(ctx context.Context) (hexutil.Big, error) {
+	account, err := a.backend.StateAccountByNumberOrHash(ctx, a.address, a.blockNrOrHash)
+	if err != nil || account == nil {
+		return hexutil.Big{}, err
+	}
+	balance, ok := new(big.Int).SetString(account.Balance, 10)
+	if !ok {
+		return hexutil.Big{}, fmt.Errorf("balance retrieved from Postgres cannot be converted to an integer: %s", account.Balance)
+	}
+	return hexutil.Big(*balance), nil
+}
+
+func (a *Account) TransactionCount(ctx context.Context) (hexutil.Uint64, error) {
+	account, err := a.backend.StateAccountByNumberOrHash(ctx, a.address, a.blockNrOrHash)
+	if err != nil || account == nil {
+		return 0, err
+	}
+	return hexutil.Uint64(account.Nonce), nil
+}
+
+func (a *Account) Code(ctx context.Context) (hexutil.Bytes, error) {
+	code, err := a.backend.CodeByNumberOrHash(ctx, a.address, a.blockNrOrHash)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(code), nil
+}
+
+func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash }) (common.Hash, error) {
+	return a.backend.StorageByNumberOrHash(ctx, a.address, args.Slot, a.blockNrOrHash)
+}
+
+// Log represents an individual log message. All arguments are mandatory.
+type Log struct {
+	backend     *eth.Backend
+	transaction *Transaction
+	log         *types.Log
+}
+
+func (l *Log) Transaction(ctx context.Context) *Transaction {
+	return l.transaction
+}
+
+func (l *Log) Account(ctx context.Context, args BlockNumberArgs) *Account {
+	return &Account{
+		backend:       l.backend,
+		address:       l.log.Address,
+		blockNrOrHash: args.NumberOrLatest(),
+	}
+}
+
+func (l *Log) Index(ctx context.Context) int32 {
+	return int32(l.log.Index)
+}
+
+func (l *Log) Topics(ctx context.Context) []common.Hash {
+	return l.log.Topics
+}
+
+func (l *Log) Data(ctx context.Context) hexutil.Bytes {
+	return hexutil.Bytes(l.log.Data)
+}
+
+// Transaction represents an Ethereum transaction.
+// backend and hash are mandatory; all others will be fetched when required.
+type Transaction struct {
+	backend *eth.Backend
+	hash    common.Hash
+	tx      *types.Transaction
+	block   *Block
+	index   uint64
+}
+
+// resolve returns the internal transaction object, fetching it if needed.
+// Only transactions in canonical blocks are indexed, so it returns nil if the transaction is not found.
+func (t *Transaction) resolve(ctx context.Context) (*types.Transaction, error) {
+	if t.tx == nil {
+		tx, blockHash, _, index, err := t.backend.GetTransaction(ctx, t.hash)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		t.tx = tx
+		blockNrOrHash := rpc.BlockNumberOrHashWithHash(blockHash, false)
+		t.block = &Block{
+			backend:      t.backend,
+			numberOrHash: &blockNrOrHash,
+			hash:         blockHash,
+		}
+		t.index = index
+	}
+	return t.tx, nil
+}
+
+func (t *Transaction) Hash(ctx context.Context) common.Hash {
+	return t.hash
+}
+
+func (t *Transaction) InputData(ctx context.Context) (hexutil.Bytes, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(tx.Data()), nil
+}
+
+func (t *Transaction) Gas(ctx context.Context) (hexutil.Uint64, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return 0, err
+	}
+	return hexutil.Uint64(tx.Gas()), nil
+}
+
+func (t *Transaction) GasPrice(ctx context.Context) (hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*tx.GasPrice()), nil
+}
+
+func (t *Transaction) Value(ctx context.Context) (hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*tx.Value()), nil
+}
+
+func (t *Transaction) Nonce(ctx context.Context) (hexutil.Uint64, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return 0, err
+	}
+	return hexutil.Uint64(tx.Nonce()), nil
+}
+
+func (t *Transaction) To(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	to := tx.To()
+	if to == nil {
+		return nil, nil
+	}
+	return &Account{
+		backend:       t.backend,
+		address:       *to,
+		blockNrOrHash: args.NumberOrLatest(),
+	}, nil
+}
+
+func (t *Transaction) From(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.Protected() {
+		signer = types.NewEIP155Signer(tx.ChainId())
+	}
+	from, _ := types.Sender(signer, tx)
+
+	return &Account{
+		backend:       t.backend,
+		address:       from,
+		blockNrOrHash: args.NumberOrLatest(),
+	}, nil
+}
+
+func (t *Transaction) Block(ctx context.Context) (*Block, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	return t.block, nil
+}
+
+func (t *Transaction) Index(ctx context.Context) (*int32, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	if t.block == nil {
+		return nil, nil
+	}
+	index := int32(t.index)
+	return &index, nil
+}
+
+// getReceipt returns the receipt associated with this transaction, if any.
+func (t *Transaction) getReceipt(ctx context.Context) (*types.Receipt, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return nil, err
+	}
+	if t.block == nil {
+		return nil, nil
+	}
+	receipts, err := t.block.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if t.index >= uint64(len(receipts)) {
+		return nil, nil
+	}
+	return receipts[t.index], nil
+}
+
+func (t *Transaction) Status(ctx context.Context) (*hexutil.Uint64, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	ret := hexutil.Uint64(receipt.Status)
+	return &ret, nil
+}
+
+func (t *Transaction) GasUsed(ctx context.Context) (*hexutil.Uint64, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	ret := hexutil.Uint64(receipt.GasUsed)
+	return &ret, nil
+}
+
+func (t *Transaction) CumulativeGasUsed(ctx context.Context) (*hexutil.Uint64, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	ret := hexutil.Uint64(receipt.CumulativeGasUsed)
+	return &ret, nil
+}
+
+func (t *Transaction) CreatedContract(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil || receipt.ContractAddress == (common.Address{}) {
+		return nil, err
+	}
+	return &Account{
+		backend:       t.backend,
+		address:       receipt.ContractAddress,
+		blockNrOrHash: args.NumberOrLatest(),
+	}, nil
+}
+
+func (t *Transaction) Logs(ctx context.Context) (*[]*Log, error) {
+	receipt, err := t.getReceipt(ctx)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	ret := make([]*Log, 0, len(receipt.Logs))
+	for _, log := range receipt.Logs {
+		ret = append(ret, &Log{
+			backend:     t.backend,
+			transaction: t,
+			log:         log,
+		})
+	}
+	return &ret, nil
+}
+
+func (t *Transaction) R(ctx context.Context) (hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Big{}, err
+	}
+	_, r, _ := tx.RawSignatureValues()
+	return hexutil.Big(*r), nil
+}
+
+func (t *Transaction) S(ctx context.Context) (hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Big{}, err
+	}
+	_, _, s := tx.RawSignatureValues()
+	return hexutil.Big(*s), nil
+}
+
+func (t *Transaction) V(ctx context.Context) (hexutil.Big, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return hexutil.Big{}, err
+	}
+	v, _, _ := tx.RawSignatureValues()
+	return hexutil.Big(*v), nil
+}
+
+// Block represents an Ethereum block.
+// backend, and numberOrHash are mandatory. All other fields are lazily fetched
+// when required.
+type Block struct {
+	backend      *eth.Backend
+	numberOrHash *rpc.BlockNumberOrHash
+	hash         common.Hash
+	header       *types.Header
+	block        *types.Block
+	receipts     types.Receipts
+	transactions types.Transactions
+}
+
+// resolve returns the internal Block object representing this block, fetching
+// it if necessary. It returns nil if the block is not indexed, as is the case for ommers.
+func (b *Block) resolve(ctx context.Context) (*types.Block, error) {
+	if b.block != nil {
+		return b.block, nil
+	}
+	if b.numberOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		b.numberOrHash = &latest
+	}
+	var err error
+	if hash, ok := b.numberOrHash.Hash(); ok {
+		b.block, err = b.backend.BlockByHash(ctx, hash)
+	} else {
+		number, _ := b.numberOrHash.Number()
+		b.block, err = b.backend.BlockByNumber(ctx, number)
+	}
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if b.block != nil && b.header == nil {
+		b.header = b.block.Header()
+	}
+	return b.block, err
+}
+
+// resolveHeader returns the internal Header object for this block, fetching it
+// if necessary. Call this function instead of `resolve` unless you need the
+// additional data (transactions and uncles).
+func (b *Block) resolveHeader(ctx context.Context) (*types.Header, error) {
+	if b.numberOrHash == nil && b.hash == (common.Hash{}) {
+		return nil, errBlockInvariant
+	}
+	var err error
+	if b.header == nil {
+		if b.hash != (common.Hash{}) {
+			b.header, err = b.backend.HeaderByHash(ctx, b.hash)
+		} else if hash, ok := b.numberOrHash.Hash(); ok {
+			b.header, err = b.backend.HeaderByHash(ctx, hash)
+		} else {
+			number, _ := b.numberOrHash.Number()
+			b.header, err = b.backend.HeaderByNumber(ctx, number)
+		}
+	}
+	return b.header, err
+}
+
+// resolveReceipts returns the list of receipts for this block, fetching them
+// along with the transactions if necessary.
+func (b *Block) resolveReceipts(ctx context.Context) (types.Receipts, error) {
+	if b.receipts == nil {
+		hash, err := b.Hash(ctx)
+		if err != nil {
+			return nil, err
+		}
+		b.receipts, b.transactions, err = b.backend.GetReceipts(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b.receipts, nil
+}
+
+func (b *Block) Number(ctx context.Context) (hexutil.Uint64, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(header.Number.Uint64()), nil
+}
+
+func (b *Block) Hash(ctx context.Context) (common.Hash, error) {
+	if b.hash == (common.Hash{}) {
+		header, err := b.resolveHeader(ctx)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		b.hash = header.Hash()
+	}
+	return b.hash, nil
+}
+
+func (b *Block) GasLimit(ctx context.Context) (hexutil.Uint64, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(header.GasLimit), nil
+}
+
+func (b *Block) GasUsed(ctx context.Context) (hexutil.Uint64, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(header.GasUsed), nil
+}
+
+func (b *Block) Parent(ctx context.Context) (*Block, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if header.Number.Uint64() == 0 {
+		return nil, nil
+	}
+	parentHash := rpc.BlockNumberOrHashWithHash(header.ParentHash, false)
+	return &Block{
+		backend:      b.backend,
+		numberOrHash: &parentHash,
+		hash:         header.ParentHash,
+	}, nil
+}
+
+func (b *Block) Difficulty(ctx context.Context) (hexutil.Big, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*header.Difficulty), nil
+}
+
+func (b *Block) Timestamp(ctx context.Context) (hexutil.Uint64, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(header.Time), nil
+}
+
+func (b *Block) Nonce(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(header.Nonce[:]), nil
+}
+
+func (b *Block) MixHash(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.MixDigest, nil
+}
+
+func (b *Block) TransactionsRoot(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.TxHash, nil
+}
+
+func (b *Block) StateRoot(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.Root, nil
+}
+
+func (b *Block) ReceiptsRoot(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.ReceiptHash, nil
+}
+
+func (b *Block) OmmerHash(ctx context.Context) (common.Hash, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return header.UncleHash, nil
+}
+
+func (b *Block) OmmerCount(ctx context.Context) (*int32, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	count := int32(len(block.Uncles()))
+	return &count, err
+}
+
+func (b *Block) Ommers(ctx context.Context) (*[]*Block, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	ret := make([]*Block, 0, len(block.Uncles()))
+	for _, uncle := range block.Uncles() {
+		blockNumberOrHash := rpc.BlockNumberOrHashWithHash(uncle.Hash(), false)
+		ret = append(ret, &Block{
+			backend:      b.backend,
+			numberOrHash: &blockNumberOrHash,
+			header:       uncle,
+		})
+	}
+	return &ret, nil
+}
+
+func (b *Block) ExtraData(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(header.Extra), nil
+}
+
+func (b *Block) LogsBloom(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return hexutil.Bytes(header.Bloom.Bytes()), nil
+}
+
+func (b *Block) TotalDifficulty(ctx context.Context) (hexutil.Big, error) {
+	hash, err := b.Hash(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	td, err := b.backend.GetTd(hash)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*td), nil
+}
+
+// BlockNumberArgs encapsulates arguments to accessors that specify a block number.
+type BlockNumberArgs struct {
+	Block *hexutil.Uint64
+}
+
+// NumberOr returns the provided block number argument, or the "current" block number or hash if none
+// was provided.
+func (a BlockNumberArgs) NumberOr(current rpc.BlockNumberOrHash) rpc.BlockNumberOrHash {
+	if a.Block != nil {
+		blockNr := rpc.BlockNumber(*a.Block)
+		return rpc.BlockNumberOrHashWithNumber(blockNr)
+	}
+	return current
+}
+
+// NumberOrLatest returns the provided block number argument, or the "latest" block number if none
+// was provided.
+func (a BlockNumberArgs) NumberOrLatest() rpc.BlockNumberOrHash {
+	return a.NumberOr(rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+}
+
+func (b *Block) Miner(ctx context.Context, args BlockNumberArgs) (*Account, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		backend:       b.backend,
+		address:       header.Coinbase,
+		blockNrOrHash: args.NumberOrLatest(),
+	}, nil
+}
+
+func (b *Block) TransactionCount(ctx context.Context) (*int32, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	count := int32(len(block.Transactions()))
+	return &count, err
+}
+
+func (b *Block) Transactions(ctx context.Context) (*[]*Transaction, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	ret := make([]*Transaction, 0, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		ret = append(ret, &Transaction{
+			backend: b.backend,
+			hash:    tx.Hash(),
+			tx:      tx,
+			block:   b,
+			index:   uint64(i),
+		})
+	}
+	return &ret, nil
+}
+
+func (b *Block) TransactionAt(ctx context.Context, args struct{ Index int32 }) (*Transaction, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if args.Index < 0 || int(args.Index) >= len(txs) {
+		return nil, nil
+	}
+	tx := txs[args.Index]
+	return &Transaction{
+		backend: b.backend,
+		hash:    tx.Hash(),
+		tx:      tx,
+		block:   b,
+		index:   uint64(args.Index),
+	}, nil
+}
+
+func (b *Block) OmmerAt(ctx context.Context, args struct{ Index int32 }) (*Block, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	uncles := block.Uncles()
+	if args.Index < 0 || int(args.Index) >= len(uncles) {
+		return nil, nil
+	}
+	uncle := uncles[args.Index]
+	blockNumberOrHash := rpc.BlockNumberOrHashWithHash(uncle.Hash(), false)
+	return &Block{
+		backend:      b.backend,
+		numberOrHash: &blockNumberOrHash,
+		header:       uncle,
+	}, nil
+}
+
+// BlockFilterCriteria encapsulates criteria passed to a `logs` accessor inside
+// a block.
+type BlockFilterCriteria struct {
+	Addresses *[]common.Address // restricts matches to events created by specific contracts
+	Topics    *[][]common.Hash  // restricts matches to events with the topics, see ethereum.FilterQuery
+}
+
+func (b *Block) Logs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) ([]*Log, error) {
+	receipts, err := b.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var crit ethereum.FilterQuery
+	if args.Filter.Addresses != nil {
+		crit.Addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		crit.Topics = *args.Filter.Topics
+	}
+	ret := make([]*Log, 0)
+	for i, receipt := range receipts {
+		transaction := &Transaction{
+			backend: b.backend,
+			hash:    b.transactions[i].Hash(),
+			tx:      b.transactions[i],
+			block:   b,
+			index:   uint64(i),
+		}
+		for _, log := range eth.FilterLogs(receipt.Logs, crit) {
+			ret = append(ret, &Log{
+				backend:     b.backend,
+				transaction: transaction,
+				log:         log,
+			})
+		}
+	}
+	return ret, nil
+}
+
+func (b *Block) Account(ctx context.Context, args struct {
+	Address common.Address
+}) (*Account, error) {
+	if b.numberOrHash == nil {
+		_, err := b.resolveHeader(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Account{
+		backend:       b.backend,
+		address:       args.Address,
+		blockNrOrHash: *b.numberOrHash,
+	}, nil
+}
+
+// CallResult encapsulates the result of an invocation of the `call` accessor.
+type CallResult struct {
+	data    hexutil.Bytes  // The return data from the call
+	gasUsed hexutil.Uint64 // The amount of gas used
+	status  hexutil.Uint64 // The return status of the call - 0 for failure or 1 for success.
+}
+
+func (c *CallResult) Data() hexutil.Bytes {
+	return c.data
+}
+
+func (c *CallResult) GasUsed() hexutil.Uint64 {
+	return c.gasUsed
+}
+
+func (c *CallResult) Status() hexutil.Uint64 {
+	return c.status
+}
+
+func (b *Block) Call(ctx context.Context, args struct {
+	Data eth.CallArgs
+}) (*CallResult, error) {
+	if b.numberOrHash == nil {
+		_, err := b.resolve(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	result, gas, failed, err := b.backend.DoCall(ctx, args.Data, *b.numberOrHash, callTimeout)
+	status := hexutil.Uint64(1)
+	if failed {
+		status = 0
+	}
+	return &CallResult{
+		data:    hexutil.Bytes(result),
+		gasUsed: hexutil.Uint64(gas),
+		status:  status,
+	}, err
+}
+
+func (b *Block) EstimateGas(ctx context.Context, args struct {
+	Data eth.CallArgs
+}) (hexutil.Uint64, error) {
+	if b.numberOrHash == nil {
+		_, err := b.resolveHeader(ctx)
+		if err != nil {
+			return hexutil.Uint64(0), err
+		}
+	}
+	gas, err := b.backend.DoEstimateGas(ctx, args.Data, *b.numberOrHash)
+	return hexutil.Uint64(gas), err
+}
+
+// Pending represents the pending state, which the watcher does not have; all of its accessors return an error
+type Pending struct{}
+
+func (p *Pending) TransactionCount(ctx context.Context) (int32, error) {
+	return 0, errNotSupported
+}
+
+func (p *Pending) Transactions(ctx context.Context) (*[]*Transaction, error) {
+	return nil, errNotSupported
+}
+
+func (p *Pending) Account(ctx context.Context, args struct {
+	Address common.Address
+}) (*Account, error) {
+	return nil, errNotSupported
+}
+
+func (p *Pending) Call(ctx context.Context, args struct {
+	Data eth.CallArgs
+}) (*CallResult, error) {
+	return nil, errNotSupported
+}
+
+func (p *Pending) EstimateGas(ctx context.Context, args struct {
+	Data eth.CallArgs
+}) (hexutil.Uint64, error) {
+	return 0, errNotSupported
+}
+
+// Resolver is the top-level object in the GraphQL hierarchy.
+type Resolver struct {
+	backend *eth.Backend
+	fees    *eth.FeeOracle
+}
+
+// NewResolver returns a new Resolver that answers queries from the provided backend
+func NewResolver(backend *eth.Backend) *Resolver {
+	return &Resolver{
+		backend: backend,
+		fees:    eth.NewFeeOracle(backend),
+	}
+}
+
+func (r *Resolver) Block(ctx context.Context, args struct {
+	Number *hexutil.Uint64
+	Hash   *common.Hash
+}) (*Block, error) {
+	var numberOrHash rpc.BlockNumberOrHash
+	if args.Number != nil {
+		numberOrHash = rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(uint64(*args.Number)))
+	} else if args.Hash != nil {
+		numberOrHash = rpc.BlockNumberOrHashWithHash(*args.Hash, false)
+	} else {
+		numberOrHash = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	}
+	block := &Block{
+		backend:      r.backend,
+		numberOrHash: &numberOrHash,
+	}
+	// Resolve the header, return nil if it isn't indexed.
+	_, err := block.resolveHeader(ctx)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (r *Resolver) Blocks(ctx context.Context, args struct {
+	From hexutil.Uint64
+	To   *hexutil.Uint64
+}) ([]*Block, error) {
+	from := rpc.BlockNumber(args.From)
+
+	var to rpc.BlockNumber
+	if args.To != nil {
+		to = rpc.BlockNumber(*args.To)
+	} else {
+		head, err := r.backend.Retriever.RetrieveLastBlockNumber()
+		if err != nil {
+			return nil, err
+		}
+		to = rpc.BlockNumber(head)
+	}
+	if to < from {
+		return []*Block{}, nil
+	}
+	ret := make([]*Block, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		numberOrHash := rpc.BlockNumberOrHashWithNumber(i)
+		ret = append(ret, &Block{
+			backend:      r.backend,
+			numberOrHash: &numberOrHash,
+		})
+	}
+	return ret, nil
+}
+
+func (r *Resolver) Pending(ctx context.Context) *Pending {
+	return &Pending{}
+}
+
+func (r *Resolver) Transaction(ctx context.Context, args struct{ Hash common.Hash }) (*Transaction, error) {
+	tx := &Transaction{
+		backend: r.backend,
+		hash:    args.Hash,
+	}
+	// Resolve the transaction; if it isn't indexed, return nil.
+	t, err := tx.resolve(ctx)
+	if err != nil {
+		return nil, err
+	} else if t == nil {
+		return nil, nil
+	}
+	return tx, nil
+}
+
+func (r *Resolver) SendRawTransaction(ctx context.Context, args struct{ Data hexutil.Bytes }) (common.Hash, error) {
+	return common.Hash{}, errNotSupported
+}
+
+// FilterCriteria encapsulates the arguments to `logs` on the root resolver object.
+type FilterCriteria struct {
+	FromBlock *hexutil.Uint64   // beginning of the queried range, nil means latest block
+	ToBlock   *hexutil.Uint64   // end of the range, nil means latest block
+	Addresses *[]common.Address // restricts matches to events created by specific contracts
+	Topics    *[][]common.Hash  // restricts matches to events with the topics, see ethereum.FilterQuery
+}
+
+func (r *Resolver) Logs(ctx context.Context, args struct{ Filter FilterCriteria }) ([]*Log, error) {
+	var begin, end int64
+	if args.Filter.FromBlock == nil || args.Filter.ToBlock == nil {
+		head, err := r.backend.Retriever.RetrieveLastBlockNumber()
+		if err != nil {
+			return nil, err
+		}
+		begin, end = head, head
+	}
+	if args.Filter.FromBlock != nil {
+		begin = int64(*args.Filter.FromBlock)
+	}
+	if args.Filter.ToBlock != nil {
+		end = int64(*args.Filter.ToBlock)
+	}
+	var crit ethereum.FilterQuery
+	if args.Filter.Addresses != nil {
+		crit.Addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		crit.Topics = *args.Filter.Topics
+	}
+	logs, err := r.backend.DerivedLogsInRange(ctx, crit, begin, end)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Log, 0, len(logs))
+	for _, log := range logs {
+		ret = append(ret, &Log{
+			backend:     r.backend,
+			transaction: &Transaction{backend: r.backend, hash: log.TxHash},
+			log:         log,
+		})
+	}
+	return ret, nil
+}
+
+func (r *Resolver) GasPrice(ctx context.Context) (hexutil.Big, error) {
+	price, err := r.fees.GasPrice(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*price), nil
+}
+
+func (r *Resolver) ProtocolVersion(ctx context.Context) (int32, error) {
+	return 0, errNotSupported
+}
+
+// SyncState represents the synchronisation status returned from the `syncing` accessor.
+type SyncState struct{}
+
+func (s *SyncState) StartingBlock() hexutil.Uint64 {
+	return 0
+}
+
+func (s *SyncState) CurrentBlock() hexutil.Uint64 {
+	return 0
+}
+
+func (s *SyncState) HighestBlock() hexutil.Uint64 {
+	return 0
+}
+
+func (s *SyncState) PulledStates() *hexutil.Uint64 {
+	return nil
+}
+
+func (s *SyncState) KnownStates() *hexutil.Uint64 {
+	return nil
+}
+
+// Syncing is not supported, the progress of the watcher's sync is not tracked against the chain head
+func (r *Resolver) Syncing() (*SyncState, error) {
+	return nil, errNotSupported
+}
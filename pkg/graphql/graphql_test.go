@@ -0,0 +1,133 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package graphql_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/params"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth/mocks"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/graphql"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+type graphQLResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func query(handler http.Handler, q string) graphQLResponse {
+	body, err := json.Marshal(map[string]string{"query": q})
+	Expect(err).ToNot(HaveOccurred())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/graphql", bytes.NewReader(body)))
+	Expect(rec.Code).To(Equal(http.StatusOK))
+	var res graphQLResponse
+	err = json.Unmarshal(rec.Body.Bytes(), &res)
+	Expect(err).ToNot(HaveOccurred())
+	return res
+}
+
+var _ = Describe("GraphQL", func() {
+	var (
+		db      *postgres.DB
+		handler http.Handler
+	)
+	BeforeEach(func() {
+		var err error
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		backend := &eth.Backend{
+			Retriever: eth.NewCIDRetriever(db),
+			Fetcher:   eth.NewIPLDPGFetcher(db),
+			DB:        db,
+			Config:    params.MainnetChainConfig,
+		}
+		handler, err = graphql.NewHandler(backend)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = eth.NewIPLDPublisherAndIndexer(db).Publish(mocks.MockConvertedPayload)
+		Expect(err).ToNot(HaveOccurred())
+	})
+	AfterEach(func() {
+		eth.TearDownDB(db)
+	})
+
+	It("Resolves a block with its transactions, receipts and logs in a single query", func() {
+		res := query(handler, `{ block(number: 1) { number hash transactionCount transactions { hash index status cumulativeGasUsed logs { index topics } } } }`)
+		Expect(res.Errors).To(BeEmpty())
+		block := res.Data["block"].(map[string]interface{})
+		Expect(block["number"]).To(Equal(hexutil.EncodeUint64(mocks.MockBlock.NumberU64())))
+		Expect(block["hash"]).To(Equal(mocks.MockBlock.Hash().Hex()))
+		Expect(block["transactionCount"]).To(BeNumerically("==", len(mocks.MockTransactions)))
+		txs := block["transactions"].([]interface{})
+		Expect(txs).To(HaveLen(len(mocks.MockTransactions)))
+		for i, tx := range txs {
+			fields := tx.(map[string]interface{})
+			Expect(fields["hash"]).To(Equal(mocks.MockTransactions[i].Hash().Hex()))
+			Expect(fields["index"]).To(BeNumerically("==", i))
+			Expect(fields["cumulativeGasUsed"]).To(Equal(hexutil.EncodeUint64(mocks.MockReceipts[i].CumulativeGasUsed)))
+			Expect(fields["logs"]).To(HaveLen(len(mocks.MockReceipts[i].Logs)))
+		}
+		firstLog := txs[0].(map[string]interface{})["logs"].([]interface{})[0].(map[string]interface{})
+		Expect(firstLog["index"]).To(BeNumerically("==", 0))
+		Expect(firstLog["topics"]).To(ConsistOf(mocks.MockLog1.Topics[0].Hex(), mocks.MockLog1.Topics[1].Hex()))
+	})
+
+	It("Resolves transactions by hash", func() {
+		res := query(handler, fmt.Sprintf(`{ transaction(hash: "%s") { index block { hash } } }`, mocks.MockTransactions[1].Hash().Hex()))
+		Expect(res.Errors).To(BeEmpty())
+		tx := res.Data["transaction"].(map[string]interface{})
+		Expect(tx["index"]).To(BeNumerically("==", 1))
+		Expect(tx["block"].(map[string]interface{})["hash"]).To(Equal(mocks.MockBlock.Hash().Hex()))
+
+		res = query(handler, `{ transaction(hash: "0x0000000000000000000000000000000000000000000000000000000000000001") { index } }`)
+		Expect(res.Errors).To(BeEmpty())
+		Expect(res.Data["transaction"]).To(BeNil())
+	})
+
+	It("Filters logs by block range, address and topics", func() {
+		res := query(handler, fmt.Sprintf(`{ logs(filter: { fromBlock: 0, toBlock: 1, addresses: ["%s"] }) { index transaction { hash } } }`, mocks.AnotherAddress.Hex()))
+		Expect(res.Errors).To(BeEmpty())
+		logs := res.Data["logs"].([]interface{})
+		Expect(logs).To(HaveLen(1))
+		log := logs[0].(map[string]interface{})
+		Expect(log["index"]).To(BeNumerically("==", 1))
+		Expect(log["transaction"].(map[string]interface{})["hash"]).To(Equal(mocks.MockTransactions[1].Hash().Hex()))
+
+		res = query(handler, fmt.Sprintf(`{ block(number: 1) { logs(filter: { topics: [["%s"]] }) { index } } }`, mocks.MockLog1.Topics[0].Hex()))
+		Expect(res.Errors).To(BeEmpty())
+		Expect(res.Data["block"].(map[string]interface{})["logs"]).To(HaveLen(1))
+	})
+
+	It("Returns null for blocks that are not indexed", func() {
+		res := query(handler, `{ block(number: 100) { hash } }`)
+		Expect(res.Errors).To(BeEmpty())
+		Expect(res.Data["block"]).To(BeNil())
+	})
+})
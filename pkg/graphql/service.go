@@ -0,0 +1,56 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth"
+)
+
+// NewHandler returns a new http.Handler that answers GraphQL queries, posted to /graphql, from the provided backend
+func NewHandler(backend *eth.Backend) (http.Handler, error) {
+	s, err := graphql.ParseSchema(schema, NewResolver(backend))
+	if err != nil {
+		return nil, err
+	}
+	h := &relay.Handler{Schema: s}
+
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", h)
+	mux.Handle("/graphql/", h)
+	return mux, nil
+}
+
+// StartHTTPEndpoint starts the GraphQL server on the provided endpoint, it returns the endpoint's listener
+func StartHTTPEndpoint(endpoint string, backend *eth.Backend) (net.Listener, error) {
+	handler, err := NewHandler(backend)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, err
+	}
+	go rpc.NewHTTPServer(nil, nil, rpc.HTTPTimeouts{}, handler).Serve(listener)
+	return listener, nil
+}
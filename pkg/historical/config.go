@@ -20,9 +20,13 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/spf13/viper"
 
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/config"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/node"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
@@ -48,6 +52,10 @@ type Config struct {
 	IPFSPath string
 	IPFSMode shared.IPFSMode
 	DBConfig config.Database
+	// Chain parameters of the bitcoin network, nil for ethereum
+	BtcParams *chaincfg.Params
+	// Chain config of the ethereum network, nil for bitcoin
+	EthChainConfig *params.ChainConfig
 
 	DB              *postgres.DB
 	HTTPClient      interface{}
@@ -115,9 +123,17 @@ func (c *Config) init() error {
 		if err != nil {
 			return err
 		}
-	case shared.Bitcoin:
+		c.EthChainConfig, err = eth.ChainConfig(shared.GetEthChainID())
+		if err != nil {
+			return err
+		}
+	case shared.Bitcoin, shared.Omni:
 		btcHTTP := viper.GetString("bitcoin.httpPath")
 		c.NodeInfo, c.HTTPClient = shared.GetBtcNodeAndClient(btcHTTP)
+		c.BtcParams, err = btc.NetworkParams(shared.GetBtcNetwork())
+		if err != nil {
+			return err
+		}
 	}
 
 	freq := viper.GetInt("superNode.frequency")
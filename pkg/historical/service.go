@@ -64,15 +64,15 @@ type BackFillService struct {
 
 // NewBackFillService returns a new BackFillInterface
 func NewBackFillService(settings *Config, screenAndServeChan chan shared.ConvertedData) (BackFillInterface, error) {
-	publisher, err := builders.NewIPLDPublisher(settings.Chain, settings.IPFSPath, settings.DB, settings.IPFSMode)
+	publisher, err := builders.NewIPLDPublisher(settings.Chain, settings.IPFSPath, settings.DB, settings.IPFSMode, settings.HTTPClient, settings.BtcParams)
 	if err != nil {
 		return nil, err
 	}
-	indexer, err := builders.NewCIDIndexer(settings.Chain, settings.DB, settings.IPFSMode)
+	indexer, err := builders.NewCIDIndexer(settings.Chain, settings.DB, settings.IPFSMode, settings.HTTPClient, settings.BtcParams)
 	if err != nil {
 		return nil, err
 	}
-	converter, err := builders.NewPayloadConverter(settings.Chain)
+	converter, err := builders.NewPayloadConverter(settings.Chain, settings.HTTPClient, settings.BtcParams, settings.EthChainConfig)
 	if err != nil {
 		return nil, err
 	}
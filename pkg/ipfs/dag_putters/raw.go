@@ -0,0 +1,40 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dag_putters
+
+import (
+	"strings"
+
+	node "github.com/ipfs/go-ipld-format"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/ipfs"
+)
+
+type RawDagPutter struct {
+	adder *ipfs.IPFS
+}
+
+func NewRawDagPutter(adder *ipfs.IPFS) *RawDagPutter {
+	return &RawDagPutter{adder: adder}
+}
+
+func (rdp *RawDagPutter) DagPut(n node.Node) (string, error) {
+	if err := rdp.adder.Add(n); err != nil && !strings.Contains(err.Error(), duplicateKeyErrorString) {
+		return "", err
+	}
+	return n.Cid().String(), nil
+}
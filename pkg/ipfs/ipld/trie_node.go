@@ -17,6 +17,7 @@
 package ipld
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 
@@ -50,55 +51,94 @@ type trieNodeLeafDecoder func([]interface{}) ([]interface{}, error)
 // cid and rawdata.
 func decodeTrieNode(c cid.Cid, b []byte,
 	leafDecoder trieNodeLeafDecoder) (*TrieNode, error) {
-	var (
-		i, decoded, elements []interface{}
-		nodeKind             string
-		err                  error
-	)
+	var i []interface{}
+	if err := rlp.DecodeBytes(b, &i); err != nil {
+		return nil, err
+	}
+
+	nodeKind, elements, err := decodeTrieNodeElements(i, c.Type(), leafDecoder)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TrieNode{
+		nodeKind: nodeKind,
+		elements: elements,
+		rawdata:  b,
+		cid:      c,
+	}, nil
+}
 
-	if err = rlp.DecodeBytes(b, &i); err != nil {
+// decodeEmbeddedTrieNode returns a TrieNode object for a node that is
+// shorter than 32 bytes and is therefore embedded in its parent instead of
+// being referenced by hash. Embedded nodes have no cid of their own.
+func decodeEmbeddedTrieNode(i []interface{}, codec uint64,
+	leafDecoder trieNodeLeafDecoder) (*TrieNode, error) {
+	nodeKind, elements, err := decodeTrieNodeElements(i, codec, leafDecoder)
+	if err != nil {
 		return nil, err
 	}
+	rawdata, err := rlp.EncodeToBytes(i)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TrieNode{
+		nodeKind: nodeKind,
+		elements: elements,
+		rawdata:  rawdata,
+	}, nil
+}
+
+// decodeTrieNodeElements returns the nodeKind and elements of
+// a node from its decoded RLP list.
+func decodeTrieNodeElements(i []interface{}, codec uint64,
+	leafDecoder trieNodeLeafDecoder) (string, []interface{}, error) {
+	var (
+		decoded, elements []interface{}
+		nodeKind          string
+		err               error
+	)
 
-	codec := c.Type()
 	switch len(i) {
 	case 2:
 		nodeKind, decoded, err = decodeCompactKey(i)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 
 		if nodeKind == "extension" {
-			elements, err = parseTrieNodeExtension(decoded, codec)
+			elements, err = parseTrieNodeExtension(decoded, codec, leafDecoder)
 		}
 		if nodeKind == "leaf" {
 			elements, err = leafDecoder(decoded)
 		}
 		if nodeKind != "extension" && nodeKind != "leaf" {
-			return nil, fmt.Errorf("unexpected nodeKind returned from decoder")
+			return "", nil, fmt.Errorf("unexpected nodeKind returned from decoder")
+		}
+		if err != nil {
+			return "", nil, err
 		}
 	case 17:
 		nodeKind = "branch"
-		elements, err = parseTrieNodeBranch(i, codec)
+		elements, err = parseTrieNodeBranch(i, codec, leafDecoder)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 	default:
-		return nil, fmt.Errorf("unknown trie node type")
+		return "", nil, fmt.Errorf("unknown trie node type")
 	}
 
-	return &TrieNode{
-		nodeKind: nodeKind,
-		elements: elements,
-		rawdata:  b,
-		cid:      c,
-	}, nil
+	return nodeKind, elements, nil
 }
 
 // decodeCompactKey takes a compact key, and returns its nodeKind and value.
 func decodeCompactKey(i []interface{}) (string, []interface{}, error) {
-	first := i[0].([]byte)
-	last := i[1].([]byte)
+	first, ok := i[0].([]byte)
+	if !ok || len(first) == 0 {
+		return "", nil, fmt.Errorf("invalid compact key")
+	}
+	last := i[1]
 
 	switch first[0] / 16 {
 	case '\x00':
@@ -127,21 +167,45 @@ func decodeCompactKey(i []interface{}) (string, []interface{}, error) {
 }
 
 // parseTrieNodeExtension helper improves readability
-func parseTrieNodeExtension(i []interface{}, codec uint64) ([]interface{}, error) {
-	return []interface{}{
-		i[0].([]byte),
-		keccak256ToCid(codec, i[1].([]byte)),
-	}, nil
+func parseTrieNodeExtension(i []interface{}, codec uint64, leafDecoder trieNodeLeafDecoder) ([]interface{}, error) {
+	switch v := i[1].(type) {
+	case []byte:
+		if len(v) != 32 {
+			return nil, fmt.Errorf("unrecognized object: %v", v)
+		}
+		return []interface{}{
+			i[0].([]byte),
+			keccak256ToCid(codec, v),
+		}, nil
+	case []interface{}:
+		child, err := decodeEmbeddedTrieNode(v, codec, leafDecoder)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{
+			i[0].([]byte),
+			child,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unable to decode extension node child: %+v", i[1])
+	}
 }
 
 // parseTrieNodeBranch helper improves readability
-func parseTrieNodeBranch(i []interface{}, codec uint64) ([]interface{}, error) {
+func parseTrieNodeBranch(i []interface{}, codec uint64, leafDecoder trieNodeLeafDecoder) ([]interface{}, error) {
 	var out []interface{}
 
 	for i, vi := range i {
+		// Children shorter than 32 bytes are embedded in the branch as lists
+		if embedded, ok := vi.([]interface{}); ok {
+			child, err := decodeEmbeddedTrieNode(embedded, codec, leafDecoder)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, child)
+			continue
+		}
 		v, ok := vi.([]byte)
-		// Sometimes this throws "panic: interface conversion: interface {} is []interface {}, not []uint8"
-		// Figure out why, and if it is okay to continue
 		if !ok {
 			return nil, fmt.Errorf("unable to decode branch node entry into []byte at position: %d value: %+v", i, vi)
 		}
@@ -256,6 +320,55 @@ func (t *TrieNode) Size() (uint64, error) {
   TrieNode functions
 */
 
+// NodeKind returns the kind of this node: leaf, extension or branch
+func (t *TrieNode) NodeKind() string {
+	return t.nodeKind
+}
+
+// Lookup follows the given key nibbles through this node
+// If the key terminates at a leaf within this node, or within one of its embedded children, the decoded leaf value is returned
+// If the key continues into a child node stored in its own IPLD block, the cid of that block is returned
+// along with the nibbles that remain to be traversed from it
+// If the key is not present in the trie all return values are nil
+func (t *TrieNode) Lookup(nibbles []byte) (interface{}, *cid.Cid, []byte, error) {
+	switch t.nodeKind {
+	case "leaf":
+		if !bytes.Equal(t.elements[0].([]byte), nibbles) {
+			return nil, nil, nil, nil
+		}
+		return t.elements[1], nil, nil, nil
+	case "extension":
+		prefix := t.elements[0].([]byte)
+		if len(nibbles) < len(prefix) || !bytes.Equal(prefix, nibbles[:len(prefix)]) {
+			return nil, nil, nil, nil
+		}
+		return lookupTrieNodeChild(t.elements[1], nibbles[len(prefix):])
+	case "branch":
+		if len(nibbles) == 0 {
+			return nil, nil, nil, fmt.Errorf("key terminates at a branch node")
+		}
+		if nibbles[0] > 15 {
+			return nil, nil, nil, fmt.Errorf("invalid nibble %d", nibbles[0])
+		}
+		return lookupTrieNodeChild(t.elements[nibbles[0]], nibbles[1:])
+	default:
+		return nil, nil, nil, fmt.Errorf("nodeKind %s not supported", t.nodeKind)
+	}
+}
+
+func lookupTrieNodeChild(child interface{}, nibbles []byte) (interface{}, *cid.Cid, []byte, error) {
+	switch c := child.(type) {
+	case nil:
+		return nil, nil, nil, nil
+	case cid.Cid:
+		return nil, &c, nibbles, nil
+	case *TrieNode:
+		return c.Lookup(nibbles)
+	default:
+		return nil, nil, nil, fmt.Errorf("unexpected trie node child type %T", child)
+	}
+}
+
 // MarshalJSON processes the transaction trie into readable JSON format.
 func (t *TrieNode) MarshalJSON() ([]byte, error) {
 	var out map[string]interface{}
@@ -344,6 +457,9 @@ func (t *TrieNode) resolveTrieNodeExtension(p []string) (interface{}, []string,
 		}
 	}
 
+	if embedded, ok := t.elements[1].(*TrieNode); ok {
+		return embedded.Resolve(rest)
+	}
 	return &node.Link{Cid: t.elements[1].(cid.Cid)}, rest, nil
 }
 
@@ -386,9 +502,11 @@ func (t *TrieNode) resolveTrieNodeBranch(p []string) (interface{}, []string, err
 		return nil, nil, fmt.Errorf("incorrect path")
 	}
 
-	child := t.elements[hidx]
-	if child != nil {
-		return &node.Link{Cid: child.(cid.Cid)}, rest, nil
+	switch child := t.elements[hidx].(type) {
+	case cid.Cid:
+		return &node.Link{Cid: child}, rest, nil
+	case *TrieNode:
+		return child.Resolve(rest)
 	}
 	return nil, nil, fmt.Errorf("no such link in this branch")
 }
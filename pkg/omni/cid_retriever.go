@@ -0,0 +1,164 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// CIDRetriever satisfies the CIDRetriever interface for the Omni Layer
+// The block ranges and gaps are those of the btc index, which the Omni Layer transactions are indexed on top of
+type CIDRetriever struct {
+	*btc.CIDRetriever
+	db *postgres.DB
+}
+
+// NewCIDRetriever returns a pointer to a new CIDRetriever which supports the CIDRetriever interface
+func NewCIDRetriever(db *postgres.DB) *CIDRetriever {
+	return &CIDRetriever{
+		CIDRetriever: btc.NewCIDRetriever(db),
+		db:           db,
+	}
+}
+
+// Retrieve is used to retrieve all of the CIDs which conform to the passed StreamFilters
+func (ocr *CIDRetriever) Retrieve(filter shared.SubscriptionSettings, blockNumber int64) ([]shared.CIDsForFetching, bool, error) {
+	streamFilter, ok := filter.(*SubscriptionSettings)
+	if !ok {
+		return nil, true, fmt.Errorf("omni retriever expected filter type %T got %T", &SubscriptionSettings{}, filter)
+	}
+	log.Debug("retrieving cids")
+
+	// Begin new db tx
+	tx, err := ocr.db.Beginx()
+	if err != nil {
+		return nil, true, err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	// Retrieve cached header CIDs
+	headers, err := ocr.RetrieveHeaderCIDs(tx, blockNumber)
+	if err != nil {
+		log.Error("header cid retrieval error")
+		return nil, true, err
+	}
+	cws := make([]shared.CIDsForFetching, len(headers))
+	empty := true
+	for i, header := range headers {
+		cw := new(CIDWrapper)
+		cw.BlockNumber = big.NewInt(blockNumber)
+		if !streamFilter.HeaderFilter.Off {
+			cw.Header = header
+			empty = false
+		}
+		// Retrieve the Omni Layer transactions and the CIDs of the bitcoin transactions carrying them
+		if !streamFilter.TxFilter.Off {
+			cw.OmniTransactions, err = ocr.RetrieveOmniTxs(tx, streamFilter.TxFilter, header.ID)
+			if err != nil {
+				log.Error("omni transaction retrieval error")
+				return nil, true, err
+			}
+			txIDs := make([]int64, len(cw.OmniTransactions))
+			for i, omniTx := range cw.OmniTransactions {
+				txIDs[i] = omniTx.TxID
+			}
+			cw.Transactions, err = ocr.RetrieveTxCIDsByIDs(tx, txIDs)
+			if err != nil {
+				log.Error("transaction cid retrieval error")
+				return nil, true, err
+			}
+			if len(cw.OmniTransactions) > 0 {
+				empty = false
+			}
+		}
+		cws[i] = cw
+	}
+
+	return cws, empty, err
+}
+
+// RetrieveOmniTxs retrieves the Omni Layer transactions in the block with the provided header id that conform to the
+// provided filter parameters, in the order they appear in the block
+func (ocr *CIDRetriever) RetrieveOmniTxs(tx *sqlx.Tx, txFilter TxFilter, headerID int64) ([]TxModel, error) {
+	log.Debug("retrieving omni transactions for header id ", headerID)
+	args := make([]interface{}, 0, 4)
+	results := make([]TxModel, 0)
+	id := 1
+	pgStr := fmt.Sprintf(`SELECT transactions.id, transactions.tx_id, transaction_cids.tx_hash, transaction_cids.index,
+			transactions.class, transactions.version, transactions.tx_type, transactions.property_id, transactions.amount,
+			transactions.sender, transactions.reference_address, transactions.payload
+			FROM omni.transactions
+			INNER JOIN btc.transaction_cids ON (transactions.tx_id = transaction_cids.id)
+			WHERE transaction_cids.header_id = $%d`, id)
+	args = append(args, headerID)
+	id++
+	if len(txFilter.TxTypes) > 0 {
+		pgStr += fmt.Sprintf(` AND transactions.tx_type = ANY($%d::INTEGER[])`, id)
+		args = append(args, pq.Array(toInt64s(txFilter.TxTypes)))
+		id++
+	}
+	if len(txFilter.PropertyIDs) > 0 {
+		pgStr += fmt.Sprintf(` AND transactions.property_id = ANY($%d::BIGINT[])`, id)
+		args = append(args, pq.Array(toInt64s(txFilter.PropertyIDs)))
+		id++
+	}
+	if len(txFilter.Addresses) > 0 {
+		pgStr += fmt.Sprintf(` AND (transactions.sender = ANY($%d::VARCHAR(66)[]) OR transactions.reference_address = ANY($%d::VARCHAR(66)[]))`, id, id)
+		args = append(args, pq.Array(txFilter.Addresses))
+	}
+	pgStr += ` ORDER BY transaction_cids.index`
+	return results, tx.Select(&results, pgStr, args...)
+}
+
+// RetrieveTxCIDsByIDs retrieves the bitcoin tx CIDs with the provided ids, in the order they appear in their block
+func (ocr *CIDRetriever) RetrieveTxCIDsByIDs(tx *sqlx.Tx, txIDs []int64) ([]btc.TxModel, error) {
+	txCIDs := make([]btc.TxModel, 0, len(txIDs))
+	if len(txIDs) == 0 {
+		return txCIDs, nil
+	}
+	pgStr := `SELECT * FROM btc.transaction_cids
+			WHERE id = ANY($1::INTEGER[])
+			ORDER BY index`
+	return txCIDs, tx.Select(&txCIDs, pgStr, pq.Array(txIDs))
+}
+
+// toInt64s converts the filter values to the signed integers postgres arrays are bound from
+func toInt64s(values []uint64) []int64 {
+	ints := make([]int64, len(values))
+	for i, v := range values {
+		ints[i] = int64(v)
+	}
+	return ints
+}
@@ -0,0 +1,109 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// PayloadConverter satisfies the PayloadConverter interface for the Omni Layer
+// It converts the bitcoin payload as the btc PayloadConverter does, and parses the Omni Layer transactions it carries
+type PayloadConverter struct {
+	converter   *btc.PayloadConverter
+	chainConfig *chaincfg.Params
+	// prevouts fetches the outputs spent by candidate transactions which are not in the same block, it can be nil
+	prevouts btc.PrevoutFetcher
+}
+
+// NewPayloadConverter creates a pointer to a new PayloadConverter which satisfies the PayloadConverter interface
+// The PrevoutFetcher, which can be nil, is used to resolve the senders of the Omni Layer transactions; without it only
+// the senders of transactions spending outputs of the same block are known, and class B transactions are skipped
+func NewPayloadConverter(chainConfig *chaincfg.Params, prevouts btc.PrevoutFetcher) *PayloadConverter {
+	return &PayloadConverter{
+		converter:   btc.NewPayloadConverter(chainConfig),
+		chainConfig: chainConfig,
+		prevouts:    prevouts,
+	}
+}
+
+// Convert method is used to convert a bitcoin BlockPayload to an IPLDPayload carrying the parsed Omni Layer transactions
+// Satisfies the shared.PayloadConverter interface
+func (pc *PayloadConverter) Convert(payload shared.RawChainData) (shared.ConvertedData, error) {
+	converted, err := pc.converter.Convert(payload)
+	if err != nil {
+		return nil, err
+	}
+	btcPayload, ok := converted.(btc.ConvertedPayload)
+	if !ok {
+		return nil, fmt.Errorf("omni converter: expected payload type %T got %T", btc.ConvertedPayload{}, converted)
+	}
+	// Transactions can spend the outputs of the transactions before them in the block
+	blockOutputs := make(map[chainhash.Hash][]btc.TxOutput, len(btcPayload.Txs))
+	for i, tx := range btcPayload.Txs {
+		blockOutputs[*tx.Hash()] = btcPayload.TxMetaData[i].TxOutputs
+	}
+	txs := make([]TxModel, 0)
+	for i, tx := range btcPayload.Txs {
+		txMeta := btcPayload.TxMetaData[i]
+		// Coinbase transactions have no sender
+		if i == 0 || !isCandidate(txMeta.TxOutputs, pc.chainConfig) {
+			continue
+		}
+		txModel, ok := ParseTransaction(txMeta.TxOutputs, pc.fetchPrevouts(tx.MsgTx(), blockOutputs), pc.chainConfig)
+		if !ok {
+			continue
+		}
+		txModel.TxHash = txMeta.TxHash
+		txModel.Index = txMeta.Index
+		txs = append(txs, txModel)
+	}
+	return ConvertedPayload{
+		ConvertedPayload: btcPayload,
+		Transactions:     txs,
+	}, nil
+}
+
+// fetchPrevouts returns the outputs spent by the inputs of the transaction, taken from the block or fetched from the node
+// It returns nil if any of them cannot be resolved, as the sender cannot be known without all of them
+func (pc *PayloadConverter) fetchPrevouts(tx *wire.MsgTx, blockOutputs map[chainhash.Hash][]btc.TxOutput) []btc.TxOutput {
+	prevouts := make([]btc.TxOutput, len(tx.TxIn))
+	for i, in := range tx.TxIn {
+		outputs, ok := blockOutputs[in.PreviousOutPoint.Hash]
+		if ok && int(in.PreviousOutPoint.Index) < len(outputs) {
+			prevouts[i] = outputs[in.PreviousOutPoint.Index]
+			continue
+		}
+		if pc.prevouts == nil {
+			return nil
+		}
+		prevout, err := pc.prevouts.FetchPrevout(in.PreviousOutPoint)
+		if err != nil {
+			log.Debugf("omni converter unable to fetch the output spent by input %d of tx %s: %s", i, tx.TxHash().String(), err.Error())
+			return nil
+		}
+		prevouts[i] = prevout
+	}
+	return prevouts
+}
@@ -0,0 +1,60 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni_test
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	btcmocks "github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc/mocks"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/omni"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/omni/mocks"
+)
+
+var _ = Describe("Converter", func() {
+	Describe("Convert", func() {
+		It("Converts the bitcoin payload and parses the Omni Layer transactions it carries", func() {
+			prevouts := &btcmocks.PrevoutFetcher{
+				Prevouts: map[wire.OutPoint]btc.TxOutput{mocks.MockPrevOutPoint: mocks.MockPrevout},
+			}
+			converter := omni.NewPayloadConverter(&chaincfg.MainNetParams, prevouts)
+			converted, err := converter.Convert(mocks.MockBlockPayload)
+			Expect(err).ToNot(HaveOccurred())
+			convertedPayload, ok := converted.(omni.ConvertedPayload)
+			Expect(ok).To(BeTrue())
+			Expect(convertedPayload.BlockPayload).To(Equal(mocks.MockBlockPayload))
+			Expect(convertedPayload.TxMetaData).To(HaveLen(3))
+			Expect(convertedPayload.Transactions).To(Equal([]omni.TxModel{mocks.MockTransaction}))
+			// Only the outputs spent by candidate transactions are fetched
+			Expect(prevouts.PassedOutpoints).To(Equal([]wire.OutPoint{mocks.MockPrevOutPoint}))
+		})
+
+		It("Leaves the sender unknown when the spent outputs cannot be resolved", func() {
+			converter := omni.NewPayloadConverter(&chaincfg.MainNetParams, nil)
+			converted, err := converter.Convert(mocks.MockBlockPayload)
+			Expect(err).ToNot(HaveOccurred())
+			convertedPayload, ok := converted.(omni.ConvertedPayload)
+			Expect(ok).To(BeTrue())
+			Expect(convertedPayload.Transactions).To(HaveLen(1))
+			Expect(convertedPayload.Transactions[0].Sender.Valid).To(BeFalse())
+			Expect(convertedPayload.Transactions[0].PropertyID).To(Equal(mocks.MockTransaction.PropertyID))
+		})
+	})
+})
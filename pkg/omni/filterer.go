@@ -0,0 +1,130 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/multiformats/go-multihash"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/ipfs"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/ipfs/ipld"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// ResponseFilterer satisfies the ResponseFilterer interface for the Omni Layer
+type ResponseFilterer struct {
+	filterer *btc.ResponseFilterer
+}
+
+// NewResponseFilterer creates a new Filterer satisfying the ResponseFilterer interface
+func NewResponseFilterer() *ResponseFilterer {
+	return &ResponseFilterer{
+		filterer: btc.NewResponseFilterer(),
+	}
+}
+
+// Filter is used to filter through Omni Layer data to extract and package requested data into a Payload
+// The header is filtered by the btc ResponseFilterer, the transactions are those carrying the matching Omni Layer transactions
+func (s *ResponseFilterer) Filter(filter shared.SubscriptionSettings, payload shared.ConvertedData) (shared.IPLDs, error) {
+	omniFilters, ok := filter.(*SubscriptionSettings)
+	if !ok {
+		return IPLDs{}, fmt.Errorf("omni filterer expected filter type %T got %T", &SubscriptionSettings{}, filter)
+	}
+	omniPayload, ok := payload.(ConvertedPayload)
+	if !ok {
+		return IPLDs{}, fmt.Errorf("omni filterer expected payload type %T got %T", ConvertedPayload{}, payload)
+	}
+	btcFilters := &btc.SubscriptionSettings{
+		Start:        omniFilters.Start,
+		End:          omniFilters.End,
+		HeaderFilter: btc.HeaderFilter{Off: omniFilters.HeaderFilter.Off},
+		TxFilter:     btc.TxFilter{Off: true},
+	}
+	iplds, err := s.filterer.Filter(btcFilters, omniPayload.ConvertedPayload)
+	if err != nil {
+		return IPLDs{}, err
+	}
+	btcIPLDs, ok := iplds.(btc.IPLDs)
+	if !ok {
+		return IPLDs{}, fmt.Errorf("omni filterer expected iplds type %T got %T", btc.IPLDs{}, iplds)
+	}
+	// The btc filterer returns no block number for payloads out of the subscription's range
+	if btcIPLDs.BlockNumber == nil {
+		return IPLDs{}, nil
+	}
+	response := IPLDs{IPLDs: btcIPLDs}
+	if err := s.filterTransactions(omniFilters.TxFilter, &response, omniPayload); err != nil {
+		return IPLDs{}, err
+	}
+	return response, nil
+}
+
+func (s *ResponseFilterer) filterTransactions(txFilter TxFilter, response *IPLDs, payload ConvertedPayload) error {
+	if txFilter.Off {
+		return nil
+	}
+	matches := make([]TxModel, 0, len(payload.Transactions))
+	response.Transactions = make([]ipfs.BlockModel, 0, len(payload.Transactions))
+	for _, omniTx := range payload.Transactions {
+		if !checkTransaction(omniTx, txFilter) {
+			continue
+		}
+		txBuffer := new(bytes.Buffer)
+		if err := payload.Txs[omniTx.Index].MsgTx().Serialize(txBuffer); err != nil {
+			return err
+		}
+		data := txBuffer.Bytes()
+		cid, err := ipld.RawdataToCid(ipld.MBitcoinTx, data, multihash.DBL_SHA2_256)
+		if err != nil {
+			return err
+		}
+		response.Transactions = append(response.Transactions, ipfs.BlockModel{
+			Data: data,
+			CID:  cid.String(),
+		})
+		matches = append(matches, omniTx)
+	}
+	response.OmniTransactions = newTransactions(matches)
+	return nil
+}
+
+// checkTransaction returns true if the provided Omni Layer transaction has a hit on the filter
+func checkTransaction(omniTx TxModel, txFilter TxFilter) bool {
+	passesTxTypeFilter := len(txFilter.TxTypes) == 0
+	for _, wantedTxType := range txFilter.TxTypes {
+		if wantedTxType == uint64(omniTx.TxType) {
+			passesTxTypeFilter = true
+		}
+	}
+	passesPropertyFilter := len(txFilter.PropertyIDs) == 0
+	for _, wantedPropertyID := range txFilter.PropertyIDs {
+		if omniTx.PropertyID.Valid && wantedPropertyID == uint64(omniTx.PropertyID.Int64) {
+			passesPropertyFilter = true
+		}
+	}
+	passesAddressFilter := len(txFilter.Addresses) == 0
+	for _, wantedAddress := range txFilter.Addresses {
+		if (omniTx.Sender.Valid && wantedAddress == omniTx.Sender.String) ||
+			(omniTx.ReferenceAddress.Valid && wantedAddress == omniTx.ReferenceAddress.String) {
+			passesAddressFilter = true
+		}
+	}
+	return passesTxTypeFilter && passesPropertyFilter && passesAddressFilter
+}
@@ -0,0 +1,106 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni_test
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/wire"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	btcmocks "github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc/mocks"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/omni"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/omni/mocks"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+var _ = Describe("Filterer", func() {
+	var (
+		filterer   *omni.ResponseFilterer
+		payload    shared.ConvertedData
+		omniTxData []byte
+	)
+	BeforeEach(func() {
+		filterer = omni.NewResponseFilterer()
+		var err error
+		prevouts := &btcmocks.PrevoutFetcher{
+			Prevouts: map[wire.OutPoint]btc.TxOutput{mocks.MockPrevOutPoint: mocks.MockPrevout},
+		}
+		payload, err = omni.NewPayloadConverter(&chaincfg.MainNetParams, prevouts).Convert(mocks.MockBlockPayload)
+		Expect(err).ToNot(HaveOccurred())
+		buf := new(bytes.Buffer)
+		Expect(mocks.MockOmniTx.Serialize(buf)).To(Succeed())
+		omniTxData = buf.Bytes()
+	})
+
+	Describe("Filter", func() {
+		It("Returns the header and the bitcoin transactions carrying the matching Omni Layer transactions", func() {
+			iplds, err := filterer.Filter(&omni.SubscriptionSettings{
+				Start: big.NewInt(0),
+				End:   big.NewInt(0),
+				TxFilter: omni.TxFilter{
+					PropertyIDs: []uint64{31},
+					Addresses:   []string{mocks.MockReferenceAddress},
+				},
+			}, payload)
+			Expect(err).ToNot(HaveOccurred())
+			response, ok := iplds.(omni.IPLDs)
+			Expect(ok).To(BeTrue())
+			Expect(response.Height()).To(Equal(mocks.MockBlockPayload.BlockHeight))
+			Expect(response.Header.Data).ToNot(BeEmpty())
+			Expect(response.Transactions).To(HaveLen(1))
+			Expect(response.Transactions[0].Data).To(Equal(omniTxData))
+			Expect(response.OmniTransactions).To(Equal([]omni.Transaction{{
+				TxHash:           mocks.MockTransaction.TxHash,
+				Class:            omni.ClassC,
+				PropertyID:       31,
+				Amount:           100000000,
+				Sender:           mocks.MockSenderAddress,
+				ReferenceAddress: mocks.MockReferenceAddress,
+				Payload:          mocks.MockSimpleSend,
+			}}))
+		})
+
+		It("Returns no transactions when none match", func() {
+			iplds, err := filterer.Filter(&omni.SubscriptionSettings{
+				Start:        big.NewInt(0),
+				End:          big.NewInt(0),
+				HeaderFilter: omni.HeaderFilter{Off: true},
+				TxFilter:     omni.TxFilter{TxTypes: []uint64{3}},
+			}, payload)
+			Expect(err).ToNot(HaveOccurred())
+			response, ok := iplds.(omni.IPLDs)
+			Expect(ok).To(BeTrue())
+			Expect(response.Header.Data).To(BeEmpty())
+			Expect(response.Transactions).To(BeEmpty())
+			Expect(response.OmniTransactions).To(BeEmpty())
+		})
+
+		It("Returns nothing for payloads out of the subscription's range", func() {
+			iplds, err := filterer.Filter(&omni.SubscriptionSettings{
+				Start: big.NewInt(mocks.MockBlockPayload.BlockHeight + 1),
+				End:   big.NewInt(0),
+			}, payload)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(iplds).To(Equal(omni.IPLDs{}))
+		})
+	})
+})
@@ -0,0 +1,110 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// CIDIndexer satisfies the CIDIndexer interface for the Omni Layer
+// It indexes the bitcoin CIDs as the btc CIDIndexer does, and the Omni Layer transactions against their bitcoin transactions
+type CIDIndexer struct {
+	db      *postgres.DB
+	indexer *btc.CIDIndexer
+}
+
+// NewCIDIndexer returns a new CIDIndexer, the chain parameters and the PrevoutFetcher, which can be nil, are passed to
+// the btc CIDIndexer
+func NewCIDIndexer(db *postgres.DB, params *chaincfg.Params, prevouts btc.PrevoutFetcher) *CIDIndexer {
+	return &CIDIndexer{
+		db:      db,
+		indexer: btc.NewCIDIndexer(db, params, prevouts),
+	}
+}
+
+// Index indexes the bitcoin CIDs and then the Omni Layer transactions
+func (in *CIDIndexer) Index(cids shared.CIDsForIndexing) error {
+	cidWrapper, ok := cids.(*CIDPayload)
+	if !ok {
+		return fmt.Errorf("omni indexer expected cids type %T got %T", &CIDPayload{}, cids)
+	}
+	if err := in.indexer.Index(&cidWrapper.CIDPayload); err != nil {
+		return err
+	}
+	return in.indexTransactions(cidWrapper.Transactions, cidWrapper.HeaderCID)
+}
+
+// Disconnect satisfies the shared.BlockDisconnector interface
+// The Omni Layer transactions are tied to the bitcoin block carrying them, so disconnecting the block is enough
+func (in *CIDIndexer) Disconnect(block shared.DisconnectedBlock) error {
+	return in.indexer.Disconnect(block)
+}
+
+// indexTransactions indexes the Omni Layer transactions of the block with the provided header, the bitcoin transactions
+// carrying them need to be indexed
+func (in *CIDIndexer) indexTransactions(transactions []TxModel, header btc.HeaderModel) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	// Begin new db tx
+	tx, err := in.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			shared.Rollback(tx)
+			panic(p)
+		} else if err != nil {
+			shared.Rollback(tx)
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	for _, transaction := range transactions {
+		if err = in.indexTransaction(tx, transaction, header); err != nil {
+			logrus.Error("omni indexer error when indexing transactions")
+			return err
+		}
+	}
+	return err
+}
+
+// indexTransaction indexes the Omni Layer transaction against the bitcoin transaction carrying it in the block with the
+// provided header
+// A transaction whose sender is known is not overwritten by a reindexing which could not resolve its sender
+func (in *CIDIndexer) indexTransaction(tx *sqlx.Tx, transaction TxModel, header btc.HeaderModel) error {
+	_, err := tx.Exec(`INSERT INTO omni.transactions (tx_id, class, version, tx_type, property_id, amount, sender, reference_address, payload)
+						SELECT transaction_cids.id, $2, $3, $4, $5, $6, $7, $8, $9 FROM btc.transaction_cids
+						INNER JOIN btc.header_cids ON (transaction_cids.header_id = header_cids.id)
+						WHERE transaction_cids.tx_hash = $1 AND header_cids.block_number = $10 AND header_cids.block_hash = $11
+						ON CONFLICT (tx_id) DO UPDATE SET (class, version, tx_type, property_id, amount, sender, reference_address, payload) = ($2, $3, $4, $5, $6, $7, $8, $9)
+						WHERE $7 IS NOT NULL OR omni.transactions.sender IS NULL`,
+		transaction.TxHash, transaction.Class, transaction.Version, transaction.TxType, transaction.PropertyID, transaction.Amount,
+		transaction.Sender, transaction.ReferenceAddress, transaction.Payload, header.BlockNumber, header.BlockHash)
+	return err
+}
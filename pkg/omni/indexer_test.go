@@ -0,0 +1,130 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni_test
+
+import (
+	"database/sql"
+	"math/big"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	btcmocks "github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc/mocks"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/omni"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/omni/mocks"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+var _ = Describe("Indexer", func() {
+	var (
+		db        *postgres.DB
+		err       error
+		repo      *omni.CIDIndexer
+		retriever *omni.CIDRetriever
+		mockData  = []byte{1, 2, 3}
+	)
+	BeforeEach(func() {
+		db, err = shared.SetupDB()
+		Expect(err).ToNot(HaveOccurred())
+		repo = omni.NewCIDIndexer(db, &chaincfg.MainNetParams, nil)
+		retriever = omni.NewCIDRetriever(db)
+		// need entries in the public.blocks with the mhkeys or the FK constraint will fail
+		shared.PublishMockIPLD(db, btcmocks.MockHeaderMhKey, mockData)
+		shared.PublishMockIPLD(db, btcmocks.MockTrxMhKey1, mockData)
+		shared.PublishMockIPLD(db, btcmocks.MockTrxMhKey2, mockData)
+		shared.PublishMockIPLD(db, btcmocks.MockTrxMhKey3, mockData)
+	})
+	AfterEach(func() {
+		omni.TearDownDB(db)
+	})
+
+	Describe("Index", func() {
+		It("Indexes the Omni Layer transactions against the bitcoin transactions carrying them", func() {
+			err = repo.Index(&mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			txs := make([]omni.TxModel, 0)
+			pgStr := `SELECT transactions.*, transaction_cids.tx_hash, transaction_cids.index
+				FROM omni.transactions INNER JOIN btc.transaction_cids ON (transactions.tx_id = transaction_cids.id)`
+			err = db.Select(&txs, pgStr)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(txs).To(HaveLen(1))
+			expected := mocks.MockIndexedTransaction
+			expected.ID, expected.TxID = txs[0].ID, txs[0].TxID
+			Expect(txs[0]).To(Equal(expected))
+		})
+
+		It("Does not lose the sender of a transaction reindexed without it", func() {
+			err = repo.Index(&mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+			unresolved := mocks.MockIndexedTransaction
+			unresolved.Sender = sql.NullString{}
+			err = repo.Index(&omni.CIDPayload{
+				CIDPayload:   btcmocks.MockCIDPayload,
+				Transactions: []omni.TxModel{unresolved},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			var sender sql.NullString
+			err = db.Get(&sender, `SELECT sender FROM omni.transactions`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sender).To(Equal(mocks.MockIndexedTransaction.Sender))
+		})
+	})
+
+	Describe("Retrieve", func() {
+		BeforeEach(func() {
+			err = repo.Index(&mocks.MockCIDPayload)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("Retrieves the Omni Layer transactions matching the filter, along with the CIDs of the bitcoin transactions carrying them", func() {
+			cids, empty, err := retriever.Retrieve(&omni.SubscriptionSettings{
+				Start:    big.NewInt(0),
+				End:      big.NewInt(0),
+				TxFilter: omni.TxFilter{Addresses: []string{mocks.MockSenderAddress}, PropertyIDs: []uint64{31}},
+			}, btcmocks.MockBlockHeight)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(empty).To(BeFalse())
+			Expect(cids).To(HaveLen(1))
+			cidWrapper, ok := cids[0].(*omni.CIDWrapper)
+			Expect(ok).To(BeTrue())
+			Expect(cidWrapper.Header.CID).To(Equal(btcmocks.MockHeaderMetaData.CID))
+			Expect(cidWrapper.OmniTransactions).To(HaveLen(1))
+			Expect(cidWrapper.OmniTransactions[0].TxHash).To(Equal(mocks.MockIndexedTransaction.TxHash))
+			Expect(cidWrapper.Transactions).To(HaveLen(1))
+			Expect(cidWrapper.Transactions[0].TxHash).To(Equal(mocks.MockIndexedTransaction.TxHash))
+			Expect(cidWrapper.Transactions[0].CID).To(Equal(btcmocks.MockTrxCID2.String()))
+		})
+
+		It("Retrieves no transactions when none match the filter", func() {
+			cids, empty, err := retriever.Retrieve(&omni.SubscriptionSettings{
+				Start:        big.NewInt(0),
+				End:          big.NewInt(0),
+				HeaderFilter: omni.HeaderFilter{Off: true},
+				TxFilter:     omni.TxFilter{TxTypes: []uint64{3}},
+			}, btcmocks.MockBlockHeight)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(empty).To(BeTrue())
+			Expect(cids).To(HaveLen(1))
+			cidWrapper, ok := cids[0].(*omni.CIDWrapper)
+			Expect(ok).To(BeTrue())
+			Expect(cidWrapper.OmniTransactions).To(BeEmpty())
+			Expect(cidWrapper.Transactions).To(BeEmpty())
+		})
+	})
+})
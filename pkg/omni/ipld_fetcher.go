@@ -0,0 +1,58 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import (
+	"fmt"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// IPLDFetcher satisfies the IPLDFetcher interface for the Omni Layer
+// It fetches the bitcoin IPLDs with the underlying btc IPLDFetcher, which interfaces with IPFS or directly with PG-IPFS
+type IPLDFetcher struct {
+	fetcher shared.IPLDFetcher
+}
+
+// NewIPLDFetcher creates a pointer to a new IPLDFetcher fetching the bitcoin IPLDs with the provided btc IPLDFetcher
+func NewIPLDFetcher(fetcher shared.IPLDFetcher) *IPLDFetcher {
+	return &IPLDFetcher{
+		fetcher: fetcher,
+	}
+}
+
+// Fetch is the exported method for fetching and returning all the IPLDS specified in the CIDWrapper, along with the
+// Omni Layer transactions
+func (f *IPLDFetcher) Fetch(cids shared.CIDsForFetching) (shared.IPLDs, error) {
+	cidWrapper, ok := cids.(*CIDWrapper)
+	if !ok {
+		return nil, fmt.Errorf("omni fetcher: expected cids type %T got %T", &CIDWrapper{}, cids)
+	}
+	iplds, err := f.fetcher.Fetch(&cidWrapper.CIDWrapper)
+	if err != nil {
+		return nil, err
+	}
+	btcIPLDs, ok := iplds.(btc.IPLDs)
+	if !ok {
+		return nil, fmt.Errorf("omni fetcher: expected iplds type %T got %T", btc.IPLDs{}, iplds)
+	}
+	return IPLDs{
+		IPLDs:            btcIPLDs,
+		OmniTransactions: newTransactions(cidWrapper.OmniTransactions),
+	}, nil
+}
@@ -0,0 +1,148 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mocks
+
+import (
+	"bytes"
+	"database/sql"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	btcmocks "github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc/mocks"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/omni"
+)
+
+// Test variables
+var (
+	MockSenderAddress    = newAddress(0x01)
+	MockReferenceAddress = newAddress(0x02)
+	// MockSimpleSend is the payload of a simple send of 1 token of property 31
+	MockSimpleSend = []byte{
+		0x00, 0x00, // version
+		0x00, 0x00, // type
+		0x00, 0x00, 0x00, 0x1f, // property id
+		0x00, 0x00, 0x00, 0x00, 0x05, 0xf5, 0xe1, 0x00, // amount
+	}
+	MockPrevOutPoint = wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 0}
+	MockPrevout      = btc.TxOutput{
+		Value:        100000,
+		PkScript:     payToAddress(MockSenderAddress),
+		ScriptClass:  uint8(txscript.PubKeyHashTy),
+		RequiredSigs: 1,
+		Addresses:    []string{MockSenderAddress},
+	}
+	// MockOmniTx is a class C simple send from the sender to the reference address, with change back to the sender
+	MockOmniTx = wire.MsgTx{
+		Version: 1,
+		TxIn: []*wire.TxIn{
+			{
+				PreviousOutPoint: MockPrevOutPoint,
+				SignatureScript:  []byte{0x00},
+				Sequence:         wire.MaxTxInSequenceNum,
+			},
+		},
+		TxOut: []*wire.TxOut{
+			{
+				Value:    546,
+				PkScript: payToAddress(MockReferenceAddress),
+			},
+			{
+				Value:    0,
+				PkScript: nullData(append(append([]byte{}, omni.Marker...), MockSimpleSend...)),
+			},
+			{
+				Value:    90000,
+				PkScript: payToAddress(MockSenderAddress),
+			},
+		},
+	}
+	// MockBlockPayload carries the Omni Layer transaction between two transactions of the btc mock block
+	MockBlockPayload = btc.BlockPayload{
+		BlockHeight: btcmocks.MockBlockHeight,
+		Header:      &btcmocks.MockBlock.Header,
+		Txs: []*btcutil.Tx{
+			btcutil.NewTx(btcmocks.MockBlock.Transactions[0]),
+			btcutil.NewTx(&MockOmniTx),
+			btcutil.NewTx(btcmocks.MockBlock.Transactions[1]),
+		},
+	}
+	MockTransaction = omni.TxModel{
+		TxHash:           MockOmniTx.TxHash().String(),
+		Index:            1,
+		Class:            omni.ClassC,
+		Version:          0,
+		TxType:           0,
+		PropertyID:       sql.NullInt64{Int64: 31, Valid: true},
+		Amount:           sql.NullInt64{Int64: 100000000, Valid: true},
+		Sender:           sql.NullString{String: MockSenderAddress, Valid: true},
+		ReferenceAddress: sql.NullString{String: MockReferenceAddress, Valid: true},
+		Payload:          MockSimpleSend,
+	}
+	// MockIndexedTransaction is an Omni Layer transaction carried by the second transaction of the btc mock CIDPayload
+	MockIndexedTransaction = omni.TxModel{
+		TxHash:           btcmocks.MockTxsMetaDataPostPublish[1].TxHash,
+		Index:            btcmocks.MockTxsMetaDataPostPublish[1].Index,
+		Class:            omni.ClassC,
+		Version:          0,
+		TxType:           0,
+		PropertyID:       sql.NullInt64{Int64: 31, Valid: true},
+		Amount:           sql.NullInt64{Int64: 100000000, Valid: true},
+		Sender:           sql.NullString{String: MockSenderAddress, Valid: true},
+		ReferenceAddress: sql.NullString{String: MockReferenceAddress, Valid: true},
+		Payload:          MockSimpleSend,
+	}
+	MockCIDPayload = omni.CIDPayload{
+		CIDPayload:   btcmocks.MockCIDPayload,
+		Transactions: []omni.TxModel{MockIndexedTransaction},
+	}
+)
+
+// newAddress returns the mainnet pay-to-pubkey-hash address of a hash filled with the provided byte
+func newAddress(b byte) string {
+	addr, err := btcutil.NewAddressPubKeyHash(bytes.Repeat([]byte{b}, 20), &chaincfg.MainNetParams)
+	if err != nil {
+		panic(err)
+	}
+	return addr.EncodeAddress()
+}
+
+// payToAddress returns the pk script paying to the mainnet address
+func payToAddress(address string) []byte {
+	addr, err := btcutil.DecodeAddress(address, &chaincfg.MainNetParams)
+	if err != nil {
+		panic(err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		panic(err)
+	}
+	return pkScript
+}
+
+// nullData returns the OP_RETURN pk script pushing the data
+func nullData(data []byte) []byte {
+	pkScript, err := txscript.NullDataScript(data)
+	if err != nil {
+		panic(err)
+	}
+	return pkScript
+}
@@ -0,0 +1,37 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import "database/sql"
+
+// TxModel is the db model for omni.transactions table
+type TxModel struct {
+	ID   int64 `db:"id"`
+	TxID int64 `db:"tx_id"`
+	// The hash and block index of the bitcoin transaction carrying the Omni Layer transaction, from btc.transaction_cids
+	TxHash  string `db:"tx_hash"`
+	Index   int64  `db:"index"`
+	Class   uint8  `db:"class"`
+	Version uint16 `db:"version"`
+	TxType  uint16 `db:"tx_type"`
+	// The property id and amount are null for the transaction types whose payload does not carry them
+	PropertyID       sql.NullInt64  `db:"property_id"`
+	Amount           sql.NullInt64  `db:"amount"`
+	Sender           sql.NullString `db:"sender"`
+	ReferenceAddress sql.NullString `db:"reference_address"`
+	Payload          []byte         `db:"payload"`
+}
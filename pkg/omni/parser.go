@@ -0,0 +1,261 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+)
+
+// Omni Layer transaction classes, numbered as they are by Omni Core
+// Class A transactions, which encode their payload in fake addresses, have been deprecated and are not parsed
+const (
+	ClassB uint8 = 2
+	ClassC uint8 = 3
+)
+
+// Exodus addresses, class B transactions pay to the Exodus address of their network
+const (
+	MainNetExodusAddress = "1EXoDusjGwvnjZUyKkxZ4UHEf77z6A5S4P"
+	TestNetExodusAddress = "mpexoDuSkGGqvqrkrjiFng38QPkJQVFyqv"
+)
+
+// Marker prefixes the payload carried by the OP_RETURN output of class C transactions
+var Marker = []byte("omni")
+
+// Omni Layer transaction types whose payload carries a property id, and those whose payload carries a property id followed
+// by an amount
+var (
+	propertyTypes = map[uint16]bool{
+		7:  true, // send to many
+		27: true, // cancel all MetaDEx offers of a pair
+		53: true, // close crowdsale
+		70: true, // change property issuer
+		71: true, // enable freezing
+		72: true, // disable freezing
+	}
+	amountTypes = map[uint16]bool{
+		0:   true, // simple send
+		3:   true, // send to owners
+		20:  true, // DEx sell offer
+		22:  true, // DEx accept offer
+		25:  true, // MetaDEx trade
+		26:  true, // cancel MetaDEx offers at a price
+		55:  true, // grant tokens
+		56:  true, // revoke tokens
+		185: true, // freeze tokens
+		186: true, // unfreeze tokens
+	}
+)
+
+const (
+	// classBPacketSize is the size of a deobfuscated class B packet, a sequence number followed by 30 bytes of payload
+	classBPacketSize = 31
+	// minPayloadSize is the size of the version and transaction type every payload starts with
+	minPayloadSize = 4
+)
+
+// ExodusAddress returns the Exodus address of the network with the provided params
+func ExodusAddress(params *chaincfg.Params) string {
+	if params.Net == wire.MainNet {
+		return MainNetExodusAddress
+	}
+	return TestNetExodusAddress
+}
+
+// ParseTransaction parses the Omni Layer transaction carried by the bitcoin transaction with the provided outputs, it
+// returns false if the transaction does not carry one
+// The prevouts are the outputs spent by the inputs of the transaction, in input order; if they are nil the sender is
+// unknown, and class B transactions, whose payload is obfuscated with the sender's address, cannot be parsed
+func ParseTransaction(outputs []btc.TxOutput, prevouts []btc.TxOutput, params *chaincfg.Params) (TxModel, bool) {
+	sender := Sender(prevouts)
+	exodus := ExodusAddress(params)
+	dataOutputs := make(map[int]bool)
+	var class uint8
+	var payload []byte
+	for i, out := range outputs {
+		data, ok := btc.NullDataPayload(out.PkScript)
+		if ok && bytes.HasPrefix(data, Marker) {
+			class, payload = ClassC, data[len(Marker):]
+			dataOutputs[i] = true
+			break
+		}
+	}
+	if class == 0 && paysTo(outputs, exodus) {
+		for i, out := range outputs {
+			if txscript.ScriptClass(out.ScriptClass) == txscript.MultiSigTy {
+				dataOutputs[i] = true
+			}
+		}
+		if len(dataOutputs) == 0 || sender == "" {
+			return TxModel{}, false
+		}
+		class, payload = ClassB, decodeClassB(outputs, sender)
+	}
+	if len(payload) < minPayloadSize {
+		return TxModel{}, false
+	}
+	model := TxModel{
+		Class:   class,
+		Version: binary.BigEndian.Uint16(payload[0:2]),
+		TxType:  binary.BigEndian.Uint16(payload[2:4]),
+		Payload: payload,
+	}
+	switch {
+	case amountTypes[model.TxType] && len(payload) >= 16:
+		model.PropertyID = sql.NullInt64{Int64: int64(binary.BigEndian.Uint32(payload[4:8])), Valid: true}
+		model.Amount = sql.NullInt64{Int64: int64(binary.BigEndian.Uint64(payload[8:16])), Valid: true}
+	case propertyTypes[model.TxType] && len(payload) >= 8:
+		model.PropertyID = sql.NullInt64{Int64: int64(binary.BigEndian.Uint32(payload[4:8])), Valid: true}
+	}
+	if sender != "" {
+		model.Sender = sql.NullString{String: sender, Valid: true}
+	}
+	if reference := referenceAddress(outputs, dataOutputs, exodus, sender); reference != "" {
+		model.ReferenceAddress = sql.NullString{String: reference, Valid: true}
+	}
+	return model, true
+}
+
+// Sender returns the sender of a transaction spending the provided outputs, the address contributing the most input
+// value; ties go to the first address in lexicographic order
+// Only outputs paying to a single address of a standard class count towards contributions
+func Sender(prevouts []btc.TxOutput) string {
+	contributions := make(map[string]int64)
+	for _, prevout := range prevouts {
+		if len(prevout.Addresses) != 1 {
+			continue
+		}
+		switch txscript.ScriptClass(prevout.ScriptClass) {
+		case txscript.PubKeyHashTy, txscript.ScriptHashTy, txscript.WitnessV0PubKeyHashTy, txscript.WitnessV0ScriptHashTy, btc.WitnessV1TaprootTy:
+			contributions[prevout.Addresses[0]] += prevout.Value
+		}
+	}
+	addresses := make([]string, 0, len(contributions))
+	for address := range contributions {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	var sender string
+	var contribution int64
+	for _, address := range addresses {
+		if sender == "" || contributions[address] > contribution {
+			sender, contribution = address, contributions[address]
+		}
+	}
+	return sender
+}
+
+// isCandidate returns whether or not a transaction with the provided outputs may carry an Omni Layer transaction, so
+// that the outputs it spends only need to be fetched for candidates
+func isCandidate(outputs []btc.TxOutput, params *chaincfg.Params) bool {
+	for _, out := range outputs {
+		if data, ok := btc.NullDataPayload(out.PkScript); ok && bytes.HasPrefix(data, Marker) {
+			return true
+		}
+	}
+	return paysTo(outputs, ExodusAddress(params))
+}
+
+// decodeClassB concatenates the payload packets obfuscated in the public keys of the bare multisig outputs
+// The first key of each output is the sender's, the others each carry a packet XORed with the first 31 bytes of the
+// packet's obfuscation hash: SHA256 of the sender's address for the first packet, and SHA256 of the uppercase hex encoding
+// of the previous hash for the following ones
+func decodeClassB(outputs []btc.TxOutput, sender string) []byte {
+	packets := make([][]byte, 0)
+	for _, out := range outputs {
+		if txscript.ScriptClass(out.ScriptClass) != txscript.MultiSigTy {
+			continue
+		}
+		pubKeys, err := txscript.PushedData(out.PkScript)
+		if err != nil || len(pubKeys) < 2 {
+			continue
+		}
+		for _, pubKey := range pubKeys[1:] {
+			// Packets are only carried by compressed keys, the first byte of which is the key's parity
+			if len(pubKey) == 33 {
+				packets = append(packets, pubKey[1:1+classBPacketSize])
+			}
+		}
+	}
+	hash := sha256.Sum256([]byte(sender))
+	payload := make([]byte, 0, len(packets)*(classBPacketSize-1))
+	for i, packet := range packets {
+		deobfuscated := make([]byte, classBPacketSize)
+		for j := range deobfuscated {
+			deobfuscated[j] = packet[j] ^ hash[j]
+		}
+		// Packets are numbered from 1, the payload ends at the first packet out of sequence
+		if int(deobfuscated[0]) != i+1 {
+			break
+		}
+		payload = append(payload, deobfuscated[1:]...)
+		hash = sha256.Sum256([]byte(strings.ToUpper(hex.EncodeToString(hash[:]))))
+	}
+	return payload
+}
+
+// referenceAddress returns the reference address of the transaction, the address of its last output which does not
+// carry data or pay to the Exodus address; when there are several candidates, those paying back to the sender are
+// considered change and skipped
+func referenceAddress(outputs []btc.TxOutput, dataOutputs map[int]bool, exodus, sender string) string {
+	candidates := make([]string, 0)
+	for i, out := range outputs {
+		if dataOutputs[i] || len(out.Addresses) != 1 || out.Addresses[0] == exodus {
+			continue
+		}
+		candidates = append(candidates, out.Addresses[0])
+	}
+	if len(candidates) > 1 {
+		nonChange := make([]string, 0, len(candidates))
+		for _, candidate := range candidates {
+			if candidate != sender {
+				nonChange = append(nonChange, candidate)
+			}
+		}
+		if len(nonChange) > 0 {
+			candidates = nonChange
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	return candidates[len(candidates)-1]
+}
+
+// paysTo returns whether or not any of the outputs pays to the address
+func paysTo(outputs []btc.TxOutput, address string) bool {
+	for _, out := range outputs {
+		for _, outAddress := range out.Addresses {
+			if outAddress == address {
+				return true
+			}
+		}
+	}
+	return false
+}
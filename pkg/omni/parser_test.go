@@ -0,0 +1,180 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/omni"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/omni/mocks"
+)
+
+// classBOutput returns a bare multisig output carrying the payload obfuscated with the sender's address, as Omni Core encodes it
+func classBOutput(sender string, payload []byte) btc.TxOutput {
+	builder := txscript.NewScriptBuilder().AddOp(txscript.OP_1).AddData(append([]byte{0x02}, bytes.Repeat([]byte{0xaa}, 32)...))
+	hash := sha256.Sum256([]byte(sender))
+	keys := 1
+	for seq := 1; len(payload) > 0; seq++ {
+		packet := make([]byte, 31)
+		packet[0] = byte(seq)
+		payload = payload[copy(packet[1:], payload):]
+		for i := range packet {
+			packet[i] ^= hash[i]
+		}
+		builder.AddData(append(append([]byte{0x02}, packet...), 0x00))
+		hash = sha256.Sum256([]byte(strings.ToUpper(hex.EncodeToString(hash[:]))))
+		keys++
+	}
+	pkScript, err := builder.AddInt64(int64(keys)).AddOp(txscript.OP_CHECKMULTISIG).Script()
+	Expect(err).ToNot(HaveOccurred())
+	return btc.TxOutput{PkScript: pkScript, ScriptClass: uint8(txscript.MultiSigTy), RequiredSigs: 1}
+}
+
+// p2pkhOutput returns an output paying to the address
+func p2pkhOutput(address string, value int64) btc.TxOutput {
+	return btc.TxOutput{Value: value, ScriptClass: uint8(txscript.PubKeyHashTy), RequiredSigs: 1, Addresses: []string{address}}
+}
+
+// classCOutput returns the OP_RETURN output of a class C transaction carrying the payload
+func classCOutput(payload []byte) btc.TxOutput {
+	pkScript, err := txscript.NullDataScript(append(append([]byte{}, omni.Marker...), payload...))
+	Expect(err).ToNot(HaveOccurred())
+	return btc.TxOutput{PkScript: pkScript, ScriptClass: uint8(txscript.NullDataTy)}
+}
+
+var _ = Describe("Parser", func() {
+	var (
+		params   = &chaincfg.MainNetParams
+		prevouts = []btc.TxOutput{mocks.MockPrevout}
+	)
+
+	Describe("ParseTransaction", func() {
+		It("Parses the payload, sender and reference address of class C transactions", func() {
+			outputs := []btc.TxOutput{
+				p2pkhOutput(mocks.MockReferenceAddress, 546),
+				classCOutput(mocks.MockSimpleSend),
+				p2pkhOutput(mocks.MockSenderAddress, 90000),
+			}
+			txModel, ok := omni.ParseTransaction(outputs, prevouts, params)
+			Expect(ok).To(BeTrue())
+			Expect(txModel).To(Equal(omni.TxModel{
+				Class:            mocks.MockTransaction.Class,
+				Version:          mocks.MockTransaction.Version,
+				TxType:           mocks.MockTransaction.TxType,
+				PropertyID:       mocks.MockTransaction.PropertyID,
+				Amount:           mocks.MockTransaction.Amount,
+				Sender:           mocks.MockTransaction.Sender,
+				ReferenceAddress: mocks.MockTransaction.ReferenceAddress,
+				Payload:          mocks.MockSimpleSend,
+			}))
+		})
+
+		It("Parses class C transactions whose sender is unknown", func() {
+			outputs := []btc.TxOutput{classCOutput(mocks.MockSimpleSend), p2pkhOutput(mocks.MockReferenceAddress, 546)}
+			txModel, ok := omni.ParseTransaction(outputs, nil, params)
+			Expect(ok).To(BeTrue())
+			Expect(txModel.Sender.Valid).To(BeFalse())
+			Expect(txModel.ReferenceAddress.String).To(Equal(mocks.MockReferenceAddress))
+		})
+
+		It("Only decodes the property id and amount of the transaction types which carry them", func() {
+			// Close crowdsale of property 3, which carries no amount
+			closeCrowdsale := []byte{0x00, 0x00, 0x00, 0x35, 0x00, 0x00, 0x00, 0x03}
+			txModel, ok := omni.ParseTransaction([]btc.TxOutput{classCOutput(closeCrowdsale)}, prevouts, params)
+			Expect(ok).To(BeTrue())
+			Expect(txModel.TxType).To(Equal(uint16(53)))
+			Expect(txModel.PropertyID.Int64).To(Equal(int64(3)))
+			Expect(txModel.Amount.Valid).To(BeFalse())
+			Expect(txModel.ReferenceAddress.Valid).To(BeFalse())
+			// Create a property with a fixed issuance, whose id is only assigned once it is processed
+			createProperty := []byte{0x00, 0x00, 0x00, 0x32, 0x01, 0x00, 0x02}
+			txModel, ok = omni.ParseTransaction([]btc.TxOutput{classCOutput(createProperty)}, prevouts, params)
+			Expect(ok).To(BeTrue())
+			Expect(txModel.TxType).To(Equal(uint16(50)))
+			Expect(txModel.PropertyID.Valid).To(BeFalse())
+			Expect(txModel.Amount.Valid).To(BeFalse())
+		})
+
+		It("Deobfuscates the payload packets of class B transactions", func() {
+			// A payload spanning two packets
+			payload := append(append([]byte{}, mocks.MockSimpleSend...), bytes.Repeat([]byte{0x07}, 20)...)
+			outputs := []btc.TxOutput{
+				p2pkhOutput(omni.MainNetExodusAddress, 6000),
+				p2pkhOutput(mocks.MockReferenceAddress, 6000),
+				classBOutput(mocks.MockSenderAddress, payload),
+			}
+			txModel, ok := omni.ParseTransaction(outputs, prevouts, params)
+			Expect(ok).To(BeTrue())
+			Expect(txModel.Class).To(Equal(omni.ClassB))
+			Expect(txModel.Payload).To(HaveLen(60))
+			Expect(txModel.Payload[:len(payload)]).To(Equal(payload))
+			Expect(txModel.Payload[len(payload):]).To(Equal(make([]byte, 60-len(payload))))
+			Expect(txModel.PropertyID).To(Equal(mocks.MockTransaction.PropertyID))
+			Expect(txModel.Amount).To(Equal(mocks.MockTransaction.Amount))
+			Expect(txModel.Sender.String).To(Equal(mocks.MockSenderAddress))
+			Expect(txModel.ReferenceAddress.String).To(Equal(mocks.MockReferenceAddress))
+		})
+
+		It("Skips class B transactions whose sender is unknown, and transactions which carry no payload", func() {
+			outputs := []btc.TxOutput{
+				p2pkhOutput(omni.MainNetExodusAddress, 6000),
+				classBOutput(mocks.MockSenderAddress, mocks.MockSimpleSend),
+			}
+			_, ok := omni.ParseTransaction(outputs, nil, params)
+			Expect(ok).To(BeFalse())
+			// Obfuscated with another address, the packets are out of sequence
+			outputs[1] = classBOutput(mocks.MockReferenceAddress, mocks.MockSimpleSend)
+			_, ok = omni.ParseTransaction(outputs, prevouts, params)
+			Expect(ok).To(BeFalse())
+			// Class A transactions are not parsed
+			_, ok = omni.ParseTransaction(outputs[:1], prevouts, params)
+			Expect(ok).To(BeFalse())
+			_, ok = omni.ParseTransaction([]btc.TxOutput{p2pkhOutput(mocks.MockReferenceAddress, 546)}, prevouts, params)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("Sender", func() {
+		It("Returns the address contributing the most input value", func() {
+			Expect(omni.Sender([]btc.TxOutput{
+				p2pkhOutput(mocks.MockReferenceAddress, 10),
+				p2pkhOutput(mocks.MockSenderAddress, 15),
+				p2pkhOutput(mocks.MockReferenceAddress, 10),
+			})).To(Equal(mocks.MockReferenceAddress))
+		})
+
+		It("Breaks ties in lexicographic order and ignores non-standard outputs", func() {
+			first, second := mocks.MockSenderAddress, mocks.MockReferenceAddress
+			if second < first {
+				first, second = second, first
+			}
+			Expect(omni.Sender([]btc.TxOutput{p2pkhOutput(second, 5), p2pkhOutput(first, 5)})).To(Equal(first))
+			nonStandard := btc.TxOutput{Value: 100, ScriptClass: uint8(txscript.NonStandardTy)}
+			Expect(omni.Sender([]btc.TxOutput{nonStandard})).To(BeEmpty())
+			Expect(omni.Sender(nil)).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,73 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/btcsuite/btcd/chaincfg"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// IPLDPublisherAndIndexer satisfies the IPLDPublisher interface for the Omni Layer
+// It publishes and indexes the bitcoin IPLDs directly in PG-IPFS as the btc IPLDPublisherAndIndexer does, and then
+// indexes the Omni Layer transactions
+type IPLDPublisherAndIndexer struct {
+	publisher *btc.IPLDPublisherAndIndexer
+	indexer   *CIDIndexer
+}
+
+// NewIPLDPublisherAndIndexer creates a pointer to a new IPLDPublisherAndIndexer which satisfies the IPLDPublisher interface
+// The chain parameters, which can be nil, are used to validate headers, and the PrevoutFetcher, which can be nil, to
+// resolve the spent outputs which have not been indexed
+func NewIPLDPublisherAndIndexer(db *postgres.DB, params *chaincfg.Params, prevouts btc.PrevoutFetcher) *IPLDPublisherAndIndexer {
+	return &IPLDPublisherAndIndexer{
+		publisher: btc.NewIPLDPublisherAndIndexer(db, params, prevouts),
+		indexer:   NewCIDIndexer(db, params, prevouts),
+	}
+}
+
+// Publish publishes and indexes the bitcoin IPLDs, and then indexes the Omni Layer transactions
+func (pub *IPLDPublisherAndIndexer) Publish(payload shared.ConvertedData) (shared.CIDsForIndexing, error) {
+	omniPayload, ok := payload.(ConvertedPayload)
+	if !ok {
+		return nil, fmt.Errorf("omni publisher expected payload type %T got %T", ConvertedPayload{}, payload)
+	}
+	if _, err := pub.publisher.Publish(omniPayload.ConvertedPayload); err != nil {
+		return nil, err
+	}
+	// This IPLDPublisher does both publishing and indexing, we do not need to pass anything forward to the indexer
+	header := btc.HeaderModel{
+		BlockNumber: strconv.Itoa(int(omniPayload.BlockHeight)),
+		BlockHash:   omniPayload.Header.BlockHash().String(),
+	}
+	return nil, pub.indexer.indexTransactions(omniPayload.Transactions, header)
+}
+
+// Index satisfies the shared.CIDIndexer interface
+func (pub *IPLDPublisherAndIndexer) Index(cids shared.CIDsForIndexing) error {
+	return nil
+}
+
+// Disconnect satisfies the shared.BlockDisconnector interface
+func (pub *IPLDPublisherAndIndexer) Disconnect(block shared.DisconnectedBlock) error {
+	return pub.indexer.Disconnect(block)
+}
@@ -0,0 +1,61 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import (
+	"fmt"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// IPLDPublisher satisfies the IPLDPublisher interface for the Omni Layer
+// The Omni Layer transactions are carried by the bitcoin transactions, so only the bitcoin IPLDs are published
+type IPLDPublisher struct {
+	publisher *btc.IPLDPublisher
+}
+
+// NewIPLDPublisher creates a pointer to a new Publisher which satisfies the IPLDPublisher interface
+func NewIPLDPublisher(ipfsPath string) (*IPLDPublisher, error) {
+	publisher, err := btc.NewIPLDPublisher(ipfsPath)
+	if err != nil {
+		return nil, err
+	}
+	return &IPLDPublisher{
+		publisher: publisher,
+	}, nil
+}
+
+// Publish publishes the bitcoin IPLDs to IPFS and returns the corresponding CIDPayload, along with the Omni Layer transactions
+func (pub *IPLDPublisher) Publish(payload shared.ConvertedData) (shared.CIDsForIndexing, error) {
+	omniPayload, ok := payload.(ConvertedPayload)
+	if !ok {
+		return nil, fmt.Errorf("omni publisher expected payload type %T got %T", ConvertedPayload{}, payload)
+	}
+	cids, err := pub.publisher.Publish(omniPayload.ConvertedPayload)
+	if err != nil {
+		return nil, err
+	}
+	btcCIDs, ok := cids.(*btc.CIDPayload)
+	if !ok {
+		return nil, fmt.Errorf("omni publisher expected cids type %T got %T", &btc.CIDPayload{}, cids)
+	}
+	return &CIDPayload{
+		CIDPayload:   *btcCIDs,
+		Transactions: omniPayload.Transactions,
+	}, nil
+}
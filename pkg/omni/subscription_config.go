@@ -0,0 +1,120 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/spf13/viper"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// SubscriptionSettings config is used by a subscriber to specify what Omni Layer data to stream from the watcher
+type SubscriptionSettings struct {
+	BackFill     bool
+	BackFillOnly bool
+	Start        *big.Int
+	End          *big.Int // set to 0 or a negative value to have no ending block
+	HeaderFilter HeaderFilter
+	TxFilter     TxFilter
+}
+
+// HeaderFilter contains filter settings for headers
+type HeaderFilter struct {
+	Off bool
+}
+
+// TxFilter contains filter settings for Omni Layer transactions
+type TxFilter struct {
+	Off         bool
+	TxTypes     []uint64 // allow filtering for txs of the specified transaction types (e.g. 0 for simple sends)
+	PropertyIDs []uint64 // allow filtering for txs carrying one of the specified property ids
+	Addresses   []string // allow filtering for txs whose sender or reference address is one of the provided addresses
+}
+
+// NewOmniSubscriptionConfig is used to initialize a SubscriptionSettings struct with env variables
+func NewOmniSubscriptionConfig() (*SubscriptionSettings, error) {
+	sc := new(SubscriptionSettings)
+	// Below default to false, which means we do not backfill by default
+	sc.BackFill = viper.GetBool("watcher.omniSubscription.historicalData")
+	sc.BackFillOnly = viper.GetBool("watcher.omniSubscription.historicalDataOnly")
+	// Below default to 0
+	// 0 start means we start at the beginning and 0 end means we continue indefinitely
+	sc.Start = big.NewInt(viper.GetInt64("watcher.omniSubscription.startingBlock"))
+	sc.End = big.NewInt(viper.GetInt64("watcher.omniSubscription.endingBlock"))
+	// Below default to false, which means we get all headers by default
+	sc.HeaderFilter = HeaderFilter{
+		Off: viper.GetBool("watcher.omniSubscription.headerFilter.off"),
+	}
+	// Below defaults to false and slices of length 0
+	// Which means we get all Omni Layer transactions by default
+	txTypes, err := getUint64Slice("watcher.omniSubscription.txFilter.txTypes")
+	if err != nil {
+		return nil, err
+	}
+	propertyIDs, err := getUint64Slice("watcher.omniSubscription.txFilter.propertyIDs")
+	if err != nil {
+		return nil, err
+	}
+	sc.TxFilter = TxFilter{
+		Off:         viper.GetBool("watcher.omniSubscription.txFilter.off"),
+		TxTypes:     txTypes,
+		PropertyIDs: propertyIDs,
+		Addresses:   viper.GetStringSlice("watcher.omniSubscription.txFilter.addresses"),
+	}
+	return sc, nil
+}
+
+// getUint64Slice reads the array of non-negative integers at the provided key
+func getUint64Slice(key string) ([]uint64, error) {
+	ints := viper.GetIntSlice(key)
+	uints := make([]uint64, len(ints))
+	for i, v := range ints {
+		if v < 0 {
+			return nil, errors.New(key + " needs to be an array of non-negative integers")
+		}
+		uints[i] = uint64(v)
+	}
+	return uints, nil
+}
+
+// StartingBlock satisfies the SubscriptionSettings() interface
+func (sc *SubscriptionSettings) StartingBlock() *big.Int {
+	return sc.Start
+}
+
+// EndingBlock satisfies the SubscriptionSettings() interface
+func (sc *SubscriptionSettings) EndingBlock() *big.Int {
+	return sc.End
+}
+
+// HistoricalData satisfies the SubscriptionSettings() interface
+func (sc *SubscriptionSettings) HistoricalData() bool {
+	return sc.BackFill
+}
+
+// HistoricalDataOnly satisfies the SubscriptionSettings() interface
+func (sc *SubscriptionSettings) HistoricalDataOnly() bool {
+	return sc.BackFillOnly
+}
+
+// ChainType satisfies the SubscriptionSettings() interface
+func (sc *SubscriptionSettings) ChainType() shared.ChainType {
+	return shared.Omni
+}
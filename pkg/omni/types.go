@@ -0,0 +1,88 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package omni
+
+import (
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+)
+
+// ConvertedPayload is the converted bitcoin payload along with the Omni Layer transactions its transactions carry
+// Returned by PayloadConverter
+// Passed to IPLDPublisher and ResponseFilterer
+type ConvertedPayload struct {
+	btc.ConvertedPayload
+	Transactions []TxModel
+}
+
+// CIDPayload is the bitcoin CIDPayload along with the Omni Layer transactions to index
+// Returned by IPLDPublisher
+// Passed to CIDIndexer
+type CIDPayload struct {
+	btc.CIDPayload
+	Transactions []TxModel
+}
+
+// CIDWrapper is used to direct fetching of the IPLDs of the bitcoin transactions carrying the retrieved Omni Layer transactions
+// Returned by CIDRetriever
+// Passed to IPLDFetcher
+type CIDWrapper struct {
+	btc.CIDWrapper
+	OmniTransactions []TxModel
+}
+
+// IPLDs is used to package the raw IPLD block data of the header and of the bitcoin transactions carrying Omni Layer
+// transactions, along with the parsed Omni Layer transactions
+// Returned by IPLDFetcher and ResponseFilterer
+type IPLDs struct {
+	btc.IPLDs
+	OmniTransactions []Transaction
+}
+
+// Transaction is an Omni Layer transaction as it is sent to subscribers
+type Transaction struct {
+	// TxHash is the hash of the bitcoin transaction carrying the Omni Layer transaction
+	TxHash  string
+	Class   uint8
+	Version uint16
+	TxType  uint16
+	// PropertyID and Amount are 0 for the transaction types whose payload does not carry them
+	PropertyID uint64
+	Amount     uint64
+	// Sender and ReferenceAddress are empty when they are unknown
+	Sender           string
+	ReferenceAddress string
+	Payload          []byte
+}
+
+// newTransactions converts the db models into the Transactions sent to subscribers
+func newTransactions(models []TxModel) []Transaction {
+	txs := make([]Transaction, len(models))
+	for i, model := range models {
+		txs[i] = Transaction{
+			TxHash:           model.TxHash,
+			Class:            model.Class,
+			Version:          model.Version,
+			TxType:           model.TxType,
+			PropertyID:       uint64(model.PropertyID.Int64),
+			Amount:           uint64(model.Amount.Int64),
+			Sender:           model.Sender.String,
+			ReferenceAddress: model.ReferenceAddress.String,
+			Payload:          model.Payload,
+		}
+	}
+	return txs
+}
@@ -20,9 +20,13 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/spf13/viper"
 
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/config"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/node"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
@@ -54,12 +58,14 @@ type Config struct {
 	IPFSPath string
 	IPFSMode shared.IPFSMode
 
-	HTTPClient  interface{}   // Note this client is expected to support the retrieval of the specified data type(s)
-	NodeInfo    node.Node     // Info for the associated node
-	Ranges      [][2]uint64   // The block height ranges to resync
-	BatchSize   uint64        // BatchSize for the resync http calls (client has to support batch sizing)
-	Timeout     time.Duration // HTTP connection timeout in seconds
-	BatchNumber uint64
+	HTTPClient     interface{}   // Note this client is expected to support the retrieval of the specified data type(s)
+	NodeInfo       node.Node     // Info for the associated node
+	Ranges         [][2]uint64   // The block height ranges to resync
+	BatchSize      uint64        // BatchSize for the resync http calls (client has to support batch sizing)
+	Timeout        time.Duration // HTTP connection timeout in seconds
+	BatchNumber    uint64
+	BtcParams      *chaincfg.Params    // Chain parameters of the bitcoin network, nil for ethereum
+	EthChainConfig *params.ChainConfig // Chain config of the ethereum network, nil for bitcoin
 }
 
 // NewConfig fills and returns a resync config from toml parameters
@@ -125,9 +131,17 @@ func NewConfig() (*Config, error) {
 		if err != nil {
 			return nil, err
 		}
-	case shared.Bitcoin:
+		c.EthChainConfig, err = eth.ChainConfig(shared.GetEthChainID())
+		if err != nil {
+			return nil, err
+		}
+	case shared.Bitcoin, shared.Omni:
 		btcHTTP := viper.GetString("bitcoin.httpPath")
 		c.NodeInfo, c.HTTPClient = shared.GetBtcNodeAndClient(btcHTTP)
+		c.BtcParams, err = btc.NetworkParams(shared.GetBtcNetwork())
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	c.DBConfig.Init()
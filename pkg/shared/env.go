@@ -39,15 +39,18 @@ const (
 	ETH_CLIENT_NAME   = "ETH_CLIENT_NAME"
 	ETH_GENESIS_BLOCK = "ETH_GENESIS_BLOCK"
 	ETH_NETWORK_ID    = "ETH_NETWORK_ID"
+	ETH_CHAIN_ID      = "ETH_CHAIN_ID"
 
 	BTC_WS_PATH       = "BTC_WS_PATH"
 	BTC_HTTP_PATH     = "BTC_HTTP_PATH"
+	BTC_ZMQ_PATH      = "BTC_ZMQ_PATH"
 	BTC_NODE_PASSWORD = "BTC_NODE_PASSWORD"
 	BTC_NODE_USER     = "BTC_NODE_USER"
 	BTC_NODE_ID       = "BTC_NODE_ID"
 	BTC_CLIENT_NAME   = "BTC_CLIENT_NAME"
 	BTC_GENESIS_BLOCK = "BTC_GENESIS_BLOCK"
 	BTC_NETWORK_ID    = "BTC_NETWORK_ID"
+	BTC_NETWORK       = "BTC_NETWORK"
 )
 
 // GetEthNodeAndClient returns eth node info and client from path url
@@ -69,6 +72,12 @@ func GetEthNodeAndClient(path string) (node.Node, *rpc.Client, error) {
 	}, rpcClient, nil
 }
 
+// GetEthChainID returns the chain id of the ethereum network the node is on from the config or env variable
+func GetEthChainID() uint64 {
+	viper.BindEnv("ethereum.chainID", ETH_CHAIN_ID)
+	return viper.GetUint64("ethereum.chainID")
+}
+
 // GetIPFSPath returns the ipfs path from the config or env variable
 func GetIPFSPath() (string, error) {
 	viper.BindEnv("ipfs.path", IPFS_PATH)
@@ -116,3 +125,9 @@ func GetBtcNodeAndClient(path string) (node.Node, *rpcclient.ConnConfig) {
 			User:         viper.GetString("bitcoin.user"),
 		}
 }
+
+// GetBtcNetwork returns the name of the bitcoin network the node is on from the config or env variable
+func GetBtcNetwork() string {
+	viper.BindEnv("bitcoin.network", BTC_NETWORK)
+	return viper.GetString("bitcoin.network")
+}
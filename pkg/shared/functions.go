@@ -23,6 +23,7 @@ import (
 	"github.com/ipfs/go-ipfs-ds-help"
 	node "github.com/ipfs/go-ipld-format"
 	"github.com/jmoiron/sqlx"
+	"github.com/multiformats/go-multihash"
 	"github.com/sirupsen/logrus"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/ipfs/ipld"
 )
@@ -93,6 +94,16 @@ func MultihashKeyFromCIDString(c string) (string, error) {
 	return blockstore.BlockPrefix.String() + dbKey.String(), nil
 }
 
+// MultihashKeyFromKeccak256 converts a keccak256 hash into a blockstore-prefixed multihash db key string
+func MultihashKeyFromKeccak256(h common.Hash) (string, error) {
+	mh, err := multihash.Encode(h.Bytes(), multihash.KECCAK_256)
+	if err != nil {
+		return "", err
+	}
+	dbKey := dshelp.MultihashToDsKey(mh)
+	return blockstore.BlockPrefix.String() + dbKey.String(), nil
+}
+
 // PublishRaw derives a cid from raw bytes and provided codec and multihash type, and writes it to the db tx
 func PublishRaw(tx *sqlx.Tx, codec, mh uint64, raw []byte) (string, error) {
 	c, err := ipld.RawdataToCid(codec, raw, mh)
@@ -25,6 +25,11 @@ type PayloadStreamer interface {
 	Stream(payloadChan chan RawChainData) (ClientSubscription, error)
 }
 
+// MempoolWatcher streams the chain-specific changes to the mempool of a node to the provided channel
+type MempoolWatcher interface {
+	Watch(payloadChan chan UnconfirmedData) (ClientSubscription, error)
+}
+
 // PayloadFetcher fetches chain-specific payloads
 type PayloadFetcher interface {
 	FetchAt(blockHeights []uint64) ([]RawChainData, error)
@@ -45,11 +50,22 @@ type CIDIndexer interface {
 	Index(cids CIDsForIndexing) error
 }
 
+// BlockDisconnector is satisfied by the CIDIndexers which can remove a disconnected block from the canonical chain they index
+type BlockDisconnector interface {
+	Disconnect(block DisconnectedBlock) error
+}
+
 // ResponseFilterer applies a filter to an IPLD payload to return a subscription response packet
 type ResponseFilterer interface {
 	Filter(filter SubscriptionSettings, payload ConvertedData) (response IPLDs, err error)
 }
 
+// UnconfirmedFilterer is satisfied by the ResponseFilterers which can filter unconfirmed transactions
+// It returns false if the subscription did not opt in to unconfirmed transactions or none of them pass its filter
+type UnconfirmedFilterer interface {
+	FilterUnconfirmed(filter SubscriptionSettings, payload UnconfirmedData) (response IPLDs, ok bool, err error)
+}
+
 // CIDRetriever retrieves cids according to a provided filter and returns a CID wrapper
 type CIDRetriever interface {
 	Retrieve(filter SubscriptionSettings, blockNumber int64) ([]CIDsForFetching, bool, error)
@@ -63,6 +79,12 @@ type IPLDFetcher interface {
 	Fetch(cids CIDsForFetching) (IPLDs, error)
 }
 
+// PayloadListener is notified of each converted payload served by the watcher, and of the reorgs that precede them
+type PayloadListener interface {
+	Notify(payload ConvertedData)
+	NotifyReorg(forkHeight int64)
+}
+
 // ClientSubscription is a general interface for chain data subscriptions
 type ClientSubscription interface {
 	Err() <-chan error
@@ -0,0 +1,42 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package shared
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Reasons for which a json-rpc call is forwarded to the upstream node
+const (
+	// ProxyUnsupported is the reason for calls to methods the watcher does not serve
+	ProxyUnsupported = "unsupported"
+	// ProxyUnavailable is the reason for calls the watcher serves but could not answer from its index
+	ProxyUnavailable = "unavailable"
+)
+
+// RecordProxiedCall counts a call to the method that was forwarded to the upstream node for the given reason
+// The counters are registered in the default metrics registry, regardless of whether or not metrics are enabled
+func RecordProxiedCall(reason, method string) {
+	metrics.GetOrRegisterCounterForced(fmt.Sprintf("proxy/%s/%s", reason, method), nil).Inc(1)
+}
+
+// RecordProxyError counts a call to the method that the upstream node failed to answer
+func RecordProxyError(method string) {
+	metrics.GetOrRegisterCounterForced(fmt.Sprintf("proxy/errors/%s", method), nil).Inc(1)
+}
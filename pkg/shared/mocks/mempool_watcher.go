@@ -0,0 +1,43 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mocks
+
+import (
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// MempoolWatcher mock struct
+type MempoolWatcher struct {
+	PassedPayloadChan chan shared.UnconfirmedData
+	ReturnSub         *rpc.ClientSubscription
+	ReturnErr         error
+	WatchPayloads     []shared.UnconfirmedData
+}
+
+// Watch mock method
+func (mw *MempoolWatcher) Watch(payloadChan chan shared.UnconfirmedData) (shared.ClientSubscription, error) {
+	mw.PassedPayloadChan = payloadChan
+
+	go func() {
+		for _, payload := range mw.WatchPayloads {
+			mw.PassedPayloadChan <- payload
+		}
+	}()
+
+	return mw.ReturnSub, mw.ReturnErr
+}
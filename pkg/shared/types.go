@@ -22,8 +22,20 @@ type RawChainData interface{}
 // The concrete type underneath StreamedIPLDs should not be a pointer
 type ConvertedData interface {
 	Height() int64
+	Hash() string
+	ParentHash() string
 }
 
+// DisconnectedBlock is streamed in place of RawChainData for each streamed block which a reorg has disconnected from the chain
+// Disconnections are streamed from the tip down, ahead of the blocks replacing them
+type DisconnectedBlock interface {
+	Height() int64
+	Hash() string
+}
+
+// UnconfirmedData is the converted form of the transactions entering and leaving the mempool of a node
+type UnconfirmedData interface{}
+
 type CIDsForIndexing interface{}
 
 type CIDsForFetching interface{}
@@ -27,6 +27,7 @@ import (
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/node"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/omni"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
 	v "github.com/vulcanize/ipfs-blockchain-watcher/version"
 )
@@ -65,6 +66,12 @@ func (api *PublicWatcherAPI) Stream(ctx context.Context, rlpParams []byte) (*rpc
 			return nil, err
 		}
 		params = &btcParams
+	case shared.Omni:
+		var omniParams omni.SubscriptionSettings
+		if err := rlp.DecodeBytes(rlpParams, &omniParams); err != nil {
+			return nil, err
+		}
+		params = &omniParams
 	default:
 		panic("SuperNode is not configured for a specific chain type")
 	}
@@ -21,9 +21,15 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/rpcclient"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/spf13/viper"
 
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/config"
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/node"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/postgres"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
@@ -40,6 +46,20 @@ const (
 	SUPERNODE_IPC_PATH  = "SUPERNODE_IPC_PATH"
 	SUPERNODE_HTTP_PATH = "SUPERNODE_HTTP_PATH"
 	SUPERNODE_BACKFILL  = "SUPERNODE_BACKFILL"
+	SUPERNODE_PROXY     = "SUPERNODE_PROXY"
+	SUPERNODE_MEMPOOL   = "SUPERNODE_MEMPOOL"
+
+	SUPERNODE_GRAPHQL      = "SUPERNODE_GRAPHQL"
+	SUPERNODE_GRAPHQL_PATH = "SUPERNODE_GRAPHQL_PATH"
+
+	SUPERNODE_ELECTRUM      = "SUPERNODE_ELECTRUM"
+	SUPERNODE_ELECTRUM_PATH = "SUPERNODE_ELECTRUM_PATH"
+
+	SUPERNODE_ESPLORA      = "SUPERNODE_ESPLORA"
+	SUPERNODE_ESPLORA_PATH = "SUPERNODE_ESPLORA_PATH"
+
+	SUPERNODE_MAX_LOG_RANGE   = "SUPERNODE_MAX_LOG_RANGE"
+	SUPERNODE_MAX_LOG_RESULTS = "SUPERNODE_MAX_LOG_RESULTS"
 
 	SYNC_MAX_IDLE_CONNECTIONS = "SYNC_MAX_IDLE_CONNECTIONS"
 	SYNC_MAX_OPEN_CONNECTIONS = "SYNC_MAX_OPEN_CONNECTIONS"
@@ -50,24 +70,44 @@ const (
 	SERVER_MAX_CONN_LIFETIME    = "SERVER_MAX_CONN_LIFETIME"
 )
 
+const defaultLogFilterLimit = 10000
+
 // Config struct
 type Config struct {
 	Chain    shared.ChainType
 	IPFSPath string
 	IPFSMode shared.IPFSMode
 	DBConfig config.Database
+	// Chain parameters of the bitcoin network, nil for ethereum
+	BtcParams *chaincfg.Params
+	// Chain config of the ethereum network, nil for bitcoin
+	EthChainConfig *params.ChainConfig
 	// Server fields
 	Serve        bool
 	ServeDBConn  *postgres.DB
 	WSEndpoint   string
 	HTTPEndpoint string
 	IPCEndpoint  string
+	// Client for the upstream node that calls the server cannot answer are forwarded to, nil if proxying is off
+	ProxyClient *rpc.Client
+	// Bounds on the block range and number of results of eth_getLogs queries
+	LogLimits eth.LogFilterLimits
+	// Endpoint of the EIP-1767 GraphQL server, empty if the GraphQL server is off
+	GraphQLEndpoint string
+	// Endpoint of the Electrum server, empty if the Electrum server is off
+	ElectrumEndpoint string
+	// Endpoint of the Esplora REST server, empty if the Esplora server is off
+	EsploraEndpoint string
 	// Sync params
 	Sync       bool
 	SyncDBConn *postgres.DB
 	Workers    int
 	WSClient   interface{}
-	NodeInfo   node.Node
+	// Client or config the payload streamer is built from, for bitcoin this is a *btc.ZMQConfig if a zmq endpoint is configured
+	StreamerClient interface{}
+	NodeInfo       node.Node
+	// Mempool switch, tracks the mempool of the node to serve unconfirmed transactions
+	Mempool bool
 	// Historical switch
 	Historical bool
 }
@@ -81,13 +121,25 @@ func NewConfig() (*Config, error) {
 	viper.BindEnv("superNode.chain", SUPERNODE_CHAIN)
 	viper.BindEnv("superNode.sync", SUPERNODE_SYNC)
 	viper.BindEnv("superNode.workers", SUPERNODE_WORKERS)
+	viper.BindEnv("superNode.mempool", SUPERNODE_MEMPOOL)
 	viper.BindEnv("ethereum.wsPath", shared.ETH_WS_PATH)
 	viper.BindEnv("bitcoin.wsPath", shared.BTC_WS_PATH)
+	viper.BindEnv("bitcoin.zmqPath", shared.BTC_ZMQ_PATH)
 	viper.BindEnv("superNode.server", SUPERNODE_SERVER)
 	viper.BindEnv("superNode.wsPath", SUPERNODE_WS_PATH)
 	viper.BindEnv("superNode.ipcPath", SUPERNODE_IPC_PATH)
 	viper.BindEnv("superNode.httpPath", SUPERNODE_HTTP_PATH)
 	viper.BindEnv("superNode.backFill", SUPERNODE_BACKFILL)
+	viper.BindEnv("superNode.proxy", SUPERNODE_PROXY)
+	viper.BindEnv("ethereum.httpPath", shared.ETH_HTTP_PATH)
+	viper.BindEnv("superNode.maxLogRange", SUPERNODE_MAX_LOG_RANGE)
+	viper.BindEnv("superNode.maxLogResults", SUPERNODE_MAX_LOG_RESULTS)
+	viper.BindEnv("superNode.graphql", SUPERNODE_GRAPHQL)
+	viper.BindEnv("superNode.graphqlPath", SUPERNODE_GRAPHQL_PATH)
+	viper.BindEnv("superNode.electrum", SUPERNODE_ELECTRUM)
+	viper.BindEnv("superNode.electrumPath", SUPERNODE_ELECTRUM_PATH)
+	viper.BindEnv("superNode.esplora", SUPERNODE_ESPLORA)
+	viper.BindEnv("superNode.esploraPath", SUPERNODE_ESPLORA_PATH)
 
 	c.Historical = viper.GetBool("superNode.backFill")
 	chain := viper.GetString("superNode.chain")
@@ -95,6 +147,18 @@ func NewConfig() (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if c.Chain == shared.Bitcoin || c.Chain == shared.Omni {
+		c.BtcParams, err = btc.NetworkParams(shared.GetBtcNetwork())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.Chain == shared.Ethereum {
+		c.EthChainConfig, err = eth.ChainConfig(shared.GetEthChainID())
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	c.IPFSMode, err = shared.GetIPFSMode()
 	if err != nil {
@@ -123,9 +187,26 @@ func NewConfig() (*Config, error) {
 			if err != nil {
 				return nil, err
 			}
-		case shared.Bitcoin:
+			c.StreamerClient = c.WSClient
+		case shared.Bitcoin, shared.Omni:
 			btcWS := viper.GetString("bitcoin.wsPath")
-			c.NodeInfo, c.WSClient = shared.GetBtcNodeAndClient(btcWS)
+			var btcClient *rpcclient.ConnConfig
+			c.NodeInfo, btcClient = shared.GetBtcNodeAndClient(btcWS)
+			c.WSClient = btcClient
+			c.StreamerClient = btcClient
+			// If bitcoind publishes zmq block notifications, stream from them instead of polling the node
+			if btcZMQ := viper.GetString("bitcoin.zmqPath"); btcZMQ != "" {
+				c.StreamerClient = &btc.ZMQConfig{
+					Endpoint: btcZMQ,
+					RPC:      btcClient,
+				}
+			}
+		}
+		if viper.GetBool("superNode.mempool") {
+			if c.Chain != shared.Bitcoin {
+				return nil, fmt.Errorf("mempool tracking is not supported for chain %s", c.Chain.String())
+			}
+			c.Mempool = true
 		}
 		syncDBConn := overrideDBConnConfig(c.DBConfig, Sync)
 		syncDB := utils.LoadPostgres(syncDBConn, c.NodeInfo)
@@ -153,6 +234,47 @@ func NewConfig() (*Config, error) {
 			httpPath = "127.0.0.1:8081"
 		}
 		c.HTTPEndpoint = httpPath
+		if viper.GetBool("superNode.proxy") {
+			if c.Chain != shared.Ethereum {
+				return nil, fmt.Errorf("proxying is not supported for chain %s", c.Chain.String())
+			}
+			ethHTTP := viper.GetString("ethereum.httpPath")
+			c.ProxyClient, err = rpc.Dial(fmt.Sprintf("http://%s", ethHTTP))
+			if err != nil {
+				return nil, err
+			}
+		}
+		if viper.GetBool("superNode.graphql") {
+			if c.Chain != shared.Ethereum {
+				return nil, fmt.Errorf("graphql is not supported for chain %s", c.Chain.String())
+			}
+			graphqlPath := viper.GetString("superNode.graphqlPath")
+			if graphqlPath == "" {
+				graphqlPath = "127.0.0.1:8083"
+			}
+			c.GraphQLEndpoint = graphqlPath
+		}
+		if viper.GetBool("superNode.electrum") {
+			if c.Chain != shared.Bitcoin {
+				return nil, fmt.Errorf("electrum is not supported for chain %s", c.Chain.String())
+			}
+			electrumPath := viper.GetString("superNode.electrumPath")
+			if electrumPath == "" {
+				electrumPath = "127.0.0.1:50001"
+			}
+			c.ElectrumEndpoint = electrumPath
+		}
+		if viper.GetBool("superNode.esplora") {
+			if c.Chain != shared.Bitcoin {
+				return nil, fmt.Errorf("esplora is not supported for chain %s", c.Chain.String())
+			}
+			esploraPath := viper.GetString("superNode.esploraPath")
+			if esploraPath == "" {
+				esploraPath = "127.0.0.1:3000"
+			}
+			c.EsploraEndpoint = esploraPath
+		}
+		c.LogLimits = logFilterLimits()
 		serveDBConn := overrideDBConnConfig(c.DBConfig, Serve)
 		serveDB := utils.LoadPostgres(serveDBConn, c.NodeInfo)
 		c.ServeDBConn = &serveDB
@@ -161,6 +283,26 @@ func NewConfig() (*Config, error) {
 	return c, nil
 }
 
+// logFilterLimits reads the eth_getLogs limits, unset limits default to defaultLogFilterLimit and non-positive ones are disabled
+func logFilterLimits() eth.LogFilterLimits {
+	maxRange := int64(defaultLogFilterLimit)
+	if viper.IsSet("superNode.maxLogRange") {
+		maxRange = viper.GetInt64("superNode.maxLogRange")
+	}
+	maxResults := defaultLogFilterLimit
+	if viper.IsSet("superNode.maxLogResults") {
+		maxResults = viper.GetInt("superNode.maxLogResults")
+	}
+	limits := eth.LogFilterLimits{}
+	if maxRange > 0 {
+		limits.MaxRange = maxRange
+	}
+	if maxResults > 0 {
+		limits.MaxResults = maxResults
+	}
+	return limits
+}
+
 type mode string
 
 var (
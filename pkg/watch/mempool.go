@@ -0,0 +1,42 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package watch
+
+import (
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+// unconfirmedPayload is forwarded to the Serve process, alongside the converted payloads, for each change to the mempool
+// of the node; it carries no block so it is served to the subscriptions which opted in to unconfirmed transactions only
+type unconfirmedPayload struct {
+	shared.UnconfirmedData
+}
+
+// Height satisfies the shared.ConvertedData interface, unconfirmed transactions are not at any height
+func (up unconfirmedPayload) Height() int64 {
+	return 0
+}
+
+// Hash satisfies the shared.ConvertedData interface, unconfirmed transactions are not in any block
+func (up unconfirmedPayload) Hash() string {
+	return ""
+}
+
+// ParentHash satisfies the shared.ConvertedData interface, unconfirmed transactions are not in any block
+func (up unconfirmedPayload) ParentHash() string {
+	return ""
+}
@@ -0,0 +1,239 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package watch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/metrics/prometheus"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+const (
+	// methodNotFoundCode is the json-rpc error code returned for methods the server does not have
+	methodNotFoundCode = -32601
+	// upstreamErrorCode is the json-rpc error code returned when the upstream node cannot be reached
+	upstreamErrorCode = -32000
+	// MetricsPath is the path on the HTTP endpoint at which the proxy metrics are served in the prometheus format
+	MetricsPath = "/debug/metrics/prometheus"
+)
+
+// StartProxyHTTPEndpoint starts an HTTP RPC endpoint for the apis in the provided modules, like rpc.StartHTTPEndpoint,
+// except that calls to methods the endpoint does not serve are forwarded to the upstream node
+// The endpoint also serves the proxy metrics at MetricsPath
+func StartProxyHTTPEndpoint(endpoint string, apis []rpc.API, modules []string, upstream *rpc.Client) (net.Listener, *rpc.Server, error) {
+	whitelist := make(map[string]bool)
+	for _, module := range modules {
+		whitelist[module] = true
+	}
+	server := rpc.NewServer()
+	for _, api := range apis {
+		if whitelist[api.Namespace] {
+			if err := server.RegisterName(api.Namespace, api.Service); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, prometheus.Handler(metrics.DefaultRegistry))
+	mux.Handle("/", NewProxyHandler(server, upstream))
+	go rpc.NewHTTPServer(nil, nil, rpc.HTTPTimeouts{}, mux).Serve(listener)
+	return listener, server, nil
+}
+
+// jsonrpcMessage is a json-rpc request or response
+type jsonrpcMessage struct {
+	Version string          `json:"jsonrpc,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Error   *jsonError      `json:"error,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+}
+
+type jsonError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// ProxyHandler serves json-rpc requests over HTTP with the watcher's rpc server
+// and forwards the calls to methods the server does not have to the upstream node
+type ProxyHandler struct {
+	server   http.Handler
+	upstream *rpc.Client
+}
+
+// NewProxyHandler creates a new ProxyHandler around the provided rpc server and upstream node client
+func NewProxyHandler(server http.Handler, upstream *rpc.Client) *ProxyHandler {
+	return &ProxyHandler{
+		server:   server,
+		upstream: upstream,
+	}
+}
+
+// ServeHTTP satisfies the http.Handler interface
+func (ph *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	buffer := newResponseBuffer()
+	ph.server.ServeHTTP(buffer, r)
+
+	// Requests and responses are either single messages or batches of them
+	requests, batch := decodeMessages(body)
+	responses, _ := decodeMessages(buffer.body.Bytes())
+	if requests == nil || responses == nil || !ph.forward(r, requests, responses) {
+		buffer.writeTo(w, buffer.body.Bytes())
+		return
+	}
+	var out []byte
+	if batch {
+		out, err = json.Marshal(responses)
+	} else {
+		out, err = json.Marshal(responses[0])
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	buffer.writeTo(w, out)
+}
+
+// forward sends the requests whose responses report that the method was not found to the upstream node,
+// replacing those responses with the upstream node's, it returns whether or not any requests were forwarded
+func (ph *ProxyHandler) forward(r *http.Request, requests, responses []*jsonrpcMessage) bool {
+	forwarded := false
+	for i, res := range responses {
+		if res.Error == nil || res.Error.Code != methodNotFoundCode {
+			continue
+		}
+		req := findRequest(requests, res.ID)
+		if req == nil {
+			continue
+		}
+		var params []json.RawMessage
+		if len(req.Params) > 0 {
+			if err := json.Unmarshal(req.Params, &params); err != nil {
+				continue
+			}
+		}
+		args := make([]interface{}, len(params))
+		for j, param := range params {
+			args[j] = param
+		}
+		shared.RecordProxiedCall(shared.ProxyUnsupported, req.Method)
+		forwarded = true
+		var result json.RawMessage
+		if err := ph.upstream.CallContext(r.Context(), &result, req.Method, args...); err != nil {
+			log.Debugf("upstream node failed to answer proxied %s call: %v", req.Method, err)
+			shared.RecordProxyError(req.Method)
+			code := upstreamErrorCode
+			if rpcErr, ok := err.(rpc.Error); ok {
+				code = rpcErr.ErrorCode()
+			}
+			responses[i] = &jsonrpcMessage{Version: res.Version, ID: res.ID, Error: &jsonError{Code: code, Message: err.Error()}}
+			continue
+		}
+		responses[i] = &jsonrpcMessage{Version: res.Version, ID: res.ID, Result: result}
+	}
+	return forwarded
+}
+
+// findRequest returns the request with the provided id
+func findRequest(requests []*jsonrpcMessage, id json.RawMessage) *jsonrpcMessage {
+	for _, req := range requests {
+		if bytes.Equal(req.ID, id) {
+			return req
+		}
+	}
+	return nil
+}
+
+// decodeMessages decodes a single json-rpc message or a batch of them, it returns nil if the data is neither
+func decodeMessages(data []byte) ([]*jsonrpcMessage, bool) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var msgs []*jsonrpcMessage
+		if err := json.Unmarshal(data, &msgs); err != nil {
+			return nil, true
+		}
+		return msgs, true
+	}
+	msg := new(jsonrpcMessage)
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, false
+	}
+	return []*jsonrpcMessage{msg}, false
+}
+
+// responseBuffer is an http.ResponseWriter that holds the response so that it can be inspected before being sent
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   *bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{
+		header: make(http.Header),
+		status: http.StatusOK,
+		body:   new(bytes.Buffer),
+	}
+}
+
+// Header satisfies the http.ResponseWriter interface
+func (rb *responseBuffer) Header() http.Header {
+	return rb.header
+}
+
+// Write satisfies the http.ResponseWriter interface
+func (rb *responseBuffer) Write(data []byte) (int, error) {
+	return rb.body.Write(data)
+}
+
+// WriteHeader satisfies the http.ResponseWriter interface
+func (rb *responseBuffer) WriteHeader(status int) {
+	rb.status = status
+}
+
+// writeTo sends the buffered headers and status with the provided body
+func (rb *responseBuffer) writeTo(w http.ResponseWriter, body []byte) {
+	for key, values := range rb.header {
+		if key == "Content-Length" {
+			continue
+		}
+		w.Header()[key] = values
+	}
+	w.WriteHeader(rb.status)
+	w.Write(body)
+}
@@ -0,0 +1,96 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package watch_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/watch"
+)
+
+type watcherService struct{}
+
+func (s *watcherService) BlockNumber() string { return "watcher" }
+
+type upstreamService struct{}
+
+func (s *upstreamService) BlockNumber() string { return "upstream" }
+
+func (s *upstreamService) ChainId() string { return "0x1" }
+
+var _ = Describe("ProxyHandler", func() {
+	var (
+		handler  http.Handler
+		upstream *rpc.Client
+	)
+	BeforeEach(func() {
+		watcherServer := rpc.NewServer()
+		err := watcherServer.RegisterName("eth", new(watcherService))
+		Expect(err).ToNot(HaveOccurred())
+		upstreamServer := rpc.NewServer()
+		err = upstreamServer.RegisterName("eth", new(upstreamService))
+		Expect(err).ToNot(HaveOccurred())
+		upstream = rpc.DialInProc(upstreamServer)
+		handler = watch.NewProxyHandler(watcherServer, upstream)
+	})
+	AfterEach(func() {
+		upstream.Close()
+	})
+
+	post := func(body string) []byte {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+		req.Header.Set("content-type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		res, err := ioutil.ReadAll(rec.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return res
+	}
+
+	It("Serves the methods the watcher has", func() {
+		var res map[string]interface{}
+		err := json.Unmarshal(post(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`), &res)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res["result"]).To(Equal("watcher"))
+	})
+
+	It("Forwards calls to methods the watcher does not have to the upstream node", func() {
+		var res map[string]interface{}
+		err := json.Unmarshal(post(`{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`), &res)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res["result"]).To(Equal("0x1"))
+		Expect(res["error"]).To(BeNil())
+	})
+
+	It("Forwards only the unsupported calls of a batch", func() {
+		var res []map[string]interface{}
+		err := json.Unmarshal(post(`[{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]},{"jsonrpc":"2.0","id":2,"method":"eth_chainId","params":[]},{"jsonrpc":"2.0","id":3,"method":"eth_unknown","params":[]}]`), &res)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res).To(HaveLen(3))
+		Expect(res[0]["result"]).To(Equal("watcher"))
+		Expect(res[1]["result"]).To(Equal("0x1"))
+		Expect(res[2]["error"]).ToNot(BeNil())
+	})
+})
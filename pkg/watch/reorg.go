@@ -0,0 +1,114 @@
+// VulcanizeDB
+// Copyright © 2019 Vulcanize
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package watch
+
+import (
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
+)
+
+const (
+	// ReorgTrackingDepth is the number of recently synced block heights the Sync process remembers the hashes of
+	ReorgTrackingDepth = 256
+)
+
+// reorgPayload is forwarded to the Serve process in place of a converted payload whose arrival
+// replaced blocks, at and above forkHeight, that have already been forwarded to it
+type reorgPayload struct {
+	shared.ConvertedData
+	forkHeight int64
+}
+
+// headTracker remembers the hashes of the blocks recently streamed by the Sync process
+// so that it can recognize when a newly streamed block replaces previously streamed ones
+type headTracker struct {
+	hashes map[int64]string
+	head   int64
+	// disconnected is set when the streamer disconnects blocks, the next update reports a reorg at forkHeight
+	disconnected bool
+	forkHeight   int64
+}
+
+func newHeadTracker() *headTracker {
+	return &headTracker{
+		hashes: make(map[int64]string),
+	}
+}
+
+// update records the payload as the new head and returns whether or not it reorganized the chain
+// If it did, it also returns the lowest height at which previously streamed blocks were replaced
+func (ht *headTracker) update(payload shared.ConvertedData) (bool, int64) {
+	height, hash, parentHash := payload.Height(), payload.Hash(), payload.ParentHash()
+	if ht.disconnected {
+		// This block replaces the blocks disconnected since the last update
+		forkHeight := ht.forkHeight
+		if height < forkHeight {
+			forkHeight = height
+		}
+		ht.disconnected = false
+		ht.record(height, hash, parentHash)
+		return true, forkHeight
+	}
+	if len(ht.hashes) == 0 {
+		ht.record(height, hash, parentHash)
+		return false, 0
+	}
+	if seen, ok := ht.hashes[height]; ok && seen == hash {
+		// We have already seen this block
+		return false, 0
+	}
+	reorg := false
+	forkHeight := height
+	if height <= ht.head {
+		// Blocks at and above this height were streamed before on a different chain
+		reorg = true
+	}
+	if seen, ok := ht.hashes[height-1]; ok && seen != parentHash {
+		// The parent we streamed at the previous height is not this block's parent, we only know
+		// that the chains diverge at or below that height so we conservatively report it as the fork height
+		reorg = true
+		forkHeight = height - 1
+	}
+	ht.record(height, hash, parentHash)
+	return reorg, forkHeight
+}
+
+// record sets the block as the head, forgetting any blocks above it and those below the tracking depth
+func (ht *headTracker) record(height int64, hash, parentHash string) {
+	for h := range ht.hashes {
+		if h > height || h <= height-ReorgTrackingDepth {
+			delete(ht.hashes, h)
+		}
+	}
+	ht.hashes[height] = hash
+	ht.hashes[height-1] = parentHash
+	ht.head = height
+}
+
+// disconnect forgets the block at the height, which the streamer has disconnected, and those above it
+// The lowest disconnected height is reported as the fork height by the next update
+func (ht *headTracker) disconnect(height int64) {
+	for h := range ht.hashes {
+		if h >= height {
+			delete(ht.hashes, h)
+		}
+	}
+	ht.head = height - 1
+	if !ht.disconnected || height < ht.forkHeight {
+		ht.forkHeight = height
+	}
+	ht.disconnected = true
+}
@@ -56,6 +56,8 @@ type Watcher interface {
 	Node() *node.Node
 	// Method to access chain type
 	Chain() shared.ChainType
+	// Method to add a listener fed every payload served, and every reorg
+	Listen(listener shared.PayloadListener)
 }
 
 // Service is the underlying struct for the watcher
@@ -78,12 +80,18 @@ type Service struct {
 	Retriever shared.CIDRetriever
 	// Chan the processor uses to subscribe to payloads from the Streamer
 	PayloadChan chan shared.RawChainData
+	// Interface for watching the mempool of the node, nil if the mempool is not tracked
+	Mempool shared.MempoolWatcher
+	// Chan the processor uses to subscribe to changes to the mempool from the MempoolWatcher
+	MempoolChan chan shared.UnconfirmedData
 	// Used to signal shutdown of the service
 	QuitChan chan bool
 	// A mapping of rpc.IDs to their subscription channels, mapped to their subscription type (hash of the StreamFilters)
 	Subscriptions map[common.Hash]map[rpc.ID]Subscription
 	// A mapping of subscription params hash to the corresponding subscription params
 	SubscriptionTypes map[common.Hash]shared.SubscriptionSettings
+	// A mapping of subscription params hash to the greatest block height served to that subscription type
+	servedHeights map[common.Hash]int64
 	// Info for the Geth node that this watcher is working with
 	NodeInfo *node.Node
 	// Number of publishAndIndex workers
@@ -96,6 +104,17 @@ type Service struct {
 	db *postgres.DB
 	// wg for syncing serve processes
 	serveWg *sync.WaitGroup
+	// The chain specific public api, nil if the chain does not have one
+	chainAPI *rpc.API
+	// Listeners fed every payload served, and every reorg
+	listeners []shared.PayloadListener
+	// Listeners fed every payload synced, and every reorg
+	syncListeners []shared.PayloadListener
+	// Held by the publishAndIndex workers while they publish and index a payload, and by the Sync process while it
+	// applies a disconnect
+	indexLock sync.RWMutex
+	// Heights of the blocks disconnected by a reorg, by hash, which the publishAndIndex workers must not index
+	disconnected map[string]int64
 }
 
 // NewWatcher creates a new Watcher using an underlying Service struct
@@ -104,19 +123,19 @@ func NewWatcher(settings *Config) (Watcher, error) {
 	var err error
 	// If we are syncing, initialize the needed interfaces
 	if settings.Sync {
-		sn.Streamer, sn.PayloadChan, err = builders.NewPayloadStreamer(settings.Chain, settings.WSClient)
+		sn.Streamer, sn.PayloadChan, err = builders.NewPayloadStreamer(settings.Chain, settings.StreamerClient)
 		if err != nil {
 			return nil, err
 		}
-		sn.Converter, err = builders.NewPayloadConverter(settings.Chain)
+		sn.Converter, err = builders.NewPayloadConverter(settings.Chain, settings.WSClient, settings.BtcParams, settings.EthChainConfig)
 		if err != nil {
 			return nil, err
 		}
-		sn.Publisher, err = builders.NewIPLDPublisher(settings.Chain, settings.IPFSPath, settings.SyncDBConn, settings.IPFSMode)
+		sn.Publisher, err = builders.NewIPLDPublisher(settings.Chain, settings.IPFSPath, settings.SyncDBConn, settings.IPFSMode, settings.WSClient, settings.BtcParams)
 		if err != nil {
 			return nil, err
 		}
-		sn.Indexer, err = builders.NewCIDIndexer(settings.Chain, settings.SyncDBConn, settings.IPFSMode)
+		sn.Indexer, err = builders.NewCIDIndexer(settings.Chain, settings.SyncDBConn, settings.IPFSMode, settings.WSClient, settings.BtcParams)
 		if err != nil {
 			return nil, err
 		}
@@ -124,6 +143,12 @@ func NewWatcher(settings *Config) (Watcher, error) {
 		if err != nil {
 			return nil, err
 		}
+		if settings.Mempool {
+			sn.Mempool, sn.MempoolChan, err = builders.NewMempoolWatcher(settings.Chain, settings.SyncDBConn, settings.WSClient, settings.BtcParams)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 	// If we are serving, initialize the needed interfaces
 	if settings.Serve {
@@ -140,10 +165,24 @@ func NewWatcher(settings *Config) (Watcher, error) {
 	sn.QuitChan = make(chan bool)
 	sn.Subscriptions = make(map[common.Hash]map[rpc.ID]Subscription)
 	sn.SubscriptionTypes = make(map[common.Hash]shared.SubscriptionSettings)
+	sn.servedHeights = make(map[common.Hash]int64)
 	sn.WorkerPoolSize = settings.Workers
 	sn.NodeInfo = &settings.NodeInfo
 	sn.ipfsPath = settings.IPFSPath
 	sn.chain = settings.Chain
+	// The chain api is constructed up front so that its listeners can be fed by the Sync and Serve processes
+	chainAPI, err := builders.NewPublicAPI(settings.Chain, sn.db, settings.IPFSPath, settings.ProxyClient, settings.LogLimits, settings.BtcParams, settings.EthChainConfig)
+	if err != nil {
+		log.Error(err)
+		return sn, nil
+	}
+	sn.chainAPI = &chainAPI.API
+	if chainAPI.ServeListener != nil {
+		sn.listeners = append(sn.listeners, chainAPI.ServeListener)
+	}
+	if chainAPI.SyncListener != nil {
+		sn.syncListeners = append(sn.syncListeners, chainAPI.SyncListener)
+	}
 	return sn, nil
 }
 
@@ -181,56 +220,99 @@ func (sap *Service) APIs() []rpc.API {
 			Public:    true,
 		},
 	}
-	chainAPI, err := builders.NewPublicAPI(sap.chain, sap.db, sap.ipfsPath)
-	if err != nil {
-		log.Error(err)
+	if sap.chainAPI == nil {
 		return apis
 	}
-	return append(apis, chainAPI)
+	return append(apis, *sap.chainAPI)
 }
 
 // Sync streams incoming raw chain data and converts it for further processing
 // It forwards the converted data to the publishAndIndex process(es) it spins up
 // If forwards the converted data to a ScreenAndServe process if it there is one listening on the passed screenAndServePayload channel
+// It detects when newly streamed data replaces previously streamed data and notifies the ScreenAndServe process of the reorg
+// Blocks the streamer reports as disconnected by a reorg are removed from the index by the Sync process itself, in order
+// and before the blocks replacing them are forwarded, once the blocks being indexed have been
+// It also hands the converted data, and any reorg, to the sync listeners of the chain api
+// If the mempool is tracked, the changes to it are forwarded to the ScreenAndServe process too
 // This continues on no matter if or how many subscribers there are
 func (sap *Service) Sync(wg *sync.WaitGroup, screenAndServePayload chan<- shared.ConvertedData) error {
 	sub, err := sap.Streamer.Stream(sap.PayloadChan)
 	if err != nil {
 		return err
 	}
+	// A nil channel is never ready, so without a mempool watcher its cases are never selected
+	var mempoolErrs <-chan error
+	if sap.Mempool != nil {
+		mempoolSub, err := sap.Mempool.Watch(sap.MempoolChan)
+		if err != nil {
+			return err
+		}
+		mempoolErrs = mempoolSub.Err()
+	}
+	sap.disconnected = make(map[string]int64)
 	// spin up publishAndIndex worker goroutines
 	publishAndIndexPayload := make(chan shared.ConvertedData, PayloadChanBufferSize)
 	for i := 1; i <= sap.WorkerPoolSize; i++ {
 		go sap.publishAndIndex(wg, i, publishAndIndexPayload)
 		log.Debugf("%s publishAndIndex worker %d successfully spun up", sap.chain.String(), i)
 	}
+	// Forward payloads to the publishAndIndex workers
+	// this channel acts as a ring buffer
+	forward := func(payload shared.ConvertedData) {
+		select {
+		case publishAndIndexPayload <- payload:
+		default:
+			<-publishAndIndexPayload
+			publishAndIndexPayload <- payload
+		}
+	}
+	heads := newHeadTracker()
 	go func() {
 		wg.Add(1)
 		defer wg.Done()
 		for {
 			select {
 			case payload := <-sap.PayloadChan:
+				if disconnected, ok := payload.(shared.DisconnectedBlock); ok {
+					log.Warnf("%s block %s at height %d has been disconnected by a reorg", sap.chain.String(), disconnected.Hash(), disconnected.Height())
+					heads.disconnect(disconnected.Height())
+					sap.disconnect(disconnected)
+					continue
+				}
 				ipldPayload, err := sap.Converter.Convert(payload)
 				if err != nil {
 					log.Errorf("watcher conversion error for chain %s: %v", sap.chain.String(), err)
 					continue
 				}
 				log.Infof("%s data streamed at head height %d", sap.chain.String(), ipldPayload.Height())
+				var servePayload shared.ConvertedData = ipldPayload
+				if reorg, forkHeight := heads.update(ipldPayload); reorg {
+					log.Warnf("%s chain reorg detected at head height %d; previously streamed blocks at and above height %d have been replaced",
+						sap.chain.String(), ipldPayload.Height(), forkHeight)
+					servePayload = reorgPayload{ConvertedData: ipldPayload, forkHeight: forkHeight}
+					for _, listener := range sap.syncListeners {
+						listener.NotifyReorg(forkHeight)
+					}
+				}
+				for _, listener := range sap.syncListeners {
+					listener.Notify(ipldPayload)
+				}
 				// If we have a ScreenAndServe process running, forward the iplds to it
 				select {
-				case screenAndServePayload <- ipldPayload:
+				case screenAndServePayload <- servePayload:
 				default:
 				}
-				// Forward the payload to the publishAndIndex workers
-				// this channel acts as a ring buffer
+				sap.reconnect(ipldPayload)
+				forward(ipldPayload)
+			case payload := <-sap.MempoolChan:
 				select {
-				case publishAndIndexPayload <- ipldPayload:
+				case screenAndServePayload <- unconfirmedPayload{UnconfirmedData: payload}:
 				default:
-					<-publishAndIndexPayload
-					publishAndIndexPayload <- ipldPayload
 				}
 			case err := <-sub.Err():
 				log.Errorf("watcher subscription error for chain %s: %v", sap.chain.String(), err)
+			case err := <-mempoolErrs:
+				log.Errorf("watcher mempool error for chain %s: %v", sap.chain.String(), err)
 			case <-sap.QuitChan:
 				log.Infof("quiting %s Sync process", sap.chain.String())
 				return
@@ -249,16 +331,9 @@ func (sap *Service) publishAndIndex(wg *sync.WaitGroup, id int, publishAndIndexP
 	for {
 		select {
 		case payload := <-publishAndIndexPayload:
-			log.Debugf("%s watcher publishAndIndex worker %d publishing data streamed at head height %d", sap.chain.String(), id, payload.Height())
-			cidPayload, err := sap.Publisher.Publish(payload)
-			if err != nil {
-				log.Errorf("%s watcher publishAndIndex worker %d publishing error: %v", sap.chain.String(), id, err)
-				continue
-			}
-			log.Debugf("%s watcher publishAndIndex worker %d indexing data streamed at head height %d", sap.chain.String(), id, payload.Height())
-			if err := sap.Indexer.Index(cidPayload); err != nil {
-				log.Errorf("%s watcher publishAndIndex worker %d indexing error: %v", sap.chain.String(), id, err)
-			}
+			sap.indexLock.RLock()
+			sap.publishAndIndexPayload(id, payload)
+			sap.indexLock.RUnlock()
 		case <-sap.QuitChan:
 			log.Infof("%s watcher publishAndIndex worker %d shutting down", sap.chain.String(), id)
 			return
@@ -266,6 +341,60 @@ func (sap *Service) publishAndIndex(wg *sync.WaitGroup, id int, publishAndIndexP
 	}
 }
 
+// publishAndIndexPayload publishes and indexes a single payload, unless its block has since been disconnected
+func (sap *Service) publishAndIndexPayload(id int, payload shared.ConvertedData) {
+	if _, ok := sap.disconnected[payload.Hash()]; ok {
+		log.Debugf("%s watcher publishAndIndex worker %d skipping block %s at height %d, it has been disconnected", sap.chain.String(), id, payload.Hash(), payload.Height())
+		return
+	}
+	log.Debugf("%s watcher publishAndIndex worker %d publishing data streamed at head height %d", sap.chain.String(), id, payload.Height())
+	cidPayload, err := sap.Publisher.Publish(payload)
+	if err != nil {
+		log.Errorf("%s watcher publishAndIndex worker %d publishing error: %v", sap.chain.String(), id, err)
+		return
+	}
+	log.Debugf("%s watcher publishAndIndex worker %d indexing data streamed at head height %d", sap.chain.String(), id, payload.Height())
+	if err := sap.Indexer.Index(cidPayload); err != nil {
+		log.Errorf("%s watcher publishAndIndex worker %d indexing error: %v", sap.chain.String(), id, err)
+	}
+}
+
+// disconnect removes the disconnected block from the canonical chain of the index, if the indexer supports it
+// It waits for the blocks being indexed by the publishAndIndex workers, and keeps the workers from indexing the block
+// if it is still waiting to be indexed
+func (sap *Service) disconnect(block shared.DisconnectedBlock) {
+	sap.indexLock.Lock()
+	defer sap.indexLock.Unlock()
+	for hash, height := range sap.disconnected {
+		if height <= block.Height()-ReorgTrackingDepth {
+			delete(sap.disconnected, hash)
+		}
+	}
+	sap.disconnected[block.Hash()] = block.Height()
+	disconnector, ok := sap.Indexer.(shared.BlockDisconnector)
+	if !ok {
+		log.Debugf("%s watcher indexer does not support disconnecting blocks", sap.chain.String())
+		return
+	}
+	log.Debugf("%s watcher Sync process disconnecting block %s at height %d", sap.chain.String(), block.Hash(), block.Height())
+	if err := disconnector.Disconnect(block); err != nil {
+		log.Errorf("%s watcher Sync process disconnecting error: %v", sap.chain.String(), err)
+	}
+}
+
+// reconnect allows the publishAndIndex workers to index a block which was disconnected and has been streamed again
+func (sap *Service) reconnect(payload shared.ConvertedData) {
+	sap.indexLock.RLock()
+	_, ok := sap.disconnected[payload.Hash()]
+	sap.indexLock.RUnlock()
+	if !ok {
+		return
+	}
+	sap.indexLock.Lock()
+	delete(sap.disconnected, payload.Hash())
+	sap.indexLock.Unlock()
+}
+
 // Serve listens for incoming converter data off the screenAndServePayload from the Sync process
 // It filters and sends this data to any subscribers to the service
 // This process can also be stood up alone, without an screenAndServePayload attached to a Sync process
@@ -278,7 +407,22 @@ func (sap *Service) Serve(wg *sync.WaitGroup, screenAndServePayload <-chan share
 		for {
 			select {
 			case payload := <-screenAndServePayload:
+				if unconfirmed, ok := payload.(unconfirmedPayload); ok {
+					sap.filterAndServeUnconfirmed(unconfirmed.UnconfirmedData)
+					continue
+				}
+				listeners := sap.servedListeners()
+				if reorg, ok := payload.(reorgPayload); ok {
+					sap.notifyReorg(reorg.forkHeight)
+					for _, listener := range listeners {
+						listener.NotifyReorg(reorg.forkHeight)
+					}
+					payload = reorg.ConvertedData
+				}
 				sap.filterAndServe(payload)
+				for _, listener := range listeners {
+					listener.Notify(payload)
+				}
 			case <-sap.QuitChan:
 				log.Infof("quiting %s Serve process", sap.chain.String())
 				return
@@ -288,6 +432,20 @@ func (sap *Service) Serve(wg *sync.WaitGroup, screenAndServePayload <-chan share
 	log.Infof("%s Serve goroutine successfully spun up", sap.chain.String())
 }
 
+// Listen adds a listener to be fed every payload served, and every reorg
+func (sap *Service) Listen(listener shared.PayloadListener) {
+	sap.Lock()
+	defer sap.Unlock()
+	sap.listeners = append(sap.listeners, listener)
+}
+
+// servedListeners returns a copy of the listeners fed by the Serve process, which can be added to while it runs
+func (sap *Service) servedListeners() []shared.PayloadListener {
+	sap.Lock()
+	defer sap.Unlock()
+	return append([]shared.PayloadListener(nil), sap.listeners...)
+}
+
 // filterAndServe filters the payload according to each subscription type and sends to the subscriptions
 func (sap *Service) filterAndServe(payload shared.ConvertedData) {
 	log.Debugf("sending %s payload to subscriptions", sap.chain.String())
@@ -328,6 +486,79 @@ func (sap *Service) filterAndServe(payload shared.ConvertedData) {
 				log.Infof("unable to send %s payload to subscription %s; channel has no receiver", sap.chain.String(), id)
 			}
 		}
+		if sap.servedHeights == nil {
+			sap.servedHeights = make(map[common.Hash]int64)
+		}
+		if served, ok := sap.servedHeights[ty]; !ok || response.Height() > served {
+			sap.servedHeights[ty] = response.Height()
+		}
+	}
+}
+
+// filterAndServeUnconfirmed filters the changes to the mempool according to each subscription type and sends to the
+// subscriptions which opted in to unconfirmed transactions
+func (sap *Service) filterAndServeUnconfirmed(payload shared.UnconfirmedData) {
+	filterer, ok := sap.Filterer.(shared.UnconfirmedFilterer)
+	if !ok {
+		log.Debugf("%s watcher filterer does not support unconfirmed transactions", sap.chain.String())
+		return
+	}
+	log.Debugf("sending %s unconfirmed payload to subscriptions", sap.chain.String())
+	sap.Lock()
+	sap.serveWg.Add(1)
+	defer sap.Unlock()
+	defer sap.serveWg.Done()
+	for ty, subs := range sap.Subscriptions {
+		subConfig, ok := sap.SubscriptionTypes[ty]
+		if !ok {
+			// The next payload served closes this subscription type
+			continue
+		}
+		response, ok, err := filterer.FilterUnconfirmed(subConfig, payload)
+		if err != nil {
+			log.Errorf("watcher unconfirmed filtering error for chain %s: %v", sap.chain.String(), err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		responseRLP, err := rlp.EncodeToBytes(response)
+		if err != nil {
+			log.Errorf("watcher rlp encoding error for chain %s: %v", sap.chain.String(), err)
+			continue
+		}
+		for id, sub := range subs {
+			select {
+			case sub.PayloadChan <- SubscriptionPayload{Data: responseRLP, Err: "", Flag: UnconfirmedFlag, Height: response.Height()}:
+				log.Debugf("sending watcher %s unconfirmed payload to subscription %s", sap.chain.String(), id)
+			default:
+				log.Infof("unable to send %s unconfirmed payload to subscription %s; channel has no receiver", sap.chain.String(), id)
+			}
+		}
+	}
+}
+
+// notifyReorg sends a reorg notice to the subscriptions which have been served data at or above the fork height
+func (sap *Service) notifyReorg(forkHeight int64) {
+	log.Debugf("sending %s reorg notice to subscriptions", sap.chain.String())
+	sap.Lock()
+	sap.serveWg.Add(1)
+	defer sap.Unlock()
+	defer sap.serveWg.Done()
+	for ty, subs := range sap.Subscriptions {
+		served, ok := sap.servedHeights[ty]
+		if !ok || served < forkHeight {
+			continue
+		}
+		for id, sub := range subs {
+			select {
+			case sub.PayloadChan <- SubscriptionPayload{Data: nil, Err: "", Flag: ReorgFlag, Height: forkHeight}:
+				log.Debugf("sending watcher %s reorg notice to subscription %s", sap.chain.String(), id)
+			default:
+				log.Infof("unable to send %s reorg notice to subscription %s; channel has no receiver", sap.chain.String(), id)
+			}
+		}
+		sap.servedHeights[ty] = forkHeight - 1
 	}
 }
 
@@ -457,6 +688,7 @@ func (sap *Service) Unsubscribe(id rpc.ID) {
 			// If we removed the last subscription of this type, remove the subscription type outright
 			delete(sap.Subscriptions, ty)
 			delete(sap.SubscriptionTypes, ty)
+			delete(sap.servedHeights, ty)
 		}
 	}
 	sap.Unlock()
@@ -506,6 +738,7 @@ func (sap *Service) close() {
 		}
 		delete(sap.Subscriptions, subType)
 		delete(sap.SubscriptionTypes, subType)
+		delete(sap.servedHeights, subType)
 	}
 }
 
@@ -519,4 +752,5 @@ func (sap *Service) closeType(subType common.Hash) {
 	}
 	delete(sap.Subscriptions, subType)
 	delete(sap.SubscriptionTypes, subType)
+	delete(sap.servedHeights, subType)
 }
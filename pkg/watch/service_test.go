@@ -17,13 +17,18 @@
 package watch_test
 
 import (
+	"math/big"
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc"
+	btcmocks "github.com/vulcanize/ipfs-blockchain-watcher/pkg/btc/mocks"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/eth/mocks"
 	"github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared"
 	mocks2 "github.com/vulcanize/ipfs-blockchain-watcher/pkg/shared/mocks"
@@ -74,5 +79,111 @@ var _ = Describe("Service", func() {
 			Expect(mockPublisher.PassedIPLDPayload).To(Equal(mocks.MockConvertedPayload))
 			Expect(mockStreamer.PassedPayloadChan).To(Equal(payloadChan))
 		})
+		It("Forwards the blocks disconnected by a reorg to the indexer", func() {
+			wg := new(sync.WaitGroup)
+			payloadChan := make(chan shared.RawChainData, 1)
+			quitChan := make(chan bool, 1)
+			mockCidIndexer := &btcmocks.CIDIndexer{}
+			disconnect := btc.BlockDisconnect{
+				BlockHeight: btcmocks.MockBlockHeight,
+				Header:      &btcmocks.MockBlock.Header,
+			}
+			mockStreamer := &mocks2.PayloadStreamer{
+				ReturnSub:      &rpc.ClientSubscription{},
+				StreamPayloads: []shared.RawChainData{disconnect},
+			}
+			mockConverter := &btcmocks.PayloadConverter{}
+			processor := &watch.Service{
+				Indexer:        mockCidIndexer,
+				Publisher:      &btcmocks.IPLDPublisher{},
+				Streamer:       mockStreamer,
+				Converter:      mockConverter,
+				PayloadChan:    payloadChan,
+				QuitChan:       quitChan,
+				WorkerPoolSize: 1,
+			}
+			err := processor.Sync(wg, nil)
+			Expect(err).ToNot(HaveOccurred())
+			time.Sleep(2 * time.Second)
+			close(quitChan)
+			wg.Wait()
+			Expect(mockCidIndexer.PassedDisconnects).To(Equal([]shared.DisconnectedBlock{disconnect}))
+			Expect(mockCidIndexer.PassedCIDPayload).To(BeEmpty())
+			Expect(mockConverter.PassedStatediffPayload).To(Equal(btc.BlockPayload{}))
+		})
+
+		It("Applies the blocks disconnected by a reorg without waiting for the publishAndIndex workers", func() {
+			wg := new(sync.WaitGroup)
+			payloadChan := make(chan shared.RawChainData, 1)
+			quitChan := make(chan bool, 1)
+			mockCidIndexer := &btcmocks.CIDIndexer{}
+			disconnect := btc.BlockDisconnect{
+				BlockHeight: btcmocks.MockBlockHeight,
+				Header:      &btcmocks.MockBlock.Header,
+			}
+			mockStreamer := &mocks2.PayloadStreamer{
+				ReturnSub:      &rpc.ClientSubscription{},
+				StreamPayloads: []shared.RawChainData{disconnect},
+			}
+			processor := &watch.Service{
+				Indexer:        mockCidIndexer,
+				Publisher:      &btcmocks.IPLDPublisher{},
+				Streamer:       mockStreamer,
+				Converter:      &btcmocks.PayloadConverter{},
+				PayloadChan:    payloadChan,
+				QuitChan:       quitChan,
+				WorkerPoolSize: 0,
+			}
+			err := processor.Sync(wg, nil)
+			Expect(err).ToNot(HaveOccurred())
+			time.Sleep(2 * time.Second)
+			close(quitChan)
+			wg.Wait()
+			Expect(mockCidIndexer.PassedDisconnects).To(Equal([]shared.DisconnectedBlock{disconnect}))
+		})
+	})
+
+	Describe("Serve", func() {
+		It("Serves the unconfirmed txs from the mempool to the subscriptions which opted in to them", func() {
+			wg := new(sync.WaitGroup)
+			quitChan := make(chan bool)
+			mockMempool := &mocks2.MempoolWatcher{
+				ReturnSub:     &rpc.ClientSubscription{},
+				WatchPayloads: []shared.UnconfirmedData{btcmocks.MockMempoolPayload},
+			}
+			optedIn := make(chan watch.SubscriptionPayload, 1)
+			optedOut := make(chan watch.SubscriptionPayload, 1)
+			processor := &watch.Service{
+				Streamer:    &mocks2.PayloadStreamer{ReturnSub: &rpc.ClientSubscription{}},
+				Filterer:    btc.NewResponseFilterer(),
+				PayloadChan: make(chan shared.RawChainData),
+				Mempool:     mockMempool,
+				MempoolChan: make(chan shared.UnconfirmedData, 1),
+				QuitChan:    quitChan,
+				Subscriptions: map[common.Hash]map[rpc.ID]watch.Subscription{
+					common.HexToHash("0x01"): {"optedIn": {ID: "optedIn", PayloadChan: optedIn}},
+					common.HexToHash("0x02"): {"optedOut": {ID: "optedOut", PayloadChan: optedOut}},
+				},
+				SubscriptionTypes: map[common.Hash]shared.SubscriptionSettings{
+					common.HexToHash("0x01"): &btc.SubscriptionSettings{Start: big.NewInt(0), End: big.NewInt(0), Unconfirmed: true},
+					common.HexToHash("0x02"): &btc.SubscriptionSettings{Start: big.NewInt(0), End: big.NewInt(0)},
+				},
+			}
+			servePayload := make(chan shared.ConvertedData, 1)
+			err := processor.Sync(wg, servePayload)
+			Expect(err).ToNot(HaveOccurred())
+			processor.Serve(wg, servePayload)
+
+			var payload watch.SubscriptionPayload
+			Eventually(optedIn).Should(Receive(&payload))
+			Expect(payload.Unconfirmed()).To(BeTrue())
+			var response btc.MempoolIPLDs
+			err = rlp.DecodeBytes(payload.Data, &response)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(response.Transactions)).To(Equal(len(btcmocks.MockMempoolPayload.Txs)))
+			Consistently(optedOut).ShouldNot(Receive())
+			close(quitChan)
+			wg.Wait()
+		})
 	})
 })
@@ -27,6 +27,8 @@ type Flag int32
 const (
 	EmptyFlag Flag = iota
 	BackFillCompleteFlag
+	ReorgFlag
+	UnconfirmedFlag
 )
 
 // Subscription holds the information for an individual client subscription to the watcher
@@ -58,3 +60,20 @@ func (sp SubscriptionPayload) BackFillComplete() bool {
 	}
 	return false
 }
+
+// Reorg returns true if the payload is a reorg notice
+// When it is, data previously sent to the subscription at and above the payload's Height has been replaced
+func (sp SubscriptionPayload) Reorg() bool {
+	if sp.Flag == ReorgFlag {
+		return true
+	}
+	return false
+}
+
+// Unconfirmed returns true if the payload carries transactions which have entered the mempool but have not been confirmed
+func (sp SubscriptionPayload) Unconfirmed() bool {
+	if sp.Flag == UnconfirmedFlag {
+		return true
+	}
+	return false
+}